@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/urfave/cli"
+)
+
+func GetSnapshotCommand() cli.Command {
+	dataDirFlag := cli.StringFlag{
+		Name:  "dataDir, d",
+		Usage: "Data directory of the miner's database",
+		Value: "NodeA_deprecated",
+	}
+	fileFlag := cli.StringFlag{
+		Name:  "file",
+		Usage: "the snapshot `FILE` name",
+	}
+
+	return cli.Command{
+		Name:  "snapshot",
+		Usage: "export or import the state of a closed epoch block",
+		Subcommands: []cli.Command{
+			{
+				Name:  "export",
+				Usage: "write the last closed epoch block of the local database to a snapshot file",
+				Action: func(c *cli.Context) error {
+					return exportSnapshot(c.String("dataDir"), c.String("file"))
+				},
+				Flags: []cli.Flag{dataDirFlag, fileFlag},
+			},
+			{
+				Name:  "import",
+				Usage: "verify a snapshot file and write it to an empty local database, the miner then starts from its epoch block",
+				Action: func(c *cli.Context) error {
+					return importSnapshot(c.String("dataDir"), c.String("file"), c.String("hash"), c.String("genesis"))
+				},
+				Flags: []cli.Flag{
+					dataDirFlag,
+					fileFlag,
+					cli.StringFlag{
+						Name:  "hash",
+						Usage: "only import the snapshot if it holds the epoch block with this `HASH` (hex), e.g. the one of a trusted node",
+					},
+					cli.StringFlag{
+						Name:  "genesis",
+						Usage: "only import the snapshot if it holds the genesis with this `HASH` (hex)",
+					},
+				},
+			},
+		},
+	}
+}
+
+func exportSnapshot(dataDirectory string, filename string) error {
+	if len(filename) == 0 {
+		return errors.New("argument missing: file")
+	}
+	if _, err := os.Stat(dataDirectory + "/" + database); os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("no database found in %v", dataDirectory))
+	}
+
+	store, err := storage.Init(dataDirectory+"/"+database, "")
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, err := miner.ExportSnapshot(store)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filename, snapshot.Encode(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot exported successfully.\n%v", snapshot.String())
+	return nil
+}
+
+func importSnapshot(dataDirectory string, filename string, hash string, genesis string) error {
+	if len(filename) == 0 {
+		return errors.New("argument missing: file")
+	}
+
+	//The snapshot can only be trusted as far as the hashes it is checked against.
+	epochBlockHash, err := parseHash("hash", hash)
+	if err != nil {
+		return err
+	}
+	genesisHash, err := parseHash("genesis", genesis)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var snapshot *protocol.Snapshot
+	if snapshot, err = snapshot.Decode(encoded); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dataDirectory, 0755); err != nil {
+		return err
+	}
+
+	store, err := storage.Init(dataDirectory+"/"+database, "")
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := miner.ImportSnapshot(store, snapshot, epochBlockHash, genesisHash); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot imported successfully.\n%v", snapshot.String())
+	return nil
+}
+
+func parseHash(name string, hash string) (parsed [32]byte, err error) {
+	if len(hash) == 0 {
+		return parsed, errors.New(fmt.Sprintf("argument missing: %v", name))
+	}
+
+	decoded, err := hex.DecodeString(hash)
+	if err != nil || len(decoded) != len(parsed) {
+		return parsed, errors.New(fmt.Sprintf("argument invalid: %v %v", name, hash))
+	}
+	copy(parsed[:], decoded)
+
+	return parsed, nil
+}
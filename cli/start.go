@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"crypto/ecdsa"
 	"fmt"
 	"os"
 
@@ -13,10 +12,22 @@ import (
 	"github.com/urfave/cli"
 )
 
+//The files in the data directory of a miner.
+const (
+	database   = "store.db"
+	wallet     = "wallet.key"
+	commitment = "commitment.key"
+)
+
 type startArgs struct {
-	dataDirectory        string
-	myNodeAddress        string
-	bootstrapNodeAddress string
+	dataDirectory          string
+	myNodeAddress          string
+	bootstrapNodeAddress   string
+	partitionedState       bool
+	wipe                   bool
+	keptEpochs             int
+	memPoolCapacity        int
+	memPoolAccountCapacity int
 }
 
 func GetStartCommand() cli.Command {
@@ -25,9 +36,14 @@ func GetStartCommand() cli.Command {
 		Usage: "start the miner",
 		Action: func(c *cli.Context) error {
 			args := &startArgs{
-				dataDirectory:        	c.String("dataDir"),
-				myNodeAddress:        	c.String("address"),
-				bootstrapNodeAddress: 	c.String("bootstrap"),
+				dataDirectory:          c.String("dataDir"),
+				myNodeAddress:          c.String("address"),
+				bootstrapNodeAddress:   c.String("bootstrap"),
+				partitionedState:       c.Bool("partitioned"),
+				wipe:                   c.Bool("wipe"),
+				keptEpochs:             c.Int("prune"),
+				memPoolCapacity:        c.Int("mempool"),
+				memPoolAccountCapacity: c.Int("mempoolPerAccount"),
 			}
 
 			if !c.IsSet("bootstrap") {
@@ -63,6 +79,28 @@ func GetStartCommand() cli.Command {
 				Usage: "Connect to bootstrap node at `IP:PORT`",
 				Value: "localhost:8000",
 			},
+			cli.BoolFlag{
+				Name:  "partitioned",
+				Usage: "Only keep the accounts of the own shard, must be set on all miners of the network",
+			},
+			cli.BoolFlag{
+				Name:  "wipe",
+				Usage: "Delete the chain and the mempool of the local database, the miner then syncs from scratch",
+			},
+			cli.IntFlag{
+				Name:  "prune",
+				Usage: "Only keep the blocks and transactions of the last `N` epochs, 0 keeps the whole chain",
+			},
+			cli.IntFlag{
+				Name:  "mempool",
+				Usage: "Keep at most `N` open transactions, those with the lowest fee rate are evicted first, 0 disables the limit",
+				Value: storage.MemPoolCapacity,
+			},
+			cli.IntFlag{
+				Name:  "mempoolPerAccount",
+				Usage: "Keep at most `N` open transactions per account, 0 disables the limit",
+				Value: storage.MemPoolAccountCapacity,
+			},
 			cli.BoolFlag{
 				Name:  "confirm",
 				Usage: "User must press enter before starting the miner",
@@ -81,17 +119,25 @@ func Start(args *startArgs) error {
 		//firstStart = true
 	}
 
-	const (
-		database       = "store.db"
-		wallet         = "wallet.key"
-		commitment     = "commitment.key"
-	)
+	storage.MemPoolCapacity = args.memPoolCapacity
+	storage.MemPoolAccountCapacity = args.memPoolAccountCapacity
 
-	storage.Init(args.dataDirectory+"/" + database, args.bootstrapNodeAddress)
-	p2p.Init(args.myNodeAddress)
+	//Without a wipe, the miner resumes with the chain of its local database. The wipe happens before the database is
+	//opened and migrated, such that it also recovers a database which cannot be migrated.
+	if args.wipe {
+		if err := storage.WipeBoltStore(args.dataDirectory + "/" + database); err != nil {
+			return err
+		}
+	}
+	store, err := storage.Init(args.dataDirectory+"/"+database, args.bootstrapNodeAddress)
+	if err != nil {
+		return err
+	}
+	p2p.Init(args.myNodeAddress, store)
+	storage.PartitionedState = args.partitionedState
+	miner.KeptEpochs = args.keptEpochs
 
-	var validatorPubKey *ecdsa.PublicKey
-	var err error
+	var validatorPubKey crypto.PublicKey
 
 	//if(p2p.IsBootstrap()){
 	//	validatorPubKey, err = crypto.ExtractECDSAPublicKeyFromFile("walletMinerA.key")
@@ -99,7 +145,7 @@ func Start(args *startArgs) error {
 	//	validatorPubKey, err = crypto.ExtractECDSAPublicKeyFromFile(args.dataDirectory + "/" + wallet)
 	//}
 
-	validatorPubKey, err = crypto.ExtractECDSAPublicKeyFromFile(args.dataDirectory + "/" + wallet)
+	validatorPubKey, err = crypto.ExtractPublicKeyFromFile(args.dataDirectory + "/" + wallet)
 	//validatorPubKey, err := crypto.ExtractECDSAPublicKeyFromFile("walletMinerA.key")
 	if err != nil {
 		return err
@@ -112,10 +158,10 @@ func Start(args *startArgs) error {
 
 	// Check if executor is root and if it's the first start
 	//if p2p.IsBootstrap() && firstStart {
-	if p2p.IsBootstrap(){
-		return miner.InitFirstStart(validatorPubKey, commPrivKey)
+	if p2p.IsBootstrap() {
+		return miner.InitFirstStart(store, validatorPubKey, commPrivKey)
 	} else {
-		return miner.Init(validatorPubKey, commPrivKey)
+		return miner.Init(store, validatorPubKey, commPrivKey)
 	}
 
 	return nil
@@ -133,6 +179,14 @@ func (args startArgs) ValidateInput() error {
 	if len(args.bootstrapNodeAddress) == 0 {
 		return errors.New("argument missing: bootstrapNodeAddress")
 	}
+
+	if args.keptEpochs < 0 {
+		return errors.New("argument invalid: prune must not be negative")
+	}
+
+	if args.memPoolCapacity < 0 || args.memPoolAccountCapacity < 0 {
+		return errors.New("argument invalid: mempool limits must not be negative")
+	}
 	return nil
 }
 
@@ -140,8 +194,17 @@ func (args startArgs) String() string {
 	return fmt.Sprintf("Starting bazo miner with arguments \n"+
 		"- My Address:\t\t\t %v\n"+
 		"- Bootstrap Address:\t\t %v\n"+
-		"- Data Directory:\t\t %v\n",
+		"- Data Directory:\t\t %v\n"+
+		"- Partitioned State:\t\t %v\n"+
+		"- Wipe Database:\t\t %v\n"+
+		"- Kept Epochs:\t\t\t %v\n"+
+		"- Mempool Limits:\t\t %v (%v per account)\n",
 		args.myNodeAddress,
 		args.bootstrapNodeAddress,
-		args.dataDirectory)
+		args.dataDirectory,
+		args.partitionedState,
+		args.wipe,
+		args.keptEpochs,
+		args.memPoolCapacity,
+		args.memPoolAccountCapacity)
 }
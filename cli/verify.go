@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/urfave/cli"
+)
+
+func GetVerifyCommand() cli.Command {
+	return cli.Command{
+		Name:  "verify",
+		Usage: "verify the chain of the local database offline and report the first block which does not verify",
+		Action: func(c *cli.Context) error {
+			return verifyChain(c.String("dataDir"))
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dataDir, d",
+				Usage: "Data directory of the miner's database",
+				Value: "NodeA_deprecated",
+			},
+		},
+	}
+}
+
+func verifyChain(dataDirectory string) error {
+	if _, err := os.Stat(dataDirectory + "/" + database); os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("no database found in %v", dataDirectory))
+	}
+
+	//The database is locked by a running miner, stop it first.
+	store, err := storage.NewReadOnlyBoltStore(dataDirectory + "/" + database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := miner.VerifyChain(store)
+	if report != nil {
+		fmt.Println(report)
+	}
+	if _, ok := err.(*miner.ChainMismatch); ok {
+		return errors.New(fmt.Sprintf("first mismatch: %v", err))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Chain verified.")
+	return nil
+}
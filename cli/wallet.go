@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"os"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/urfave/cli"
 )
@@ -12,20 +16,54 @@ func GetGenerateWalletCommand() cli.Command {
 		Usage:	"generate a new pair of wallet keys",
 		Action:	func(c *cli.Context) error {
 			filename := c.String("file")
-			privKey, err := crypto.ExtractECDSAKeyFromFile(filename)
+
+			switch c.String("scheme") {
+			case "", "ecdsa":
+			case "ed25519":
+				//Existing key files are kept, like ECDSA key files.
+				if _, err := os.Stat(filename); os.IsNotExist(err) {
+					if err := crypto.CreateEd25519KeyFile(filename); err != nil {
+						return err
+					}
+				}
+			default:
+				return errors.New(fmt.Sprintf("unknown signature scheme: %v", c.String("scheme")))
+			}
+
+			privKey, err := crypto.ExtractKeyFromFile(filename)
+			if err != nil {
+				return err
+			}
+
+			address, err := crypto.GetAddressFromPrivKey(privKey)
+			if err != nil {
+				return err
+			}
 
 			fmt.Printf("Wallet generated successfully.\n")
-			fmt.Printf("PubKeyX: %x\n", privKey.PublicKey.X)
-			fmt.Printf("PubKeyY: %x\n", privKey.PublicKey.Y)
-			fmt.Printf("PrivKey: %x\n", privKey.D)
+			switch key := privKey.(type) {
+			case *ecdsa.PrivateKey:
+				fmt.Printf("PubKeyX: %x\n", key.PublicKey.X)
+				fmt.Printf("PubKeyY: %x\n", key.PublicKey.Y)
+				fmt.Printf("PrivKey: %x\n", key.D)
+			case ed25519.PrivateKey:
+				fmt.Printf("PubKey: %x\n", key.Public())
+				fmt.Printf("PrivKey: %x\n", key.Seed())
+			}
+			fmt.Printf("Address: %x\n", address)
 
-			return err
+			return nil
 		},
 		Flags:	[]cli.Flag {
 			cli.StringFlag {
 				Name: 	"file",
 				Usage: 	"the new key's `FILE` name",
 			},
+			cli.StringFlag {
+				Name: 	"scheme",
+				Usage: 	"the signature `SCHEME` of the new key (ecdsa or ed25519)",
+				Value:	"ecdsa",
+			},
 		},
 	}
 }
@@ -3,8 +3,10 @@ package crypto
 import (
 	"bufio"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -13,6 +15,78 @@ import (
 	"strings"
 )
 
+//Ed25519 key files start with this line, followed by the public key and the seed of the private key (hex). ECDSA key
+//files have no such line.
+const ED25519_KEY_FILE_HEADER = "ed25519"
+
+//Reads a key of any supported scheme. If the file does not exist, a new ECDSA key is created.
+func ExtractKeyFromFile(filename string) (privKey PrivateKey, err error) {
+	isEd25519, err := isEd25519KeyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEd25519 {
+		return ExtractECDSAKeyFromFile(filename)
+	}
+
+	lines := ReadFile(filename)
+	if len(lines) < 3 {
+		return nil, errors.New("Could not read key from file: Ed25519 key is incomplete.")
+	}
+
+	seed, err := hex.DecodeString(lines[2])
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("Could not read key from file: invalid Ed25519 seed.")
+	}
+
+	ed25519Key := ed25519.NewKeyFromSeed(seed)
+	if hex.EncodeToString(ed25519Key.Public().(ed25519.PublicKey)) != lines[1] {
+		return nil, errors.New("The Ed25519 public key does not match the private key.")
+	}
+
+	return ed25519Key, nil
+}
+
+//Reads the public key of any supported scheme. If the file does not exist, a new ECDSA key is created.
+func ExtractPublicKeyFromFile(filename string) (pubKey PublicKey, err error) {
+	isEd25519, err := isEd25519KeyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEd25519 {
+		return ExtractECDSAPublicKeyFromFile(filename)
+	}
+
+	lines := ReadFile(filename)
+	if len(lines) < 2 {
+		return nil, errors.New("Could not read key from file: Ed25519 key is incomplete.")
+	}
+
+	ed25519PubKey, err := hex.DecodeString(lines[1])
+	if err != nil || len(ed25519PubKey) != ed25519.PublicKeySize {
+		return nil, errors.New("Could not read key from file: invalid Ed25519 public key.")
+	}
+
+	return ed25519.PublicKey(ed25519PubKey), nil
+}
+
+func isEd25519KeyFile(filename string) (bool, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	filehandle, err := os.Open(filename)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("%v", err))
+	}
+	defer filehandle.Close()
+
+	header, _ := bufio.NewReader(filehandle).ReadString('\n')
+	return strings.TrimSpace(header) == ED25519_KEY_FILE_HEADER, nil
+}
+
 func ExtractECDSAKeyFromFile(filename string) (privKey *ecdsa.PrivateKey, err error) {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		err = CreateECDSAKeyFile(filename)
@@ -125,8 +199,8 @@ func ReadFile(filename string) (lines []string) {
 }
 
 func GetAddressFromPubKey(pubKey *ecdsa.PublicKey) (address [64]byte) {
-	copy(address[:32], pubKey.X.Bytes())
-	copy(address[32:], pubKey.Y.Bytes())
+	copy(address[32-len(pubKey.X.Bytes()):32], pubKey.X.Bytes())
+	copy(address[64-len(pubKey.Y.Bytes()):], pubKey.Y.Bytes())
 
 	return address
 }
@@ -187,3 +261,39 @@ func CreateECDSAKeyFile(filename string) (err error) {
 
 	return nil
 }
+
+//Creates a key file for the given scheme (see SCHEME_*).
+func CreateKeyFile(filename string, scheme byte) error {
+	switch scheme {
+	case SCHEME_ECDSA_P256:
+		return CreateECDSAKeyFile(filename)
+	case SCHEME_ED25519:
+		return CreateEd25519KeyFile(filename)
+	default:
+		return errors.New(fmt.Sprintf("Signature scheme %d is not supported.", scheme))
+	}
+}
+
+func CreateEd25519KeyFile(filename string) (err error) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(filename); !os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("Key file %v already exists.", filename))
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(ED25519_KEY_FILE_HEADER + "\n" + hex.EncodeToString(pubKey) + "\n" + hex.EncodeToString(privKey.Seed()) + "\n")
+	if err != nil {
+		return errors.New("failed to write key to file")
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+//Signature schemes of account keys. The scheme is tagged in the last byte of the address, all bytes between the key
+//and the tag are zero. P-256 addresses are the raw X || Y coordinates of the public key and carry no tag.
+const (
+	SCHEME_ECDSA_P256 = 0
+	SCHEME_ED25519    = 1
+)
+
+//Private keys of all supported schemes: *ecdsa.PrivateKey or ed25519.PrivateKey.
+type PrivateKey interface{}
+
+//Public keys of all supported schemes: *ecdsa.PublicKey or ed25519.PublicKey.
+type PublicKey interface{}
+
+func GetAddressScheme(address [64]byte) byte {
+	for _, b := range address[ed25519.PublicKeySize:63] {
+		if b != 0 {
+			return SCHEME_ECDSA_P256
+		}
+	}
+
+	return address[63]
+}
+
+func GetAddressFromEd25519PubKey(pubKey ed25519.PublicKey) (address [64]byte) {
+	copy(address[:ed25519.PublicKeySize], pubKey)
+	address[63] = SCHEME_ED25519
+
+	return address
+}
+
+func GetAddress(pubKey PublicKey) (address [64]byte, err error) {
+	switch key := pubKey.(type) {
+	case *ecdsa.PublicKey:
+		return GetAddressFromPubKey(key), nil
+	case ed25519.PublicKey:
+		return GetAddressFromEd25519PubKey(key), nil
+	default:
+		return address, errors.New(fmt.Sprintf("Public key type %T is not supported.", pubKey))
+	}
+}
+
+func GetAddressFromPrivKey(privKey PrivateKey) (address [64]byte, err error) {
+	switch key := privKey.(type) {
+	case *ecdsa.PrivateKey:
+		return GetAddressFromPubKey(&key.PublicKey), nil
+	case ed25519.PrivateKey:
+		return GetAddressFromEd25519PubKey(key.Public().(ed25519.PublicKey)), nil
+	default:
+		return address, errors.New(fmt.Sprintf("Private key type %T is not supported.", privKey))
+	}
+}
+
+//ECDSA signatures are R || S, each padded to 32 bytes. Ed25519 signatures are 64 bytes long.
+func Sign(privKey PrivateKey, hash []byte) (sig [64]byte, err error) {
+	switch key := privKey.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, hash)
+		if err != nil {
+			return sig, err
+		}
+
+		copy(sig[32-len(r.Bytes()):32], r.Bytes())
+		copy(sig[64-len(s.Bytes()):], s.Bytes())
+	case ed25519.PrivateKey:
+		copy(sig[:], ed25519.Sign(key, hash))
+	default:
+		return sig, errors.New(fmt.Sprintf("Private key type %T is not supported.", privKey))
+	}
+
+	return sig, nil
+}
+
+//Verifies the signature with the key of the scheme the address is tagged with.
+func VerifySignature(address [64]byte, hash []byte, sig [64]byte) bool {
+	switch GetAddressScheme(address) {
+	case SCHEME_ECDSA_P256:
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(GetPubKeyFromAddress(address), hash, r, s)
+	case SCHEME_ED25519:
+		return ed25519.Verify(ed25519.PublicKey(address[:ed25519.PublicKeySize]), hash, sig[:])
+	default:
+		return false
+	}
+}
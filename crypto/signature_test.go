@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"testing"
+)
+
+func TestSignAndVerifySchemes(t *testing.T) {
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	hash := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, privKey := range []PrivateKey{ecdsaKey, ed25519Key} {
+		address, err := GetAddressFromPrivKey(privKey)
+		if err != nil {
+			t.Fatalf("Address could not be derived: %v\n", err)
+		}
+
+		sig, err := Sign(privKey, hash)
+		if err != nil {
+			t.Fatalf("Hash could not be signed: %v\n", err)
+		}
+
+		if !VerifySignature(address, hash, sig) {
+			t.Errorf("Signature of %T could not be verified.\n", privKey)
+		}
+
+		sig[0] ^= 0xff
+		if VerifySignature(address, hash, sig) {
+			t.Errorf("Modified signature of %T has been verified.\n", privKey)
+		}
+	}
+
+	if scheme := GetAddressScheme(GetAddressFromPubKey(&ecdsaKey.PublicKey)); scheme != SCHEME_ECDSA_P256 {
+		t.Errorf("P-256 address has scheme %d.\n", scheme)
+	}
+
+	ed25519Address := GetAddressFromEd25519PubKey(ed25519Key.Public().(ed25519.PublicKey))
+	if scheme := GetAddressScheme(ed25519Address); scheme != SCHEME_ED25519 {
+		t.Errorf("Ed25519 address has scheme %d.\n", scheme)
+	}
+
+	//A signature of one scheme is not valid for the address of another scheme.
+	sig, _ := Sign(ed25519Key, hash)
+	if VerifySignature(GetAddressFromPubKey(&ecdsaKey.PublicKey), hash, sig) {
+		t.Error("Ed25519 signature has been verified with a P-256 address.\n")
+	}
+}
+
+func TestEd25519KeyFile(t *testing.T) {
+	os.Remove(KEY_TEST_FILE)
+	defer os.Remove(KEY_TEST_FILE)
+
+	if err := CreateKeyFile(KEY_TEST_FILE, SCHEME_ED25519); err != nil {
+		t.Fatalf("Ed25519 key file could not be created: %v\n", err)
+	}
+
+	privKey, err := ExtractKeyFromFile(KEY_TEST_FILE)
+	if err != nil {
+		t.Fatalf("Ed25519 key could not be extracted: %v\n", err)
+	}
+
+	pubKey, err := ExtractPublicKeyFromFile(KEY_TEST_FILE)
+	if err != nil {
+		t.Fatalf("Ed25519 public key could not be extracted: %v\n", err)
+	}
+
+	privAddress, _ := GetAddressFromPrivKey(privKey)
+	pubAddress, _ := GetAddress(pubKey)
+	if privAddress != pubAddress || GetAddressScheme(pubAddress) != SCHEME_ED25519 {
+		t.Errorf("Ed25519 key file addresses differ: %x vs. %x\n", privAddress, pubAddress)
+	}
+
+	if err := CreateEd25519KeyFile(KEY_TEST_FILE); err == nil {
+		t.Error("Existing key file has been overwritten.\n")
+	}
+}
@@ -22,6 +22,8 @@ func main() {
 		cli.GetStartCommand(),
 		cli.GetGenerateWalletCommand(),
 		cli.GetGenerateCommitmentCommand(),
+		cli.GetSnapshotCommand(),
+		cli.GetVerifyCommand(),
 	}
 
 	err := app.Run(os.Args)
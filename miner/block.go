@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -16,12 +17,10 @@ import (
 )
 
 //Datastructure to fetch the payload of all transactions, needed for state validation.
+//The txs are kept by the id of their type, in the order of the block.
 type blockData struct {
-	contractTxSlice    []*protocol.ContractTx
-	fundsTxSlice  []*protocol.FundsTx
-	configTxSlice []*protocol.ConfigTx
-	stakeTxSlice  []*protocol.StakeTx
-	block         *protocol.Block
+	txs   map[byte][]protocol.Transaction
+	block *protocol.Block
 }
 
 //Block constructor, argument is the previous block in the blockchain.
@@ -53,13 +52,19 @@ func finalizeBlock(block *protocol.Block) error {
 	//Merkle tree includes the hashes of all txs.
 	block.MerkleRoot = protocol.BuildMerkleTree(block).MerkleRoot()
 
-	validatorAcc, err := storage.ReadAccount(validatorAccAddress)
+	validatorAcc, err := store.ReadAccount(validatorAccAddress)
 	if err != nil {
 		return err
 	}
 
 	copy(block.Beneficiary[:], validatorAcc.Address[:])
 
+	//The state root commits to the state after this block has been applied. If the block cannot be applied to the
+	//state of its predecessor, it is rejected by validate() anyway.
+	if block.MerklePatriciaRoot, err = postStateRoot(block); err != nil {
+		return errors.New(fmt.Sprintf("State root of block at height %d could not be calculated: %v", block.Height, err))
+	}
+
 	// Cryptographic Sortition for PoS in Bazo
 	// The commitment proof stores a signed message of the Height at which this block was created.
 	commitmentProof, err := crypto.SignMessageWithRSAKey(commPrivKey, fmt.Sprint(block.Height))
@@ -85,10 +90,10 @@ func finalizeBlock(block *protocol.Block) error {
 	block.Hash = sha3.Sum256(append(nonceBuf[:], partialHash[:]...))
 
 	//This doesn't need to be hashed, because we already have the merkle tree taking care of consistency.
-	block.NrContractTx = uint16(len(block.ContractTxData))
-	block.NrFundsTx = uint16(len(block.FundsTxData))
-	block.NrConfigTx = uint8(len(block.ConfigTxData))
-	block.NrStakeTx = uint16(len(block.StakeTxData))
+	block.NrContractTx = uint16(len(block.TxHashes(protocol.CONTRACTTX_TYPE)))
+	block.NrFundsTx = uint16(len(block.TxHashes(protocol.FUNDSTX_TYPE)))
+	block.NrConfigTx = uint8(len(block.TxHashes(protocol.CONFIGTX_TYPE)))
+	block.NrStakeTx = uint16(len(block.TxHashes(protocol.STAKETX_TYPE)))
 
 	copy(block.CommitmentProof[0:crypto.COMM_PROOF_LENGTH], commitmentProof[:])
 
@@ -100,7 +105,7 @@ func finalizeBlock(block *protocol.Block) error {
  */
 func finalizeEpochBlock(epochBlock *protocol.EpochBlock) error {
 
-	validatorAcc, err := storage.ReadAccount(validatorAccAddress)
+	validatorAcc, err := store.ReadAccount(validatorAccAddress)
 	if err != nil {
 		return err
 	}
@@ -113,7 +118,22 @@ func finalizeEpochBlock(epochBlock *protocol.EpochBlock) error {
 		return err
 	}
 
-	partialHash := epochBlock.HashEpochBlock()
+	//The epoch block carries the whole state, the root lets everybody check it against the persisted state trie.
+	//In partitioned mode, it only carries the staking accounts and the validators fetch the accounts of their shard.
+	epochState := storage.State
+	if storage.PartitionedState {
+		epochState = stakingState()
+	}
+	stateTrie := protocol.NewMemoryTrieDatabase()
+	epochBlock.MerklePatriciaRoot = protocol.BuildStateTrie(epochState, stateTrie)
+	if err := store.WriteStateTrie(stateTrie); err != nil {
+		return err
+	}
+
+	//The validators of the new shards check the accounts they fetch against the roots of the shard states.
+	if epochBlock.ShardStateRoots, err = shardStateRoots(epochBlock.Height - 1); err != nil {
+		return err
+	}
 
 	/*Determine new number of shards needed based on current state*/
 	NumberOfShards = DetNumberOfShards()
@@ -129,7 +149,10 @@ func finalizeEpochBlock(epochBlock *protocol.EpochBlock) error {
 
 	storage.ThisShardID = ValidatorShardMap.ValMapping[validatorAccAddress]
 
-	epochBlock.State = storage.State
+	//The mapping is random, it cannot be derived from the state by others. It is covered by the hash instead.
+	partialHash := epochBlock.HashEpochBlock()
+
+	epochBlock.State = epochState
 	FileLogger.Printf("Before Epoch Block proofofstake for height: %d\n",epochBlock.Height)
 
 	nonce, err := proofOfStakeEpoch(getDifficulty(), lastEpochBlock.Hash, epochBlock.Height, validatorAcc.Balance, commitmentProof)
@@ -159,8 +182,8 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	if tx.TxFee() < activeParameters.Fee_minimum {
 		logger.Printf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
 		FileLogger.Printf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
-		err := fmt.Sprintf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
-		return errors.New(err)
+		err := fmt.Sprintf("Transaction fee too low: %v (minimum is: %v)", tx.TxFee(), activeParameters.Fee_minimum)
+		return protocol.NewTxRejection(protocol.TXREJECT_FEE_TOO_LOW, err)
 	}
 
 	//There is a trade-off what tests can be made now and which have to be delayed (when dynamic state is needed
@@ -169,41 +192,26 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	//the address (public key of signature) in the transaction inside the tx -> would resulted in bigger tx size.
 	//So the trade-off is effectively clean abstraction vs. tx size. Everything related to fundsTx is postponed because
 	//the txs depend on each other.
-	if !verify(tx) {
-		logger.Printf("Transaction could not be verified: %v\n", tx)
-		FileLogger.Printf("Transaction could not be verified: %v\n", tx)
-		return errors.New("Transaction could not be verified.")
+	if err := verify(tx); err != nil {
+		logger.Printf("Transaction could not be verified (%v): %v\n", err, tx)
+		FileLogger.Printf("Transaction could not be verified (%v): %v\n", err, tx)
+		return err
 	}
 
-	switch tx.(type) {
-	case *protocol.ContractTx:
-		err := addContractTx(b, tx.(*protocol.ContractTx))
-		if err != nil {
-			FileLogger.Printf("Adding contractTx tx failed (%v): %v\n", err, tx.(*protocol.ContractTx))
-			return err
-		}
-	case *protocol.FundsTx:
-		err := addFundsTx(b, tx.(*protocol.FundsTx))
-		if err != nil {
-			FileLogger.Printf("Adding fundsTx tx failed (%v): %v\n", err, tx.(*protocol.FundsTx))
-			return err
-		}
-	case *protocol.ConfigTx:
-		err := addConfigTx(b, tx.(*protocol.ConfigTx))
-		if err != nil {
-			FileLogger.Printf("Adding configTx tx failed (%v): %v\n", err, tx.(*protocol.ConfigTx))
-			return err
-		}
-	case *protocol.StakeTx:
-		err := addStakeTx(b, tx.(*protocol.StakeTx))
-		if err != nil {
-			FileLogger.Printf("Adding stakeTx tx failed (%v): %v\n", err, tx.(*protocol.StakeTx))
-			return err
-		}
-	default:
-		return errors.New("Transaction type not recognized.")
+	txType := protocol.TypeOf(tx)
+	if txType == nil || txType.Add == nil {
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction type not recognized.")
 	}
 
+	if err := txType.Add(b, tx); err != nil {
+		FileLogger.Printf("Adding %v failed (%v): %v\n", txType.Name, err, tx)
+		return err
+	}
+
+	//Add the tx hash to the block header and write it to open storage (non-validated transactions).
+	b.AddTxHash(txType.Id, tx.Hash())
+	logger.Printf("Added tx to the %v hashes: (%x)\n", txType.Name, tx.Hash())
+	FileLogger.Printf("Added tx to the %v hashes: (%x)\n", txType.Name, tx.Hash())
 	return nil
 }
 
@@ -212,14 +220,10 @@ func addContractTx(b *protocol.Block, tx *protocol.ContractTx) error {
 	//set in the header (2nd bit).
 	if tx.Header&0x02 != 0x02 {
 		if _, exists := storage.State[tx.PubKey]; exists {
-			return errors.New("Account already exists.")
+			return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Account already exists.")
 		}
 	}
 
-	//Add the tx hash to the block header and write it to open storage (non-validated transactions).
-	b.ContractTxData = append(b.ContractTxData, tx.Hash())
-	logger.Printf("Added tx to the ContractTxData slice: (%x)\n", tx.Hash())
-	FileLogger.Printf("Added tx to the ContractTxData slice: (%x)\n", tx.Hash())
 	return nil
 }
 
@@ -238,17 +242,19 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 		}
 	}
 
-	//Vice versa for receiver account.
-	if _, exists := b.StateCopy[tx.To]; !exists {
-		if acc := storage.State[tx.To]; acc != nil {
-			if acc.Address == tx.To {
-				newAcc := protocol.Account{}
-				newAcc = *acc
-				b.StateCopy[tx.To] = &newAcc
+	//Vice versa for the receiver accounts (several in case of a batch transfer).
+	for _, recipient := range tx.Recipients() {
+		if _, exists := b.StateCopy[recipient.To]; !exists {
+			if acc := storage.State[recipient.To]; acc != nil {
+				if acc.Address == recipient.To {
+					newAcc := protocol.Account{}
+					newAcc = *acc
+					b.StateCopy[recipient.To] = &newAcc
+				}
+			} else {
+				newToAcc := protocol.NewAccount(recipient.To, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+				b.StateCopy[recipient.To] = &newToAcc
 			}
-		} else {
-			newToAcc := protocol.NewAccount(tx.To, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
-			b.StateCopy[tx.To] = &newToAcc
 		}
 	}
 
@@ -256,30 +262,46 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 	//fee + amount to spend as balance available.
 	if !storage.IsRootKey(tx.From) {
 		if (tx.Amount + tx.Fee) > b.StateCopy[tx.From].Balance {
-			return errors.New("Not enough funds to complete the transaction!")
+			return protocol.NewTxRejection(protocol.TXREJECT_INSUFFICIENT_FUNDS, "Not enough funds to complete the transaction!")
 		}
 	}
 
+	//The signatures of multi-signature accounts can only be checked once the sender account is known.
+	if err := verifyMultiSig(tx, b.StateCopy[tx.From]); err != nil {
+		return err
+	}
+
 	//Transaction count need to match the state, preventing replay attacks.
 	if b.StateCopy[tx.From].TxCnt != tx.TxCnt {
 		err := fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt)", tx.TxCnt, b.StateCopy[tx.From].TxCnt)
-		return errors.New(err)
+		return protocol.NewTxRejection(protocol.TXREJECT_NONCE_MISMATCH, err)
 	}
 
-	//Prevent balance overflow in receiver account.
-	if b.StateCopy[tx.To].Balance+tx.Amount > MAX_MONEY {
-		err := fmt.Sprintf("Transaction amount (%v) leads to overflow at receiver account balance (%v).\n", tx.Amount, b.StateCopy[tx.To].Balance)
-		return errors.New(err)
+	if err := verifyBatchShard(tx); err != nil {
+		return err
+	}
+
+	//The receiver of a cross-shard tx is credited by its own shard.
+	crossShard := isCrossShardTx(tx)
+
+	//Prevent balance overflow in receiver accounts.
+	if !crossShard {
+		for _, recipient := range tx.Recipients() {
+			if b.StateCopy[recipient.To].Balance+recipient.Amount > MAX_MONEY {
+				err := fmt.Sprintf("Transaction amount (%v) leads to overflow at receiver account balance (%v).", recipient.Amount, b.StateCopy[recipient.To].Balance)
+				return protocol.NewTxRejection(protocol.TXREJECT_BOUNDS_VIOLATION, err)
+			}
+		}
 	}
 
 	//Check if transaction has data and the receiver account has a smart contract
-	if tx.Data != nil && b.StateCopy[tx.To].Contract != nil {
+	if !crossShard && tx.Data != nil && b.StateCopy[tx.To].Contract != nil {
 		context := protocol.NewContext(*b.StateCopy[tx.To], *tx)
 		virtualMachine := vm.NewVM(context)
 
 		// Check if vm execution run without error
 		if !virtualMachine.Exec(false) {
-			return errors.New(virtualMachine.GetErrorMsg())
+			return protocol.NewTxRejection(protocol.TXREJECT_VM_ERROR, virtualMachine.GetErrorMsg())
 		}
 
 		//Update changes vm has made to the contract variables
@@ -291,21 +313,18 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 	accSender.TxCnt += 1
 	accSender.Balance -= tx.Amount
 
-	accReceiver := b.StateCopy[tx.To]
-	accReceiver.Balance += tx.Amount
+	if !crossShard {
+		for _, recipient := range tx.Recipients() {
+			accReceiver := b.StateCopy[recipient.To]
+			accReceiver.Balance += recipient.Amount
+		}
+	}
 
-	//Add the tx hash to the block header and write it to open storage (non-validated transactions).
-	b.FundsTxData = append(b.FundsTxData, tx.Hash())
-	logger.Printf("Added tx to the FundsTxData slice: (%x)\n", tx.Hash())
-	FileLogger.Printf("Added tx to the FundsTxData slice: %v\n", tx.String())
 	return nil
 }
 
 func addConfigTx(b *protocol.Block, tx *protocol.ConfigTx) error {
 	//No further checks needed, static checks were already done with verify().
-	b.ConfigTxData = append(b.ConfigTxData, tx.Hash())
-	logger.Printf("Added tx to the ConfigTxData slice: (%x)\n", tx.Hash())
-	FileLogger.Printf("Added tx to the ConfigTxData slice: (%x)\n", tx.Hash())
 	return nil
 }
 
@@ -329,13 +348,13 @@ func addStakeTx(b *protocol.Block, tx *protocol.StakeTx) error {
 	//fee + minimum amount that is required for staking.
 	if !storage.IsRootKey(tx.Account) {
 		if (tx.Fee + activeParameters.Staking_minimum) >= b.StateCopy[tx.Account].Balance {
-			return errors.New("Not enough funds to complete the transaction!")
+			return protocol.NewTxRejection(protocol.TXREJECT_INSUFFICIENT_FUNDS, "Not enough funds to complete the transaction!")
 		}
 	}
 
 	//Account has bool already set to the desired value.
 	if b.StateCopy[tx.Account].IsStaking == tx.IsStaking {
-		return errors.New("Account has bool already set to the desired value.")
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Account has bool already set to the desired value.")
 	}
 
 	//Update state copy.
@@ -344,195 +363,86 @@ func addStakeTx(b *protocol.Block, tx *protocol.StakeTx) error {
 	accSender.CommitmentKey = tx.CommitmentKey
 
 	//No further checks needed, static checks were already done with verify().
-	b.StakeTxData = append(b.StakeTxData, tx.Hash())
-	logger.Printf("Added tx to the StakeTxData slice: (%x)\n", tx.Hash())
-	FileLogger.Printf("Added tx to the StakeTxData slice: (%x)\n", tx.Hash())
 	return nil
 }
 
-//We use slices (not maps) because order is now important.
-func fetchContractTxData(block *protocol.Block, contractTxSlice []*protocol.ContractTx, initialSetup bool, errChan chan error) {
-	for cnt, txHash := range block.ContractTxData {
-		var tx protocol.Transaction
-		var contractTx *protocol.ContractTx
-
-		closedTx := storage.ReadClosedTx(txHash)
-		if closedTx != nil {
-			if initialSetup {
-				contractTx = closedTx.(*protocol.ContractTx)
-				contractTxSlice[cnt] = contractTx
-				continue
-			} else {
-				//Reject blocks that have txs which have already been validated.
-				errChan <- errors.New("Block validation had contractTx that was already in a previous block.")
-				return
-			}
-		}
-
-		//TODO Optimize code (duplicated)
-		//Tx is either in open storage or needs to be fetched from the network.
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			contractTx = tx.(*protocol.ContractTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.CONTRACTTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("ContractTx could not be read: %v", err))
-				return
-			}
-
-			//Blocking Wait
-			select {
-			case contractTx = <-p2p.ContractTxChan:
-				//Limit the waiting time for TXFETCH_TIMEOUT seconds.
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("ContractTx fetch timed out.")
-			}
-			//This check is important. A malicious miner might have sent us a tx whose hash is a different one
-			//from what we requested.
-			if contractTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
-		}
-
-		contractTxSlice[cnt] = contractTx
+//Fetches the txs of all registered tx types of the block. We fetch tx data for each type in parallel -> performance
+//boost.
+func fetchBlockTxs(block *protocol.Block, initialSetup bool) (map[byte][]protocol.Transaction, error) {
+	txs := make(map[byte][]protocol.Transaction)
+	errChan := make(chan error, len(protocol.TxTypes()))
+
+	for _, txType := range protocol.TxTypes() {
+		//We need to allocate slice space for the underlying array when we pass them as reference.
+		txSlice := make([]protocol.Transaction, len(block.TxHashes(txType.Id)))
+		txs[txType.Id] = txSlice
+		go fetchTxData(block, txType, txSlice, initialSetup, errChan)
 	}
 
-	errChan <- nil
-}
-
-func fetchFundsTxData(block *protocol.Block, fundsTxSlice []*protocol.FundsTx, initialSetup bool, errChan chan error) {
-	for cnt, txHash := range block.FundsTxData {
-		var tx protocol.Transaction
-		var fundsTx *protocol.FundsTx
-
-		closedTx := storage.ReadClosedTx(txHash)
-		if closedTx != nil {
-			if initialSetup {
-				fundsTx = closedTx.(*protocol.FundsTx)
-				fundsTxSlice[cnt] = fundsTx
-				continue
-			} else {
-				errChan <- errors.New("Block validation had fundsTx that was already in a previous block.")
-				return
-			}
-		}
-		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		txINVALID := storage.ReadINVALIDOpenTx(txHash)
-		if tx != nil {
-			fundsTx = tx.(*protocol.FundsTx)
-		} else if  txINVALID != nil && verify(txINVALID) {
-			fundsTx = txINVALID.(*protocol.FundsTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.FUNDSTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
-				return
-			}
-			select {
-			case fundsTx = <-p2p.FundsTxChan:
-				storage.WriteOpenTx(fundsTx)
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("FundsTx fetch timed out")
-				return
-			}
-			if fundsTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
+	//Wait for all goroutines to finish.
+	var err error
+	for range protocol.TxTypes() {
+		if fetchErr := <-errChan; fetchErr != nil && err == nil {
+			err = fetchErr
 		}
+	}
 
-		fundsTxSlice[cnt] = fundsTx
+	if err != nil {
+		return nil, err
 	}
 
-	errChan <- nil
+	return txs, nil
 }
 
-func fetchConfigTxData(block *protocol.Block, configTxSlice []*protocol.ConfigTx, initialSetup bool, errChan chan error) {
-	for cnt, txHash := range block.ConfigTxData {
+//We use slices (not maps) because order is now important.
+func fetchTxData(block *protocol.Block, txType *protocol.TxType, txSlice []protocol.Transaction, initialSetup bool, errChan chan error) {
+	for cnt, txHash := range block.TxHashes(txType.Id) {
 		var tx protocol.Transaction
-		var configTx *protocol.ConfigTx
 
-		closedTx := storage.ReadClosedTx(txHash)
+		closedTx := store.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				configTx = closedTx.(*protocol.ConfigTx)
-				configTxSlice[cnt] = configTx
+				txSlice[cnt] = closedTx
 				continue
 			} else {
-				errChan <- errors.New("Block validation had configTx that was already in a previous block.")
-				return
-			}
-		}
-
-		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			configTx = tx.(*protocol.ConfigTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.CONFIGTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("ConfigTx could not be read: %v", err))
-				return
-			}
-
-			select {
-			case configTx = <-p2p.ConfigTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("ConfigTx fetch timed out.")
+				//Reject blocks that have txs which have already been validated.
+				errChan <- errors.New(fmt.Sprintf("Block validation had %v that was already in a previous block.", txType.Name))
 				return
 			}
-			if configTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
 		}
 
-		configTxSlice[cnt] = configTx
-	}
-
-	errChan <- nil
-}
-
-func fetchStakeTxData(block *protocol.Block, stakeTxSlice []*protocol.StakeTx, initialSetup bool, errChan chan error) {
-	for cnt, txHash := range block.StakeTxData {
-		var tx protocol.Transaction
-		var stakeTx *protocol.StakeTx
-
-		closedTx := storage.ReadClosedTx(txHash)
-		if closedTx != nil {
-			if initialSetup {
-				stakeTx = closedTx.(*protocol.StakeTx)
-				stakeTxSlice[cnt] = stakeTx
-				continue
-			} else {
-				errChan <- errors.New("Block validation had stakeTx that was already in a previous block.")
-				return
-			}
+		//Tx is either in open storage or needs to be fetched from the network.
+		tx = store.ReadOpenTx(txHash)
+		txINVALID := store.ReadINVALIDOpenTx(txHash)
+		if tx == nil && txINVALID != nil && verify(txINVALID) == nil {
+			tx = txINVALID
 		}
 
-		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			stakeTx = tx.(*protocol.StakeTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.STAKETX_REQ)
+		if tx == nil {
+			err := p2p.TxReq(txHash, txType.ReqMsg)
 			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("StakeTx could not be read: %v", err))
+				errChan <- errors.New(fmt.Sprintf("%v could not be read: %v", txType.Name, err))
 				return
 			}
 
+			//Blocking Wait
 			select {
-			case stakeTx = <-p2p.StakeTxChan:
+			//The fetched tx is not written to the mempool, it is only verified with the block, which may be forged.
+			case tx = <-p2p.TxResChan(txType):
+				//Limit the waiting time for TXFETCH_TIMEOUT seconds.
 			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("StakeTx fetch timed out.")
+				errChan <- errors.New(fmt.Sprintf("%v fetch timed out.", txType.Name))
 				return
 			}
-			if stakeTx.Hash() != txHash {
+			//This check is important. A malicious miner might have sent us a tx whose hash is a different one
+			//from what we requested.
+			if tx.Hash() != txHash {
 				errChan <- errors.New("Received txHash did not correspond to our request.")
+				return
 			}
 		}
 
-		stakeTxSlice[cnt] = stakeTx
+		txSlice[cnt] = tx
 	}
 
 	errChan <- nil
@@ -548,6 +458,8 @@ func validate(b *protocol.Block, initialSetup bool) error {
 	//validated concurrently.
 	blockValidation.Lock()
 	defer blockValidation.Unlock()
+	//Runs before the unlock, the mempool queues the txs by the nonces of the new state.
+	defer updateMemPoolNonces()
 
 	//Prepare data structure to fill tx payloads.
 	blockDataMap := make(map[[32]byte]blockData)
@@ -573,7 +485,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 	if len(blocksToRollback) == 0 {
 		for _, block := range blocksToValidate {
 			//Fetching payload data from the txs (if necessary, ask other miners).
-			accTxs, fundsTxs, configTxs, stakeTxs, err := preValidate(block, initialSetup)
+			txs, err := preValidate(block, initialSetup)
 
 			//Check if the validator that added the block has previously voted on different competing chains (find slashing proof).
 			//The proof will be stored in the global slashing dictionary.
@@ -585,11 +497,17 @@ func validate(b *protocol.Block, initialSetup bool) error {
 				return err
 			}
 
-			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, block}
+			blockDataMap[block.Hash] = blockData{txs, block}
 			//Save state before and after validateState() in order to generate state transition which is sent to the other shards
 			var previousStateCopy = CopyState(storage.State)
 
-			if err := validateState(blockDataMap[block.Hash]); err != nil {
+			if !applyMinedBlockState(block, previousStateCopy) {
+				if err := validateState(blockDataMap[block.Hash]); err != nil {
+					return err
+				}
+			}
+
+			if err := validateStateRoot(block, previousStateCopy, initialSetup); err != nil {
 				return err
 			}
 
@@ -608,7 +526,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 		for _, block := range blocksToValidate {
 			FileLogger.Printf("Block to validate: (%x)\n", block.Hash[0:8])
 			//Fetching payload data from the txs (if necessary, ask other miners).
-			accTxs, fundsTxs, configTxs, stakeTxs, err := preValidate(block, initialSetup)
+			txs, err := preValidate(block, initialSetup)
 
 			//Check if the validator that added the block has previously voted on different competing chains (find slashing proof).
 			//The proof will be stored in the global slashing dictionary.
@@ -620,7 +538,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 				return err
 			}
 
-			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, block}
+			blockDataMap[block.Hash] = blockData{txs, block}
 			//Save state before and after validateState() in order to generate state transition which is sent to the other shards
 			var previousStateCopy = CopyState(storage.State)
 
@@ -628,6 +546,10 @@ func validate(b *protocol.Block, initialSetup bool) error {
 				return err
 			}
 
+			if err := validateStateRoot(block, previousStateCopy, initialSetup); err != nil {
+				return err
+			}
+
 			storage.RelativeState = storage.GetRelativeState(previousStateCopy,storage.State)
 
 			postValidate(blockDataMap[block.Hash], initialSetup)
@@ -655,79 +577,185 @@ func CopyState(state map[[64]byte]*protocol.Account) map[[64]byte]protocol.Accou
 	return copyState
 }
 
+//Puts the state back to the given copy. Accounts are updated in place because other data structures (e.g., the root
+//keys) hold pointers to them.
+func restoreState(state map[[64]byte]protocol.Account) {
+	for address := range storage.State {
+		if _, exists := state[address]; !exists {
+			delete(storage.State, address)
+		}
+	}
+
+	for address, acc := range state {
+		if current := storage.State[address]; current != nil {
+			*current = acc
+		} else {
+			restoredAcc := acc
+			storage.State[address] = &restoredAcc
+		}
+	}
+}
+
+//The nodes of the state trie of the miner. New nodes are kept in memory until they are written with the next validated
+//block, the others are read from the store.
+type trieNodes struct {
+	pending protocol.MemoryTrieDatabase
+}
+
+func (nodes *trieNodes) GetTrieNode(hash [32]byte) []byte {
+	if node := nodes.pending.GetTrieNode(hash); node != nil {
+		return node
+	}
+	return store.ReadStateTrieNode(hash)
+}
+
+func (nodes *trieNodes) PutTrieNode(hash [32]byte, node []byte) {
+	nodes.pending.PutTrieNode(hash, node)
+}
+
+var (
+	stateTrieNodes = &trieNodes{protocol.NewMemoryTrieDatabase()}
+	//The trie of the state, the root after a block is calculated from the accounts which changed since the last one.
+	stateTrie = protocol.NewStateTrie(stateTrieNodes)
+
+	//The state an own block results in, see postStateRoot().
+	minedBlockState struct {
+		block     *protocol.Block
+		prevState map[[64]byte]protocol.Account
+		postState map[[64]byte]protocol.Account
+	}
+)
+
+//Calculates the state root the block results in. The block is applied to the current state, which is restored
+//afterwards. The resulting state is kept, such that validate() does not apply the block a second time.
+func postStateRoot(block *protocol.Block) ([32]byte, error) {
+	txs, err := fetchBlockTxs(block, false)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	previousStateCopy := CopyState(storage.State)
+	defer restoreState(previousStateCopy)
+
+	//If the block is mined on top of a block that is not the last closed one (e.g., on a fork), the state of the
+	//predecessor is needed. The same rollback as in validate() is applied to the state, starting at the lastBlock.
+	var blocksToRollback []*protocol.Block
+	for tmpBlock := lastBlock; tmpBlock != nil && tmpBlock.Hash != block.PrevHash; tmpBlock = store.ReadClosedBlock(tmpBlock.PrevHash) {
+		blocksToRollback = append(blocksToRollback, tmpBlock)
+		if store.ReadClosedBlock(tmpBlock.PrevHash) == nil {
+			//The predecessor is not part of the closed chain (e.g., an epoch block), nothing to roll back.
+			blocksToRollback = nil
+		}
+	}
+
+	for _, tmpBlock := range blocksToRollback {
+		tmpTxs, err := preValidateRollback(tmpBlock)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		validateStateRollback(blockData{tmpTxs, tmpBlock})
+	}
+
+	if err := validateState(blockData{txs, block}); err != nil {
+		return [32]byte{}, err
+	}
+
+	if len(blocksToRollback) == 0 {
+		minedBlockState.block, minedBlockState.prevState, minedBlockState.postState = block, previousStateCopy, CopyState(storage.State)
+	}
+
+	return stateTrie.Root(storage.State), nil
+}
+
+//Takes over the state postStateRoot() calculated for an own block, if the state did not change since. Returns false if
+//the block must be applied with validateState().
+func applyMinedBlockState(block *protocol.Block, state map[[64]byte]protocol.Account) bool {
+	mined := minedBlockState
+	minedBlockState.block, minedBlockState.prevState, minedBlockState.postState = nil, nil, nil
+
+	if mined.block != block || !reflect.DeepEqual(mined.prevState, state) {
+		return false
+	}
+
+	restoreState(mined.postState)
+	return true
+}
+
+//Checks the state root of the block against the state after validateState(). If the root does not match, the state
+//is restored to the given copy. The trie of a valid block is persisted, such that the state at this height can be
+//proven later on.
+func validateStateRoot(block *protocol.Block, previousStateCopy map[[64]byte]protocol.Account, initialSetup bool) error {
+	if stateTrie.Root(storage.State) != block.MerklePatriciaRoot {
+		restoreState(previousStateCopy)
+		return errors.New("Merkle Patricia Root is incorrect.")
+	}
+
+	if !initialSetup {
+		err := store.WriteStateTrie(stateTrieNodes.pending)
+		stateTrieNodes.pending = protocol.NewMemoryTrieDatabase()
+		return err
+	}
+
+	return nil
+}
+
 //Doesn't involve any state changes.
-func preValidate(block *protocol.Block, initialSetup bool) (contractTxSlice []*protocol.ContractTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, err error) {
+func preValidate(block *protocol.Block, initialSetup bool) (txs map[byte][]protocol.Transaction, err error) {
 	//This dynamic check is only done if we're up-to-date with syncing, otherwise timestamp is not checked.
 	//Other miners (which are up-to-date) made sure that this is correct.
 	if !initialSetup && uptodate {
 		if err := timestampCheck(block.Timestamp); err != nil {
-			return nil, nil, nil, nil, err
+			return nil, err
 		}
 	}
 
 	//Check block size.
 	if block.GetSize() > activeParameters.Block_size {
-		return nil, nil, nil, nil, errors.New("Block size too large.")
+		return nil, errors.New("Block size too large.")
 	}
 
 	//Duplicates are not allowed, use tx hash hashmap to easily check for duplicates.
 	duplicates := make(map[[32]byte]bool)
-	for _, txHash := range block.ContractTxData {
-		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Account Transaction Hash detected.")
-		}
-		duplicates[txHash] = true
-	}
-	for _, txHash := range block.FundsTxData {
-		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Funds Transaction Hash detected.")
+	for _, txType := range protocol.TxTypes() {
+		for _, txHash := range block.TxHashes(txType.Id) {
+			if _, exists := duplicates[txHash]; exists {
+				return nil, errors.New(fmt.Sprintf("Duplicate %v Hash detected.", txType.Name))
+			}
+			duplicates[txHash] = true
 		}
-		duplicates[txHash] = true
 	}
-	for _, txHash := range block.ConfigTxData {
-		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Config Transaction Hash detected.")
+	for _, receipt := range block.Receipts {
+		if err := verifyReceipt(receipt, block, initialSetup); err != nil {
+			return nil, err
 		}
-		duplicates[txHash] = true
-	}
-	for _, txHash := range block.StakeTxData {
-		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Stake Transaction Hash detected.")
+		if _, exists := duplicates[receipt.Tx.Hash()]; exists {
+			return nil, errors.New("Duplicate Receipt detected.")
 		}
-		duplicates[txHash] = true
+		duplicates[receipt.Tx.Hash()] = true
 	}
 
-	//We fetch tx data for each type in parallel -> performance boost.
-	errChan := make(chan error, 4)
-
-	//We need to allocate slice space for the underlying array when we pass them as reference.
-	contractTxSlice = make([]*protocol.ContractTx, block.NrContractTx)
-	fundsTxSlice = make([]*protocol.FundsTx, block.NrFundsTx)
-	configTxSlice = make([]*protocol.ConfigTx, block.NrConfigTx)
-	stakeTxSlice = make([]*protocol.StakeTx, block.NrStakeTx)
-
-	go fetchContractTxData(block, contractTxSlice, initialSetup, errChan)
-	go fetchFundsTxData(block, fundsTxSlice, initialSetup, errChan)
-	go fetchConfigTxData(block, configTxSlice, initialSetup, errChan)
-	go fetchStakeTxData(block, stakeTxSlice, initialSetup, errChan)
+	//Fetching payload data from the txs (if necessary, ask other miners).
+	if txs, err = fetchBlockTxs(block, initialSetup); err != nil {
+		return nil, err
+	}
 
-	//Wait for all goroutines to finish.
-	for cnt := 0; cnt < 4; cnt++ {
-		err = <-errChan
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	//Txs can only be included within their validity window.
+	if err := verifyValidity(block.Height, blockData{txs, block}.allTxs()); err != nil {
+		return nil, err
 	}
 
 	//Check state contains beneficiary.
-	acc, err := storage.ReadAccount(block.Beneficiary)
+	acc, err := store.ReadAccount(block.Beneficiary)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, err
 	}
 
 	//Check if node is part of the validator set.
 	if !acc.IsStaking {
-		return nil, nil, nil, nil, errors.New(fmt.Sprintf("Validator (%x) is not part of the validator set.", acc.Address[0:8]))
+		return nil, errors.New(fmt.Sprintf("Validator (%x) is not part of the validator set.", acc.Address[0:8]))
 	}
 
 	//First, initialize an RSA Public Key instance with the modulus of the proposer of the block (acc)
@@ -735,12 +763,12 @@ func preValidate(block *protocol.Block, initialSetup bool) (contractTxSlice []*p
 	//Invalid if the commitment proof can not be verified with the public key of the proposer
 	commitmentPubKey, err := crypto.CreateRSAPubKeyFromBytes(acc.CommitmentKey)
 	if err != nil {
-		return nil, nil, nil, nil, errors.New("Invalid commitment key in account.")
+		return nil, errors.New("Invalid commitment key in account.")
 	}
 
 	err = crypto.VerifyMessageWithRSAKey(commitmentPubKey, fmt.Sprint(block.Height), block.CommitmentProof)
 	if err != nil {
-		return nil, nil, nil, nil, errors.New("The submitted commitment proof can not be verified.")
+		return nil, errors.New("The submitted commitment proof can not be verified.")
 	}
 
 	//Invalid if PoS calculation is not correct.
@@ -748,131 +776,159 @@ func preValidate(block *protocol.Block, initialSetup bool) (contractTxSlice []*p
 
 	//PoS validation
 	if !validateProofOfStake(getDifficulty(), prevProofs, block.Height, acc.Balance, block.CommitmentProof, block.Timestamp) {
-		return nil, nil, nil, nil, errors.New("The nonce is incorrect.")
+		return nil, errors.New("The nonce is incorrect.")
 	}
 
 	//Invalid if PoS is too far in the future.
 	now := time.Now()
 	if block.Timestamp > now.Unix()+int64(activeParameters.Accepted_time_diff) {
-		return nil, nil, nil, nil, errors.New("The timestamp is too far in the future. " + string(block.Timestamp) + " vs " + string(now.Unix()))
+		return nil, errors.New("The timestamp is too far in the future. " + string(block.Timestamp) + " vs " + string(now.Unix()))
 	}
 
 	//Check for minimum waiting time.
 	if block.Height-acc.StakingBlockHeight < uint32(activeParameters.Waiting_minimum) {
-		return nil, nil, nil, nil, errors.New("The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(activeParameters.Waiting_minimum))
+		return nil, errors.New("The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(activeParameters.Waiting_minimum))
 	}
 
 	//Check if block contains a proof for two conflicting block hashes, else no proof provided.
 	if block.SlashedAddress != [64]byte{} {
 		if _, err = slashingCheck(block.SlashedAddress, block.ConflictingBlockHash1, block.ConflictingBlockHash2); err != nil {
-			return nil, nil, nil, nil, err
+			return nil, err
 		}
 	}
 
 	//Merkle Tree validation
 	if protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
-		return nil, nil, nil, nil, errors.New("Merkle Root is incorrect.")
+		return nil, errors.New("Merkle Root is incorrect.")
 	}
 
-	return contractTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, err
+	return txs, err
 }
 
 //Dynamic state check.
-//The sequence of validation matters
+//The sequence of validation matters, the txs are applied in the order of the registered tx types.
 func validateState(data blockData) (err error) {
-	accStateChange(data.contractTxSlice)
-
-	err = fundsStateChange(data.fundsTxSlice)
-	if err != nil {
-		accStateChangeRollback(data.contractTxSlice)
-		return err
-	}
-
-	if err := stakeStateChange(data.stakeTxSlice, data.block.Height); err != nil {
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.contractTxSlice)
-		return err
+	var appliedTxTypes []*protocol.TxType
+	for _, txType := range protocol.TxTypes() {
+		if txType.Apply == nil {
+			continue
+		}
+		if err := txType.Apply(data.txs[txType.Id], data.block); err != nil {
+			txStateChangeRollback(data, appliedTxTypes)
+			return err
+		}
+		appliedTxTypes = append(appliedTxTypes, txType)
 	}
 
-	if err := collectTxFees(data.contractTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary); err != nil {
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.contractTxSlice)
+	if err := collectTxFees(data.allTxs(), data.block.Beneficiary); err != nil {
+		txStateChangeRollback(data, appliedTxTypes)
 		return err
 	}
 
 	if err := collectBlockReward(activeParameters.Block_reward, data.block.Beneficiary); err != nil {
-		collectTxFeesRollback(data.contractTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.contractTxSlice)
+		collectTxFeesRollback(data.allTxs(), data.block.Beneficiary)
+		txStateChangeRollback(data, appliedTxTypes)
 		return err
 	}
 
 	if err := collectSlashReward(activeParameters.Slash_reward, data.block); err != nil {
 		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.contractTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.contractTxSlice)
+		collectTxFeesRollback(data.allTxs(), data.block.Beneficiary)
+		txStateChangeRollback(data, appliedTxTypes)
 		return err
 	}
 
 	if err := updateStakingHeight(data.block); err != nil {
 		collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
 		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.contractTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-		stakeStateChangeRollback(data.stakeTxSlice)
-		fundsStateChangeRollback(data.fundsTxSlice)
-		accStateChangeRollback(data.contractTxSlice)
+		collectTxFeesRollback(data.allTxs(), data.block.Beneficiary)
+		txStateChangeRollback(data, appliedTxTypes)
 		return err
 	}
 
 	return nil
 }
 
+//Rolls back the state changes of the given tx types in reverse order.
+func txStateChangeRollback(data blockData, txTypes []*protocol.TxType) {
+	for cnt := len(txTypes) - 1; cnt >= 0; cnt-- {
+		if txTypes[cnt].Rollback != nil {
+			txTypes[cnt].Rollback(data.txs[txTypes[cnt].Id], data.block)
+		}
+	}
+}
 
 func postValidate(data blockData, initialSetup bool) {
 	//The new system parameters get active if the block was successfully validated
 	//This is done after state validation (in contrast to contractTx/fundsTx).
 	//Conversely, if blocks are rolled back, the system parameters are changed first.
-	configStateChange(data.configTxSlice, data.block.Hash)
+	configStateChange(data.configTxs(), data.block.Hash)
 	//Collects meta information about the block (and handled difficulty adaption).
 	collectStatistics(data.block)
 
 	if !initialSetup {
 		//Write all open transactions to closed/validated storage.
-		for _, tx := range data.contractTxSlice {
-			storage.WriteClosedTx(tx)
-			storage.DeleteOpenTx(tx)
+		for _, tx := range data.allTxs() {
+			store.WriteClosedTx(tx, data.block.Height)
+			store.DeleteOpenTx(tx)
 		}
 
-		for _, tx := range data.fundsTxSlice {
-			storage.WriteClosedTx(tx)
-			storage.DeleteOpenTx(tx)
+		if fundsTxs := data.fundsTxs(); len(fundsTxs) > 0 {
+			broadcastVerifiedTxs(fundsTxs)
 		}
 
-		for _, tx := range data.configTxSlice {
-			storage.WriteClosedTx(tx)
-			storage.DeleteOpenTx(tx)
+		//Credited receipts must not be included again.
+		for _, receipt := range data.block.Receipts {
+			store.WriteReceipt(receipt, data.block.Height)
+			deleteOpenReceipt(receipt.Tx.Hash())
 		}
 
-		for _, tx := range data.stakeTxSlice {
-			storage.WriteClosedTx(tx)
-			storage.DeleteOpenTx(tx)
+		//Txs whose validity window ended with this block can never be included anymore.
+		if expiredTxs := store.DeleteExpiredOpenTxs(data.block.Height); len(expiredTxs) > 0 {
+			FileLogger.Printf("Removed %d expired transactions from the mempool\n", len(expiredTxs))
 		}
 
-		if len(data.fundsTxSlice) > 0 {
-			broadcastVerifiedTxs(data.fundsTxSlice)
+		//A tx replaced by a higher fee tx in this mempool may have been included by another miner, the replacement can
+		//never be included then.
+		if conflictingTxs := store.DeleteStaleOpenTxs(usesNonceOf(data.allTxs())); len(conflictingTxs) > 0 {
+			FileLogger.Printf("Removed %d transactions with the nonce of an included transaction from the mempool\n", len(conflictingTxs))
 		}
 
 		//It might be that block is not in the openblock storage, but this doesn't matter.
-		storage.DeleteOpenBlock(data.block.Hash)
-		storage.WriteClosedBlock(data.block)
+		store.DeleteOpenBlock(data.block.Hash)
+		store.WriteClosedBlock(data.block)
 
 		// Write last block to db and delete last block's ancestor.
-		storage.DeleteAllLastClosedBlock()
-		storage.WriteLastClosedBlock(data.block)
+		store.DeleteAllLastClosedBlock()
+		store.WriteLastClosedBlock(data.block)
+	}
+}
+
+//Returns whether a tx has the nonce of one of the given txs of the same type and account.
+func usesNonceOf(txs []protocol.Transaction) func(tx protocol.Transaction) bool {
+	type nonceSlot struct {
+		txType  byte
+		account [64]byte
+		nonce   uint32
+	}
+	slotOf := func(tx protocol.Transaction) (nonceSlot, bool) {
+		txType := protocol.TypeOf(tx)
+		if txType == nil || txType.Nonce == nil || txType.ShardKey == nil {
+			return nonceSlot{}, false
+		}
+		return nonceSlot{txType.Id, txType.ShardKey(tx), txType.Nonce(tx)}, true
+	}
+
+	used := make(map[nonceSlot]bool)
+	for _, tx := range txs {
+		if slot, ok := slotOf(tx); ok {
+			used[slot] = true
+		}
+	}
+
+	return func(tx protocol.Transaction) bool {
+		slot, ok := slotOf(tx)
+		return ok && used[slot]
 	}
 }
 
@@ -904,9 +960,23 @@ func slashingCheck(slashedAddress [64]byte, conflictingBlockHash1, conflictingBl
 		return false, errors.New(fmt.Sprintf(prefix + "Conflicting block hashes are the same."))
 	}
 
+	//The validator signed an invalid state transition, the second hash is the one of the fraud proof.
+	if fraudProof := store.ReadFraudProof(conflictingBlockHash2); fraudProof != nil {
+		if fraudProof.Block.Hash != conflictingBlockHash1 || fraudProof.StateTransition.Validator != slashedAddress {
+			return false, errors.New(fmt.Sprintf(prefix + "Fraud proof does not match the slashed validator."))
+		}
+		if _, err := verifyFraudProof(fraudProof); err != nil {
+			return false, errors.New(fmt.Sprintf(prefix + "%v", err))
+		}
+
+		delete(slashingDict, slashedAddress)
+
+		return true, nil
+	}
+
 	//Fetch the blocks for the provided block hashes.
-	conflictingBlock1 := storage.ReadClosedBlock(conflictingBlockHash1)
-	conflictingBlock2 := storage.ReadClosedBlock(conflictingBlockHash2)
+	conflictingBlock1 := store.ReadClosedBlock(conflictingBlockHash1)
+	conflictingBlock2 := store.ReadClosedBlock(conflictingBlockHash2)
 
 	if IsInSameChain(conflictingBlock1, conflictingBlock2) {
 		return false, errors.New(fmt.Sprintf(prefix + "Conflicting block hashes are on the same chain."))
@@ -915,7 +985,7 @@ func slashingCheck(slashedAddress [64]byte, conflictingBlockHash1, conflictingBl
 	//TODO Optimize code (duplicated)
 	//If this block is unknown we need to check if its in the openblock storage or we must request it.
 	if conflictingBlock1 == nil {
-		conflictingBlock1 = storage.ReadOpenBlock(conflictingBlockHash1)
+		conflictingBlock1 = store.ReadOpenBlock(conflictingBlockHash1)
 		if conflictingBlock1 == nil {
 			//Fetch the block we apparently missed from the network.
 			p2p.BlockReq(conflictingBlockHash1)
@@ -923,7 +993,10 @@ func slashingCheck(slashedAddress [64]byte, conflictingBlockHash1, conflictingBl
 			//Blocking wait
 			select {
 			case encodedBlock := <-p2p.BlockReqChan:
-				conflictingBlock1 = conflictingBlock1.Decode(encodedBlock)
+				var err error
+				if conflictingBlock1, err = conflictingBlock1.Decode(encodedBlock); err != nil {
+					return false, errors.New(fmt.Sprintf(prefix + "Block with the provided conflicting hash (1) could not be decoded: %v", err))
+				}
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
 				return false, errors.New(fmt.Sprintf(prefix + "Could not find a block with the provided conflicting hash (1)."))
@@ -939,7 +1012,7 @@ func slashingCheck(slashedAddress [64]byte, conflictingBlockHash1, conflictingBl
 	//TODO Optimize code (duplicated)
 	//If this block is unknown we need to check if its in the openblock storage or we must request it.
 	if conflictingBlock2 == nil {
-		conflictingBlock2 = storage.ReadOpenBlock(conflictingBlockHash2)
+		conflictingBlock2 = store.ReadOpenBlock(conflictingBlockHash2)
 		if conflictingBlock2 == nil {
 			//Fetch the block we apparently missed from the network.
 			p2p.BlockReq(conflictingBlockHash2)
@@ -947,7 +1020,10 @@ func slashingCheck(slashedAddress [64]byte, conflictingBlockHash1, conflictingBl
 			//Blocking wait
 			select {
 			case encodedBlock := <-p2p.BlockReqChan:
-				conflictingBlock2 = conflictingBlock2.Decode(encodedBlock)
+				var err error
+				if conflictingBlock2, err = conflictingBlock2.Decode(encodedBlock); err != nil {
+					return false, errors.New(fmt.Sprintf(prefix + "Block with the provided conflicting hash (2) could not be decoded: %v", err))
+				}
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
 				return false, errors.New(fmt.Sprintf(prefix + "Could not find a block with the provided conflicting hash (2)."))
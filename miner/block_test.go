@@ -30,7 +30,9 @@ func TestBlock(t *testing.T) {
 
 	var decodedBlock *protocol.Block
 
-	decodedBlock = decodedBlock.Decode(encodedBlock)
+	if decodedBlock, err = decodedBlock.Decode(encodedBlock); err != nil {
+		t.Fatalf("Block decoding failed (%v)\n", err)
+	}
 
 	err = validate(decodedBlock, false)
 
@@ -40,16 +42,16 @@ func TestBlock(t *testing.T) {
 	if err != nil {
 		t.Errorf("Block validation failed (%v)\n", err)
 	}
-	if !reflect.DeepEqual(hashFundsSlice, decodedBlock.FundsTxData) {
+	if !reflect.DeepEqual(hashFundsSlice, decodedBlock.TxHashes(protocol.FUNDSTX_TYPE)) {
 		t.Error("FundsTx data is not properly serialized!")
 	}
-	if !reflect.DeepEqual(hashAccSlice, decodedBlock.ContractTxData) {
+	if !reflect.DeepEqual(hashAccSlice, decodedBlock.TxHashes(protocol.CONTRACTTX_TYPE)) {
 		t.Error("ContractTx data is not properly serialized!")
 	}
-	if !reflect.DeepEqual(hashConfigSlice, decodedBlock.ConfigTxData) {
+	if !reflect.DeepEqual(hashConfigSlice, decodedBlock.TxHashes(protocol.CONFIGTX_TYPE)) {
 		t.Error("ConfigTx data is not properly serialized!")
 	}
-	if !reflect.DeepEqual(hashStakeSlice, decodedBlock.StakeTxData) {
+	if !reflect.DeepEqual(hashStakeSlice, decodedBlock.TxHashes(protocol.STAKETX_TYPE)) {
 		t.Error("StakeTx data is not properly serialized!")
 	}
 	if !reflect.DeepEqual(b, decodedBlock) {
@@ -81,8 +83,8 @@ func TestBlockTxDuplicates(t *testing.T) {
 	}
 	t.Log(lastBlock)
 
-	if len(b.ConfigTxData) > 0 {
-		b.ConfigTxData = append(b.ConfigTxData, b.ConfigTxData[0])
+	if len(b.TxHashes(protocol.CONFIGTX_TYPE)) > 0 {
+		b.AddTxHash(protocol.CONFIGTX_TYPE, b.TxHashes(protocol.CONFIGTX_TYPE)[0])
 	}
 
 	if err := finalizeBlock(b); err != nil {
@@ -96,6 +98,109 @@ func TestBlockTxDuplicates(t *testing.T) {
 
 }
 
+//Txs can only be included within their validity window
+func TestBlockTxValidity(t *testing.T) {
+	cleanAndPrepare()
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	if err := tx.SetValidity(3, 5, PrivKeyAccA); err != nil {
+		t.Fatalf("Validity window could not be set: %v\n", err)
+	}
+	store.WriteOpenTx(tx)
+
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Tx could not be added: %v\n", err)
+	}
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Block finalization failed. (%v)\n", err)
+	}
+
+	if err := validate(b, false); err == nil {
+		t.Error("Block with a tx which is not valid yet has been validated.")
+	}
+
+	if err := verifyValidity(3, []protocol.Transaction{tx}); err != nil {
+		t.Errorf("Tx is not valid within its validity window: %v\n", err)
+	}
+
+	//Expired txs are removed from the mempool.
+	if expiredTxs := store.DeleteExpiredOpenTxs(4); len(expiredTxs) != 0 || store.ReadOpenTx(tx.Hash()) == nil {
+		t.Error("Tx has been removed from the mempool before its expiry.")
+	}
+	if expiredTxs := store.DeleteExpiredOpenTxs(5); len(expiredTxs) != 1 || store.ReadOpenTx(tx.Hash()) != nil {
+		t.Error("Expired tx has not been removed from the mempool.")
+	}
+}
+
+//Blocks must commit to the state after their application
+func TestBlockStateRoot(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	createBlockWithTxs(b)
+	if err := finalizeBlock(b); err != nil {
+		t.Errorf("Block finalization failed (%v)\n", err)
+		return
+	}
+
+	if b.MerklePatriciaRoot == [32]byte{} {
+		t.Error("Block does not commit to the state.")
+	}
+
+	//A block with a wrong state root must be rejected and must not change the state.
+	stateBefore := CopyState(storage.State)
+	root := b.MerklePatriciaRoot
+	b.MerklePatriciaRoot[0] ^= 0xff
+	if err := validate(b, false); err == nil {
+		t.Error("Block with an incorrect Merkle Patricia Root has been validated.")
+	}
+	if !reflect.DeepEqual(stateBefore, CopyState(storage.State)) {
+		t.Error("State has been changed by a block with an incorrect Merkle Patricia Root.")
+	}
+
+	b.MerklePatriciaRoot = root
+	if err := validate(b, false); err != nil {
+		t.Errorf("Block validation failed (%v)\n", err)
+	}
+	if protocol.StateRoot(storage.State) != root {
+		t.Error("State does not match the Merkle Patricia Root after validation.")
+	}
+
+	//The state at this height can be proven with the persisted trie.
+	validatorAcc, err := store.ReadStateTrieAccount(root, validatorAccAddress)
+	if err != nil {
+		t.Errorf("Reading the validator account from the state trie failed (%v)\n", err)
+	} else if validatorAcc.Balance != storage.State[validatorAccAddress].Balance {
+		t.Errorf("Validator balance in the state trie does not match: %v vs. %v\n", validatorAcc.Balance, storage.State[validatorAccAddress].Balance)
+	}
+
+	//A block which cannot be applied to the state is not finalized.
+	b2 := newBlock(b.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b.Height+1)
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	if err := addTx(b2, tx); err != nil {
+		t.Fatalf("Tx could not be added: %v\n", err)
+	}
+	store.WriteOpenTx(tx)
+	balance := accA.Balance
+	accA.Balance = 0
+	if err := finalizeBlock(b2); err == nil {
+		t.Error("Block which cannot be applied to the state has been finalized.")
+	}
+	accA.Balance = balance
+
+	//The state an own block results in is taken over by validate().
+	if err := finalizeBlock(b2); err != nil || minedBlockState.block != b2 {
+		t.Fatalf("Block finalization failed (%v)\n", err)
+	}
+	if err := validate(b2, false); err != nil {
+		t.Errorf("Block validation failed (%v)\n", err)
+	}
+	if minedBlockState.block != nil || protocol.StateRoot(storage.State) != b2.MerklePatriciaRoot || accA.Balance != balance-11 {
+		t.Error("State of the own block has not been taken over.")
+	}
+}
+
 //Blocks that link to the previous block and have valid txs should pass
 func TestMultipleBlocks(t *testing.T) {
 	cleanAndPrepare()
@@ -129,6 +234,29 @@ func TestMultipleBlocks(t *testing.T) {
 	}
 }
 
+//A tx replaced in the mempool can still be included by another miner, its replacement is removed then.
+func TestIncludedReplacedTx(t *testing.T) {
+	cleanAndPrepare()
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 10, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	replacement, _ := protocol.ConstrFundsTx(0x01, 10, 20, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	store.WriteOpenTx(replacement)
+	store.WriteINVALIDOpenTx(tx, nil)
+
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Tx could not be added: %v\n", err)
+	}
+	finalizeBlock(b)
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Block validation failed: %v\n", err)
+	}
+
+	if store.ReadOpenTx(replacement.Hash()) != nil {
+		t.Error("Replacement of the included tx is still in the mempool.")
+	}
+}
+
 //Test the blocktimestamp check
 func TestTimestampCheck(t *testing.T) {
 	cleanAndPrepare()
@@ -168,11 +296,11 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100+1, randVar.Uint64()%100+1, uint32(cnt), accA.Address, accB.Address, PrivKeyAccA, nil)
 		if err := addTx(b, tx); err == nil {
 			//Might  be that we generated a block that was already generated before
-			if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadClosedTx(tx.Hash()) != nil {
+			if store.ReadOpenTx(tx.Hash()) != nil || store.ReadClosedTx(tx.Hash()) != nil {
 				continue
 			}
 			hashFundsSlice = append(hashFundsSlice, tx.Hash())
-			storage.WriteOpenTx(tx)
+			store.WriteOpenTx(tx)
 		} else {
 			fmt.Print(err)
 		}
@@ -182,11 +310,11 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 	for cnt := 0; cnt < loopMax; cnt++ {
 		tx, _, _ := protocol.ConstrContractTx(0, randVar.Uint64()%100+1, PrivKeyRoot, nil, nil)
 		if err := addTx(b, tx); err == nil {
-			if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadClosedTx(tx.Hash()) != nil {
+			if store.ReadOpenTx(tx.Hash()) != nil || store.ReadClosedTx(tx.Hash()) != nil {
 				continue
 			}
 			hashAccSlice = append(hashAccSlice, tx.Hash())
-			storage.WriteOpenTx(tx)
+			store.WriteOpenTx(tx)
 		} else {
 			fmt.Print(err)
 		}
@@ -199,7 +327,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 		if err != nil {
 			fmt.Print(err)
 		}
-		if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadClosedTx(tx.Hash()) != nil {
+		if store.ReadOpenTx(tx.Hash()) != nil || store.ReadClosedTx(tx.Hash()) != nil {
 			continue
 		}
 
@@ -210,7 +338,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 		if err := addTx(b, tx); err == nil {
 
 			hashConfigSlice = append(hashConfigSlice, tx.Hash())
-			storage.WriteOpenTx(tx)
+			store.WriteOpenTx(tx)
 		} else {
 			fmt.Print(err)
 		}
@@ -223,7 +351,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 //func TestReadLastClosedBlock(t *testing.T) {
 //	cleanAndPrepare()
 //
-//	lastClosedBlock := storage.ReadLastClosedBlock()
+//	lastClosedBlock := store.ReadLastClosedBlock()
 //
 //	if !reflect.DeepEqual(lastClosedBlock, genesisBlock) {
 //		t.Errorf("Genesis Block is not read as a closed block:\n%v\n%v", lastClosedBlock, genesisBlock)
@@ -233,7 +361,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 //	var lastClosedBlocksAfterGenesis []*protocol.Block
 //	lastClosedBlocksAfterGenesis = append(lastClosedBlocksAfterGenesis, genesisBlock)
 //
-//	lastClosedBlocks := storage.ReadAllClosedBlocks()
+//	lastClosedBlocks := storage.ReadAllClosedBlocks(store)
 //	if !reflect.DeepEqual(lastClosedBlocks, lastClosedBlocksAfterGenesis) {
 //		t.Errorf("Closed blocks are not equal after genesis block:\n%v\n%v", lastClosedBlocks, lastClosedBlocksAfterGenesis)
 //	}
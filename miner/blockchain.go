@@ -1,8 +1,8 @@
 package miner
 
 import (
-	"crypto/ecdsa"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
@@ -22,6 +22,9 @@ var (
 	logger                  *log.Logger
 	// This logger creates the log file 'hlog-for-XXXX.txt' where 'XXXX' is the port of the validator
 	FileLogger                  *log.Logger
+	//The store the chain of this node is kept in. The miner state is kept in package variables, a process therefore
+	//runs a single miner.
+	store                   storage.Store
 	blockValidation         = &sync.Mutex{}
 	parameterSlice          []Parameters
 	activeParameters        *Parameters
@@ -51,7 +54,13 @@ var (
 /**
 	The function 'InitFirstStart' will be executed by the bootstrapping node who is responsible for starting the blockchain
  */
-func InitFirstStart(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
+func InitFirstStart(nodeStore storage.Store, wallet crypto.PublicKey, commitment *rsa.PrivateKey) error {
+	if store != nil {
+		return errors.New("A miner already runs in this process.")
+	}
+	store = nodeStore
+	store.SetTxVerifier(verifyOpenTx)
+
 	var err error
 	FileConnections, err = os.OpenFile(fmt.Sprintf("hash-prevhash-%v.txt",strings.Split(p2p.Ipport, ":")[1]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	FileConnectionsLog, err = os.OpenFile(fmt.Sprintf("hlog-for-%v.txt",strings.Split(p2p.Ipport, ":")[1]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -63,27 +72,39 @@ func InitFirstStart(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 	FileLogger = storage.InitFileLogger()
 	FileLogger.SetOutput(FileConnectionsLog)
 
-	rootAddress := crypto.GetAddressFromPubKey(wallet)
+	//After a restart, the chain is resumed from the local database instead of creating a new one.
+	if genesis, err := store.ReadGenesis(); err != nil {
+		return err
+	} else if genesis != nil {
+		logger.Printf("Resuming with the genesis (%x) of the local database\n", genesis.Hash())
+		FileLogger.Printf("Resuming with the genesis (%x) of the local database\n", genesis.Hash())
+		return Init(nodeStore, wallet, commitment)
+	}
+
+	rootAddress, err := crypto.GetAddress(wallet)
+	if err != nil {
+		return err
+	}
 
 	var rootCommitment [crypto.COMM_KEY_LENGTH]byte
 	copy(rootCommitment[:], commitment.N.Bytes())
 
 	genesis := protocol.NewGenesis(rootAddress, rootCommitment)
-	storage.WriteGenesis(&genesis)
+	store.WriteGenesis(&genesis)
 
 	/*Write First Epoch block chained to the genesis block*/
 	initialEpochBlock := protocol.NewEpochBlock([][32]byte{genesis.Hash()}, 0)
 	initialEpochBlock.Hash = initialEpochBlock.HashEpochBlock()
 	FirstEpochBlock = initialEpochBlock
 	initialEpochBlock.State = storage.State
-	storage.WriteFirstEpochBlock(initialEpochBlock)
+	store.WriteFirstEpochBlock(initialEpochBlock)
 
-	storage.WriteClosedEpochBlock(initialEpochBlock)
+	store.WriteClosedEpochBlock(initialEpochBlock)
 
-	storage.DeleteAllLastClosedEpochBlock()
-	storage.WriteLastClosedEpochBlock(initialEpochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(initialEpochBlock)
 
-	FileLogger.Printf("Last Epoch block hash: (%x)\n",storage.ReadLastClosedEpochBlock().Hash)
+	FileLogger.Printf("Last Epoch block hash: (%x)\n",store.ReadLastClosedEpochBlock().Hash)
 
 	firstValMapping := protocol.NewMapping()
 	initialEpochBlock.ValMapping = firstValMapping
@@ -92,20 +113,30 @@ func InitFirstStart(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 	FileConnections.WriteString(fmt.Sprintf(`"GENESIS \n Hash : %x"`+`[color = green, shape = hexagon]`+"\n",hashGenesis[0:8]))
 	FileConnections.WriteString(fmt.Sprintf(`"EPOCH BLOCK: \n Hash : %x \n Height : %d \nMPT : %x"`+`[color = red, shape = box]`+"\n",initialEpochBlock.Hash[0:8],initialEpochBlock.Height,initialEpochBlock.MerklePatriciaRoot[0:8]))
 
-	return Init(wallet, commitment)
+	return Init(nodeStore, wallet, commitment)
 }
 
 /**
 	Init is executed by all validators, and serves as the miner entry point
  */
-func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
+func Init(nodeStore storage.Store, wallet crypto.PublicKey, commitment *rsa.PrivateKey) error {
+	if store != nil {
+		return errors.New("A miner already runs in this process.")
+	}
+	store = nodeStore
+	store.SetTxVerifier(verifyOpenTx)
+
 	//this bool indicates whether the first epoch is over. Only in the first epoch, the bootstrapping node is assigning the
 	//validators to the shards and broadcasts this assignment to the other miners
 	firstEpochOver = false
 
 	FileConnections, _ = os.OpenFile(fmt.Sprintf("hash-prevhash-%v.txt",strings.Split(p2p.Ipport, ":")[1]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	FileConnectionsLog, _ = os.OpenFile(fmt.Sprintf("hlog-for-%v.txt",strings.Split(p2p.Ipport, ":")[1]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	validatorAccAddress = crypto.GetAddressFromPubKey(wallet)
+	validatorAddress, err := crypto.GetAddress(wallet)
+	if err != nil {
+		return err
+	}
+	validatorAccAddress = validatorAddress
 	commPrivKey = commitment
 
 	//Set up logger.
@@ -121,7 +152,6 @@ func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 	target = append(target, 15)
 
 	var initialBlock *protocol.Block
-	var err error
 
 	//Listen for incoming blocks from the network
 	go incomingData()
@@ -129,6 +159,30 @@ func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 	go incomingEpochData()
 	//Listen for incoming state transitions the network
 	go incomingStateData()
+	//Listen for incoming fraud proofs from the network
+	go incomingFraudProofs()
+
+	//A validator which has been running before resumes from the last epoch block and the closed blocks of its local
+	//database.
+	if epochBlock := store.ReadLastClosedEpochBlock(); epochBlock != nil && epochBlock.Height > 0 {
+		if err = resumeState(epochBlock); err != nil {
+			return err
+		}
+
+		logger.Printf("Resumed at height %d after epoch block (%x)\n", lastBlock.Height, lastEpochBlock.Hash[0:8])
+		FileLogger.Printf("Resumed at height %d after epoch block (%x)\n", lastBlock.Height, lastEpochBlock.Hash[0:8])
+
+		if lastBlock == dummyLastBlock {
+			revalidateOpenTxs(lastEpochBlock.Height)
+			FirstStartAfterEpoch = true
+			epochMining(lastEpochBlock.Hash, lastEpochBlock.Height)
+		} else {
+			revalidateOpenTxs(lastBlock.Height)
+			epochMining(lastBlock.Hash, lastBlock.Height)
+		}
+
+		return nil
+	}
 
 	//Since new validators only join after the currently running epoch ends, they do no need to download the whole shardchain history,
 	//but can continue with their work after the next epoch block and directly set their state to the global state of the first received epoch block
@@ -139,6 +193,7 @@ func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 		}
 		FileConnections.WriteString(fmt.Sprintf(`"Hash : %x \n Height : %d" -> "Hash : %x \n Height : %d"`+"\n", initialBlock.PrevHash[0:8],initialBlock.Height-1,initialBlock.Hash[0:8],initialBlock.Height))
 		lastBlock = initialBlock
+		revalidateOpenTxs(lastBlock.Height)
 	} else {
 		for{
 			//As the non-bootstrapping node, wait until I receive the last epoch block as well as the validator assignment
@@ -150,7 +205,13 @@ func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 					storage.ThisShardID = ValidatorShardMap.ValMapping[validatorAccAddress] //Save my ShardID
 					FirstStartAfterEpoch = true
 
+					//In partitioned mode, the epoch block only carries the staking accounts
+					if storage.PartitionedState {
+						fetchShardState(int(lastEpochBlock.Height), nil)
+					}
+
 					lastBlock = dummyLastBlock
+					revalidateOpenTxs(lastEpochBlock.Height)
 					epochMining(lastEpochBlock.Hash,lastEpochBlock.Height) //start mining based on the received Epoch Block
 				}
 			}
@@ -182,6 +243,41 @@ func Init(wallet *ecdsa.PublicKey, commitment *rsa.PrivateKey) error {
 	return nil
 }
 
+//The mempool reloaded from the journal is not trusted: the txs of rolled back blocks are written to it without
+//verification, and the state may have changed since the others were verified. Txs which have been validated, expired
+//or whose nonce has been used in the meantime, or which do not verify against the current state, are evicted.
+//The state is read under the lock of the block validation, which is taken before the lock of the mempool.
+func revalidateOpenTxs(height uint32) {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	staleTxs := store.DeleteStaleOpenTxs(func(tx protocol.Transaction) bool {
+		if store.ReadClosedTx(tx.Hash()) != nil || protocol.IsExpired(tx, height) {
+			return true
+		}
+
+		txType := protocol.TypeOf(tx)
+		if txType == nil || txType.Verify(tx) != nil {
+			return true
+		}
+
+		if txType.Nonce != nil {
+			if payer, ok := txType.FeePayer(tx); ok {
+				if acc, exists := storage.State[payer]; exists && txType.Nonce(tx) < acc.TxCnt {
+					return true
+				}
+			}
+		}
+
+		return false
+	})
+
+	updateMemPoolNonces()
+
+	logger.Printf("Evicted %d stale transactions from the reloaded mempool\n", len(staleTxs))
+	FileLogger.Printf("Evicted %d stale transactions from the reloaded mempool\n", len(staleTxs))
+}
+
 /**
 	Main function of Bazo which is running all the time with the goal of mining blocks and competing for the creation of epoch blocks.
  */
@@ -201,81 +297,8 @@ func epochMining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 		FileLogger.Printf("Before checking my state stash for lastblock height: %d\n",lastBlock.Height)
 		syncStartTime = time.Now().Unix()
 
-		//generate sequence of all shard IDs starting from 1
-		shardIDs := makeRange(1,NumberOfShards)
-		FileLogger.Printf("Number of shards: %d\n",NumberOfShards)
-
-		//This map keeps track of the shards whose state transitions have been processed.
-		//Once all entries are set to true, the synchronisation is done and the validator can continue with mining of the next shard block
-		shardIDStateBoolMap := make(map[int]bool)
-		for k, _ := range shardIDStateBoolMap {
-			shardIDStateBoolMap[k] = false
-		}
-
-		for{
-			//If there is only one shard, then skip synchronisation mechanism
-			if(NumberOfShards == 1){
-				break
-			}
-
-			//Retrieve all state transitions from the local state with the height of my last block
-			stateStashForHeight := protocol.ReturnStateTransitionForHeight(storage.ReceivedStateStash,lastBlock.Height)
-
-			if(len(stateStashForHeight) != 0){
-				//Iterate through state transitions and apply them to local state, keep track of processed shards
-				for _,st := range stateStashForHeight{
-					if(shardIDStateBoolMap[st.ShardID] == false){
-						//Apply all relative account changes to my local state
-						storage.State = storage.ApplyRelativeState(storage.State,st.RelativeStateChange)
-						//Delete transactions from Mempool (Transaction pool), which were validated
-						//by the other shards to avoid starvation in the mempool
-						DeleteTransactionFromMempool(st.ContractTxData,st.FundsTxData,st.ConfigTxData,st.StakeTxData)
-						//Set the particular shard as being processed
-						shardIDStateBoolMap[st.ShardID] = true
-
-						FileLogger.Printf("Processed state transition of shard: %d\n",st.ShardID)
-					}
-				}
-				//If all state transitions have been received, stop synchronisation
-				if (len(stateStashForHeight) == NumberOfShards-1){
-					break
-				}
-			}
-
-			//Iterate over shard IDs to check which ones are still missing, and request them from the network
-			for _,id := range shardIDs{
-				if(id != storage.ThisShardID && shardIDStateBoolMap[id] == false){
-					var stateTransition *protocol.StateTransition
-
-					FileLogger.Printf("requesting state transition for lastblock height: %d\n",lastBlock.Height)
-
-					p2p.StateTransitionReqShard(id,int(lastBlock.Height))
-					//Blocking wait
-					select {
-					case encodedStateTransition := <-p2p.StateTransitionShardReqChan:
-						stateTransition = stateTransition.DecodeTransition(encodedStateTransition)
-						//Apply state transition to my local state
-						storage.State = storage.ApplyRelativeState(storage.State,stateTransition.RelativeStateChange)
+		syncStateTransitions(lastBlock.Height)
 
-						FileLogger.Printf("Writing state back to stash Shard ID: %v  VS my shard ID: %v - Height: %d\n",stateTransition.ShardID,storage.ThisShardID,stateTransition.Height)
-						storage.ReceivedStateStash.Set(stateTransition.HashTransition(),stateTransition)
-
-						//Delete transactions from mempool, which were validated by the other shards
-						DeleteTransactionFromMempool(stateTransition.ContractTxData,stateTransition.FundsTxData,stateTransition.ConfigTxData,stateTransition.StakeTxData)
-
-						shardIDStateBoolMap[stateTransition.ShardID] = true
-
-						FileLogger.Printf("Processed state transition of shard: %d\n",stateTransition.ShardID)
-
-						//Limit waiting time to 5 seconds seconds before aborting.
-					case <-time.After(5 * time.Second):
-						FileLogger.Printf("have been waiting for 5 seconds for lastblock height: %d\n",lastBlock.Height)
-						//It the requested state transition has not been received, then continue with requesting the other missing ones
-						continue
-					}
-				}
-			}
-		}
 		//Log the end of synchronisation
 		logger.Printf("After checking my state stash for lastblock height: %d\n",lastBlock.Height)
 		FileLogger.Printf("After checking my state stash for lastblock height: %d\n",lastBlock.Height)
@@ -320,10 +343,11 @@ func epochMining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 				FileLogger.Printf("Broadcast epoch block (%x)\n", epochBlock.Hash[0:8])
 				//Broadcast epoch block to other nodes such that they can update their validator-shard assignment
 				broadcastEpochBlock(epochBlock)
-				storage.WriteClosedEpochBlock(epochBlock)
-				storage.DeleteAllLastClosedEpochBlock()
-				storage.WriteLastClosedEpochBlock(epochBlock)
+				store.WriteClosedEpochBlock(epochBlock)
+				store.DeleteAllLastClosedEpochBlock()
+				store.WriteLastClosedEpochBlock(epochBlock)
 				lastEpochBlock = epochBlock
+				pruneChain()
 
 				logger.Printf("Created Validator Shard Mapping :\n")
 				logger.Printf(ValidatorShardMap.String())
@@ -344,11 +368,15 @@ func epochMining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 			//General rule: Accept the last received epoch block as the valid one.
 			time.Sleep(5*time.Second)
 
+			//The validator might have been assigned to another shard
+			syncShardState()
+
 			prevBlockIsEpochBlock = true
 			firstEpochOver = true
 			//Continue mining with the hash of the last epoch block
 			mining(lastEpochBlock.Hash, lastEpochBlock.Height)
 		} else if(lastEpochBlock.Height == lastBlock.Height+1){
+			syncShardState()
 			prevBlockIsEpochBlock = true
 			mining(lastEpochBlock.Hash, lastEpochBlock.Height) //lastblock was received before we started creation of next epoch block
 		} else {
@@ -357,6 +385,97 @@ func epochMining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 	}
 }
 
+/**
+	Applies the state transitions the other shards created for the given height to the local state. Missing state
+	transitions are requested from the network until all shards have been processed.
+ */
+func syncStateTransitions(height uint32) {
+	//generate sequence of all shard IDs starting from 1
+	shardIDs := makeRange(1,NumberOfShards)
+	FileLogger.Printf("Number of shards: %d\n",NumberOfShards)
+
+	//This map keeps track of the shards whose state transitions have been processed.
+	//Once all entries are set to true, the synchronisation is done and the validator can continue with mining of the next shard block
+	shardIDStateBoolMap := make(map[int]bool)
+	for k, _ := range shardIDStateBoolMap {
+		shardIDStateBoolMap[k] = false
+	}
+
+	for{
+		//If there is only one shard, then skip synchronisation mechanism
+		if(NumberOfShards == 1){
+			break
+		}
+
+		//Retrieve all state transitions from the local state with the height of my last block
+		stateStashForHeight := protocol.ReturnStateTransitionForHeight(storage.ReceivedStateStash,height)
+
+		if(len(stateStashForHeight) != 0){
+			//Iterate through state transitions and apply them to local state, keep track of processed shards
+			for _,st := range stateStashForHeight{
+				if(shardIDStateBoolMap[st.ShardID] == false){
+					//Apply all relative account changes to my local state
+					applyStateTransition(st)
+					//Delete transactions from Mempool (Transaction pool), which were validated
+					//by the other shards to avoid starvation in the mempool
+					DeleteTransactionFromMempool(st.ContractTxData,st.FundsTxData,st.ConfigTxData,st.StakeTxData)
+					//Set the particular shard as being processed
+					shardIDStateBoolMap[st.ShardID] = true
+
+					FileLogger.Printf("Processed state transition of shard: %d\n",st.ShardID)
+				}
+			}
+			//If all state transitions have been received, stop synchronisation
+			if (len(stateStashForHeight) == NumberOfShards-1){
+				break
+			}
+		}
+
+		//Iterate over shard IDs to check which ones are still missing, and request them from the network
+		for _,id := range shardIDs{
+			if(id != storage.ThisShardID && shardIDStateBoolMap[id] == false){
+				var stateTransition *protocol.StateTransition
+
+				FileLogger.Printf("requesting state transition for lastblock height: %d\n",height)
+
+				p2p.StateTransitionReqShard(id,int(height))
+				//Blocking wait
+				select {
+				case encodedStateTransition := <-p2p.StateTransitionShardReqChan:
+					var err error
+					if stateTransition, err = stateTransition.DecodeTransition(encodedStateTransition); err != nil {
+						FileLogger.Printf("Received state transition could not be decoded: %v\n", err)
+						continue
+					}
+					//Only apply state transitions which have been signed by the validator of the shard
+					if err = verifyStateTransition(stateTransition); err != nil {
+						FileLogger.Printf("Received state transition of shard %d rejected: %v\n", stateTransition.ShardID, err)
+						continue
+					}
+					//Apply state transition to my local state
+					applyStateTransition(stateTransition)
+
+					FileLogger.Printf("Writing state back to stash Shard ID: %v  VS my shard ID: %v - Height: %d\n",stateTransition.ShardID,storage.ThisShardID,stateTransition.Height)
+					storage.ReceivedStateStash.Set(stateTransition.HashTransition(),stateTransition)
+
+					//Delete transactions from mempool, which were validated by the other shards
+					DeleteTransactionFromMempool(stateTransition.ContractTxData,stateTransition.FundsTxData,stateTransition.ConfigTxData,stateTransition.StakeTxData)
+
+					shardIDStateBoolMap[stateTransition.ShardID] = true
+
+					FileLogger.Printf("Processed state transition of shard: %d\n",stateTransition.ShardID)
+
+					//Limit waiting time to 5 seconds seconds before aborting.
+				case <-time.After(5 * time.Second):
+					FileLogger.Printf("have been waiting for 5 seconds for lastblock height: %d\n",height)
+					//It the requested state transition has not been received, then continue with requesting the other missing ones
+					continue
+				}
+			}
+		}
+	}
+}
+
 /**
 	This function is executed once at every block height of the shard chain.
 	Goal is to create a shard block and state transition and broadcast them to the other nodes.
@@ -383,7 +502,7 @@ func mining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 	FileLogger.Printf("After preparing Block Height: %v\n",currentBlock.Height)
 	blockValidation.Unlock()
 
-	//_, err := storage.ReadAccount(validatorAccAddress)
+	//_, err := store.ReadAccount(validatorAccAddress)
 	//if err != nil {
 	//	logger.Printf("%v\n", err)
 	//	FileLogger.Printf("%v\n", err)
@@ -410,7 +529,21 @@ func mining(hashPrevBlock [32]byte, heightPrevBlock uint32) {
 		if err == nil{
 			//Generate state transition for this block. This data is needed by the other shards to update their local states.
 			stateTransition := protocol.NewStateTransition(storage.RelativeState,int(currentBlock.Height),storage.ThisShardID,currentBlock.Hash,
-				currentBlock.ContractTxData,currentBlock.FundsTxData,currentBlock.ConfigTxData,currentBlock.StakeTxData)
+				currentBlock.TxHashes(protocol.CONTRACTTX_TYPE),currentBlock.TxHashes(protocol.FUNDSTX_TYPE),currentBlock.TxHashes(protocol.CONFIGTX_TYPE),currentBlock.TxHashes(protocol.STAKETX_TYPE))
+			//Sign the state transition, such that the other shards can verify that it was produced by this validator.
+			stateTransition.Validator = validatorAccAddress
+			//At the last block of an epoch, the accounts of the shard are kept for the validators of the new shards
+			//and their root is committed, such that it ends up in the epoch block.
+			if currentBlock.Height == uint32(lastEpochBlock.Height)+uint32(activeParameters.epoch_length) {
+				blockValidation.Lock()
+				stateTransition.ShardStateRoot = writeShardStateSnapshot(int(currentBlock.Height) + 1).Root()
+				blockValidation.Unlock()
+			}
+			stHash := stateTransition.HashTransition()
+			if stateTransition.CommitmentSig, err = crypto.SignMessageWithRSAKey(commPrivKey, fmt.Sprintf("%x", stHash)); err != nil {
+				logger.Printf("State transition for height %d could not be signed: %v\n", currentBlock.Height, err)
+				FileLogger.Printf("State transition for height %d could not be signed: %v\n", currentBlock.Height, err)
+			}
 
 			FileLogger.Printf("Broadcast state transition for height %d\n", currentBlock.Height)
 			//Broadcast state transition to other shards
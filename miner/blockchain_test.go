@@ -0,0 +1,50 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Txs of the reloaded mempool which became stale while the miner was down are evicted.
+func TestRevalidateOpenTxs(t *testing.T) {
+	cleanAndPrepare()
+
+	accA.TxCnt = 1
+
+	validTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 1, accA.Address, accB.Address, PrivKeyAccA, nil)
+	usedNonceTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accA.Address, accB.Address, PrivKeyAccA, nil)
+	forgedTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 2, accA.Address, accB.Address, PrivKeyAccB, nil)
+	expiredTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 3, accA.Address, accB.Address, PrivKeyAccA, nil)
+	expiredTx.SetValidity(0, 5, PrivKeyAccA)
+	closedTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 4, accA.Address, accB.Address, PrivKeyAccA, nil)
+	store.WriteClosedTx(closedTx, 1)
+
+	for _, tx := range []protocol.Transaction{validTx, usedNonceTx, forgedTx, expiredTx, closedTx} {
+		store.WriteOpenTx(tx)
+	}
+
+	revalidateOpenTxs(10)
+
+	if store.ReadOpenTx(validTx.Hash()) == nil {
+		t.Error("Valid tx has been evicted from the mempool.")
+	}
+	for _, tx := range []protocol.Transaction{usedNonceTx, forgedTx, expiredTx, closedTx} {
+		if store.ReadOpenTx(tx.Hash()) != nil {
+			t.Errorf("Stale tx has not been evicted from the mempool: %v\n", tx)
+		}
+	}
+}
+
+//The miner state is kept in package variables, a second miner cannot be started in the same process.
+func TestInitRejectsSecondMiner(t *testing.T) {
+	minerStore := store
+
+	if err := Init(storage.NewMemoryStore(), nil, nil); err == nil || store != minerStore {
+		t.Error("Second miner was started in the same process.")
+	}
+	if err := InitFirstStart(storage.NewMemoryStore(), nil, nil); err == nil || store != minerStore {
+		t.Error("Second bootstrapping miner was started in the same process.")
+	}
+}
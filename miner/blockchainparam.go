@@ -104,8 +104,8 @@ func collectStatistics(b *protocol.Block) {
 	logger.Printf("Block TPS: %v TX/Sec\n", blockTPS)
 	FileLogger.Printf("Block TPS: %v TX/Sec\n", blockTPS)
 
-	logger.Printf("MemPool Size: %d\n", storage.GetMemPoolSize())
-	FileLogger.Printf("MemPool Size: %d\n", storage.GetMemPoolSize())
+	logger.Printf("MemPool Size: %d\n", store.GetMemPoolSize())
+	FileLogger.Printf("MemPool Size: %d\n", store.GetMemPoolSize())
 
 	if localBlockCount == int64(activeParameters.Diff_interval) {
 		currentTargetTime.last = b.Timestamp
@@ -2,34 +2,34 @@ package miner
 
 import (
 	"encoding/binary"
+
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"sort"
 )
 
-//The code here is needed if a new block is built. All open (not yet validated) transactions are first fetched
-//from the mempool and then sorted. The sorting is important because if transactions are fetched from the mempool
-//they're received in random order (because it's implemented as a map). However, if a user wants to issue more fundsTxs
-//they need to be sorted according to increasing txCnt, this greatly increases throughput.
-
-type openTxs []protocol.Transaction
-
+//The code here is needed if a new block is built. All open (not yet validated) transactions are fetched from the
+//mempool in the order of their priority: txs with a higher fee rate come first, while the fundsTxs of a sender follow
+//each other in the order of increasing txCnt, which greatly increases throughput. Queued txs, which wait for a tx
+//with a missing txCnt, are not considered.
 func prepareBlock(block *protocol.Block) {
-	//Fetch all txs from mempool (opentxs).
-	opentxs := storage.ReadAllOpenTxs()
-
-	//This copy is strange, but seems to be necessary to leverage the sort interface.
-	//Shouldn't be too bad because no deep copy.
-	var tmpCopy openTxs
-	tmpCopy = opentxs
+	//Fetch all pending txs from mempool (opentxs), by the nonces of the current state.
+	updateMemPoolNonces()
+	opentxs := store.ReadPendingTxs()
 
-	sort.Sort(tmpCopy)
+	//Once a tx of an account with a nonce could not be added, its following txs cannot be added either. They are
+	//kept in the mempool for the next block instead of being marked invalid.
+	blockedAccounts := make(map[[64]byte]bool)
 
 	//Keep track of transactions from assigned for my shard and which are valid. Only consider these ones when filling a block
 	//Otherwhise we would also count invalid transactions from my shard, this prevents well-filled blocks.
 	txFromThisShard := 0
 
 	for _, tx := range opentxs {
+		//Txs outside of their validity window stay in the mempool until they become valid or expire.
+		if !protocol.IsValidAt(tx, block.Height) {
+			continue
+		}
+
 		/*When fetching and adding Txs from the MemPool, first check if it belongs to my shard. Only if so, then add tx to the block*/
 		txAssignedShard := assignTransactionToShard(tx)
 
@@ -40,107 +40,101 @@ func prepareBlock(block *protocol.Block) {
 				break
 			}
 
-			switch tx.(type) {
-			case *protocol.StakeTx:
-				//Add StakeTXs only when preparing the last block before the next epoch block
+			//Add StakeTXs only when preparing the last block before the next epoch block
+			if tx.Type() == protocol.STAKETX_TYPE {
 				if (int(lastBlock.Height) == int(lastEpochBlock.Height) + int(activeParameters.epoch_length) - 1) {
 					err := addTx(block, tx)
 					if err == nil {
 						txFromThisShard += 1
 					}
 				}
-			case *protocol.ContractTx, *protocol.FundsTx, *protocol.ConfigTx:
-				err := addTx(block, tx)
-				if err != nil {
-					//If the tx is invalid, we remove it completely, prevents starvation in the mempool.
-					//storage.DeleteOpenTx(tx)
-					storage.WriteINVALIDOpenTx(tx)
-					//storage.DeleteOpenTx(tx)
-				} else {
-					txFromThisShard += 1
-				}
+				continue
 			}
 
+			account, hasNonce := nonceAccount(tx)
+			if hasNonce && blockedAccounts[account] {
+				continue
+			}
+
+			err := addTx(block, tx)
+			if err != nil {
+				//If the tx is invalid, we remove it completely, prevents starvation in the mempool.
+				//store.DeleteOpenTx(tx)
+				store.WriteINVALIDOpenTx(tx, err)
+				//store.DeleteOpenTx(tx)
+				if hasNonce {
+					blockedAccounts[account] = true
+				}
+			} else {
+				txFromThisShard += 1
+			}
 		}
 	}
+
+	//Credit the cross-shard FundsTxs other shards sent to this shard.
+	addReceipts(block)
+}
+
+//Passes the nonces of the state to the mempool, which queues the txs by them. Must be called with blockValidation
+//held, such that the state does not change while it is read.
+func updateMemPoolNonces() {
+	nonces := make(map[[64]byte]uint32, len(storage.State))
+	for address, acc := range storage.State {
+		nonces[address] = acc.TxCnt
+	}
+	store.WriteAccountNonces(nonces)
 }
 
 /**
-	Transactions are sharded based on the public address of the sender
+	Transactions are sharded based on the public address of the sender, the registered tx type declares which address
+	that is (e.g., the issuer of a contractTx).
  */
 func assignTransactionToShard(transaction protocol.Transaction) (shardNr int) {
-	//Convert Address/Issuer ([64]bytes) included in TX to bigInt for the modulo operation to determine the assigned shard ID.
-	switch transaction.(type) {
-		case *protocol.ContractTx:
-			var byteToConvert [64]byte
-			byteToConvert = transaction.(*protocol.ContractTx).Issuer
-			var calculatedInt int
-			calculatedInt = int(binary.BigEndian.Uint64(byteToConvert[:8]))
-			return int((Abs(int32(calculatedInt)) % int32(NumberOfShards)) + 1)
-		case *protocol.FundsTx:
-			var byteToConvert [64]byte
-			byteToConvert = transaction.(*protocol.FundsTx).From
-			var calculatedInt int
-			calculatedInt = int(binary.BigEndian.Uint64(byteToConvert[:8]))
-			return int((Abs(int32(calculatedInt)) % int32(NumberOfShards)) + 1)
-		case *protocol.ConfigTx:
-			var byteToConvert [64]byte
-			byteToConvert = transaction.(*protocol.ConfigTx).Sig
-			var calculatedInt int
-			calculatedInt = int(binary.BigEndian.Uint64(byteToConvert[:8]))
-			return int((Abs(int32(calculatedInt)) % int32(NumberOfShards)) + 1)
-		case *protocol.StakeTx:
-			var byteToConvert [64]byte
-			byteToConvert = transaction.(*protocol.StakeTx).Account
-			var calculatedInt int
-			calculatedInt = int(binary.BigEndian.Uint64(byteToConvert[:8]))
-			return int((Abs(int32(calculatedInt)) % int32(NumberOfShards)) + 1)
-		default:
-			return 1 // default shard ID
-		}
+	txType := protocol.TypeOf(transaction)
+	if txType == nil {
+		return 1 // default shard ID
+	}
+
+	return assignAddressToShard(txType.ShardKey(transaction))
 }
 
-func Abs(x int32) int32 {
-	if x < 0 {
-		return -x
+//The account whose nonce sequence the tx belongs to, if its type has a nonce.
+func nonceAccount(transaction protocol.Transaction) (account [64]byte, ok bool) {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil || txType.Nonce == nil || txType.ShardKey == nil {
+		return account, false
 	}
-	return x
+	return txType.ShardKey(transaction), true
 }
 
-//Implement the sort interface
-func (f openTxs) Len() int {
-	return len(f)
+//Shard IDs start at 1. The first 8 bytes of the address decide which shard an account (and the transactions it issues)
+//belongs to.
+func assignAddressToShard(address [64]byte) (shardNr int) {
+	return addressShard(address, NumberOfShards)
 }
 
-func (f openTxs) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
+//The shard of the address under the given number of shards, e.g. the one of the partition held in partitioned mode.
+func addressShard(address [64]byte, numberOfShards int) int {
+	calculatedInt := int32(binary.BigEndian.Uint64(address[:8]))
+	if calculatedInt < 0 {
+		calculatedInt = -calculatedInt
+	}
+	return int(calculatedInt%int32(numberOfShards)) + 1
 }
 
-func (f openTxs) Less(i, j int) bool {
-	//Comparison only makes sense if both tx are fundsTxs.
-	//Why can we only do that with switch, and not e.g., if tx.(type) == ..?
-	switch f[i].(type) {
-	case *protocol.ContractTx:
-		//We only want to sort a subset of all transactions, namely all fundsTxs.
-		//However, to successfully do that we have to place all other txs at the beginning.
-		//The order between contractTxs and configTxs doesn't matter.
-		return true
-	case *protocol.ConfigTx:
-		return true
-	case *protocol.StakeTx:
-		return true
-	}
+//A FundsTx is cross-shard if the receiver belongs to another shard than the sender. The shard of the sender only debits
+//the amount, the receiver is credited by its own shard with a receipt. Txs with data (smart contract calls) are still
+//executed entirely by the shard of the sender, because the contract of the receiver is needed for that. The same holds
+//for batch transfers, whose recipients must belong to the shard of the sender (see verifyBatchShard).
+func isCrossShardTx(tx *protocol.FundsTx) bool {
+	return NumberOfShards > 1 && tx.Data == nil && !tx.IsBatch() && assignAddressToShard(tx.From) != assignAddressToShard(tx.To)
+}
 
-	switch f[j].(type) {
-	case *protocol.ContractTx:
-		return false
-	case *protocol.ConfigTx:
-		return false
-	case *protocol.StakeTx:
-		return false
+func Abs(x int32) int32 {
+	if x < 0 {
+		return -x
 	}
-
-	return f[i].(*protocol.FundsTx).TxCnt < f[j].(*protocol.FundsTx).TxCnt
+	return x
 }
 
 /**
@@ -149,33 +143,33 @@ func (f openTxs) Less(i, j int) bool {
  */
 func DeleteTransactionFromMempool(contractData [][32]byte, fundsData [][32]byte, configData [][32]byte, stakeData [][32]byte) {
 	for _,fundsTX := range fundsData{
-		if(storage.ReadOpenTx(fundsTX) != nil){
-			storage.DeleteOpenTx(storage.ReadOpenTx(fundsTX))
+		if(store.ReadOpenTx(fundsTX) != nil){
+			store.DeleteOpenTx(store.ReadOpenTx(fundsTX))
 			FileLogger.Printf("Deleted transaction (%x) from the MemPool.\n",fundsTX)
 		}
 	}
 
 	for _,configTX := range configData{
-		if(storage.ReadOpenTx(configTX) != nil){
-			storage.DeleteOpenTx(storage.ReadOpenTx(configTX))
+		if(store.ReadOpenTx(configTX) != nil){
+			store.DeleteOpenTx(store.ReadOpenTx(configTX))
 			FileLogger.Printf("Deleted transaction (%x) from the MemPool.\n",configTX)
 		}
 	}
 
 	for _,stakeTX := range stakeData{
-		if(storage.ReadOpenTx(stakeTX) != nil){
-			storage.DeleteOpenTx(storage.ReadOpenTx(stakeTX))
+		if(store.ReadOpenTx(stakeTX) != nil){
+			store.DeleteOpenTx(store.ReadOpenTx(stakeTX))
 			FileLogger.Printf("Deleted transaction (%x) from the MemPool.\n",stakeTX)
 		}
 	}
 
 	for _,contractTX := range contractData{
-		if(storage.ReadOpenTx(contractTX) != nil){
-			storage.DeleteOpenTx(storage.ReadOpenTx(contractTX))
+		if(store.ReadOpenTx(contractTX) != nil){
+			store.DeleteOpenTx(store.ReadOpenTx(contractTX))
 			FileLogger.Printf("Deleted transaction (%x) from the MemPool.\n",contractTX)
 		}
 	}
 
-	//logger.Printf("Deleted transaction count: %d - New Mempool Size: %d\n",len(txPayload.FundsTxData)+len(txPayload.StakeTxData)+len(txPayload.ContractTxData)+ len(txPayload.ConfigTxData),storage.GetMemPoolSize())
-	FileLogger.Printf("Deleted transaction count: %d - New Mempool Size: %d\n",len(contractData)+len(fundsData)+len(configData)+ len(stakeData),storage.GetMemPoolSize())
+	//logger.Printf("Deleted transaction count: %d - New Mempool Size: %d\n",len(txPayload.FundsTxData)+len(txPayload.StakeTxData)+len(txPayload.ContractTxData)+ len(txPayload.ConfigTxData),store.GetMemPoolSize())
+	FileLogger.Printf("Deleted transaction count: %d - New Mempool Size: %d\n",len(contractData)+len(fundsData)+len(configData)+ len(stakeData),store.GetMemPoolSize())
 }
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 func TestPrepareAndSortTxs(t *testing.T) {
@@ -20,20 +19,20 @@ func TestPrepareAndSortTxs(t *testing.T) {
 		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100+1, randVar.Uint64()%100+1, uint32(cnt), accA.Address, accB.Address, PrivKeyAccA, nil)
 		tx2, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100+1, randVar.Uint64()%100+1, uint32(cnt), accB.Address, accA.Address, PrivKeyAccB, nil)
 
-		if verifyFundsTx(tx) {
-			storage.WriteOpenTx(tx)
+		if verifyFundsTx(tx) == nil {
+			store.WriteOpenTx(tx)
 		}
 
-		if verifyFundsTx(tx2) {
-			storage.WriteOpenTx(tx2)
+		if verifyFundsTx(tx2) == nil {
+			store.WriteOpenTx(tx2)
 		}
 	}
 
 	//Add other tx types as well to make the test more challenging
 	for cnt := 0; cnt < testsize; cnt++ {
 		tx, _, _ := protocol.ConstrContractTx(0x01, randVar.Uint64()%100+1, PrivKeyRoot, nil, nil)
-		if verifyContractTx(tx) {
-			storage.WriteOpenTx(tx)
+		if verifyContractTx(tx) == nil {
+			store.WriteOpenTx(tx)
 		}
 	}
 
@@ -44,8 +43,8 @@ func TestPrepareAndSortTxs(t *testing.T) {
 		if tx.Id == 3 || tx.Id == 1 {
 			continue
 		}
-		if verifyConfigTx(tx) {
-			storage.WriteOpenTx(tx)
+		if verifyConfigTx(tx) == nil {
+			store.WriteOpenTx(tx)
 		}
 	}
 
@@ -61,3 +60,41 @@ func TestPrepareAndSortTxs(t *testing.T) {
 		t.Errorf("NrFundsTx (%v) vs. testsize*2 (%v)\n", b.NrFundsTx, testsize*2)
 	}
 }
+
+//Queued txs are not included, and the txs following a tx which cannot be added stay in the mempool.
+func TestPrepareBlockNonceGap(t *testing.T) {
+	cleanAndPrepare()
+	NumberOfShards = 1
+	ValidatorShardMap.ValMapping[validatorAccAddress] = 1
+
+	queuedTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt+2, accA.Address, accB.Address, PrivKeyAccA, nil)
+	store.WriteOpenTx(queuedTx)
+
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	prepareBlock(b)
+	if len(b.TxHashes(protocol.FUNDSTX_TYPE)) != 0 {
+		t.Errorf("Queued tx was added to the block: %x\n", b.TxHashes(protocol.FUNDSTX_TYPE))
+	}
+	if status := store.ReadTxStatus(queuedTx.Hash()); status.Status != protocol.TXSTATUS_QUEUED {
+		t.Errorf("Wrong status of the queued tx: %v\n", status)
+	}
+
+	//The gap is filled by a tx the sender cannot afford.
+	overdrawnTx, _ := protocol.ConstrFundsTx(0x01, accA.Balance+1, 1, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	nextTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, accA.TxCnt+1, accA.Address, accB.Address, PrivKeyAccA, nil)
+	store.WriteOpenTx(overdrawnTx)
+	store.WriteOpenTx(nextTx)
+
+	b = newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	prepareBlock(b)
+	if len(b.TxHashes(protocol.FUNDSTX_TYPE)) != 0 {
+		t.Errorf("Txs following an invalid tx were added to the block: %x\n", b.TxHashes(protocol.FUNDSTX_TYPE))
+	}
+	if status := store.ReadTxStatus(overdrawnTx.Hash()); status.Status != protocol.TXSTATUS_REJECTED || status.Code != protocol.TXREJECT_INSUFFICIENT_FUNDS {
+		t.Errorf("Invalid tx was not rejected for insufficient funds: %v\n", status)
+	}
+	//The invalid tx left the open mempool, the txs following it wait for a replacement.
+	if store.ReadTxStatus(nextTx.Hash()).Status != protocol.TXSTATUS_QUEUED || store.ReadTxStatus(queuedTx.Hash()).Status != protocol.TXSTATUS_QUEUED {
+		t.Error("Txs following an invalid tx were not queued.")
+	}
+}
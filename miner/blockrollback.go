@@ -2,23 +2,23 @@ package miner
 
 import (
 	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 //Already validated block but not part of the current longest chain.
 //No need for an additional state mutex, because this function is called while the blockValidation mutex is actively held.
 func rollback(b *protocol.Block) error {
 	FileLogger.Printf("Rolling back block (%x)\n",b.Hash[0:8])
-	contractTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, err := preValidateRollback(b)
+	txs, err := preValidateRollback(b)
 	if err != nil {
 		return err
 	}
 
-	data := blockData{contractTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, b}
+	data := blockData{txs, b}
 
 	//Going back to pre-block system parameters before the state is rolled back.
-	configStateChangeRollback(data.configTxSlice, b.Hash)
+	configStateChangeRollback(data.configTxs(), b.Hash)
 
 	//TODO Does not throw error but crashes
 	validateStateRollback(data)
@@ -30,106 +30,63 @@ func rollback(b *protocol.Block) error {
 	return nil
 }
 
-func preValidateRollback(b *protocol.Block) (contractTxSlice []*protocol.ContractTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, err error) {
-	//Fetch all transactions from closed storage.
-	for _, hash := range b.ContractTxData {
-		var contractTx *protocol.ContractTx
-		tx := storage.ReadClosedTx(hash)
-		if tx == nil {
-			//This should never happen, because all validated transactions are in closed storage.
-			return nil, nil, nil, nil, errors.New("CRITICAL: Validated contractTx was not in the confirmed tx storage")
-		} else {
-			contractTx = tx.(*protocol.ContractTx)
-		}
-		contractTxSlice = append(contractTxSlice, contractTx)
-	}
-
-	for _, hash := range b.FundsTxData {
-		var fundsTx *protocol.FundsTx
-		tx := storage.ReadClosedTx(hash)
-		if tx == nil {
-			return nil, nil, nil, nil, errors.New("CRITICAL: Validated fundsTx was not in the confirmed tx storage")
-		} else {
-			fundsTx = tx.(*protocol.FundsTx)
-		}
-		fundsTxSlice = append(fundsTxSlice, fundsTx)
-	}
-
-	for _, hash := range b.ConfigTxData {
-		var configTx *protocol.ConfigTx
-		tx := storage.ReadClosedTx(hash)
-		if tx == nil {
-			return nil, nil, nil, nil, errors.New("CRITICAL: Validated configTx was not in the confirmed tx storage")
-		} else {
-			configTx = tx.(*protocol.ConfigTx)
-		}
-		configTxSlice = append(configTxSlice, configTx)
-	}
+func preValidateRollback(b *protocol.Block) (txs map[byte][]protocol.Transaction, err error) {
+	txs = make(map[byte][]protocol.Transaction)
 
-	for _, hash := range b.StakeTxData {
-		var stakeTx *protocol.StakeTx
-		tx := storage.ReadClosedTx(hash)
-		if tx == nil {
-			return nil, nil, nil, nil, errors.New("CRITICAL: Validated stakeTx was not in the confirmed tx storage")
-		} else {
-			stakeTx = tx.(*protocol.StakeTx)
+	//Fetch all transactions from closed storage.
+	for _, txType := range protocol.TxTypes() {
+		for _, hash := range b.TxHashes(txType.Id) {
+			tx := store.ReadClosedTx(hash)
+			if tx == nil {
+				//This should never happen, because all validated transactions are in closed storage.
+				return nil, errors.New(fmt.Sprintf("CRITICAL: Validated %v was not in the confirmed tx storage", txType.Name))
+			}
+			txs[txType.Id] = append(txs[txType.Id], tx)
 		}
-		stakeTxSlice = append(stakeTxSlice, stakeTx)
 	}
 
-	return contractTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, nil
+	return txs, nil
 }
 
 func validateStateRollback(data blockData) {
 	collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
 	collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-	collectTxFeesRollback(data.contractTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
-	stakeStateChangeRollback(data.stakeTxSlice)
-	fundsStateChangeRollback(data.fundsTxSlice)
-	accStateChangeRollback(data.contractTxSlice)
+	collectTxFeesRollback(data.allTxs(), data.block.Beneficiary)
+	txStateChangeRollback(data, protocol.TxTypes())
 }
 
 func postValidateRollback(data blockData) {
 	//Put all validated txs into invalidated state.
-	for _, tx := range data.contractTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
-	}
-
-	for _, tx := range data.fundsTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
-	}
-
-	for _, tx := range data.configTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+	for _, tx := range data.allTxs() {
+		store.WriteVerifiedOpenTx(tx)
+		store.DeleteClosedTx(tx)
 	}
 
-	for _, tx := range data.stakeTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+	//Receipts of rolled back blocks can be credited again.
+	for _, receipt := range data.block.Receipts {
+		store.DeleteReceipt(receipt.Tx.Hash())
+		writeOpenReceipt(receipt)
 	}
 
 	collectStatisticsRollback(data.block)
 
-	lastBlock = storage.ReadClosedBlock(data.block.PrevHash) // May be an epoch block
+	lastBlock = store.ReadClosedBlock(data.block.PrevHash) // May be an epoch block
 
 	if(lastBlock == nil){
 		lastBlock = dummyLastBlock
 		//For transactions we switch from closed to open. However, we do not write back blocks
 		//to open storage, because in case of rollback the chain they belonged to is likely to starve.
-		storage.DeleteClosedBlock(data.block.Hash)
+		store.DeleteClosedBlock(data.block.Hash)
 
 		//Save the previous block as the last closed block.
-		storage.DeleteAllLastClosedBlock()
-		storage.WriteLastClosedBlock(lastBlock)
+		store.DeleteAllLastClosedBlock()
+		store.WriteLastClosedBlock(lastBlock)
 	} else {
-		storage.DeleteClosedBlock(data.block.Hash)
+		store.DeleteClosedBlock(data.block.Hash)
 
 		//Save the previous block as the last closed block.
-		storage.DeleteAllLastClosedBlock()
-		storage.WriteLastClosedBlock(lastBlock)
+		store.DeleteAllLastClosedBlock()
+		store.WriteLastClosedBlock(lastBlock)
 	}
 
 
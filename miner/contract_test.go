@@ -71,7 +71,7 @@ func TestMultipleBlocksWithStateChangeContractTx(t *testing.T) {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	acc, _ := storage.ReadAccount(contractAddress)
+	acc, _ := store.ReadAccount(contractAddress)
 	contractVariables := acc.ContractVariables
 	expected := []protocol.ByteArray{[]byte{0, 17}}
 	if !reflect.DeepEqual(contractVariables, expected) {
@@ -119,7 +119,7 @@ func TestMultipleBlocksWithDoubleStateChangeContractTx(t *testing.T) {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	acc, _ := storage.ReadAccount(contractAddress)
+	acc, _ := store.ReadAccount(contractAddress)
 	contractVariables := acc.ContractVariables
 	expected := []protocol.ByteArray{[]byte{0, 32}}
 	if !reflect.DeepEqual(contractVariables, expected) {
@@ -193,7 +193,7 @@ func TestMultipleBlocksWithTokenizationContractTx(t *testing.T) {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	acc, _ := storage.ReadAccount(contractAddress)
+	acc, _ := store.ReadAccount(contractAddress)
 	m, err := vm.MapFromByteArray(acc.ContractVariables[2])
 	if err != nil {
 		t.Errorf(err.Error())
@@ -249,7 +249,7 @@ func TestMultipleBlocksWithTokenizationContractTxWhichAddsKey(t *testing.T) {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	acc, _ := storage.ReadAccount(contractAddress)
+	acc, _ := store.ReadAccount(contractAddress)
 	m, err := vm.MapFromByteArray(acc.ContractVariables[2])
 	if err != nil {
 		t.Errorf(err.Error())
@@ -270,7 +270,7 @@ func TestMultipleBlocksWithTokenizationContractTxWhichAddsKey(t *testing.T) {
 func createBlockWithSingleContractDeployTx(b *protocol.Block, contract []byte, contractVariables []protocol.ByteArray) [64]byte {
 	tx, contractPrivKey, _ := protocol.ConstrContractTx(0, 1000000, PrivKeyRoot, contract, contractVariables)
 	if err := addTx(b, tx); err == nil {
-		storage.WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 		return crypto.GetAddressFromPubKey(&contractPrivKey.PublicKey)
 	} else {
 		fmt.Print(err)
@@ -281,19 +281,19 @@ func createBlockWithSingleContractDeployTx(b *protocol.Block, contract []byte, c
 func createBlockWithSingleContractCallTx(contractAddress [64]byte, b *protocol.Block, transactionData []byte) {
 	tx, _ := protocol.ConstrFundsTx(0x01, rand.Uint64()%100+1, 100000, uint32(accA.TxCnt), accA.Address, contractAddress, PrivKeyAccA, transactionData)
 	if err := addTx(b, tx); err == nil {
-		storage.WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 	} else {
 		fmt.Print(err)
 	}
 }
 
 func createBlockWithSingleContractCallTxDefined(b *protocol.Block, transactionData []byte, from [64]byte, to [64]byte) {
-	accA, _ := storage.ReadAccount(from)
-	accB, _ := storage.ReadAccount(to)
+	accA, _ := store.ReadAccount(from)
+	accB, _ := store.ReadAccount(to)
 
 	tx, _ := protocol.ConstrFundsTx(0x01, rand.Uint64()%100+1, rand.Uint64()%100+1, uint32(accA.TxCnt), accA.Address, accB.Address, PrivKeyAccA, transactionData)
 	if err := addTx(b, tx); err == nil {
-		storage.WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 	} else {
 		fmt.Print(err)
 	}
@@ -302,7 +302,7 @@ func createBlockWithSingleContractCallTxDefined(b *protocol.Block, transactionDa
 func getAccountsWithContracts() []protocol.Account {
 	var accounts []protocol.Account
 	for address := range storage.State {
-		acc, _ := storage.ReadAccount(address)
+		acc, _ := store.ReadAccount(address)
 		if acc.Contract != nil {
 			accounts = append(accounts, *acc)
 		}
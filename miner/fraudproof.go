@@ -0,0 +1,335 @@
+package miner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+/**
+	Validators do not have the state of the other shards, hence they cannot validate foreign blocks. What they can do
+	is re-executing the transactions of a foreign block relative to the state: every transaction results in a fixed
+	change of the balances and transaction counters involved. If the relative state change of a state transition
+	deviates from this, the state transition is invalid and the validator who signed it is slashed.
+ */
+
+var (
+	fraudProofMutex = &sync.Mutex{}
+	//The relative state changes which have been applied to the local state, keyed by the hash of the state transition.
+	appliedStateTransitions = make(map[[32]byte]map[[64]byte]*protocol.RelativeAccount)
+	//Corrected relative state changes of state transitions which have not been applied yet.
+	correctedStateTransitions = make(map[[32]byte]map[[64]byte]*protocol.RelativeAccount)
+	handledFraudProofs = make(map[[32]byte]bool)
+)
+
+//Applies the relative state change of a foreign shard to the local state. If the state transition has already been
+//proven to be fraudulent, the corrected state change is applied instead.
+func applyStateTransition(st *protocol.StateTransition) {
+	fraudProofMutex.Lock()
+	defer fraudProofMutex.Unlock()
+
+	stHash := st.HashTransition()
+	stateChange := st.RelativeStateChange
+	if correctedChange, exists := correctedStateTransitions[stHash]; exists {
+		stateChange = correctedChange
+		delete(correctedStateTransitions, stHash)
+	}
+
+	storage.State = storage.ApplyRelativeState(storage.State, stateChange)
+	partitionState()
+	appliedStateTransitions[stHash] = stateChange
+
+	//State transitions which left the stash can no longer be challenged.
+	for hash := range appliedStateTransitions {
+		if !storage.ReceivedStateStash.StateTransitionIncluded(hash) && hash != stHash {
+			delete(appliedStateTransitions, hash)
+		}
+	}
+}
+
+//The block hash is calculated before the commitment proof and the timestamp are set (see finalizeBlock()).
+func verifyBlockHash(block *protocol.Block) bool {
+	blockCopy := *block
+	blockCopy.CommitmentProof = [crypto.COMM_PROOF_LENGTH]byte{}
+	blockCopy.Timestamp = 0
+
+	partialHash := blockCopy.HashBlock()
+	return block.Hash == sha3.Sum256(append(block.Nonce[:], partialHash[:]...))
+}
+
+//Re-executes the transactions of a block relative to the state, i.e. the same adjustments of balances and transaction
+//counters are made as in validateState(), without checking them against the state.
+func expectedStateChange(block *protocol.Block, contractTxs []*protocol.ContractTx, fundsTxs []*protocol.FundsTx,
+	configTxs []*protocol.ConfigTx, stakeTxs []*protocol.StakeTx) map[[64]byte]*protocol.RelativeAccount {
+	stateChange := make(map[[64]byte]*protocol.RelativeAccount)
+
+	adjust := func(address [64]byte, balance int64, txCnt int32) *protocol.RelativeAccount {
+		accRel, exists := stateChange[address]
+		if !exists {
+			accRel = &protocol.RelativeAccount{Address: address}
+			stateChange[address] = accRel
+		}
+		accRel.Balance += balance
+		accRel.TxCnt += txCnt
+		return accRel
+	}
+
+	for _, tx := range contractTxs {
+		accRel := adjust(tx.PubKey, 0, 0)
+		accRel.Issuer = tx.Issuer
+		accRel.Contract = tx.Contract
+		accRel.ContractVariables = tx.ContractVariables
+		adjust(block.Beneficiary, int64(tx.Fee), 0)
+	}
+
+	for _, tx := range fundsTxs {
+		//Root accounts issue new coins
+		if storage.IsRootKey(tx.From) {
+			adjust(tx.From, int64(tx.Amount+tx.Fee), 0)
+		}
+		adjust(tx.From, -int64(tx.Amount+tx.Fee), 1)
+		//The receiver of a cross-shard tx is credited by its own shard.
+		if !isCrossShardTx(tx) {
+			for _, recipient := range tx.Recipients() {
+				adjust(recipient.To, int64(recipient.Amount), 0)
+			}
+		}
+		adjust(block.Beneficiary, int64(tx.Fee), 0)
+	}
+
+	for _, receipt := range block.Receipts {
+		adjust(receipt.Tx.To, int64(receipt.Tx.Amount), 0)
+	}
+
+	for _, tx := range configTxs {
+		adjust(block.Beneficiary, int64(tx.Fee), 0)
+	}
+
+	for _, tx := range stakeTxs {
+		adjust(tx.Account, -int64(tx.Fee), 0)
+		adjust(block.Beneficiary, int64(tx.Fee), 0)
+	}
+
+	adjust(block.Beneficiary, int64(activeParameters.Block_reward), 0)
+
+	if block.SlashedAddress != [64]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} {
+		adjust(block.Beneficiary, int64(activeParameters.Slash_reward), 0)
+		adjust(block.SlashedAddress, -int64(activeParameters.Staking_minimum), 0)
+	}
+
+	return stateChange
+}
+
+func equalTxHashes(hashes1, hashes2 [][32]byte) bool {
+	if len(hashes1) != len(hashes2) {
+		return false
+	}
+
+	for i := range hashes1 {
+		if hashes1[i] != hashes2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Checks that the fraud proof is self-contained and consistent, i.e. the state transition was signed by the validator
+//of the block, the transactions are the ones of the block and at least one of the disputed accounts has been changed
+//differently than the transactions of the block imply. Returns the corrected relative state change.
+func verifyFraudProof(proof *protocol.FraudProof) (map[[64]byte]*protocol.RelativeAccount, error) {
+	if proof == nil || proof.Block == nil || proof.StateTransition == nil {
+		return nil, errors.New("Fraud proof is incomplete.")
+	}
+
+	block := proof.Block
+	st := proof.StateTransition
+
+	if err := verifyStateTransition(st); err != nil {
+		return nil, err
+	}
+
+	if st.BlockHash != block.Hash || !verifyBlockHash(block) {
+		return nil, errors.New(fmt.Sprintf("Block (%x) is not the block of the state transition.", block.Hash[0:8]))
+	}
+
+	if st.ShardID != block.ShardId || st.Height != int(block.Height) || st.Validator != block.Beneficiary {
+		return nil, errors.New(fmt.Sprintf("State transition does not match block (%x).", block.Hash[0:8]))
+	}
+
+	if protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
+		return nil, errors.New(fmt.Sprintf("Merkle root of block (%x) is invalid.", block.Hash[0:8]))
+	}
+
+	var contractTxHashes, fundsTxHashes, configTxHashes, stakeTxHashes [][32]byte
+	for _, tx := range proof.ContractTxs {
+		contractTxHashes = append(contractTxHashes, tx.Hash())
+	}
+	for _, tx := range proof.FundsTxs {
+		fundsTxHashes = append(fundsTxHashes, tx.Hash())
+	}
+	for _, tx := range proof.ConfigTxs {
+		configTxHashes = append(configTxHashes, tx.Hash())
+	}
+	for _, tx := range proof.StakeTxs {
+		stakeTxHashes = append(stakeTxHashes, tx.Hash())
+	}
+
+	if !equalTxHashes(contractTxHashes, block.TxHashes(protocol.CONTRACTTX_TYPE)) || !equalTxHashes(contractTxHashes, st.ContractTxData) ||
+		!equalTxHashes(fundsTxHashes, block.TxHashes(protocol.FUNDSTX_TYPE)) || !equalTxHashes(fundsTxHashes, st.FundsTxData) ||
+		!equalTxHashes(configTxHashes, block.TxHashes(protocol.CONFIGTX_TYPE)) || !equalTxHashes(configTxHashes, st.ConfigTxData) ||
+		!equalTxHashes(stakeTxHashes, block.TxHashes(protocol.STAKETX_TYPE)) || !equalTxHashes(stakeTxHashes, st.StakeTxData) {
+		return nil, errors.New(fmt.Sprintf("Transactions of fraud proof do not match block (%x).", block.Hash[0:8]))
+	}
+
+	expectedChange := expectedStateChange(block, proof.ContractTxs, proof.FundsTxs, proof.ConfigTxs, proof.StakeTxs)
+
+	fraudulent := false
+	for _, address := range proof.DisputedAccounts {
+		//Accounts missing in a state change have not been changed.
+		claimed, expected := protocol.RelativeAccount{}, protocol.RelativeAccount{}
+		if accRel, exists := st.RelativeStateChange[address]; exists {
+			claimed = *accRel
+		}
+		if accRel, exists := expectedChange[address]; exists {
+			expected = *accRel
+		}
+
+		if claimed.Balance != expected.Balance || claimed.TxCnt != expected.TxCnt {
+			fraudulent = true
+			break
+		}
+	}
+
+	if !fraudulent {
+		return nil, errors.New(fmt.Sprintf("State transition of block (%x) matches its transactions for all disputed accounts.", block.Hash[0:8]))
+	}
+
+	//Balances and transaction counters are replaced, all other properties cannot be verified without the state.
+	correctedChange := make(map[[64]byte]*protocol.RelativeAccount)
+	for address, accRel := range st.RelativeStateChange {
+		accCorrected := *accRel
+		accCorrected.Balance, accCorrected.TxCnt = 0, 0
+		correctedChange[address] = &accCorrected
+	}
+	for address, accRel := range expectedChange {
+		if accCorrected, exists := correctedChange[address]; exists {
+			accCorrected.Balance, accCorrected.TxCnt = accRel.Balance, accRel.TxCnt
+		} else {
+			correctedChange[address] = accRel
+		}
+	}
+
+	return correctedChange, nil
+}
+
+//Verifies a fraud proof, rolls back the invalid state transition (if it has been applied already) and adds a slashing
+//proof against the validator who signed it.
+func handleFraudProof(proof *protocol.FraudProof) error {
+	proofHash := proof.Hash()
+
+	fraudProofMutex.Lock()
+	handled := handledFraudProofs[proofHash]
+	fraudProofMutex.Unlock()
+	if handled {
+		return nil
+	}
+
+	correctedChange, err := verifyFraudProof(proof)
+	if err != nil {
+		return err
+	}
+
+	st := proof.StateTransition
+	stHash := st.HashTransition()
+
+	//Same lock order as in validate(), the local state must not change while the state transition is being replaced.
+	blockValidation.Lock()
+	fraudProofMutex.Lock()
+	if handledFraudProofs[proofHash] {
+		fraudProofMutex.Unlock()
+		blockValidation.Unlock()
+		return nil
+	}
+	handledFraudProofs[proofHash] = true
+
+	if appliedChange, exists := appliedStateTransitions[stHash]; exists {
+		storage.State = storage.RevertRelativeState(storage.State, appliedChange)
+		storage.State = storage.ApplyRelativeState(storage.State, correctedChange)
+		partitionState()
+		appliedStateTransitions[stHash] = correctedChange
+	} else {
+		correctedStateTransitions[stHash] = correctedChange
+	}
+
+	//The hash of the fraud proof takes the place of the second conflicting block, see slashingCheck().
+	slashingDict[st.Validator] = SlashingProof{ConflictingBlockHash1: proof.Block.Hash, ConflictingBlockHash2: proofHash}
+	fraudProofMutex.Unlock()
+	blockValidation.Unlock()
+
+	logger.Printf("Accepted fraud proof (%x) against validator (%x) of shard %d.\n", proofHash[0:8], st.Validator[0:8], st.ShardID)
+	FileLogger.Printf("Accepted fraud proof (%x) against validator (%x) of shard %d.\n", proofHash[0:8], st.Validator[0:8], st.ShardID)
+
+	if err := store.WriteFraudProof(proof); err != nil {
+		return err
+	}
+
+	broadcastFraudProof(proof)
+
+	return nil
+}
+
+//Re-executes a foreign block and challenges its state transition if it does not match the transactions of the block.
+func challengeStateTransition(block *protocol.Block, st *protocol.StateTransition) {
+	if block.Hash != st.BlockHash || block.ShardId == storage.ThisShardID {
+		return
+	}
+
+	txs, err := fetchBlockTxs(block, true)
+	if err != nil {
+		FileLogger.Printf("Block (%x) of shard %d could not be re-executed: %v\n", block.Hash[0:8], block.ShardId, err)
+		return
+	}
+
+	data := blockData{txs, block}
+	contractTxs, fundsTxs, configTxs, stakeTxs := data.contractTxs(), data.fundsTxs(), data.configTxs(), data.stakeTxs()
+
+	expectedChange := expectedStateChange(block, contractTxs, fundsTxs, configTxs, stakeTxs)
+
+	var disputedAccounts [][64]byte
+	for address, expected := range expectedChange {
+		if claimed, exists := st.RelativeStateChange[address]; !exists || claimed.Balance != expected.Balance || claimed.TxCnt != expected.TxCnt {
+			disputedAccounts = append(disputedAccounts, address)
+		}
+	}
+	for address, claimed := range st.RelativeStateChange {
+		if _, exists := expectedChange[address]; !exists && (claimed.Balance != 0 || claimed.TxCnt != 0) {
+			disputedAccounts = append(disputedAccounts, address)
+		}
+	}
+
+	if len(disputedAccounts) == 0 {
+		return
+	}
+
+	//Sorted, such that all validators challenging the same state transition come up with the same proof.
+	sort.Slice(disputedAccounts, func(i, j int) bool {
+		return bytes.Compare(disputedAccounts[i][:], disputedAccounts[j][:]) < 0
+	})
+
+	proof := protocol.NewFraudProof(block, st, contractTxs, fundsTxs, configTxs, stakeTxs, disputedAccounts)
+
+	logger.Printf("State transition of block (%x) from shard %d is invalid, %d accounts disputed.\n", block.Hash[0:8], block.ShardId, len(disputedAccounts))
+	FileLogger.Printf("State transition of block (%x) from shard %d is invalid, %d accounts disputed.\n", block.Hash[0:8], block.ShardId, len(disputedAccounts))
+
+	if err := handleFraudProof(proof); err != nil {
+		FileLogger.Printf("Fraud proof against block (%x) rejected: %v\n", block.Hash[0:8], err)
+	}
+}
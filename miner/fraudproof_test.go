@@ -0,0 +1,129 @@
+package miner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+//Creates a block of another shard validated by accA, hashed the same way as in finalizeBlock().
+func createForeignBlock(shardId int, fundsTxs []*protocol.FundsTx) *protocol.Block {
+	block := protocol.NewBlock([32]byte{'0'}, 10)
+	block.ShardId = shardId
+	block.Beneficiary = accA.Address
+	for _, tx := range fundsTxs {
+		block.AddTxHash(protocol.FUNDSTX_TYPE, tx.Hash())
+	}
+	block.NrFundsTx = uint16(len(block.TxHashes(protocol.FUNDSTX_TYPE)))
+	block.MerkleRoot = protocol.BuildMerkleTree(block).MerkleRoot()
+	block.Nonce = [8]byte{'1'}
+
+	partialHash := block.HashBlock()
+	block.Hash = sha3.Sum256(append(block.Nonce[:], partialHash[:]...))
+	block.CommitmentProof = [crypto.COMM_PROOF_LENGTH]byte{'2'}
+	block.Timestamp = 3
+
+	return block
+}
+
+func createSignedStateTransition(block *protocol.Block, stateChange map[[64]byte]*protocol.RelativeAccount) *protocol.StateTransition {
+	st := protocol.NewStateTransition(stateChange, int(block.Height), block.ShardId, block.Hash,
+		block.TxHashes(protocol.CONTRACTTX_TYPE), block.TxHashes(protocol.FUNDSTX_TYPE), block.TxHashes(protocol.CONFIGTX_TYPE), block.TxHashes(protocol.STAKETX_TYPE))
+	st.Validator = accA.Address
+	stHash := st.HashTransition()
+	st.CommitmentSig, _ = crypto.SignMessageWithRSAKey(CommPrivKeyAccA, fmt.Sprintf("%x", stHash))
+
+	return st
+}
+
+func TestFraudProof(t *testing.T) {
+	cleanAndPrepare()
+
+	prevValidatorShardMap := ValidatorShardMap
+	defer func() { ValidatorShardMap = prevValidatorShardMap }()
+
+	ValidatorShardMap = protocol.NewMapping()
+	ValidatorShardMap.ValMapping[accA.Address] = 2
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 50, 2, accB.TxCnt, accB.Address, accA.Address, PrivKeyAccB, nil)
+	fundsTxs := []*protocol.FundsTx{tx}
+	block := createForeignBlock(2, fundsTxs)
+
+	expectedChange := expectedStateChange(block, nil, fundsTxs, nil, nil)
+	if expectedChange[accB.Address].Balance != -52 || expectedChange[accB.Address].TxCnt != 1 ||
+		expectedChange[accA.Address].Balance != int64(50+2+activeParameters.Block_reward) {
+		t.Fatalf("Unexpected relative state change: %v, %v\n", *expectedChange[accA.Address], *expectedChange[accB.Address])
+	}
+
+	//An honest state transition cannot be challenged.
+	honestSt := createSignedStateTransition(block, expectedStateChange(block, nil, fundsTxs, nil, nil))
+	honestProof := protocol.NewFraudProof(block, honestSt, nil, fundsTxs, nil, nil, [][64]byte{accA.Address, accB.Address})
+	if _, err := verifyFraudProof(honestProof); err == nil {
+		t.Error("Fraud proof against an honest state transition has been accepted.")
+	}
+
+	//The validator credits itself more than the block implies.
+	fraudulentChange := expectedStateChange(block, nil, fundsTxs, nil, nil)
+	fraudulentChange[accA.Address].Balance += 1000
+	fraudulentSt := createSignedStateTransition(block, fraudulentChange)
+	proof := protocol.NewFraudProof(block, fraudulentSt, nil, fundsTxs, nil, nil, [][64]byte{accA.Address})
+
+	correctedChange, err := verifyFraudProof(proof)
+	if err != nil {
+		t.Fatalf("Fraud proof has not been accepted: %v\n", err)
+	}
+	if correctedChange[accA.Address].Balance != expectedChange[accA.Address].Balance {
+		t.Errorf("Corrected balance %v does not match expected balance %v.\n", correctedChange[accA.Address].Balance, expectedChange[accA.Address].Balance)
+	}
+
+	//Transactions which are not part of the block are rejected.
+	otherTx, _ := protocol.ConstrFundsTx(0x01, 60, 2, accB.TxCnt, accB.Address, accA.Address, PrivKeyAccB, nil)
+	tamperedProof := protocol.NewFraudProof(block, fraudulentSt, nil, []*protocol.FundsTx{otherTx}, nil, nil, [][64]byte{accA.Address})
+	if _, err := verifyFraudProof(tamperedProof); err == nil {
+		t.Error("Fraud proof with transactions not included in the block has been accepted.")
+	}
+
+	//The block must match its hash.
+	tamperedBlock := *block
+	tamperedBlock.SlashedAddress = accB.Address
+	tamperedProof = protocol.NewFraudProof(&tamperedBlock, fraudulentSt, nil, fundsTxs, nil, nil, [][64]byte{accA.Address})
+	if _, err := verifyFraudProof(tamperedProof); err == nil {
+		t.Error("Fraud proof with a tampered block has been accepted.")
+	}
+
+	//The fraudulent state transition has already been applied and must be rolled back.
+	balanceBefore := storage.State[accA.Address].Balance
+	applyStateTransition(fraudulentSt)
+	if storage.State[accA.Address].Balance != balanceBefore+uint64(fraudulentChange[accA.Address].Balance) {
+		t.Fatalf("State transition has not been applied.")
+	}
+
+	go func() { <-p2p.FraudProofOut }()
+	if err := handleFraudProof(proof); err != nil {
+		t.Fatalf("Fraud proof could not be handled: %v\n", err)
+	}
+
+	if storage.State[accA.Address].Balance != balanceBefore+uint64(expectedChange[accA.Address].Balance) {
+		t.Errorf("Fraudulent state transition has not been rolled back: Balance %v vs. %v\n",
+			storage.State[accA.Address].Balance, balanceBefore+uint64(expectedChange[accA.Address].Balance))
+	}
+
+	//The validator who signed the state transition is slashed with the fraud proof.
+	slashingProof, exists := slashingDict[accA.Address]
+	if !exists || slashingProof.ConflictingBlockHash1 != block.Hash || slashingProof.ConflictingBlockHash2 != proof.Hash() {
+		t.Fatal("Slashing proof for fraudulent validator has not been added.")
+	}
+
+	if valid, err := slashingCheck(accA.Address, slashingProof.ConflictingBlockHash1, slashingProof.ConflictingBlockHash2); !valid {
+		t.Errorf("Slashing proof based on fraud proof is invalid: %v\n", err)
+	}
+
+	if valid, _ := slashingCheck(accB.Address, slashingProof.ConflictingBlockHash1, slashingProof.ConflictingBlockHash2); valid {
+		t.Error("Fraud proof has been accepted to slash another validator.")
+	}
+}
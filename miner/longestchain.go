@@ -70,12 +70,12 @@ func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToVali
 		FileLogger.Printf("Added block (%x) to rollback blocks\n",tmpBlock.Hash[0:8])
 		//The block needs to be in closed storage.
 		tmpBlockNewHash := tmpBlock.PrevHash
-		tmpBlock = storage.ReadClosedBlock(tmpBlockNewHash)
+		tmpBlock = store.ReadClosedBlock(tmpBlockNewHash)
 		if(tmpBlock != nil){
 			FileLogger.Printf("New tmpBlock: (%x)\n",tmpBlock.Hash[0:8])
 		} else {
 			FileLogger.Printf("tmpBlock is nil. No Block found in closed storage for hash: (%x)\n",tmpBlockNewHash[0:8])
-			if(ancestorHash == storage.ReadLastClosedEpochBlock().Hash){
+			if(ancestorHash == store.ReadLastClosedEpochBlock().Hash){
 				break
 			}
 		}
@@ -102,7 +102,7 @@ OUTER:
 		prevBlockHash := newBlock.PrevHash
 
 		//Search in closed (Validated) blocks first
-		potentialAncestor := storage.ReadClosedBlock(prevBlockHash)
+		potentialAncestor := store.ReadClosedBlock(prevBlockHash)
 		if potentialAncestor != nil {
 			//Found ancestor because it is found in our closed block storage.
 			//We went back in time, so reverse order.
@@ -110,7 +110,7 @@ OUTER:
 			return potentialAncestor.Hash, newChain
 		} else {
 			//Check if ancestor is an epoch block
-			potentialEpochAncestorHash := storage.ReadLastClosedEpochBlock().Hash
+			potentialEpochAncestorHash := store.ReadLastClosedEpochBlock().Hash
 			if prevBlockHash == potentialEpochAncestorHash {
 				//Found ancestor because it is found in our closed block storage.
 				//We went back in time, so reverse order.
@@ -121,7 +121,7 @@ OUTER:
 
 
 		//It might be the case that we already started a sync and the block is in the openblock storage.
-		newBlock = storage.ReadOpenBlock(prevBlockHash)
+		newBlock = store.ReadOpenBlock(prevBlockHash)
 		if newBlock != nil {
 			continue
 		}
@@ -144,7 +144,10 @@ OUTER:
 		//Blocking wait
 		select {
 		case encodedBlock := <-p2p.BlockReqChan:
-			newBlock = newBlock.Decode(encodedBlock)
+			var err error
+			if newBlock, err = newBlock.Decode(encodedBlock); err != nil {
+				return [32]byte{}, nil
+			}
 			storage.WriteToReceivedStash(newBlock)
 			//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
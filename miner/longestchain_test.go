@@ -2,7 +2,6 @@ package miner
 
 import (
 	"github.com/bazo-blockchain/bazo-miner/crypto"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 	"testing"
 )
 
@@ -41,31 +40,29 @@ func TestGetBlockSequences(t *testing.T) {
 	}
 
 	//PoW needs lastBlock, have to set it manually
-	//lastBlock = storage.ReadClosedBlock([32]byte{})
+	//lastBlock = store.ReadClosedBlock([32]byte{})
 	lastBlock = initialBlock
 	//c := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	//The blocks of the competing chain carry no txs, the state of the current chain is not rolled back for them.
 	c := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
-	createBlockWithTxs(c)
 	if err := finalizeBlock(c); err != nil {
 		t.Error(err)
 		return
 	}
-	storage.WriteOpenBlock(c)
+	store.WriteOpenBlock(c)
 
 	//PoW needs lastBlock, have to set it manually
 	lastBlock = c
 	c2 := newBlock(c.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, c.Height+1)
-	createBlockWithTxs(c2)
 	if err := finalizeBlock(c2); err != nil {
 		t.Error(err)
 		return
 	}
-	storage.WriteOpenBlock(c2)
+	store.WriteOpenBlock(c2)
 
 	//PoW needs lastBlock, have to set it manually
 	lastBlock = c2
 	c3 := newBlock(c2.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, c.Height+1)
-	createBlockWithTxs(c3)
 	finalizeBlock(c3)
 
 	lastBlock = b2
@@ -108,24 +105,21 @@ func TestGetBlockSequences(t *testing.T) {
 
 	//Blockchain now: genesis <- b <- b2 <- b3
 	//Competing chain: genesis <- c <- c2 <- c3
-	//lastBlock = storage.ReadClosedBlock([32]byte{})
+	//lastBlock = store.ReadClosedBlock([32]byte{})
 	lastBlock = initialBlock
 
 	//c = newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
 	c = newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
-	createBlockWithTxs(c)
 	finalizeBlock(c)
-	storage.WriteOpenBlock(c)
+	store.WriteOpenBlock(c)
 
 	lastBlock = c
 	c2 = newBlock(c.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, c.Height+1)
-	createBlockWithTxs(c2)
 	finalizeBlock(c2)
-	storage.WriteOpenBlock(c2)
+	store.WriteOpenBlock(c2)
 
 	lastBlock = c2
 	c3 = newBlock(c2.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, c2.Height+1)
-	createBlockWithTxs(c3)
 	finalizeBlock(c3)
 
 	//Make sure that the new blockchain of equal length does not get activated
@@ -162,18 +156,17 @@ func TestGetNewChain(t *testing.T) {
 
 	//Blockchain now: genesis <- b
 	//New chain: genesis <- c <- c2
-	//lastBlock = storage.ReadClosedBlock([32]byte{})
+	//lastBlock = store.ReadClosedBlock([32]byte{})
 	//c := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
 	lastBlock = initialBlock
+	//The blocks of the competing chain carry no txs, the state of the current chain is not rolled back for them.
 	c := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
 
-	createBlockWithTxs(c)
 	finalizeBlock(c)
-	storage.WriteOpenBlock(c)
+	store.WriteOpenBlock(c)
 
 	lastBlock = c
 	c2 := newBlock(c.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, c.Height+1)
-	createBlockWithTxs(c2)
 	finalizeBlock(c2)
 
 	lastBlock = b
@@ -164,7 +164,7 @@ func addRootAccounts() {
 //The state changes (accounts, funds, system parameters etc.) need to be reverted before any new test starts
 //So every test has the same view on the blockchain
 func cleanAndPrepare() {
-	storage.DeleteAll()
+	store.DeleteAll()
 
 	tmpState := make(map[[64]byte]*protocol.Account)
 	tmpRootKeys := make(map[[64]byte]*protocol.Account)
@@ -203,22 +203,22 @@ func cleanAndPrepare() {
 		crypto.GetBytesFromRSAPubKey(&CommPrivKeyRoot.PublicKey))
 
 	lastEpochBlock = protocol.NewEpochBlock([][32]byte{genesis.Hash()}, 0)
-	storage.WriteClosedEpochBlock(lastEpochBlock)
+	store.WriteClosedEpochBlock(lastEpochBlock)
 
-	storage.DeleteAllLastClosedEpochBlock()
-	storage.WriteLastClosedEpochBlock(lastEpochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(lastEpochBlock)
 
 	commitmentProof, _ := crypto.SignMessageWithRSAKey(CommPrivKeyRoot, "1")
 	initialBlock = newBlock(lastEpochBlock.HashEpochBlock(), commitmentProof, 1)
-	storage.WriteClosedBlock(initialBlock)
+	store.WriteClosedBlock(initialBlock)
 	lastBlock = initialBlock
 	lastBlock.Hash = lastBlock.HashBlock()
 
 	collectStatistics(initialBlock)
-	if err := storage.WriteClosedBlock(initialBlock); err != nil {
+	if err := store.WriteClosedBlock(initialBlock); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
-	if err := storage.WriteLastClosedBlock(initialBlock); err != nil {
+	if err := store.WriteLastClosedBlock(initialBlock); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
 
@@ -238,8 +238,8 @@ func cleanAndPrepare() {
 }
 
 func TestMain(m *testing.M) {
-	storage.Init(TestDBFileName, TestIpPort)
-	p2p.Init(TestIpPort)
+	store, _ = storage.Init(TestDBFileName, TestIpPort)
+	p2p.Init(TestIpPort, store)
 	p2p.InitLogging()
 
 	logger = storage.InitLogger()
@@ -256,8 +256,15 @@ func TestMain(m *testing.M) {
 	retCode := m.Run()
 
 	//Teardown
-	storage.TearDown()
+	store.Close()
 	os.Remove(TestDBFileName)
 	os.Remove(TestKeyFileName)
 	os.Exit(retCode)
 }
+
+func transactionsOf(fundsTxs []*protocol.FundsTx) (txs []protocol.Transaction) {
+	for _, tx := range fundsTxs {
+		txs = append(txs, tx)
+	}
+	return txs
+}
@@ -23,6 +23,13 @@ func incomingStateData(){
 		processStateData(stateTransition)
 	}
 }
+//Constantly listen to incoming fraud proofs from the network
+func incomingFraudProofs() {
+	for {
+		fraudProof := <-p2p.FraudProofIn
+		processFraudProof(fraudProof)
+	}
+}
 //Constantly listen to incoming epoch block data from the network
 func incomingEpochData() {
 	for {
@@ -36,12 +43,25 @@ func incomingEpochData() {
 
 func processEpochBlock(eb []byte) {
 	var epochBlock *protocol.EpochBlock
-	epochBlock = epochBlock.Decode(eb)
+	epochBlock, err := epochBlock.Decode(eb)
+	if err != nil {
+		logger.Printf("Received Epoch Block could not be decoded: %v\n", err)
+		FileLogger.Printf("Received Epoch Block could not be decoded: %v\n", err)
+		return
+	}
 
-	if(storage.ReadClosedEpochBlock(epochBlock.Hash) != nil){
+	if(store.ReadClosedEpochBlock(epochBlock.Hash) != nil){
 		logger.Printf("Received Epoch Block (%x) already in storage\n", epochBlock.Hash[0:8])
 		FileLogger.Printf("Received Epoch Block (%x) already in storage\n", epochBlock.Hash[0:8])
 		return
+	} else if protocol.StateRoot(epochBlock.State) != epochBlock.MerklePatriciaRoot {
+		logger.Printf("Received Epoch Block (%x) rejected: state does not match the Merkle Patricia Root\n", epochBlock.Hash[0:8])
+		FileLogger.Printf("Received Epoch Block (%x) rejected: state does not match the Merkle Patricia Root\n", epochBlock.Hash[0:8])
+		return
+	} else if err := verifyShardStateRoots(epochBlock); err != nil {
+		logger.Printf("Received Epoch Block (%x) rejected: %v\n", epochBlock.Hash[0:8], err)
+		FileLogger.Printf("Received Epoch Block (%x) rejected: %v\n", epochBlock.Hash[0:8], err)
+		return
 	} else {
 		//Accept the last received epoch block as the valid one. From the epoch block, retrieve the global state and the
 		//valiadator-shard mapping. Upon successful acceptance, broadcast the epoch block
@@ -51,10 +71,11 @@ func processEpochBlock(eb []byte) {
 		NumberOfShards = epochBlock.NofShards
 		storage.ThisShardID = ValidatorShardMap.ValMapping[validatorAccAddress]
 		lastEpochBlock = epochBlock
-		storage.WriteClosedEpochBlock(epochBlock)
+		store.WriteClosedEpochBlock(epochBlock)
 
-		storage.DeleteAllLastClosedEpochBlock()
-		storage.WriteLastClosedEpochBlock(epochBlock)
+		store.DeleteAllLastClosedEpochBlock()
+		store.WriteLastClosedEpochBlock(epochBlock)
+		pruneChain()
 
 		broadcastEpochBlock(lastEpochBlock)
 	}
@@ -62,11 +83,20 @@ func processEpochBlock(eb []byte) {
 
 func processStateData(payload []byte) {
 	var stateTransition *protocol.StateTransition
-	stateTransition = stateTransition.DecodeTransition(payload)
+	stateTransition, err := stateTransition.DecodeTransition(payload)
+	if err != nil {
+		FileLogger.Printf("Received state transition could not be decoded: %v\n", err)
+		return
+	}
 
 	if(lastEpochBlock != nil){
 		//Only process state transition data form other shards and from the current epoch
 		if (stateTransition.ShardID != storage.ThisShardID && stateTransition.Height > int(lastEpochBlock.Height)){
+			//Neither store nor redistribute state transitions which were not signed by the validator of the shard
+			if err := verifyStateTransition(stateTransition); err != nil {
+				FileLogger.Printf("Received state transition of shard %d rejected: %v\n", stateTransition.ShardID, err)
+				return
+			}
 			stateHash := stateTransition.HashTransition()
 			if (storage.ReceivedStateStash.StateTransitionIncluded(stateHash) == false){
 				FileLogger.Printf("Writing state to stash Shard ID: %v  VS my shard ID: %v - Height: %d - Hash: %x\n",stateTransition.ShardID,storage.ThisShardID,stateTransition.Height,stateHash[0:8])
@@ -75,6 +105,10 @@ func processStateData(payload []byte) {
 				FileLogger.Printf("Length state stash keys: %d\n",len(storage.ReceivedStateStash.Keys))
 				FileLogger.Printf("Redistributing state transition\n")
 				broadcastStateTransition(stateTransition)
+				//Re-execute the block of the state transition, if it has already been received
+				if block := storage.ReadReceivedBlock(stateTransition.BlockHash); block != nil {
+					go challengeStateTransition(block, stateTransition)
+				}
 			} else {
 				FileLogger.Printf("Received state transition already included: Shard ID: %v  VS my shard ID: %v - Height: %d - Hash: %x\n",stateTransition.ShardID,storage.ThisShardID,stateTransition.Height,stateHash[0:8])
 				return
@@ -83,9 +117,31 @@ func processStateData(payload []byte) {
 	}
 }
 
+func processFraudProof(payload []byte) {
+	var proof *protocol.FraudProof
+	proof, err := proof.Decode(payload)
+	if err != nil {
+		FileLogger.Printf("Received fraud proof could not be decoded: %v\n", err)
+		return
+	}
+
+	if lastEpochBlock != nil {
+		proofHash := proof.Hash()
+		if err := handleFraudProof(proof); err != nil {
+			logger.Printf("Received fraud proof (%x) rejected: %v\n", proofHash[0:8], err)
+			FileLogger.Printf("Received fraud proof (%x) rejected: %v\n", proofHash[0:8], err)
+		}
+	}
+}
+
 func processBlock(payload []byte) {
 	var block *protocol.Block
-	block = block.Decode(payload)
+	block, err := block.Decode(payload)
+	if err != nil {
+		logger.Printf("Received block could not be decoded: %v\n", err)
+		FileLogger.Printf("Received block could not be decoded: %v\n", err)
+		return
+	}
 
 	if(lastEpochBlock != nil){
 		FileLogger.Printf("Received block (%x) from shard %d with height: %d\n", block.Hash[0:8],block.ShardId,block.Height)
@@ -93,13 +149,19 @@ func processBlock(payload []byte) {
 		if(!storage.BlockAlreadyInStash(storage.ReceivedBlockStash,block.Hash) && block.ShardId != storage.ThisShardID){
 			storage.WriteToReceivedStash(block)
 			broadcastBlock(block)
+			//Credit the cross-shard FundsTxs of the block to this shard
+			go collectReceipts(block)
+			//Re-execute the block, if its state transition has already been received
+			if stateTransition := protocol.ReturnStateTransitionForBlockHash(storage.ReceivedStateStash, block.Hash); stateTransition != nil {
+				go challengeStateTransition(block, stateTransition)
+			}
 		} else {
 			FileLogger.Printf("Received block (%x) already in block stash\n",block.Hash[0:8])
 		}
 
 		if block.ShardId == storage.ThisShardID && block.Height > lastEpochBlock.Height {
 			//Block already confirmed and validated
-			if storage.ReadClosedBlock(block.Hash) != nil {
+			if store.ReadClosedBlock(block.Hash) != nil {
 				logger.Printf("Received block (%x) has already been validated.\n", block.Hash[0:8])
 				FileLogger.Printf("Received block (%x) has already been validated.\n", block.Hash[0:8])
 				return
@@ -132,7 +194,7 @@ func broadcastBlock(block *protocol.Block) {
 	//Make a deep copy of the block (since it is a pointer and will be saved to db later).
 	//Otherwise the block's bloom filter is initialized on the original block.
 	//var blockCopy = *block
-	//blockCopy.InitBloomFilter(append(storage.GetTxPubKeys(&blockCopy)))
+	//blockCopy.InitBloomFilter(append(storage.GetTxPubKeys(store, &blockCopy)))
 	//p2p.BlockHeaderOut <- blockCopy.EncodeHeader()
 }
 
@@ -140,6 +202,10 @@ func broadcastStateTransition(st *protocol.StateTransition) {
 	p2p.StateTransitionOut <- st.EncodeTransition()
 }
 
+func broadcastFraudProof(proof *protocol.FraudProof) {
+	p2p.FraudProofOut <- proof.Encode()
+}
+
 func broadcastEpochBlock(epochBlock *protocol.EpochBlock) {
 	FileLogger.Printf("Writing Epoch block (%x) to channel EpochBlockOut\n", epochBlock.Hash[0:8])
 	p2p.EpochBlockOut <- epochBlock.Encode()
@@ -153,5 +219,5 @@ func broadcastVerifiedTxs(txs []*protocol.FundsTx) {
 		verifiedTxs = append(verifiedTxs, tx.Encode()[:])
 	}
 
-	p2p.VerifiedTxsOut <- protocol.Encode(verifiedTxs, protocol.FUNDSTX_SIZE)
+	p2p.VerifiedTxsOut <- protocol.EncodeList(verifiedTxs)
 }
\ No newline at end of file
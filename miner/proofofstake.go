@@ -271,7 +271,7 @@ func proofOfStakeEpoch(diff uint8,
 
 func GetLatestProofs(n int, block *protocol.Block) (prevProofs [][crypto.COMM_PROOF_LENGTH]byte) {
 	for block.Height > lastEpochBlock.Height && n > 0 {
-		block = storage.ReadClosedBlock(block.PrevHash)
+		block = store.ReadClosedBlock(block.PrevHash)
 		if(block == nil){
 			break
 		}
@@ -3,7 +3,6 @@ package miner
 import (
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -51,7 +50,7 @@ func TestGetLatestProofs(t *testing.T) {
 	if err := finalizeBlock(b); err != nil {
 		t.Error("Error finalizing b1", err)
 	}
-	storage.WriteClosedBlock(b)
+	store.WriteClosedBlock(b)
 	proofs = append([][crypto.COMM_PROOF_LENGTH]byte{b.CommitmentProof}, proofs...)
 
 	prevProofs := GetLatestProofs(1, b)
@@ -72,7 +71,7 @@ func TestGetLatestProofs(t *testing.T) {
 	}
 	proofs = append([][crypto.COMM_PROOF_LENGTH]byte{b1.CommitmentProof}, proofs...)
 	validate(b1, false)
-	storage.WriteClosedBlock(b1)
+	store.WriteClosedBlock(b1)
 	lastBlock = b1
 
 	b2 := newBlock(b1.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b1.Height+1)
@@ -85,10 +84,10 @@ func TestGetLatestProofs(t *testing.T) {
 	t.Log("Proofs Slice:\n")
 	t.Logf("%v",CommitmentProofSliceToString(proofs))
 
-	storage.WriteClosedBlock(b2)
+	store.WriteClosedBlock(b2)
 
 	b3 := newBlock(b2.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b2.Height+1)
-	storage.WriteClosedBlock(b3)
+	store.WriteClosedBlock(b3)
 
 	prevProofs = GetLatestProofs(3, b3)
 	t.Log("prevProofs Slice with n=3 :\n")
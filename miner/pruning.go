@@ -0,0 +1,44 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//In pruned mode, the shard blocks and txs of the last KeptEpochs epochs are kept, older ones are deleted together with
+//the state trie nodes only their roots reach. The epoch blocks are always kept, each of them carries the complete state.
+//0 keeps the whole chain.
+var KeptEpochs int
+
+//Prunes the shard blocks below the KeptEpochs-th last epoch block, called whenever an epoch block is closed.
+func pruneChain() {
+	if KeptEpochs <= 0 || lastEpochBlock == nil {
+		return
+	}
+
+	var boundary *protocol.EpochBlock
+	kept := 0
+	store.ForEachClosedEpochBlock(lastEpochBlock.Height, 0, func(epochBlock *protocol.EpochBlock) bool {
+		boundary = epochBlock
+		kept++
+		return kept < KeptEpochs
+	})
+
+	if kept < KeptEpochs {
+		return
+	}
+
+	//The state tries of the blocks being validated are written with blockValidation held, they must not be pruned
+	//before their blocks are closed.
+	blockValidation.Lock()
+	pruned, err := store.PruneClosedBlocks(boundary.Height)
+	blockValidation.Unlock()
+	if err != nil {
+		logger.Printf("Pruning the blocks below height %d failed: %v\n", boundary.Height, err)
+		FileLogger.Printf("Pruning the blocks below height %d failed: %v\n", boundary.Height, err)
+		return
+	}
+	if pruned > 0 {
+		logger.Printf("Pruned %d block(s) below height %d\n", pruned, boundary.Height)
+		FileLogger.Printf("Pruned %d block(s) below height %d\n", pruned, boundary.Height)
+	}
+}
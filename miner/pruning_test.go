@@ -0,0 +1,56 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"testing"
+)
+
+func TestPruneChain(t *testing.T) {
+	cleanAndPrepare()
+	defer func() { KeptEpochs = 0 }()
+
+	// epoch block (0) <- b5 <- epoch block (10) <- b15 <- epoch block (20) <- b25
+	var blocks []*protocol.Block
+	for _, height := range []uint32{5, 15, 25} {
+		b := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		b.Hash = [32]byte{'b', byte(height)}
+		store.WriteClosedBlock(b)
+		blocks = append(blocks, b)
+	}
+	for _, height := range []uint32{10, 20} {
+		epochBlock := protocol.NewEpochBlock(nil, height)
+		epochBlock.Hash = [32]byte{'e', byte(height)}
+		store.WriteClosedEpochBlock(epochBlock)
+		lastEpochBlock = epochBlock
+	}
+
+	//Without pruning, the whole chain is kept.
+	pruneChain()
+	if store.ReadPruneHeight() != 0 {
+		t.Errorf("Chain was pruned up to height %d without pruning\n", store.ReadPruneHeight())
+	}
+
+	KeptEpochs = 4
+	pruneChain()
+	if store.ReadPruneHeight() != 0 {
+		t.Errorf("Chain with fewer epochs than kept was pruned up to height %d\n", store.ReadPruneHeight())
+	}
+
+	KeptEpochs = 2
+	pruneChain()
+	if store.ReadPruneHeight() != 10 || store.ReadClosedBlock(blocks[0].Hash) != nil || store.ReadClosedBlock(blocks[1].Hash) == nil {
+		t.Errorf("Blocks of the last 2 epochs were not kept, prune height %d\n", store.ReadPruneHeight())
+	}
+
+	KeptEpochs = 1
+	pruneChain()
+	if store.ReadPruneHeight() != 20 || store.ReadClosedBlock(blocks[1].Hash) != nil || store.ReadClosedBlock(blocks[2].Hash) == nil {
+		t.Errorf("Blocks of the last epoch were not kept, prune height %d\n", store.ReadPruneHeight())
+	}
+
+	//The epoch blocks are kept.
+	if store.ReadClosedEpochBlockByHeight(10) == nil || store.ReadClosedEpochBlockByHeight(0) == nil {
+		t.Error("Epoch blocks were pruned.")
+	}
+}
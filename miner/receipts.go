@@ -0,0 +1,104 @@
+package miner
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Receipts of cross-shard FundsTxs to this shard which have not been credited yet, keyed by the hash of the FundsTx.
+var (
+	receiptMutex = &sync.Mutex{}
+	openReceipts = make(map[[32]byte]*protocol.Receipt)
+)
+
+func writeOpenReceipt(receipt *protocol.Receipt) {
+	receiptMutex.Lock()
+	defer receiptMutex.Unlock()
+
+	openReceipts[receipt.Tx.Hash()] = receipt
+}
+
+func deleteOpenReceipt(txHash [32]byte) {
+	receiptMutex.Lock()
+	defer receiptMutex.Unlock()
+
+	delete(openReceipts, txHash)
+}
+
+//Sorted by the hash of the FundsTx, such that the same receipts are included independent of the map order.
+func readOpenReceipts() (receipts []*protocol.Receipt) {
+	receiptMutex.Lock()
+	defer receiptMutex.Unlock()
+
+	for _, receipt := range openReceipts {
+		receipts = append(receipts, receipt)
+	}
+
+	sort.Slice(receipts, func(i, j int) bool {
+		hash1, hash2 := receipts[i].Tx.Hash(), receipts[j].Tx.Hash()
+		return bytes.Compare(hash1[:], hash2[:]) < 0
+	})
+
+	return receipts
+}
+
+//Creates the receipts for all cross-shard FundsTxs of a foreign block which transfer funds to this shard.
+func collectReceipts(block *protocol.Block) {
+	if block.ShardId == storage.ThisShardID || len(block.TxHashes(protocol.FUNDSTX_TYPE)) == 0 {
+		return
+	}
+
+	txs := make([]protocol.Transaction, len(block.TxHashes(protocol.FUNDSTX_TYPE)))
+	errChan := make(chan error, 1)
+	go fetchTxData(block, protocol.GetTxType(protocol.FUNDSTX_TYPE), txs, true, errChan)
+	if err := <-errChan; err != nil {
+		FileLogger.Printf("Receipts of block (%x) could not be created: %v\n", block.Hash[0:8], err)
+		return
+	}
+
+	for _, tx := range fundsTxsOf(txs) {
+		if !isCrossShardTx(tx) || assignAddressToShard(tx.From) != block.ShardId || assignAddressToShard(tx.To) != storage.ThisShardID {
+			continue
+		}
+
+		txHash := tx.Hash()
+		if store.ReadReceipt(txHash) != nil {
+			continue
+		}
+
+		merkleProof, err := protocol.MerkleProof(block, txHash)
+		if err != nil {
+			FileLogger.Printf("Receipt of tx (%x) could not be created: %v\n", txHash[0:8], err)
+			continue
+		}
+
+		writeOpenReceipt(protocol.NewReceipt(tx, block.ShardId, block.Hash, merkleProof))
+		FileLogger.Printf("Created receipt for tx (%x) of shard %d\n", txHash[0:8], block.ShardId)
+	}
+}
+
+//Adds the open receipts to the block, as long as the block size permits.
+func addReceipts(block *protocol.Block) {
+	for _, receipt := range readOpenReceipts() {
+		if block.GetSize()+receipt.Size() > activeParameters.Block_size {
+			break
+		}
+
+		txHash := receipt.Tx.Hash()
+		if err := verifyReceipt(receipt, block, false); err != nil {
+			FileLogger.Printf("Receipt (%x) could not be added: %v\n", txHash[0:8], err)
+			//Already credited receipts will never become valid again.
+			if store.ReadReceipt(txHash) != nil {
+				deleteOpenReceipt(txHash)
+			}
+			continue
+		}
+
+		block.Receipts = append(block.Receipts, receipt)
+		FileLogger.Printf("Added receipt to the block: (%x)\n", txHash[0:8])
+	}
+}
@@ -0,0 +1,118 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestCrossShardFundsTx(t *testing.T) {
+	cleanAndPrepare()
+
+	prevNumberOfShards, prevThisShardID, prevValidatorShardMap := NumberOfShards, storage.ThisShardID, ValidatorShardMap
+	defer func() {
+		NumberOfShards, storage.ThisShardID, ValidatorShardMap = prevNumberOfShards, prevThisShardID, prevValidatorShardMap
+		storage.ReceivedBlockStash = make([]*protocol.Block, 0)
+		storage.ReceivedStateStash = protocol.NewStateStash()
+		openReceipts = make(map[[32]byte]*protocol.Receipt)
+	}()
+
+	NumberOfShards = 2
+	fromShard := assignAddressToShard(accA.Address)
+	ValidatorShardMap = protocol.NewMapping()
+	ValidatorShardMap.ValMapping[accA.Address] = fromShard
+
+	//Find a receiver in the other shard
+	var to [64]byte
+	for to[7] = 0; assignAddressToShard(to) == fromShard; to[7]++ {
+	}
+	toShard := assignAddressToShard(to)
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 50, 1, accA.TxCnt, accA.Address, to, PrivKeyAccA, nil)
+	if !isCrossShardTx(tx) {
+		t.Fatal("FundsTx to another shard is not cross-shard.")
+	}
+
+	//The shard of the sender only debits the amount.
+	balanceBefore := accA.Balance
+	if err := fundsStateChange([]*protocol.FundsTx{tx}); err != nil {
+		t.Fatalf("Cross-shard FundsTx could not be applied: %v\n", err)
+	}
+	if accA.Balance != balanceBefore-50 || storage.State[to] != nil {
+		t.Errorf("Cross-shard FundsTx has not only been debited: Balance %v vs. %v\n", accA.Balance, balanceBefore-50)
+	}
+	fundsStateChangeRollback([]*protocol.FundsTx{tx})
+	if accA.Balance != balanceBefore {
+		t.Errorf("Cross-shard FundsTx has not been rolled back: Balance %v vs. %v\n", accA.Balance, balanceBefore)
+	}
+
+	//The shard of the receiver creates a receipt for the block of the sender shard.
+	store.WriteOpenTx(tx)
+	senderBlock := createForeignBlock(fromShard, []*protocol.FundsTx{tx})
+	storage.WriteToReceivedStash(senderBlock)
+	storage.ThisShardID = toShard
+
+	collectReceipts(senderBlock)
+	if len(readOpenReceipts()) != 1 {
+		t.Fatalf("Receipt has not been created.")
+	}
+
+	//The block of the sender shard is only trusted once its validator signed a state transition for it.
+	block := newBlock(senderBlock.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	block.ShardId = toShard
+	addReceipts(block)
+	if len(block.Receipts) != 0 {
+		t.Fatalf("Receipt of an unauthenticated block has been added to the block.")
+	}
+
+	st := createSignedStateTransition(senderBlock, nil)
+	storage.ReceivedStateStash.Set(st.HashTransition(), st)
+	addReceipts(block)
+	if len(block.Receipts) != 1 || block.Receipts[0].Tx.Hash() != tx.Hash() {
+		t.Fatalf("Receipt has not been added to the block.")
+	}
+
+	if err := receiptStateChange(block.Receipts); err != nil {
+		t.Fatalf("Receipt could not be credited: %v\n", err)
+	}
+	if storage.State[to] == nil || storage.State[to].Balance != 50 {
+		t.Error("Receiver has not been credited.")
+	}
+
+	receipt := block.Receipts[0]
+
+	//Receipts are only valid with a proof of the transaction in the block of the sender.
+	tamperedReceipt := *receipt
+	tamperedReceipt.MerkleProof = [][32]byte{{'1'}, {'2'}}
+	if err := verifyReceipt(&tamperedReceipt, block, false); err == nil {
+		t.Error("Receipt with invalid Merkle proof has been verified.")
+	}
+
+	tamperedReceipt = *receipt
+	tamperedReceipt.BlockHash = [32]byte{'3'}
+	if err := verifyReceipt(&tamperedReceipt, block, false); err == nil {
+		t.Error("Receipt of an unknown block has been verified.")
+	}
+
+	//A block of the sender shard made up by someone who is not its validator is rejected.
+	forgedTx, _ := protocol.ConstrFundsTx(0x01, 1000, 1, accB.TxCnt, accB.Address, to, PrivKeyAccB, nil)
+	forgedBlock := createForeignBlock(fromShard, []*protocol.FundsTx{forgedTx})
+	forgedBlock.Beneficiary = accB.Address
+	storage.WriteToReceivedStash(forgedBlock)
+	forgedSt := createSignedStateTransition(forgedBlock, nil)
+	forgedSt.Validator = accB.Address
+	storage.ReceivedStateStash.Set(forgedSt.HashTransition(), forgedSt)
+	merkleProof, _ := protocol.MerkleProof(forgedBlock, forgedTx.Hash())
+	forgedReceipt := protocol.NewReceipt(forgedTx, fromShard, forgedBlock.Hash, merkleProof)
+	if err := verifyReceipt(forgedReceipt, block, false); err == nil {
+		t.Error("Receipt of a forged block of the sender shard has been verified.")
+	}
+
+	//Every receipt can only be credited once.
+	store.WriteReceipt(receipt, block.Height)
+	if err := verifyReceipt(receipt, block, false); err == nil {
+		t.Error("Credited receipt has been verified again.")
+	}
+}
@@ -39,8 +39,13 @@ func TestGenerateNodes(t *testing.T) {
 				t.Errorf("Error while creating node directory %v\n",err)
 			}
 		}
-		storage.Init(NodesDirectory+strNode+"/storage.db", TestIpPort)
-		_, err := crypto.ExtractECDSAPublicKeyFromFile(NodesDirectory+strNode+"/wallet.key")
+		nodeStore, err := storage.Init(NodesDirectory+strNode+"/storage.db", TestIpPort)
+		if err != nil {
+			t.Errorf("Error while initializing the storage %v\n",err)
+			return
+		}
+		nodeStore.Close()
+		_, err = crypto.ExtractECDSAPublicKeyFromFile(NodesDirectory+strNode+"/wallet.key")
 		if err != nil {
 			return
 		}
@@ -73,8 +78,13 @@ func TestShardingWith20Nodes(t *testing.T) {
 				t.Errorf("Error while creating node directory %v\n",err)
 			}
 		}
-		storage.Init(NodesDirectory+strNode+"/storage.db", TestIpPort)
-		_, err := crypto.ExtractECDSAPublicKeyFromFile(NodesDirectory+strNode+"/wallet.key")
+		nodeStore, err := storage.Init(NodesDirectory+strNode+"/storage.db", TestIpPort)
+		if err != nil {
+			t.Errorf("Error while initializing the storage %v\n",err)
+			return
+		}
+		nodeStore.Close()
+		_, err = crypto.ExtractECDSAPublicKeyFromFile(NodesDirectory+strNode+"/wallet.key")
 		if err != nil {
 			return
 		}
@@ -0,0 +1,235 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//In partitioned mode, a validator only keeps the accounts of its shard. The staking accounts are kept as well, they are
+//needed for the validator-shard assignment and the proof of stake and are part of every epoch block.
+func ownsAccount(acc *protocol.Account) bool {
+	return !storage.PartitionedState || storage.PartitionNumberOfShards == 0 || acc.IsStaking ||
+		addressShard(acc.Address, storage.PartitionNumberOfShards) == storage.PartitionShardID
+}
+
+//Removes the accounts of other shards from the local state, e.g. the ones created by state transitions of other shards.
+func partitionState() {
+	for address, acc := range storage.State {
+		if !ownsAccount(acc) {
+			delete(storage.State, address)
+		}
+	}
+}
+
+func stakingState() map[[64]byte]*protocol.Account {
+	state := make(map[[64]byte]*protocol.Account)
+	for address, acc := range storage.State {
+		if acc.IsStaking {
+			state[address] = acc
+		}
+	}
+
+	return state
+}
+
+//Keeps a copy of the accounts of this shard at the last block of an epoch, such that they can be served to the
+//validators of the new shards while this validator continues mining. Its root is committed in the state transition of
+//the block. Must be called with blockValidation held.
+func writeShardStateSnapshot(epochHeight int) *protocol.ShardState {
+	var accounts []*protocol.Account
+	for _, acc := range storage.State {
+		if !acc.IsStaking && assignAddressToShard(acc.Address) == storage.ThisShardID {
+			accCopy := *acc
+			accounts = append(accounts, &accCopy)
+		}
+	}
+
+	snapshot := protocol.NewShardState(epochHeight, storage.ThisShardID, NumberOfShards, accounts)
+	storage.WriteShardStateSnapshot(snapshot)
+
+	return snapshot
+}
+
+//The roots of the shard states committed in the state transitions of the last blocks of the epoch, the own one
+//included. The root of shard i is at index i-1.
+func shardStateRoots(height uint32) ([][32]byte, error) {
+	stateTransitions := protocol.ReturnStateTransitionForHeight(storage.ReceivedStateStash, height)
+	if own := storage.ReadStateTransitionFromOwnStash(int(height)); own != nil {
+		stateTransitions = append(stateTransitions, own)
+	}
+
+	roots := make([][32]byte, NumberOfShards)
+	committed := make(map[int]bool)
+	for _, st := range stateTransitions {
+		if st.ShardID >= 1 && st.ShardID <= NumberOfShards {
+			roots[st.ShardID-1] = st.ShardStateRoot
+			committed[st.ShardID] = true
+		}
+	}
+
+	for shardID := 1; shardID <= NumberOfShards; shardID++ {
+		if !committed[shardID] {
+			return nil, errors.New(fmt.Sprintf("State transition of shard %d at height %d is missing.", shardID, height))
+		}
+	}
+
+	return roots, nil
+}
+
+//Checks the roots of the shard states in a received epoch block against the state transitions this validator holds.
+func verifyShardStateRoots(epochBlock *protocol.EpochBlock) error {
+	height := epochBlock.Height - 1
+	stateTransitions := protocol.ReturnStateTransitionForHeight(storage.ReceivedStateStash, height)
+	if own := storage.ReadStateTransitionFromOwnStash(int(height)); own != nil {
+		stateTransitions = append(stateTransitions, own)
+	}
+
+	for _, st := range stateTransitions {
+		if st.ShardID < 1 || st.ShardID > len(epochBlock.ShardStateRoots) || epochBlock.ShardStateRoots[st.ShardID-1] != st.ShardStateRoot {
+			return errors.New(fmt.Sprintf("Root of shard %d does not match its state transition.", st.ShardID))
+		}
+	}
+
+	return nil
+}
+
+/**
+	Executed at every epoch block, as soon as the new validator-shard assignment is known. If this validator is assigned
+	to another shard or the number of shards changed, the accounts of the new shard are fetched from the other validators.
+ */
+func syncShardState() {
+	if !storage.PartitionedState {
+		return
+	}
+
+	if storage.PartitionShardID == storage.ThisShardID && storage.PartitionNumberOfShards == NumberOfShards {
+		return
+	}
+
+	//The whole state is held (e.g., in the first epoch), the accounts of the other shards can simply be dropped.
+	if storage.PartitionNumberOfShards == 0 {
+		blockValidation.Lock()
+		storage.PartitionShardID, storage.PartitionNumberOfShards = storage.ThisShardID, NumberOfShards
+		partitionState()
+		blockValidation.Unlock()
+		return
+	}
+
+	height := int(lastEpochBlock.Height)
+	fetchShardState(height, storage.ReadShardStateSnapshot(height))
+}
+
+//Collects the accounts of the new shard from the validators of all shards of the previous epoch, since every shard of the
+//previous epoch might hold some of them. Every shard state is checked against its root in the epoch block. The own
+//shard state of the previous epoch is passed as held (nil if none).
+func fetchShardState(height int, held *protocol.ShardState) {
+	roots := lastEpochBlock.ShardStateRoots
+	if len(roots) == 0 {
+		logger.Printf("Epoch block (%x) does not commit to the shard states, the state of shard %d cannot be fetched\n", lastEpochBlock.Hash[0:8], storage.ThisShardID)
+		FileLogger.Printf("Epoch block (%x) does not commit to the shard states, the state of shard %d cannot be fetched\n", lastEpochBlock.Hash[0:8], storage.ThisShardID)
+		return
+	}
+
+	accounts := make(map[[64]byte]*protocol.Account)
+	receivedShards := make(map[int]bool)
+
+	//Shards without accounts do not need to be fetched.
+	for i, root := range roots {
+		if root == [32]byte{} {
+			receivedShards[i+1] = true
+		}
+	}
+
+	if held != nil && verifyShardState(held, height, roots) == nil {
+		addShardStateAccounts(accounts, held)
+		receivedShards[held.ShardID] = true
+	}
+
+	logger.Printf("Fetching state of shard %d for epoch height: %d\n", storage.ThisShardID, height)
+	FileLogger.Printf("Fetching state of shard %d for epoch height: %d\n", storage.ThisShardID, height)
+
+	if len(receivedShards) < len(roots) {
+		p2p.ShardStateReq(height)
+	}
+	for len(receivedShards) < len(roots) {
+		select {
+		case encodedShardState := <-p2p.ShardStateReqChan:
+			var shardState *protocol.ShardState
+			shardState, err := shardState.Decode(encodedShardState)
+			if err != nil {
+				FileLogger.Printf("Received shard state could not be decoded: %v\n", err)
+				continue
+			}
+			if err := verifyShardState(shardState, height, roots); err != nil {
+				FileLogger.Printf("Received shard state rejected: %v\n", err)
+				continue
+			}
+			if receivedShards[shardState.ShardID] {
+				continue
+			}
+
+			addShardStateAccounts(accounts, shardState)
+			receivedShards[shardState.ShardID] = true
+			FileLogger.Printf("Received %d accounts of the previous shard %d\n", len(shardState.Accounts), shardState.ShardID)
+		//Limit waiting time to 5 seconds before requesting the state again.
+		case <-time.After(5 * time.Second):
+			FileLogger.Printf("have been waiting for 5 seconds for the state of shard %d\n", storage.ThisShardID)
+			p2p.ShardStateReq(height)
+		}
+	}
+
+	blockValidation.Lock()
+	storage.PartitionShardID, storage.PartitionNumberOfShards = storage.ThisShardID, NumberOfShards
+	partitionState()
+	for address, acc := range accounts {
+		if current := storage.State[address]; current == nil || !current.IsStaking {
+			storage.State[address] = acc
+		}
+	}
+	blockValidation.Unlock()
+
+	logger.Printf("Fetched %d accounts of shard %d\n", len(accounts), storage.ThisShardID)
+	FileLogger.Printf("Fetched %d accounts of shard %d\n", len(accounts), storage.ThisShardID)
+}
+
+//Only the accounts of the shard this validator is assigned to are taken over.
+func addShardStateAccounts(accounts map[[64]byte]*protocol.Account, shardState *protocol.ShardState) {
+	for _, acc := range shardState.Accounts {
+		if assignAddressToShard(acc.Address) == storage.ThisShardID {
+			accCopy := *acc
+			accounts[acc.Address] = &accCopy
+		}
+	}
+}
+
+//A shard state must hold all accounts of one shard of the previous epoch and match the root of this shard.
+func verifyShardState(shardState *protocol.ShardState, height int, roots [][32]byte) error {
+	if shardState.Height != height {
+		return errors.New(fmt.Sprintf("Shard state is for epoch height %d instead of %d.", shardState.Height, height))
+	}
+
+	if shardState.NumberOfShards != len(roots) {
+		return errors.New(fmt.Sprintf("Shard state is for %d shards instead of %d.", shardState.NumberOfShards, len(roots)))
+	}
+
+	if shardState.ShardID < 1 || shardState.ShardID > shardState.NumberOfShards {
+		return errors.New(fmt.Sprintf("Shard %d of %d shards is invalid.", shardState.ShardID, shardState.NumberOfShards))
+	}
+
+	for _, acc := range shardState.Accounts {
+		if acc.IsStaking || addressShard(acc.Address, shardState.NumberOfShards) != shardState.ShardID {
+			return errors.New(fmt.Sprintf("Account (%x) does not belong to shard %d.", acc.Address[0:8], shardState.ShardID))
+		}
+	}
+
+	if shardState.Root() != roots[shardState.ShardID-1] {
+		return errors.New(fmt.Sprintf("Accounts of shard %d do not match its root in the epoch block.", shardState.ShardID))
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestPartitionedState(t *testing.T) {
+	cleanAndPrepare()
+
+	prevNumberOfShards, prevThisShardID := NumberOfShards, storage.ThisShardID
+	defer func() {
+		NumberOfShards, storage.ThisShardID = prevNumberOfShards, prevThisShardID
+		storage.PartitionedState, storage.PartitionShardID, storage.PartitionNumberOfShards = false, 0, 0
+		storage.WriteShardStateSnapshot(nil)
+	}()
+
+	storage.PartitionedState = true
+	NumberOfShards = 2
+
+	//With two shards, accShard1 belongs to shard 1 and accShard2 to shard 2.
+	var addressShard1, addressShard2 [64]byte
+	addressShard1[7], addressShard2[7] = 2, 1
+	accShard1 := protocol.NewAccount(addressShard1, [64]byte{}, 100, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	accShard2 := protocol.NewAccount(addressShard2, [64]byte{}, 200, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	storage.State[addressShard1] = &accShard1
+	storage.State[addressShard2] = &accShard2
+
+	//In the first epoch, the whole state is held and the accounts of the other shard are dropped.
+	storage.ThisShardID = 1
+	syncShardState()
+
+	if storage.State[addressShard1] == nil || storage.State[addressShard2] != nil {
+		t.Fatal("Accounts of the other shard have not been dropped.")
+	}
+	if storage.State[validatorAccAddress] == nil || !storage.State[validatorAccAddress].IsStaking {
+		t.Fatal("Staking account has been dropped.")
+	}
+
+	//Accounts of other shards created by state transitions are dropped as well.
+	stateChange := make(map[[64]byte]*protocol.RelativeAccount)
+	accRel := protocol.NewRelativeAccount(addressShard2, [64]byte{}, 50, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	stateChange[addressShard2] = &accRel
+	applyStateTransition(protocol.NewStateTransition(stateChange, 1, 2, [32]byte{'1'}, nil, nil, nil, nil))
+	if storage.State[addressShard2] != nil {
+		t.Error("Account of the other shard has been created by a state transition.")
+	}
+
+	//At the last block of the epoch, the accounts of shard 1 are kept for the new shards and their root is committed.
+	height := int(lastEpochBlock.Height)
+	snapshot := writeShardStateSnapshot(height)
+	kept := false
+	for _, acc := range snapshot.Accounts {
+		if acc.IsStaking || assignAddressToShard(acc.Address) != 1 {
+			t.Errorf("Snapshot holds an account of another shard: %v\n", acc)
+		}
+		kept = kept || acc.Address == addressShard1
+	}
+	if !kept {
+		t.Fatal("Snapshot does not hold the accounts of the shard.")
+	}
+	lastEpochBlock.ShardStateRoots = [][32]byte{snapshot.Root(), protocol.NewShardState(height, 2, 2, []*protocol.Account{&accShard2}).Root()}
+
+	//The validator is assigned to shard 2 and fetches its accounts from the validator which held them.
+	storage.ThisShardID = 2
+	go func() {
+		//Accounts which do not match the root, claim no shards or belong to another shard are rejected.
+		forged := accShard2
+		forged.Balance = 1000
+		p2p.ShardStateReqChan <- protocol.NewShardState(height, 2, 2, []*protocol.Account{&forged}).Encode()
+		p2p.ShardStateReqChan <- protocol.NewShardState(height, 2, 0, []*protocol.Account{&forged}).Encode()
+		p2p.ShardStateReqChan <- protocol.NewShardState(height, 2, 2, []*protocol.Account{&accShard1}).Encode()
+		p2p.ShardStateReqChan <- protocol.NewShardState(height, 2, 2, []*protocol.Account{&accShard2}).Encode()
+	}()
+	syncShardState()
+
+	if storage.State[addressShard2] == nil || storage.State[addressShard2].Balance != 200 || storage.State[addressShard1] != nil {
+		t.Fatal("Accounts of the new shard have not been fetched.")
+	}
+	if storage.PartitionShardID != 2 || storage.PartitionNumberOfShards != 2 {
+		t.Errorf("Partition has not been updated: Shard %d of %d.\n", storage.PartitionShardID, storage.PartitionNumberOfShards)
+	}
+
+	//The accounts of the previous shard are still served to its new validators.
+	if shardState := storage.ReadShardStateSnapshot(height); shardState != snapshot {
+		t.Errorf("Accounts of the previous shard are not served: %v\n", shardState)
+	}
+
+	//An epoch block must carry the roots of the state transitions.
+	stateTransition := protocol.NewStateTransition(nil, 1000, 1, [32]byte{'2'}, nil, nil, nil, nil)
+	stateTransition.ShardStateRoot = snapshot.Root()
+	storage.ReceivedStateStash.Set(stateTransition.HashTransition(), stateTransition)
+	epochBlock := protocol.NewEpochBlock(nil, 1001)
+	epochBlock.ShardStateRoots = [][32]byte{lastEpochBlock.ShardStateRoots[1], lastEpochBlock.ShardStateRoots[1]}
+	if err := verifyShardStateRoots(epochBlock); err == nil {
+		t.Error("Epoch block with a wrong root of a shard state has been accepted.")
+	}
+	epochBlock.ShardStateRoots[0] = snapshot.Root()
+	if err := verifyShardStateRoots(epochBlock); err != nil {
+		t.Errorf("Epoch block with the roots of the shard states has been rejected: %v\n", err)
+	}
+}
+
+func TestAssignAddressToShard(t *testing.T) {
+	var address [64]byte
+	address[7] = 5
+
+	if shard := addressShard(address, 1); shard != 1 {
+		t.Errorf("Address assigned to shard %d with only one shard.\n", shard)
+	}
+
+	if shard := addressShard(address, 3); shard != 3 {
+		t.Errorf("Address assigned to shard %d instead of 3.\n", shard)
+	}
+
+	//Shard IDs start at 1
+	address[7] = 6
+	if shard := addressShard(address, 3); shard != 1 {
+		t.Errorf("Address assigned to shard %d instead of 1.\n", shard)
+	}
+}
@@ -3,7 +3,6 @@ package miner
 import (
 	"errors"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 type SlashingProof struct {
@@ -14,35 +13,45 @@ type SlashingProof struct {
 //Find a proof where a validator votes on two different chains within the slashing window
 func seekSlashingProof(block *protocol.Block) error {
 	//check if block is being added to your chain
-	lastClosedBlock := storage.ReadLastClosedBlock()
+	lastClosedBlock := store.ReadLastClosedBlock()
 	if lastClosedBlock == nil {
 		return errors.New("Latest block not found.")
 	}
 
-	lastEpochBlockHash := storage.ReadLastClosedEpochBlock().Hash
+	lastEpochBlockHash := store.ReadLastClosedEpochBlock().Hash
 
 	//When the block is added ontop of your chain then there is no slashing needed
 	if lastClosedBlock.Hash == block.Hash || lastClosedBlock.Hash == block.PrevHash ||
 		block.Hash == lastEpochBlockHash || block.PrevHash == lastEpochBlockHash{
 		return nil
 	} else {
-		//Get the latest blocks and check if there is proof for multi-voting within the slashing window
-		//TODO @simibac Why loading all closed blocks if we check over the slashing window?
-		prevBlocks := storage.ReadAllClosedBlocks()
+		//Check the closed blocks within the slashing window for proof of multi-voting, starting with the highest one
+		window := activeParameters.Slashing_window_size
+		if window == 0 {
+			return nil
+		}
 
-		if prevBlocks == nil {
+		from := uint64(block.Height) + window - 1
+		if from > uint64(lastClosedBlock.Height) {
+			from = uint64(lastClosedBlock.Height)
+		}
+		var to uint64
+		if uint64(block.Height) >= window {
+			to = uint64(block.Height) - window + 1
+		}
+		if from < to {
 			return nil
 		}
-		for _, prevBlock := range prevBlocks {
+
+		store.ForEachClosedBlock(uint32(from), uint32(to), func(prevBlock *protocol.Block) bool {
 			if IsInSameChain(prevBlock, block) {
-				return nil
+				return false
 			}
-			if prevBlock.Beneficiary == block.Beneficiary &&
-				(uint64(prevBlock.Height) < uint64(block.Height)+activeParameters.Slashing_window_size ||
-					uint64(block.Height) < uint64(prevBlock.Height)+activeParameters.Slashing_window_size) {
+			if prevBlock.Beneficiary == block.Beneficiary {
 				slashingDict[block.Beneficiary] = SlashingProof{ConflictingBlockHash1: block.Hash, ConflictingBlockHash2: prevBlock.Hash}
 			}
-		}
+			return true
+		})
 	}
 	return nil
 }
@@ -52,7 +61,7 @@ func IsInSameChain(b1, b2 *protocol.Block) bool {
 	var higherBlock *protocol.Block
 	var lowerBlock *protocol.Block
 
-	if b1.Height == b2.Height {
+	if b1 == nil || b2 == nil || b1.Height == b2.Height {
 		return false
 	}
 
@@ -64,12 +73,20 @@ func IsInSameChain(b1, b2 *protocol.Block) bool {
 		lowerBlock = b1
 	}
 
-	for higherBlock.Height > 0 {
-		higherBlock = storage.ReadClosedBlock(higherBlock.PrevHash)
-		if higherBlock.Hash == lowerBlock.Hash {
+	//Blocks listed in the height index are on the chain of this node.
+	if indexed := store.ReadClosedBlockByHeight(higherBlock.Height); indexed != nil && indexed.Hash == higherBlock.Hash {
+		if indexed := store.ReadClosedBlockByHeight(lowerBlock.Height); indexed != nil && indexed.Hash == lowerBlock.Hash {
 			return true
 		}
 	}
 
-	return false
+	//Otherwise walk back from the higher block down to the height of the lower one.
+	for higherBlock.Height > lowerBlock.Height {
+		higherBlock = store.ReadClosedBlock(higherBlock.PrevHash)
+		if higherBlock == nil {
+			return false
+		}
+	}
+
+	return higherBlock.Hash == lowerBlock.Hash
 }
@@ -2,7 +2,6 @@ package miner
 
 import (
 	"github.com/bazo-blockchain/bazo-miner/crypto"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 	"reflect"
 	"testing"
 )
@@ -10,7 +9,7 @@ import (
 func TestSlashingCondition(t *testing.T) {
 	cleanAndPrepare()
 
-	myAcc, _ := storage.ReadAccount(validatorAccAddress)
+	myAcc, _ := store.ReadAccount(validatorAccAddress)
 	initBalance := myAcc.Balance
 
 	//forkBlock := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
@@ -77,3 +76,45 @@ func TestSlashingCondition(t *testing.T) {
 		t.Error("Slashing reward is not properly added.", initBalance, myAcc.Balance, expectedBalance)
 	}
 }
+
+func TestIsInSameChain(t *testing.T) {
+	cleanAndPrepare()
+
+	// genesis <- b1 <- b2
+	b1 := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, lastBlock.Height+1)
+	if err := finalizeBlock(b1); err != nil {
+		t.Errorf("Block finalization for b1 (%v) failed: %v\n", b1, err)
+	}
+	if err := validate(b1, false); err != nil {
+		t.Errorf("Block validation for b1 (%v) failed: %v\n", b1, err)
+	}
+	b2 := newBlock(b1.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b1.Height+1)
+	if err := finalizeBlock(b2); err != nil {
+		t.Errorf("Block finalization for b2 (%v) failed: %v\n", b2, err)
+	}
+	if err := validate(b2, false); err != nil {
+		t.Errorf("Block validation for b2 (%v) failed: %v\n", b2, err)
+	}
+
+	// b1 <- forkBlock <- forkBlock2, the fork is not closed
+	forkBlock := newBlock(b1.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b1.Height+1)
+	forkBlock.Hash = [32]byte{'f', '1'}
+	forkBlock2 := newBlock(forkBlock.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, forkBlock.Height+1)
+	forkBlock2.Hash = [32]byte{'f', '2'}
+
+	if !IsInSameChain(b1, b2) || !IsInSameChain(b2, b1) {
+		t.Error("Closed blocks are not in the same chain.")
+	}
+	if IsInSameChain(b2, forkBlock) {
+		t.Error("Blocks at the same height are in the same chain.")
+	}
+	if !IsInSameChain(forkBlock, b1) {
+		t.Error("Fork block is not in the chain of its closed predecessor.")
+	}
+	if IsInSameChain(forkBlock2, b1) || IsInSameChain(forkBlock2, b2) {
+		t.Error("Block on top of an unknown fork is in the chain.")
+	}
+	if IsInSameChain(nil, b1) {
+		t.Error("Missing block is in the chain.")
+	}
+}
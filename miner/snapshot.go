@@ -0,0 +1,142 @@
+package miner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+//Returns the snapshot of the last closed epoch block of the store. The initial epoch block carries no state and
+//cannot be exported, neither can the epoch blocks of a partitioned network (see holdsWholeState).
+func ExportSnapshot(nodeStore storage.Store) (*protocol.Snapshot, error) {
+	epochBlock := nodeStore.ReadLastClosedEpochBlock()
+	if epochBlock == nil || epochBlock.Height == 0 {
+		return nil, errors.New("No closed epoch block to export.")
+	}
+
+	genesis, err := nodeStore.ReadGenesis()
+	if err != nil {
+		return nil, err
+	}
+	if genesis == nil {
+		return nil, errors.New("Genesis not found.")
+	}
+
+	snapshot := protocol.NewSnapshot(genesis, epochBlock)
+	if err := VerifySnapshot(snapshot, epochBlock.Hash, genesis.Hash()); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+//Checks the genesis and the epoch block of the snapshot against the given hashes, the epoch block against its own hash
+//and its state against the Merkle Patricia root. The hash of the epoch block covers the validator mapping.
+func VerifySnapshot(snapshot *protocol.Snapshot, epochBlockHash [32]byte, genesisHash [32]byte) error {
+	epochBlock := snapshot.EpochBlock
+	if epochBlock == nil || snapshot.Genesis == nil {
+		return errors.New("Snapshot is incomplete.")
+	}
+
+	//The epoch block does not commit to the genesis, it can only be checked against the hash the operator trusts.
+	if hash := snapshot.Genesis.Hash(); hash != genesisHash {
+		return errors.New(fmt.Sprintf("Snapshot holds genesis (%x) instead of (%x).", hash[0:8], genesisHash[0:8]))
+	}
+
+	if epochBlock.Hash != epochBlockHash {
+		return errors.New(fmt.Sprintf("Snapshot holds epoch block (%x) instead of (%x).", epochBlock.Hash[0:8], epochBlockHash[0:8]))
+	}
+
+	if epochBlock.Height == 0 || !verifyEpochBlockHash(epochBlock) {
+		return errors.New(fmt.Sprintf("Epoch block (%x) does not match its hash.", epochBlock.Hash[0:8]))
+	}
+
+	if protocol.StateRoot(epochBlock.State) != epochBlock.MerklePatriciaRoot {
+		return errors.New(fmt.Sprintf("State of epoch block (%x) does not match the Merkle Patricia Root.", epochBlock.Hash[0:8]))
+	}
+
+	if epochBlock.ValMapping == nil || epochBlock.ValMapping.EpochHeight != int(epochBlock.Height) {
+		return errors.New(fmt.Sprintf("Validator mapping of epoch block (%x) does not belong to height %d.", epochBlock.Hash[0:8], epochBlock.Height))
+	}
+
+	if !holdsWholeState(epochBlock) {
+		return errors.New(fmt.Sprintf("Epoch block (%x) only holds the staking accounts of a partitioned network.", epochBlock.Hash[0:8]))
+	}
+
+	return nil
+}
+
+//In partitioned mode, the epoch block only carries the staking accounts. It holds the whole state if it holds
+//accounts of every shard whose shard state is not empty.
+func holdsWholeState(epochBlock *protocol.EpochBlock) bool {
+	numberOfShards := len(epochBlock.ShardStateRoots)
+	if numberOfShards == 0 {
+		return true
+	}
+
+	heldShards := make(map[int]bool)
+	for _, acc := range epochBlock.State {
+		if !acc.IsStaking {
+			heldShards[addressShard(acc.Address, numberOfShards)] = true
+		}
+	}
+
+	for i, root := range epochBlock.ShardStateRoots {
+		if root != [32]byte{} && !heldShards[i+1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Writes the verified snapshot to a store without chain, the miner then resumes from its epoch block on start.
+func ImportSnapshot(nodeStore storage.Store, snapshot *protocol.Snapshot, epochBlockHash [32]byte, genesisHash [32]byte) error {
+	if err := VerifySnapshot(snapshot, epochBlockHash, genesisHash); err != nil {
+		return err
+	}
+
+	if nodeStore.ReadLastClosedEpochBlock() != nil || nodeStore.ReadLastClosedBlock() != nil {
+		return errors.New("The database already holds a chain.")
+	}
+
+	epochBlock := snapshot.EpochBlock
+
+	//Persist the state trie, such that accounts of the epoch block can be proven right away.
+	stateTrie := protocol.NewMemoryTrieDatabase()
+	protocol.BuildStateTrie(epochBlock.State, stateTrie)
+	if err := nodeStore.WriteStateTrie(stateTrie); err != nil {
+		return err
+	}
+
+	if err := nodeStore.WriteGenesis(snapshot.Genesis); err != nil {
+		return err
+	}
+	if err := nodeStore.WriteClosedEpochBlock(epochBlock); err != nil {
+		return err
+	}
+
+	return nodeStore.WriteLastClosedEpochBlock(epochBlock)
+}
+
+//The epoch block hash is calculated before the state, the commitment proof and the timestamp are set, but after the
+//validator mapping (see finalizeEpochBlock()).
+func verifyEpochBlockHash(epochBlock *protocol.EpochBlock) bool {
+	header := protocol.NewEpochBlock(epochBlock.PrevShardHashes, epochBlock.Height)
+	header.Header = epochBlock.Header
+	header.MerkleRoot = epochBlock.MerkleRoot
+	header.MerklePatriciaRoot = epochBlock.MerklePatriciaRoot
+	header.ShardStateRoots = epochBlock.ShardStateRoots
+	header.ValMapping = epochBlock.ValMapping
+	header.NofShards = epochBlock.NofShards
+
+	var nonceBuf [8]byte
+	binary.BigEndian.PutUint64(nonceBuf[:], uint64(epochBlock.Timestamp))
+
+	partialHash := header.HashEpochBlock()
+	return epochBlock.Hash == sha3.Sum256(append(nonceBuf[:], partialHash[:]...))
+}
@@ -0,0 +1,108 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"testing"
+)
+
+func TestSnapshotExportImport(t *testing.T) {
+	cleanAndPrepare()
+
+	//Without a closed epoch block after the initial one, there is nothing to export.
+	if _, err := ExportSnapshot(store); err == nil {
+		t.Error("Initial epoch block was exported.")
+	}
+
+	genesis := protocol.NewGenesis(validatorAccAddress, [crypto.COMM_KEY_LENGTH]byte{})
+	store.WriteGenesis(&genesis)
+
+	//The epoch block takes the roots of the shard states from the state transitions of the last blocks.
+	storage.OwnStateTransitionStash = nil
+	storage.WriteToOwnStateTransitionkStash(protocol.NewStateTransition(nil, int(lastBlock.Height), 1, lastBlock.Hash, nil, nil, nil, nil))
+	for shardID := 2; shardID <= NumberOfShards; shardID++ {
+		st := protocol.NewStateTransition(nil, int(lastBlock.Height), shardID, [32]byte{byte(shardID)}, nil, nil, nil, nil)
+		storage.ReceivedStateStash.Set(st.HashTransition(), st)
+	}
+	epochBlock := protocol.NewEpochBlock([][32]byte{lastBlock.Hash}, lastBlock.Height+1)
+	if err := finalizeEpochBlock(epochBlock); err != nil {
+		t.Fatalf("Epoch block finalization failed: %v\n", err)
+	}
+	store.WriteClosedEpochBlock(epochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(epochBlock)
+
+	snapshot, err := ExportSnapshot(store)
+	if err != nil {
+		t.Fatalf("Snapshot export failed: %v\n", err)
+	}
+
+	var decoded *protocol.Snapshot
+	if decoded, err = decoded.Decode(snapshot.Encode()); err != nil {
+		t.Fatalf("Snapshot decoding failed: %v\n", err)
+	}
+
+	//The snapshot is only imported into a store without chain and if it holds the expected epoch block.
+	if err := ImportSnapshot(store, decoded, epochBlock.Hash, genesis.Hash()); err == nil {
+		t.Error("Snapshot was imported into a store with a chain.")
+	}
+
+	nodeStore := storage.NewMemoryStore()
+	if err := ImportSnapshot(nodeStore, decoded, [32]byte{'x'}, genesis.Hash()); err == nil {
+		t.Error("Snapshot of another epoch block was imported.")
+	}
+	if err := ImportSnapshot(nodeStore, decoded, epochBlock.Hash, [32]byte{'x'}); err == nil {
+		t.Error("Snapshot of another genesis was imported.")
+	}
+	if err := ImportSnapshot(nodeStore, decoded, epochBlock.Hash, genesis.Hash()); err != nil {
+		t.Fatalf("Snapshot import failed: %v\n", err)
+	}
+
+	if imported := nodeStore.ReadLastClosedEpochBlock(); imported == nil || imported.Hash != epochBlock.Hash {
+		t.Error("Imported epoch block is not the last closed epoch block.")
+	}
+	if imported, _ := nodeStore.ReadGenesis(); imported == nil || imported.Hash() != genesis.Hash() {
+		t.Error("Genesis was not imported.")
+	}
+	if acc, err := nodeStore.ReadStateTrieAccount(epochBlock.MerklePatriciaRoot, validatorAccAddress); err != nil || acc == nil {
+		t.Errorf("State trie of the snapshot was not imported: %v\n", err)
+	}
+
+	//A forged validator mapping is rejected, the hash of the epoch block covers it.
+	forged, _ := decoded.Decode(snapshot.Encode())
+	forged.EpochBlock.ValMapping.ValMapping[[64]byte{'v'}] = 1
+	if err := VerifySnapshot(forged, epochBlock.Hash, genesis.Hash()); err == nil {
+		t.Error("Snapshot with a forged validator mapping was verified.")
+	}
+	forged, _ = decoded.Decode(snapshot.Encode())
+	for validator, shardId := range forged.EpochBlock.ValMapping.ValMapping {
+		forged.EpochBlock.ValMapping.ValMapping[validator] = shardId + 1
+	}
+	if err := VerifySnapshot(forged, epochBlock.Hash, genesis.Hash()); err == nil {
+		t.Error("Snapshot with a reassigned validator was verified.")
+	}
+
+	//A manipulated state is rejected.
+	for _, acc := range decoded.EpochBlock.State {
+		acc.Balance++
+		break
+	}
+	if err := VerifySnapshot(decoded, epochBlock.Hash, genesis.Hash()); err == nil {
+		t.Error("Snapshot with manipulated state was verified.")
+	}
+
+	//The epoch block of a partitioned network only holds the staking accounts of shards which have further accounts.
+	var address [64]byte
+	address[7] = 1
+	acc := protocol.NewAccount(address, [64]byte{}, 100, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	epochBlock.ShardStateRoots = [][32]byte{protocol.NewShardState(int(epochBlock.Height), 1, 1, []*protocol.Account{&acc}).Root()}
+	epochBlock.State = stakingState()
+	if holdsWholeState(epochBlock) {
+		t.Error("Epoch block with the staking accounts only holds the whole state.")
+	}
+	epochBlock.State[address] = &acc
+	if !holdsWholeState(epochBlock) {
+		t.Error("Epoch block with the accounts of all shards does not hold the whole state.")
+	}
+}
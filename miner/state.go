@@ -130,7 +130,7 @@ func initState() (initialBlock *protocol.Block, err error) {
 	//Request last epoch block from the network
 	if(p2p.IsBootstrap()){
 		var eb *protocol.EpochBlock
-		eb = storage.ReadLastClosedEpochBlock()
+		eb = store.ReadLastClosedEpochBlock()
 		lastEpochBlock = eb
 		if(lastEpochBlock == nil){
 			lastEpochBlock = initialEpochBlock
@@ -163,8 +163,127 @@ func initState() (initialBlock *protocol.Block, err error) {
 	return initialBlock, nil
 }
 
+//Restores the state of a validator from its local database. The state of the last epoch block is replayed with the
+//closed blocks of the own shard which followed it. Only the blocks the validator missed are fetched from the network.
+func resumeState(epochBlock *protocol.EpochBlock) error {
+	lastEpochBlock = epochBlock
+	ValidatorShardMap = epochBlock.ValMapping
+	NumberOfShards = epochBlock.NofShards
+	storage.ThisShardID = ValidatorShardMap.ValMapping[validatorAccAddress]
+	storage.State = epochBlock.State
+
+	if genesis, err := store.ReadGenesis(); err != nil {
+		return err
+	} else if genesis != nil {
+		if rootAcc, exists := storage.State[genesis.RootAddress]; exists {
+			storage.RootKeys[genesis.RootAddress] = rootAcc
+		}
+	}
+
+	//In partitioned mode, the epoch block only carries the staking accounts
+	if storage.PartitionedState {
+		fetchShardState(int(epochBlock.Height), nil)
+	}
+
+	closedBlocks := readClosedBlocksSince(epochBlock)
+	tipHash := epochBlock.Hash
+	if len(closedBlocks) > 0 {
+		tipHash = closedBlocks[0].Hash
+	}
+	allClosedBlocks := append(fetchMissingBlocks(epochBlock, tipHash), closedBlocks...)
+
+	//Switch array order to validate the oldest block first
+	storage.AllClosedBlocksAsc = InvertBlockArray(allClosedBlocks)
+
+	lastBlock = dummyLastBlock
+	for i, block := range storage.AllClosedBlocksAsc {
+		if err := validateClosedBlock(block); err != nil {
+			return err
+		}
+
+		//The state transitions of the last block are applied by epochMining.
+		if NumberOfShards > 1 && i < len(storage.AllClosedBlocksAsc)-1 {
+			syncStateTransitions(block.Height)
+		}
+	}
+
+	return nil
+}
+
+//Returns the closed blocks from the last closed block back to the given epoch block, newest first. Blocks which do not
+//chain up to the epoch block belong to an earlier epoch or a fork and are ignored.
+func readClosedBlocksSince(epochBlock *protocol.EpochBlock) (closedBlocks []*protocol.Block) {
+	nextBlock := store.ReadLastClosedBlock()
+	for nextBlock != nil && nextBlock.Height > epochBlock.Height {
+		closedBlocks = append(closedBlocks, nextBlock)
+		if nextBlock.Height == epochBlock.Height+1 {
+			if nextBlock.PrevHash != epochBlock.Hash {
+				return nil
+			}
+			return closedBlocks
+		}
+		nextBlock = store.ReadClosedBlock(nextBlock.PrevHash)
+	}
+
+	return nil
+}
+
+//Requests the last block of the own shard from the network and fetches its predecessors down to the block with the
+//given hash, newest first. Blocks of other shards or chains which do not lead to the given block are ignored.
+func fetchMissingBlocks(epochBlock *protocol.EpochBlock, tipHash [32]byte) (missingBlocks []*protocol.Block) {
+	if err := p2p.LastBlockReq(); err != nil {
+		return nil
+	}
+
+	var block *protocol.Block
+	select {
+	case encodedBlock := <-p2p.BlockReqChan:
+		var err error
+		if block, err = block.Decode(encodedBlock); err != nil {
+			return nil
+		}
+	case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
+		return nil
+	}
+
+	if block.ShardId != storage.ThisShardID {
+		return nil
+	}
+
+	for block.Hash != tipHash {
+		if block.Height <= epochBlock.Height || store.ReadClosedBlock(block.Hash) != nil {
+			return nil
+		}
+		missingBlocks = append(missingBlocks, block)
+
+		p2p.BlockReq(block.PrevHash)
+		select {
+		case encodedBlock := <-p2p.BlockReqChan:
+			var err error
+			if block, err = block.Decode(encodedBlock); err != nil {
+				return nil
+			}
+		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
+			return nil
+		}
+	}
+
+	for _, missingBlock := range missingBlocks {
+		store.WriteClosedBlock(missingBlock)
+	}
+	if len(missingBlocks) > 0 {
+		store.DeleteAllLastClosedBlock()
+		store.WriteLastClosedBlock(missingBlocks[0])
+	}
+
+	logger.Printf("Fetched %d missing block(s) from the network\n", len(missingBlocks))
+	FileLogger.Printf("Fetched %d missing block(s) from the network\n", len(missingBlocks))
+
+	return missingBlocks
+}
+
 func initGenesis() (genesis *protocol.Genesis, err error) {
-	if genesis, err = storage.ReadGenesis(); err != nil {
+	if genesis, err = store.ReadGenesis(); err != nil {
 		return nil, err
 	}
 
@@ -175,20 +294,22 @@ func initGenesis() (genesis *protocol.Genesis, err error) {
 		// blocking wait
 		select {
 		case encodedGenesis := <-p2p.GenesisReqChan:
-			genesis = genesis.Decode(encodedGenesis)
+			if genesis, err = genesis.Decode(encodedGenesis); err != nil {
+				return nil, err
+			}
 			logger.Printf("Received genesis: %v", genesis.String())
 			FileLogger.Printf("Received genesis: %v", genesis.String())
 		case <-time.After(GENESISFETCH_TIMEOUT * time.Second):
 			return nil, errors.New("genesis fetch timeout")
 		}
 
-		storage.WriteGenesis(genesis)
+		store.WriteGenesis(genesis)
 	}
 	return genesis, nil
 }
 
 func initEpochBlock() (initialEpochBlock *protocol.EpochBlock, err error) {
-	if initialEpochBlock, err = storage.ReadFirstEpochBlock(); err != nil {
+	if initialEpochBlock, err = store.ReadFirstEpochBlock(); err != nil {
 		return nil, err
 	}
 
@@ -197,7 +318,9 @@ func initEpochBlock() (initialEpochBlock *protocol.EpochBlock, err error) {
 
 		select {
 		case encodedFirstEpochBlock := <-p2p.FirstEpochBlockReqChan:
-			initialEpochBlock = initialEpochBlock.Decode(encodedFirstEpochBlock)
+			if initialEpochBlock, err = initialEpochBlock.Decode(encodedFirstEpochBlock); err != nil {
+				return nil, err
+			}
 			logger.Printf("Received first Epoch Block: %v\n", initialEpochBlock.String())
 			FileLogger.Printf("Received first Epoch Block: %v\n", initialEpochBlock.String())
 		case <-time.After(EPOCHBLOCKFETCH_TIMEOUT* time.Second):
@@ -205,10 +328,10 @@ func initEpochBlock() (initialEpochBlock *protocol.EpochBlock, err error) {
 		}
 
 		initialEpochBlock.State = storage.State
-		storage.WriteClosedEpochBlock(initialEpochBlock)
+		store.WriteClosedEpochBlock(initialEpochBlock)
 
-		storage.DeleteAllLastClosedEpochBlock()
-		storage.WriteLastClosedEpochBlock(initialEpochBlock)
+		store.DeleteAllLastClosedEpochBlock()
+		store.WriteLastClosedEpochBlock(initialEpochBlock)
 	}
 	return initialEpochBlock, nil
 }
@@ -221,7 +344,9 @@ func getLastEpochBlock() (lastEpochBlock *protocol.EpochBlock, err error) {
 
 	select {
 	case encodedLastEpochBlock := <-p2p.LastEpochBlockReqChan:
-		eb = eb.Decode(encodedLastEpochBlock)
+		if eb, err = eb.Decode(encodedLastEpochBlock); err != nil {
+			return nil, err
+		}
 		logger.Printf("Received last Epoch Block: %v\n", eb.String())
 		FileLogger.Printf("Received last Epoch Block: %v\n", eb.String())
 	case <-time.After(EPOCHBLOCKFETCH_TIMEOUT* time.Second):
@@ -229,10 +354,10 @@ func getLastEpochBlock() (lastEpochBlock *protocol.EpochBlock, err error) {
 	}
 
 
-	storage.WriteClosedEpochBlock(eb)
+	store.WriteClosedEpochBlock(eb)
 
-	storage.DeleteAllLastClosedEpochBlock()
-	storage.WriteLastClosedEpochBlock(eb)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(eb)
 
 	return eb, nil
 }
@@ -248,10 +373,10 @@ func initClosedBlocks(lastEpochBlock *protocol.EpochBlock) error {
 	var allClosedBlocks []*protocol.Block
 	if p2p.IsBootstrap() {
 		/*Get all closed blocks from the last block back to the last epoch block, i.e. all closed blocks from the current epoch*/
-		if nextBlock := storage.ReadLastClosedBlock(); nextBlock != nil && nextBlock.Height > lastEpochBlock.Height{
+		if nextBlock := store.ReadLastClosedBlock(); nextBlock != nil && nextBlock.Height > lastEpochBlock.Height{
 			allClosedBlocks = append(allClosedBlocks, nextBlock)
 			for nextBlock.Height > lastEpochBlock.Height + 1 {
-				nextBlock = storage.ReadClosedBlock(nextBlock.PrevHash)
+				nextBlock = store.ReadClosedBlock(nextBlock.PrevHash)
 				allClosedBlocks = append(allClosedBlocks, nextBlock)
 			}
 		}
@@ -262,14 +387,17 @@ func initClosedBlocks(lastEpochBlock *protocol.EpochBlock) error {
 		//Blocking wait
 		select {
 		case encodedBlock := <-p2p.BlockReqChan:
-			lastBlock = lastBlock.Decode(encodedBlock)
+			var err error
+			if lastBlock, err = lastBlock.Decode(encodedBlock); err != nil {
+				return err
+			}
 			//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
 			return errors.New("block fetch timeout")
 		}
 
-		storage.WriteClosedBlock(lastBlock)
-		storage.WriteLastClosedBlock(lastBlock)
+		store.WriteClosedBlock(lastBlock)
+		store.WriteLastClosedBlock(lastBlock)
 		if len(allClosedBlocks) > 0 && allClosedBlocks[len(allClosedBlocks)-1].Hash == lastBlock.Hash {
 			fmt.Printf("Block with height %v already exists", lastBlock.Height)
 		} else {
@@ -287,13 +415,16 @@ func initClosedBlocks(lastEpochBlock *protocol.EpochBlock) error {
 			p2p.BlockReq(lastBlock.PrevHash)
 			select {
 			case encodedBlock := <-p2p.BlockReqChan:
-				lastBlock = lastBlock.Decode(encodedBlock)
+				var err error
+				if lastBlock, err = lastBlock.Decode(encodedBlock); err != nil {
+					return err
+				}
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
 				logger.Println("Timed out")
 			}
 
-			storage.WriteClosedBlock(lastBlock)
+			store.WriteClosedBlock(lastBlock)
 			if len(allClosedBlocks) > 0 && allClosedBlocks[len(allClosedBlocks)-1].Hash == lastBlock.Hash {
 				fmt.Printf("Block with height %v already exists", lastBlock.Height)
 			} else {
@@ -326,9 +457,9 @@ func getInitialBlock(lastEpochBlock *protocol.EpochBlock) (initialBlock *protoco
 		//Append genesis block to the map and save in storage
 		storage.AllClosedBlocksAsc = append(storage.AllClosedBlocksAsc, initialBlock)
 
-		storage.DeleteAllLastClosedBlock()
-		storage.WriteLastClosedBlock(initialBlock)
-		storage.WriteClosedBlock(initialBlock)
+		store.DeleteAllLastClosedBlock()
+		store.WriteLastClosedBlock(initialBlock)
+		store.WriteClosedBlock(initialBlock)
 	}
 
 	return initialBlock, nil
@@ -338,51 +469,61 @@ func validateClosedBlocks() error {
 
 	//Validate all closed blocks and update state
 	for _, blockToValidate := range storage.AllClosedBlocksAsc {
-		//Prepare datastructure to fill tx payloads
-		blockDataMap := make(map[[32]byte]blockData)
-
-		//Do not validate the genesis block, since a lot of properties are set to nil
-		if blockToValidate.Hash != [32]byte{} && blockToValidate.Height != uint32(1) {
-			//Fetching payload data from the txs (if necessary, ask other miners)
-			contractTxs, fundsTxs, configTxs, stakeTxs, err := preValidate(blockToValidate, true)
-			if err != nil {
-				return errors.New(fmt.Sprintf("Block (%x) could not be prevalidated: %v\n", blockToValidate.Hash[0:8], err))
-			}
+		if err := validateClosedBlock(blockToValidate); err != nil {
+			return err
+		}
+	}
 
-			blockDataMap[blockToValidate.Hash] = blockData{contractTxs, fundsTxs, configTxs, stakeTxs, blockToValidate}
+	logger.Printf("%v block(s) validated. Chain good to go.\n", len(storage.AllClosedBlocksAsc))
+	FileLogger.Printf("%v block(s) validated. Chain good to go.\n", len(storage.AllClosedBlocksAsc))
+	//file.Close()
+	return nil
+}
 
-			err = validateState(blockDataMap[blockToValidate.Hash])
-			if err != nil {
-				return errors.New(fmt.Sprintf("Block (%x) could not be state validated: %v\n", blockToValidate.Hash[0:8], err))
-			}
+//Validates a closed block during the initial setup, the last validated block becomes the lastBlock.
+func validateClosedBlock(blockToValidate *protocol.Block) error {
+	//Prepare datastructure to fill tx payloads
+	blockDataMap := make(map[[32]byte]blockData)
 
-			postValidate(blockDataMap[blockToValidate.Hash], true)
-		} else {
-			blockDataMap[blockToValidate.Hash] = blockData{nil, nil, nil, nil, blockToValidate}
+	//Do not validate the genesis block, since a lot of properties are set to nil
+	if blockToValidate.Hash != [32]byte{} && blockToValidate.Height != uint32(1) {
+		//Fetching payload data from the txs (if necessary, ask other miners)
+		txs, err := preValidate(blockToValidate, true)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Block (%x) could not be prevalidated: %v\n", blockToValidate.Hash[0:8], err))
+		}
 
-			postValidate(blockDataMap[blockToValidate.Hash], true)
+		blockDataMap[blockToValidate.Hash] = blockData{txs, blockToValidate}
+
+		err = validateState(blockDataMap[blockToValidate.Hash])
+		if err != nil {
+			return errors.New(fmt.Sprintf("Block (%x) could not be state validated: %v\n", blockToValidate.Hash[0:8], err))
 		}
 
-		logger.Printf("Validated block with height %v\n", blockToValidate.Height)
-		FileLogger.Printf("Validated block with height %v\n", blockToValidate.Height)
-		//FileConnections.WriteString(fmt.Sprintf("'%x' -> '%x'\n",blockToValidate.PrevHash[0:15],blockToValidate.Hash[0:15]))
+		postValidate(blockDataMap[blockToValidate.Hash], true)
+	} else {
+		blockDataMap[blockToValidate.Hash] = blockData{nil, blockToValidate}
 
-		//Set the last validated block as the lastBlock
-		lastBlock = blockToValidate
+		postValidate(blockDataMap[blockToValidate.Hash], true)
 	}
 
-	logger.Printf("%v block(s) validated. Chain good to go.\n", len(storage.AllClosedBlocksAsc))
-	FileLogger.Printf("%v block(s) validated. Chain good to go.\n", len(storage.AllClosedBlocksAsc))
-	//file.Close()
+	logger.Printf("Validated block with height %v\n", blockToValidate.Height)
+	FileLogger.Printf("Validated block with height %v\n", blockToValidate.Height)
+	//FileConnections.WriteString(fmt.Sprintf("'%x' -> '%x'\n",blockToValidate.PrevHash[0:15],blockToValidate.Hash[0:15]))
+
+	//Set the last validated block as the lastBlock
+	lastBlock = blockToValidate
+
 	return nil
 }
 
 func accStateChange(txSlice []*protocol.ContractTx) {
 	for _, tx := range txSlice {
-		acc, _ := storage.ReadAccount(tx.PubKey)
+		acc, _ := store.ReadAccount(tx.PubKey)
 		if acc == nil {
 			newAcc := protocol.NewAccount(tx.PubKey, tx.Issuer, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, tx.Contract, tx.ContractVariables)
-			storage.WriteAccount(&newAcc)
+			newAcc.MultiSigKeys, newAcc.MultiSigThreshold = tx.MultiSigKeys, tx.MultiSigThreshold
+			store.WriteAccount(&newAcc)
 			//RelativeStateBalance[acc.Address] = 0
 		}
 	}
@@ -392,7 +533,7 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 	for _, tx := range txSlice {
 		var rootAcc *protocol.Account
 		//Check if we have to issue new coins (in case a root account signed the tx)
-		if rootAcc, err = storage.ReadRootAccount(tx.From); err != nil {
+		if rootAcc, err = store.ReadRootAccount(tx.From); err != nil {
 			return err
 		}
 
@@ -406,20 +547,36 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 			rootAcc.Balance += tx.Fee
 		}
 
-		accSender, _ := storage.ReadAccount(tx.From)
+		accSender, _ := store.ReadAccount(tx.From)
 		if accSender == nil {
 			newFromAcc := protocol.NewAccount(tx.From, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
 			accSender = &newFromAcc
-			storage.WriteAccount(accSender)
+			store.WriteAccount(accSender)
 			//RelativeStateBalance[accSender.Address] = 0
 		}
 
-		accReceiver, _ := storage.ReadAccount(tx.To)
-		if accReceiver == nil {
-			newToAcc := protocol.NewAccount(tx.To, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
-			accReceiver = &newToAcc
-			storage.WriteAccount(accReceiver)
-			//RelativeStateBalance[accSender.Address] = 0
+		//The receiver of a cross-shard tx is credited by its own shard with a receipt.
+		crossShard := isCrossShardTx(tx)
+
+		//All outputs of a batch transfer are checked before any of them is credited.
+		recipients := tx.Recipients()
+		accReceivers := make([]*protocol.Account, len(recipients))
+		for i, recipient := range recipients {
+			accReceivers[i], _ = store.ReadAccount(recipient.To)
+			if accReceivers[i] == nil && !crossShard {
+				newToAcc := protocol.NewAccount(recipient.To, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+				accReceivers[i] = &newToAcc
+				store.WriteAccount(accReceivers[i])
+				//RelativeStateBalance[accSender.Address] = 0
+			}
+		}
+
+		//Check the signatures of multi-signature accounts
+		err = verifyMultiSig(tx, accSender)
+
+		//Check that the recipients of a batch transfer belong to this shard
+		if shardErr := verifyBatchShard(tx); shardErr != nil {
+			err = shardErr
 		}
 
 		//Check transaction counter
@@ -438,8 +595,10 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 		}
 
 		//Overflow protection
-		if tx.Amount+accReceiver.Balance > MAX_MONEY {
-			err = errors.New("transaction amount would lead to balance overflow at the receiver account")
+		for i, recipient := range recipients {
+			if !crossShard && recipient.Amount+accReceivers[i].Balance > MAX_MONEY {
+				err = errors.New("transaction amount would lead to balance overflow at the receiver account")
+			}
 		}
 
 		if err != nil {
@@ -455,7 +614,33 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 		//We're manipulating pointer, no need to write back
 		accSender.TxCnt += 1
 		accSender.Balance -= tx.Amount
-		accReceiver.Balance += tx.Amount
+		if !crossShard {
+			for i, recipient := range recipients {
+				accReceivers[i].Balance += recipient.Amount
+			}
+		}
+	}
+
+	return nil
+}
+
+//Credits the receivers of the cross-shard FundsTxs, which were debited by the shard of the sender.
+func receiptStateChange(receipts []*protocol.Receipt) (err error) {
+	for cnt, receipt := range receipts {
+		accReceiver, _ := store.ReadAccount(receipt.Tx.To)
+		if accReceiver == nil {
+			newToAcc := protocol.NewAccount(receipt.Tx.To, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+			accReceiver = &newToAcc
+			store.WriteAccount(accReceiver)
+		}
+
+		//Overflow protection
+		if receipt.Tx.Amount+accReceiver.Balance > MAX_MONEY {
+			receiptStateChangeRollback(receipts[:cnt])
+			return errors.New("receipt amount would lead to balance overflow at the receiver account")
+		}
+
+		accReceiver.Balance += receipt.Tx.Amount
 	}
 
 	return nil
@@ -485,7 +670,10 @@ func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
 func stakeStateChange(txSlice []*protocol.StakeTx, height uint32) (err error) {
 	for _, tx := range txSlice {
 		var accSender *protocol.Account
-		accSender, err = storage.ReadAccount(tx.Account)
+		accSender, err = store.ReadAccount(tx.Account)
+		if err != nil {
+			return err
+		}
 
 		//Check staking state
 		if tx.IsStaking == accSender.IsStaking {
@@ -515,87 +703,38 @@ func stakeStateChange(txSlice []*protocol.StakeTx, height uint32) (err error) {
 	return nil
 }
 
-func collectTxFees(contractTxSlice []*protocol.ContractTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, minerAddress [64]byte) (err error) {
-	var tmpContractTx []*protocol.ContractTx
-	var tmpFundsTx []*protocol.FundsTx
-	var tmpConfigTx []*protocol.ConfigTx
-	var tmpStakeTx []*protocol.StakeTx
+func collectTxFees(txs []protocol.Transaction, minerAddress [64]byte) (err error) {
+	var tmpTxs []protocol.Transaction
 
-	minerAcc, err := storage.ReadAccount(minerAddress)
+	minerAcc, err := store.ReadAccount(minerAddress)
 	if err != nil {
 		return err
 	}
 
-	var senderAcc *protocol.Account
-
-	for _, tx := range contractTxSlice {
-		if minerAcc.Balance+tx.Fee > MAX_MONEY {
-			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
-		}
-
-		if err != nil {
-			//Rollback of all previously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpContractTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerAddress)
-			return err
-		}
-
-		//Money gets created from thin air, no need to subtract money from root key
-		minerAcc.Balance += tx.Fee
-		tmpContractTx = append(tmpContractTx, tx)
-	}
-
-	//subtract fees from sender (check if that is allowed has already been done in the block validation)
-	for _, tx := range fundsTxSlice {
+	for _, tx := range txs {
 		//Prevent protocol account from overflowing
-		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+		if minerAcc.Balance+tx.TxFee() > MAX_MONEY {
 			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 		}
 
-		senderAcc, err = storage.ReadAccount(tx.From)
-
-		if err != nil {
-			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpContractTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerAddress)
-			return err
-		}
-
-		minerAcc.Balance += tx.Fee
-		senderAcc.Balance -= tx.Fee
-		tmpFundsTx = append(tmpFundsTx, tx)
-	}
-
-	for _, tx := range configTxSlice {
-		if minerAcc.Balance+tx.Fee > MAX_MONEY {
-			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		//Subtract fees from sender (check if that is allowed has already been done in the block validation). The fees
+		//of txs signed by a root account are created from thin air.
+		var senderAcc *protocol.Account
+		if payer, paysFee := protocol.TypeOf(tx).FeePayer(tx); paysFee && err == nil {
+			senderAcc, err = store.ReadAccount(payer)
 		}
 
 		if err != nil {
-			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpContractTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerAddress)
+			//Rollback of all previously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpTxs, minerAddress)
 			return err
 		}
 
-		//No need to subtract money because signed by root account
-		minerAcc.Balance += tx.Fee
-		tmpConfigTx = append(tmpConfigTx, tx)
-	}
-
-	for _, tx := range stakeTxSlice {
-		if minerAcc.Balance+tx.Fee > MAX_MONEY {
-			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
-		}
-
-		senderAcc, err = storage.ReadAccount(tx.Account)
-
-		if err != nil {
-			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpContractTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerAddress)
-			return err
+		minerAcc.Balance += tx.TxFee()
+		if senderAcc != nil {
+			senderAcc.Balance -= tx.TxFee()
 		}
-
-		senderAcc.Balance -= tx.Fee
-		minerAcc.Balance += tx.Fee
-		tmpStakeTx = append(tmpStakeTx, tx)
+		tmpTxs = append(tmpTxs, tx)
 	}
 
 	return nil
@@ -603,7 +742,7 @@ func collectTxFees(contractTxSlice []*protocol.ContractTx, fundsTxSlice []*proto
 
 func collectBlockReward(reward uint64, minerAddress [64]byte) (err error) {
 	var miner *protocol.Account
-	miner, err = storage.ReadAccount(minerAddress)
+	miner, err = store.ReadAccount(minerAddress)
 
 	if miner.Balance+reward > MAX_MONEY {
 		err = errors.New("Block reward would lead to balance overflow at the miner account.")
@@ -622,8 +761,8 @@ func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
 	//Check if proof is provided. If proof was incorrect, prevalidation would already have failed.
 	if block.SlashedAddress != [64]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} {
 		var minerAcc, slashedAcc *protocol.Account
-		minerAcc, err = storage.ReadAccount(block.Beneficiary)
-		slashedAcc, err = storage.ReadAccount(block.SlashedAddress)
+		minerAcc, err = store.ReadAccount(block.Beneficiary)
+		slashedAcc, err = store.ReadAccount(block.SlashedAddress)
 
 		if minerAcc.Balance+reward > MAX_MONEY {
 			err = errors.New("Slash reward would lead to balance overflow at the miner account.")
@@ -646,7 +785,7 @@ func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
 
 //No rollback method exists
 func updateStakingHeight(block *protocol.Block) error {
-	acc, err := storage.ReadAccount(block.Beneficiary)
+	acc, err := store.ReadAccount(block.Beneficiary)
 	if err != nil {
 		return err
 	}
@@ -656,13 +795,14 @@ func updateStakingHeight(block *protocol.Block) error {
 	return nil
 }
 
+//Multi-signature accounts are kept without balance, their key set cannot be restored once it is deleted.
 func deleteZeroBalanceAccounts() error {
 	for _, acc := range storage.State {
-		if acc.Balance > 0 || acc.Contract != nil {
+		if acc.Balance > 0 || acc.Contract != nil || acc.IsMultiSig() {
 			continue
 		}
 
-		storage.DeleteAccount(acc.Address)
+		store.DeleteAccount(acc.Address)
 	}
 
 	return nil
@@ -2,12 +2,11 @@ package miner
 
 import (
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 func accStateChangeRollback(txSlice []*protocol.ContractTx) {
 	for _, contractTx := range txSlice {
-		storage.DeleteAccount(contractTx.PubKey)
+		store.DeleteAccount(contractTx.PubKey)
 	}
 }
 
@@ -16,21 +15,33 @@ func fundsStateChangeRollback(txSlice []*protocol.FundsTx) {
 	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
 		tx := txSlice[cnt]
 
-		accSender, _ := storage.ReadAccount(tx.From)
-		accReceiver, _ := storage.ReadAccount(tx.To)
+		accSender, _ := store.ReadAccount(tx.From)
 
 		accSender.TxCnt -= 1
 		accSender.Balance += tx.Amount
-		accReceiver.Balance -= tx.Amount
+		if !isCrossShardTx(tx) {
+			for _, recipient := range tx.Recipients() {
+				accReceiver, _ := store.ReadAccount(recipient.To)
+				accReceiver.Balance -= recipient.Amount
+			}
+		}
 
 		//If new coins were issued, revert
-		if rootAcc, _ := storage.ReadRootAccount(tx.From); rootAcc != nil {
+		if rootAcc, _ := store.ReadRootAccount(tx.From); rootAcc != nil {
 			rootAcc.Balance -= tx.Amount
 			rootAcc.Balance -= tx.Fee
 		}
 	}
 }
 
+func receiptStateChangeRollback(receipts []*protocol.Receipt) {
+	//Rollback in reverse order than original state change
+	for cnt := len(receipts) - 1; cnt >= 0; cnt-- {
+		accReceiver, _ := store.ReadAccount(receipts[cnt].Tx.To)
+		accReceiver.Balance -= receipts[cnt].Tx.Amount
+	}
+}
+
 func configStateChangeRollback(txSlice []*protocol.ConfigTx, blockHash [32]byte) {
 	if len(txSlice) == 0 {
 		return
@@ -55,50 +66,35 @@ func stakeStateChangeRollback(txSlice []*protocol.StakeTx) {
 	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
 		tx := txSlice[cnt]
 
-		accSender, _ := storage.ReadAccount(tx.Account)
+		accSender, _ := store.ReadAccount(tx.Account)
 		//Rolling back stakingBlockHeight not needed
 		accSender.IsStaking = !accSender.IsStaking
 	}
 }
 
-func collectTxFeesRollback(contractTx []*protocol.ContractTx, fundsTx []*protocol.FundsTx, configTx []*protocol.ConfigTx, stakeTx []*protocol.StakeTx, minerAddress [64]byte) {
-	minerAcc, _ := storage.ReadAccount(minerAddress)
-
-	//Subtract fees from sender (check if that is allowed has already been done in the block validation)
-	for _, tx := range contractTx {
-		//Money was created out of thin air, no need to write back
-		minerAcc.Balance -= tx.Fee
-	}
+func collectTxFeesRollback(txs []protocol.Transaction, minerAddress [64]byte) {
+	minerAcc, _ := store.ReadAccount(minerAddress)
 
-	for _, tx := range fundsTx {
-		minerAcc.Balance -= tx.Fee
+	//Give the fees back to the sender, fees which were created out of thin air are not written back.
+	for _, tx := range txs {
+		minerAcc.Balance -= tx.TxFee()
 
-		senderAcc, _ := storage.ReadAccount(tx.From)
-		senderAcc.Balance += tx.Fee
-	}
-
-	for _, tx := range configTx {
-		//Money was created out of thin air, no need to write back
-		minerAcc.Balance -= tx.Fee
-	}
-
-	for _, tx := range stakeTx {
-		minerAcc.Balance -= tx.Fee
-
-		senderAcc, _ := storage.ReadAccount(tx.Account)
-		senderAcc.Balance += tx.Fee
+		if payer, paysFee := protocol.TypeOf(tx).FeePayer(tx); paysFee {
+			senderAcc, _ := store.ReadAccount(payer)
+			senderAcc.Balance += tx.TxFee()
+		}
 	}
 }
 
 func collectBlockRewardRollback(reward uint64, minerAddress [64]byte) {
-	minerAcc, _ := storage.ReadAccount(minerAddress)
+	minerAcc, _ := store.ReadAccount(minerAddress)
 	minerAcc.Balance -= reward
 }
 
 func collectSlashRewardRollback(reward uint64, block *protocol.Block) {
 	if block.SlashedAddress != [64]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} {
-		minerAcc, _ := storage.ReadAccount(block.Beneficiary)
-		slashedAcc, _ := storage.ReadAccount(block.SlashedAddress)
+		minerAcc, _ := store.ReadAccount(block.Beneficiary)
+		slashedAcc, _ := store.ReadAccount(block.SlashedAddress)
 
 		minerAcc.Balance -= reward
 		slashedAcc.Balance += activeParameters.Staking_minimum
@@ -162,11 +162,11 @@ func TestCollectTxFeesRollback(t *testing.T) {
 		fee += tx.Fee
 	}
 
-	collectTxFees(nil, funds, nil, nil, validatorAcc.Address)
+	collectTxFees(transactionsOf(funds), validatorAcc.Address)
 	if minerBal+fee != validatorAcc.Balance {
 		t.Errorf("%v + %v != %v\n", minerBal, fee, validatorAcc.Balance)
 	}
-	collectTxFeesRollback(nil, funds, nil, nil, validatorAcc.Address)
+	collectTxFeesRollback(transactionsOf(funds), validatorAcc.Address)
 	if minerBal != validatorAcc.Balance {
 		t.Errorf("Tx fees rollback failed: %v != %v\n", minerBal, validatorAcc.Balance)
 	}
@@ -186,7 +186,7 @@ func TestCollectTxFeesRollback(t *testing.T) {
 	//Should throw an error and result in a rollback, because of acc balance overflow
 	tmpBlock := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
 	tmpBlock.Beneficiary = validatorAcc.Address
-	data := blockData{nil, funds2, nil, nil, tmpBlock}
+	data := blockData{map[byte][]protocol.Transaction{protocol.FUNDSTX_TYPE: transactionsOf(funds2)}, tmpBlock}
 	if err := validateState(data); err == nil ||
 		minerBal != validatorAcc.Balance ||
 		accA.Balance != accABal ||
@@ -194,3 +194,56 @@ func TestCollectTxFeesRollback(t *testing.T) {
 		t.Errorf("No rollback resulted, %v != %v\n", minerBal, validatorAcc.Balance)
 	}
 }
+
+func TestBatchFundsTxStateChangeRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	//Batch recipients need to belong to the shard of the sender.
+	var outputs []protocol.FundsTxOutput
+	for len(outputs) < 5 {
+		var address [64]byte
+		rand.Read(address[:])
+		if assignAddressToShard(address) == assignAddressToShard(accA.Address) {
+			outputs = append(outputs, protocol.FundsTxOutput{To: address, Amount: uint64(len(outputs) + 1)})
+		}
+	}
+	outputs = append(outputs, protocol.FundsTxOutput{To: accB.Address, Amount: 100})
+
+	tx, _ := protocol.ConstrBatchFundsTx(0x01, 1, 0, accA.Address, outputs, PrivKeyAccA)
+	if verifyFundsTx(tx) != nil {
+		t.Fatalf("Batch FundsTx could not be verified: %v\n", tx)
+	}
+
+	balanceA, balanceB := accA.Balance, accB.Balance
+	if err := fundsStateChange([]*protocol.FundsTx{tx}); err != nil {
+		t.Fatalf("Batch FundsTx could not be applied: %v\n", err)
+	}
+
+	if accA.Balance != balanceA-tx.Amount || accA.TxCnt != 1 || accB.Balance != balanceB+100 {
+		t.Error("Batch FundsTx state change failed!")
+	}
+	for _, output := range outputs[:5] {
+		if acc := storage.State[output.To]; acc == nil || acc.Balance != output.Amount {
+			t.Errorf("Batch FundsTx output has not been credited: %v\n", output)
+		}
+	}
+
+	fundsStateChangeRollback([]*protocol.FundsTx{tx})
+	if accA.Balance != balanceA || accA.TxCnt != 0 || accB.Balance != balanceB {
+		t.Error("Batch FundsTx rollback failed!")
+	}
+	for _, output := range outputs[:5] {
+		if acc := storage.State[output.To]; acc == nil || acc.Balance != 0 {
+			t.Errorf("Batch FundsTx output has not been rolled back: %v\n", output)
+		}
+	}
+
+	//A batch with one overflowing output is not applied at all.
+	accB.Balance = MAX_MONEY
+	if err := fundsStateChange([]*protocol.FundsTx{tx}); err == nil {
+		t.Error("Batch FundsTx leading to an overflow has been applied.")
+	}
+	if accA.Balance != balanceA || storage.State[outputs[0].To].Balance != 0 {
+		t.Error("Batch FundsTx has been applied partially.")
+	}
+}
@@ -0,0 +1,123 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Verification and state changes depend on the State, which should only be of concern to the miner, not to the
+//protocol package. The miner therefore hooks them into the tx types registered in the protocol package.
+func init() {
+	contractTxType := protocol.GetTxType(protocol.CONTRACTTX_TYPE)
+	contractTxType.Verify = func(tx protocol.Transaction) error {
+		return verifyContractTx(tx.(*protocol.ContractTx))
+	}
+	contractTxType.Add = func(b *protocol.Block, tx protocol.Transaction) error {
+		return addContractTx(b, tx.(*protocol.ContractTx))
+	}
+	contractTxType.Apply = func(txs []protocol.Transaction, block *protocol.Block) error {
+		accStateChange(contractTxsOf(txs))
+		return nil
+	}
+	contractTxType.Rollback = func(txs []protocol.Transaction, block *protocol.Block) {
+		accStateChangeRollback(contractTxsOf(txs))
+	}
+
+	//The receipts of the block credit the receivers of cross-shard FundsTxs, they are applied together with the
+	//FundsTxs.
+	fundsTxType := protocol.GetTxType(protocol.FUNDSTX_TYPE)
+	fundsTxType.Verify = func(tx protocol.Transaction) error {
+		return verifyFundsTx(tx.(*protocol.FundsTx))
+	}
+	fundsTxType.Add = func(b *protocol.Block, tx protocol.Transaction) error {
+		return addFundsTx(b, tx.(*protocol.FundsTx))
+	}
+	fundsTxType.Apply = func(txs []protocol.Transaction, block *protocol.Block) error {
+		if err := fundsStateChange(fundsTxsOf(txs)); err != nil {
+			return err
+		}
+		if err := receiptStateChange(block.Receipts); err != nil {
+			fundsStateChangeRollback(fundsTxsOf(txs))
+			return err
+		}
+		return nil
+	}
+	fundsTxType.Rollback = func(txs []protocol.Transaction, block *protocol.Block) {
+		receiptStateChangeRollback(block.Receipts)
+		fundsStateChangeRollback(fundsTxsOf(txs))
+	}
+
+	//ConfigTxs do not change the state, the new system parameters get active in postValidate.
+	configTxType := protocol.GetTxType(protocol.CONFIGTX_TYPE)
+	configTxType.Verify = func(tx protocol.Transaction) error {
+		return verifyConfigTx(tx.(*protocol.ConfigTx))
+	}
+	configTxType.Add = func(b *protocol.Block, tx protocol.Transaction) error {
+		return addConfigTx(b, tx.(*protocol.ConfigTx))
+	}
+
+	stakeTxType := protocol.GetTxType(protocol.STAKETX_TYPE)
+	stakeTxType.Verify = func(tx protocol.Transaction) error {
+		return verifyStakeTx(tx.(*protocol.StakeTx))
+	}
+	stakeTxType.Add = func(b *protocol.Block, tx protocol.Transaction) error {
+		return addStakeTx(b, tx.(*protocol.StakeTx))
+	}
+	stakeTxType.Apply = func(txs []protocol.Transaction, block *protocol.Block) error {
+		return stakeStateChange(stakeTxsOf(txs), block.Height)
+	}
+	stakeTxType.Rollback = func(txs []protocol.Transaction, block *protocol.Block) {
+		stakeStateChangeRollback(stakeTxsOf(txs))
+	}
+}
+
+//All txs of the block in the order of the registered tx types.
+func (data blockData) allTxs() (txs []protocol.Transaction) {
+	for _, txType := range protocol.TxTypes() {
+		txs = append(txs, data.txs[txType.Id]...)
+	}
+	return txs
+}
+
+func (data blockData) contractTxs() []*protocol.ContractTx {
+	return contractTxsOf(data.txs[protocol.CONTRACTTX_TYPE])
+}
+
+func (data blockData) fundsTxs() []*protocol.FundsTx {
+	return fundsTxsOf(data.txs[protocol.FUNDSTX_TYPE])
+}
+
+func (data blockData) configTxs() []*protocol.ConfigTx {
+	return configTxsOf(data.txs[protocol.CONFIGTX_TYPE])
+}
+
+func (data blockData) stakeTxs() []*protocol.StakeTx {
+	return stakeTxsOf(data.txs[protocol.STAKETX_TYPE])
+}
+
+func contractTxsOf(txs []protocol.Transaction) (contractTxs []*protocol.ContractTx) {
+	for _, tx := range txs {
+		contractTxs = append(contractTxs, tx.(*protocol.ContractTx))
+	}
+	return contractTxs
+}
+
+func fundsTxsOf(txs []protocol.Transaction) (fundsTxs []*protocol.FundsTx) {
+	for _, tx := range txs {
+		fundsTxs = append(fundsTxs, tx.(*protocol.FundsTx))
+	}
+	return fundsTxs
+}
+
+func configTxsOf(txs []protocol.Transaction) (configTxs []*protocol.ConfigTx) {
+	for _, tx := range txs {
+		configTxs = append(configTxs, tx.(*protocol.ConfigTx))
+	}
+	return configTxs
+}
+
+func stakeTxsOf(txs []protocol.Transaction) (stakeTxs []*protocol.StakeTx) {
+	for _, tx := range txs {
+		stakeTxs = append(stakeTxs, tx.(*protocol.StakeTx))
+	}
+	return stakeTxs
+}
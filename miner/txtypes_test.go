@@ -0,0 +1,100 @@
+package miner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+const TESTTX_TYPE = 0x20
+
+//A tx type which is only known to the tests, it is dispatched through the registry like the built-in types.
+type testTx struct {
+	Issuer [64]byte
+	Cnt    uint32
+	Fee    uint64
+}
+
+func (tx *testTx) Hash() [32]byte { return protocol.SerializeHashContent(*tx) }
+func (tx *testTx) Encode() []byte { return nil }
+func (tx *testTx) TxFee() uint64 { return tx.Fee }
+func (tx *testTx) Size() uint64 { return 72 }
+func (tx *testTx) Validity() (uint32, uint32) { return 0, 0 }
+func (tx *testTx) Type() byte { return TESTTX_TYPE }
+
+func registerTestTxType() *protocol.TxType {
+	if txType := protocol.GetTxType(TESTTX_TYPE); txType != nil {
+		return txType
+	}
+
+	txType := &protocol.TxType{
+		Id:            TESTTX_TYPE,
+		Name:          "TestTx",
+		ShardKey:      func(tx protocol.Transaction) [64]byte { return tx.(*testTx).Issuer },
+		Nonce:         func(tx protocol.Transaction) uint32 { return tx.(*testTx).Cnt },
+		FeePayer:      func(tx protocol.Transaction) ([64]byte, bool) { return tx.(*testTx).Issuer, true },
+		Bucket:        "closedtests",
+		Verify: func(tx protocol.Transaction) error {
+			if tx.(*testTx).Issuer == [64]byte{} {
+				return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Issuer is missing.")
+			}
+			return nil
+		},
+		Add: func(b *protocol.Block, tx protocol.Transaction) error {
+			if _, exists := b.StateCopy[tx.(*testTx).Issuer]; !exists {
+				return errors.New("Issuer does not exist.")
+			}
+			return nil
+		},
+	}
+	protocol.RegisterTxType(txType)
+
+	return txType
+}
+
+func TestTxTypeDispatch(t *testing.T) {
+	cleanAndPrepare()
+	registerTestTxType()
+
+	tx := &testTx{Issuer: accA.Address, Cnt: 2, Fee: activeParameters.Fee_minimum}
+	if verify(tx) != nil || verify(&testTx{Fee: activeParameters.Fee_minimum}) == nil {
+		t.Error("TestTx was not verified by its type.\n")
+	}
+
+	b := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	b.StateCopy = make(map[[64]byte]*protocol.Account)
+	b.StateCopy[accA.Address] = accA
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("TestTx could not be added: %v\n", err)
+	}
+	if err := addTx(b, &testTx{Issuer: accB.Address, Fee: activeParameters.Fee_minimum}); err == nil {
+		t.Error("TestTx of an unknown issuer was added.\n")
+	}
+
+	if assignTransactionToShard(tx) != assignAddressToShard(accA.Address) {
+		t.Error("TestTx was not assigned to the shard of its issuer.\n")
+	}
+
+	//The txs of an account with a nonce are ordered by nonce, regardless of their type and fee.
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 1, 1, 1, accA.Address, accB.Address, PrivKeyAccA, nil)
+	higherFeeTx := &testTx{Issuer: accA.Address, Cnt: 2, Fee: 1000}
+	store.WriteOpenTx(higherFeeTx)
+	store.WriteOpenTx(fundsTx)
+	if txs := store.ReadAllOpenTxs(); len(txs) != 2 || txs[0] != protocol.Transaction(fundsTx) {
+		t.Errorf("Txs were not ordered by nonce: %v\n", txs)
+	}
+	store.DeleteOpenTx(higherFeeTx)
+	store.DeleteOpenTx(fundsTx)
+
+	minerBal, issuerBal := validatorAcc.Balance, accA.Balance
+	collectTxFees([]protocol.Transaction{tx}, validatorAcc.Address)
+	if validatorAcc.Balance != minerBal+tx.Fee || accA.Balance != issuerBal-tx.Fee {
+		t.Error("TestTx fee was not paid by its issuer.\n")
+	}
+	collectTxFeesRollback([]protocol.Transaction{tx}, validatorAcc.Address)
+	if validatorAcc.Balance != minerBal || accA.Balance != issuerBal {
+		t.Error("TestTx fee rollback failed.\n")
+	}
+}
@@ -1,138 +1,394 @@
 package miner
 
 import (
-	"crypto/ecdsa"
+	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"math/big"
 )
 
-//We can't use polymorphism, e.g. we can't use tx.verify() because the Transaction interface doesn't declare
-//the verify method. This is because verification depends on the State (e.g., dynamic properties), which
-//should only be of concern to the miner, not to the protocol package. However, this has the disadvantage
-//that we have to do case distinction here.
-func verify(tx protocol.Transaction) bool {
-	var verified bool
+//Verification depends on the State (e.g., dynamic properties), which should only be of concern to the miner, not to
+//the protocol package. The verifiers are therefore hooked into the registered tx types by the miner (see txtypes.go).
+//Returns a *protocol.TxRejection if the tx is invalid.
+func verify(tx protocol.Transaction) error {
+	txType := protocol.TypeOf(tx)
+	if txType == nil || txType.Verify == nil {
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction type not recognized.")
+	}
+	return txType.Verify(tx)
+}
+
+//Txs received from the network are checked before they are admitted to the mempool, such that a tx which is not signed
+//or which its sender cannot pay cannot replace or evict another tx. The state is read under the lock of the block
+//validation, the mempool therefore calls the verifier without holding its own lock.
+func verifyOpenTx(tx protocol.Transaction) error {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if err := verify(tx); err != nil {
+		return err
+	}
+
+	txType := protocol.TypeOf(tx)
+	if txType.FeePayer == nil {
+		return nil
+	}
+	payer, pays := txType.FeePayer(tx)
+	if !pays || storage.IsRootKey(payer) {
+		return nil
+	}
+
+	acc := storage.State[payer]
+	if acc == nil {
+		//In partitioned mode, the accounts of the other shards are unknown.
+		if !ownsAccount(&protocol.Account{Address: payer}) {
+			return nil
+		}
+		return protocol.NewTxRejection(protocol.TXREJECT_INSUFFICIENT_FUNDS, fmt.Sprintf("Account (%x) does not exist.", payer[0:8]))
+	}
+
+	var amount uint64
+	if fundsTx, ok := tx.(*protocol.FundsTx); ok {
+		if err := verifyMultiSig(fundsTx, acc); err != nil {
+			return err
+		}
+		amount = fundsTx.Amount
+	}
+
+	if tx.TxFee() > acc.Balance || amount > acc.Balance-tx.TxFee() {
+		return protocol.NewTxRejection(protocol.TXREJECT_INSUFFICIENT_FUNDS, "Not enough funds to complete the transaction!")
+	}
 
-	switch tx.(type) {
-	case *protocol.FundsTx:
-		verified = verifyFundsTx(tx.(*protocol.FundsTx))
-	case *protocol.ContractTx:
-		verified = verifyContractTx(tx.(*protocol.ContractTx))
-	case *protocol.ConfigTx:
-		verified = verifyConfigTx(tx.(*protocol.ConfigTx))
-	case *protocol.StakeTx:
-		verified = verifyStakeTx(tx.(*protocol.StakeTx))
+	if txType.Nonce != nil && txType.Nonce(tx) < acc.TxCnt {
+		return protocol.NewTxRejection(protocol.TXREJECT_NONCE_MISMATCH, fmt.Sprintf("Nonce %d has already been used, the account is at %d.", txType.Nonce(tx), acc.TxCnt))
 	}
 
-	return verified
+	return nil
 }
 
-func verifyFundsTx(tx *protocol.FundsTx) bool {
+func verifyFundsTx(tx *protocol.FundsTx) error {
 	if tx == nil {
-		return false
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction does not exist.")
 	}
 
-	r, s := new(big.Int), new(big.Int)
-
 	//fundsTx only makes sense if amount > 0
 	if tx.Amount == 0 || tx.Amount > MAX_MONEY {
 		logger.Printf("Invalid transaction amount: %v\n", tx.Amount)
 		FileLogger.Printf("Invalid transaction amount: %v\n", tx.Amount)
-		return false
+		return protocol.NewTxRejection(protocol.TXREJECT_BOUNDS_VIOLATION, fmt.Sprintf("Invalid transaction amount: %v", tx.Amount))
+	}
+
+	if tx.IsBatch() && !verifyBatchOutputs(tx) {
+		logger.Printf("Invalid batch transfer outputs: %v\n", tx.Outputs)
+		FileLogger.Printf("Invalid batch transfer outputs: %v\n", tx.Outputs)
+		return protocol.NewTxRejection(protocol.TXREJECT_BOUNDS_VIOLATION, "Invalid batch transfer outputs.")
+	}
+
+	if tx.From == tx.To {
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Sender and receiver are the same account.")
 	}
 
 	accFromHash := protocol.SerializeHashContent(tx.From)
 	accToHash := protocol.SerializeHashContent(tx.To)
 
-	r.SetBytes(tx.Sig[:32])
-	s.SetBytes(tx.Sig[32:])
+	//The signatures of a multi-signature account depend on its key set in the state, they are checked together with the
+	//other dynamic properties (see verifyMultiSig).
+	if len(tx.MultiSigs) > 0 {
+		return nil
+	}
 
 	txHash := tx.Hash()
 
-	pubKey := crypto.GetPubKeyFromAddress(tx.From)
-	if ecdsa.Verify(pubKey, txHash[:], r, s) && tx.From != tx.To {
-		return true
-	} else {
+	//The signature scheme is given by the tag of the address.
+	if !crypto.VerifySignature(tx.From, txHash[:], tx.Sig) {
 		logger.Printf("Sig invalid. FromHash: %x\nToHash: %x\n", accFromHash[0:8], accToHash[0:8])
 		FileLogger.Printf("Sig invalid. FromHash: %x\nToHash: %x\n", accFromHash[0:8], accToHash[0:8])
-		return false
+		return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, "Signature of the sender is invalid.")
 	}
+
+	return nil
 }
 
-func verifyContractTx(tx *protocol.ContractTx) bool {
-	if tx == nil {
+//A batch transfer pays distinct recipients other than the sender and its amount is the sum of the outputs. Batch
+//transfers cannot call smart contracts, the To field and Data are left empty.
+func verifyBatchOutputs(tx *protocol.FundsTx) bool {
+	if tx.To != [64]byte{} || tx.Data != nil {
 		return false
 	}
 
-	r, s := new(big.Int), new(big.Int)
+	recipients := make(map[[64]byte]bool)
+	var sum uint64
+	for _, output := range tx.Outputs {
+		if output.Amount == 0 || output.Amount > MAX_MONEY || output.To == tx.From || recipients[output.To] {
+			return false
+		}
+
+		recipients[output.To] = true
+		if sum += output.Amount; sum > MAX_MONEY {
+			return false
+		}
+	}
 
-	r.SetBytes(tx.Sig[:32])
-	s.SetBytes(tx.Sig[32:])
+	return sum == tx.Amount
+}
+
+//Batch transfers are executed entirely by the shard of the sender, hence all recipients need to belong to it.
+func verifyBatchShard(tx *protocol.FundsTx) error {
+	for _, output := range tx.Outputs {
+		if NumberOfShards > 1 && assignAddressToShard(output.To) != assignAddressToShard(tx.From) {
+			return protocol.NewTxRejection(protocol.TXREJECT_INVALID, fmt.Sprintf("Recipient (%x) of the batch transfer belongs to another shard.", output.To[0:8]))
+		}
+	}
+
+	return nil
+}
+
+//A fundsTx spending from a multi-signature account needs valid signatures of at least threshold distinct keys of the
+//account, the signature of the account address itself is not sufficient. Other accounts must not carry multi-signatures.
+func verifyMultiSig(tx *protocol.FundsTx, acc *protocol.Account) error {
+	if !acc.IsMultiSig() {
+		if len(tx.MultiSigs) > 0 {
+			return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, "Sender is not a multi-signature account.")
+		}
+		return nil
+	}
+
+	if len(tx.MultiSigs) > len(acc.MultiSigKeys) {
+		return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, fmt.Sprintf("Transaction carries %d signatures for %d keys.", len(tx.MultiSigs), len(acc.MultiSigKeys)))
+	}
+
+	txHash := tx.Hash()
+	signed := make([]bool, len(acc.MultiSigKeys))
+	var nrSigned uint32
+
+	for _, sig := range tx.MultiSigs {
+		for i, key := range acc.MultiSigKeys {
+			if !signed[i] && crypto.VerifySignature(key, txHash[:], sig) {
+				signed[i] = true
+				nrSigned++
+				break
+			}
+		}
+	}
+
+	if nrSigned < acc.MultiSigThreshold {
+		return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, fmt.Sprintf("Only %d of %d required signatures are valid.", nrSigned, acc.MultiSigThreshold))
+	}
+
+	return nil
+}
+
+//The key set of a multi-signature account must consist of distinct keys and the threshold must be satisfiable.
+func verifyMultiSigKeys(keys [][64]byte, threshold uint32) bool {
+	if threshold == 0 || threshold > uint32(len(keys)) {
+		return len(keys) == 0 && threshold == 0
+	}
+
+	distinctKeys := make(map[[64]byte]bool)
+	for _, key := range keys {
+		if distinctKeys[key] {
+			return false
+		}
+		distinctKeys[key] = true
+	}
+
+	return true
+}
+
+func verifyContractTx(tx *protocol.ContractTx) error {
+	if tx == nil {
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction does not exist.")
+	}
+
+	if !verifyMultiSigKeys(tx.MultiSigKeys, tx.MultiSigThreshold) {
+		logger.Printf("Invalid multi-signature key set: %v of %v keys\n", tx.MultiSigThreshold, len(tx.MultiSigKeys))
+		FileLogger.Printf("Invalid multi-signature key set: %v of %v keys\n", tx.MultiSigThreshold, len(tx.MultiSigKeys))
+		return protocol.NewTxRejection(protocol.TXREJECT_BOUNDS_VIOLATION, fmt.Sprintf("Invalid multi-signature key set: %v of %v keys", tx.MultiSigThreshold, len(tx.MultiSigKeys)))
+	}
 
 	for _, rootAcc := range storage.RootKeys {
-		pubKey := crypto.GetPubKeyFromAddress(rootAcc.Address)
 		txHash := tx.Hash()
 
 		//Only the hash of the pubkey is hashed and verified here
-		if ecdsa.Verify(pubKey, txHash[:], r, s) == true {
-			return true
+		if crypto.VerifySignature(rootAcc.Address, txHash[:], tx.Sig) {
+			return nil
 		}
 	}
 
-	return false
+	return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, "Transaction is not signed by a root account.")
 }
 
-func verifyConfigTx(tx *protocol.ConfigTx) bool {
+func verifyConfigTx(tx *protocol.ConfigTx) error {
 	if tx == nil {
-		return false
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction does not exist.")
 	}
 
 	//account creation can only be done with a valid priv/pub key which is hard-coded
-	r, s := new(big.Int), new(big.Int)
-
-	r.SetBytes(tx.Sig[:32])
-	s.SetBytes(tx.Sig[32:])
-
 	for _, rootAcc := range storage.RootKeys {
-		pubKey := crypto.GetPubKeyFromAddress(rootAcc.Address)
 		txHash := tx.Hash()
-		if ecdsa.Verify(pubKey, txHash[:], r, s) == true {
-			return true
+		if crypto.VerifySignature(rootAcc.Address, txHash[:], tx.Sig) {
+			return nil
 		}
 	}
 
-	return false
+	return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, "Transaction is not signed by a root account.")
 }
 
-func verifyStakeTx(tx *protocol.StakeTx) bool {
+func verifyStakeTx(tx *protocol.StakeTx) error {
 	if tx == nil {
 		logger.Println("Transactions does not exist.")
-		return false
+		return protocol.NewTxRejection(protocol.TXREJECT_INVALID, "Transaction does not exist.")
 	}
 
-	//Check if account is present in the actual state
+	//Check if account is present in the actual state. An unknown account is not written to the state, verification
+	//also runs for txs received over the network before they are admitted to the mempool.
 	acc := storage.State[tx.Account]
 	if acc == nil {
-		// TODO: Requires a Mutex?
 		newAcc := protocol.NewAccount(tx.Account, [64]byte{}, 0, false, [256]byte{}, nil, nil)
 		acc = &newAcc
-		storage.WriteAccount(acc)
 	}
 
-	r, s := new(big.Int), new(big.Int)
-
-	r.SetBytes(tx.Sig[:32])
-	s.SetBytes(tx.Sig[32:])
-
 	tx.Account = acc.Address
 
 	txHash := tx.Hash()
 
-	pubKey := crypto.GetPubKeyFromAddress(acc.Address)
+	if !crypto.VerifySignature(acc.Address, txHash[:], tx.Sig) {
+		return protocol.NewTxRejection(protocol.TXREJECT_BAD_SIGNATURE, "Signature of the staking account is invalid.")
+	}
+	return nil
+}
+
+//State transitions are only accepted from the validator which is assigned to the shard of the transition in the
+//current epoch. The signature is created with the commitment key of the validator over the hash of the whole content.
+func verifyStateTransition(st *protocol.StateTransition) error {
+	if st == nil {
+		return errors.New("State transition does not exist.")
+	}
+
+	if ValidatorShardMap == nil {
+		return errors.New("No validator-shard mapping available for the current epoch.")
+	}
+
+	shardId, assigned := ValidatorShardMap.ValMapping[st.Validator]
+	if !assigned {
+		return errors.New(fmt.Sprintf("Validator (%x) is not part of the current epoch.", st.Validator[0:8]))
+	}
+
+	if shardId != st.ShardID {
+		return errors.New(fmt.Sprintf("Validator (%x) is assigned to shard %d, not to shard %d.", st.Validator[0:8], shardId, st.ShardID))
+	}
+
+	acc, err := store.ReadAccount(st.Validator)
+	if err != nil {
+		return err
+	}
+
+	commitmentPubKey, err := crypto.CreateRSAPubKeyFromBytes(acc.CommitmentKey)
+	if err != nil {
+		return errors.New("Invalid commitment key in account.")
+	}
+
+	stHash := st.HashTransition()
+	if err := crypto.VerifyMessageWithRSAKey(commitmentPubKey, fmt.Sprintf("%x", stHash), st.CommitmentSig); err != nil {
+		return errors.New(fmt.Sprintf("Signature of state transition (%x) is invalid.", stHash[0:8]))
+	}
+
+	return nil
+}
+
+//Receipts are checked against the block of the sender shard, which must have been received before.
+func verifyReceipt(receipt *protocol.Receipt, block *protocol.Block, initialSetup bool) error {
+	if receipt == nil || receipt.Tx == nil {
+		return errors.New("Receipt does not contain a transaction.")
+	}
+
+	txHash := receipt.Tx.Hash()
+	if err := verifyFundsTx(receipt.Tx); err != nil {
+		return errors.New(fmt.Sprintf("Transaction of receipt (%x) is invalid: %v", txHash[0:8], err))
+	}
+
+	if !isCrossShardTx(receipt.Tx) || assignAddressToShard(receipt.Tx.To) != block.ShardId || assignAddressToShard(receipt.Tx.From) != receipt.ShardId {
+		return errors.New(fmt.Sprintf("Receipt (%x) is not a transfer from shard %d to shard %d.", txHash[0:8], receipt.ShardId, block.ShardId))
+	}
+
+	//Receipts of blocks which are already part of the chain have been checked when the blocks were validated.
+	if initialSetup {
+		return nil
+	}
+
+	if store.ReadReceipt(txHash) != nil {
+		return errors.New(fmt.Sprintf("Receipt (%x) has already been credited.", txHash[0:8]))
+	}
+
+	senderBlock := storage.ReadReceivedBlock(receipt.BlockHash)
+	if senderBlock == nil {
+		return errors.New(fmt.Sprintf("Block (%x) of receipt (%x) is unknown.", receipt.BlockHash[0:8], txHash[0:8]))
+	}
+
+	if senderBlock.ShardId != receipt.ShardId || !verifyBlockHash(senderBlock) {
+		return errors.New(fmt.Sprintf("Block (%x) of receipt (%x) is invalid.", receipt.BlockHash[0:8], txHash[0:8]))
+	}
+
+	if err := verifySenderBlock(senderBlock); err != nil {
+		return errors.New(fmt.Sprintf("Block (%x) of receipt (%x) is not authenticated: %v", receipt.BlockHash[0:8], txHash[0:8], err))
+	}
+
+	if !protocol.VerifyMerkleProof(senderBlock.MerkleRoot, txHash, receipt.MerkleProof) {
+		return errors.New(fmt.Sprintf("Merkle proof of receipt (%x) is invalid.", txHash[0:8]))
+	}
+
+	return nil
+}
+
+//Anyone can broadcast a block of another shard, its hash does not prove who proposed it. A block of another shard is
+//only trusted if the validator of that shard signed a state transition for it, which commits to the block hash and to
+//the txs of the block.
+func verifySenderBlock(block *protocol.Block) error {
+	st := protocol.ReturnStateTransitionForBlockHash(storage.ReceivedStateStash, block.Hash)
+	if st == nil {
+		return errors.New("No state transition of the block has been received.")
+	}
+
+	if err := verifyStateTransition(st); err != nil {
+		return err
+	}
+
+	if st.ShardID != block.ShardId || st.Validator != block.Beneficiary || st.Height != int(block.Height) {
+		stHash := st.HashTransition()
+		return errors.New(fmt.Sprintf("State transition (%x) does not belong to the block.", stHash[0:8]))
+	}
+
+	if !equalHashes(st.FundsTxData, block.TxHashes(protocol.FUNDSTX_TYPE)) {
+		return errors.New("State transition does not commit to the FundsTxs of the block.")
+	}
+
+	return nil
+}
+
+func equalHashes(a [][32]byte, b [][32]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Every tx of a block must be valid at the height of the block.
+func verifyValidity(height uint32, txs []protocol.Transaction) error {
+	for _, tx := range txs {
+		if !protocol.IsValidAt(tx, height) {
+			validFrom, validUntil := tx.Validity()
+			txHash := tx.Hash()
+			return errors.New(fmt.Sprintf("Transaction (%x) is only valid from height %d until %d, not at %d.", txHash[0:8], validFrom, validUntil, height))
+		}
+	}
 
-	return ecdsa.Verify(pubKey, txHash[:], r, s)
+	return nil
 }
 
 //Returns true if id is in the list of possible ids and rational value for payload parameter.
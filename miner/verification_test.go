@@ -1,11 +1,16 @@
 package miner
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/ed25519"
+	"fmt"
 	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 func TestFundsTxVerification(t *testing.T) {
@@ -14,12 +19,38 @@ func TestFundsTxVerification(t *testing.T) {
 	loopMax := int(randVar.Uint64() % 1000)
 	for i := 0; i < loopMax; i++ {
 		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100000+1, randVar.Uint64()%10+1, uint32(i), accA.Address, accB.Address, PrivKeyAccA, nil)
-		if verifyFundsTx(tx) == false {
+		if verifyFundsTx(tx) != nil {
 			t.Errorf("Tx could not be verified: \n%v", tx)
 		}
 	}
 }
 
+//Ed25519 accounts sign their txs with the scheme their address is tagged with.
+func TestEd25519FundsTxVerification(t *testing.T) {
+	cleanAndPrepare()
+
+	_, ed25519Key, _ := ed25519.GenerateKey(cryptorand.Reader)
+	address, _ := crypto.GetAddressFromPrivKey(ed25519Key)
+	acc := protocol.NewAccount(address, [64]byte{}, 1000, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	store.WriteAccount(&acc)
+
+	tx, err := protocol.ConstrFundsTx(0x01, 10, 1, 0, address, accB.Address, ed25519Key, nil)
+	if err != nil || verifyFundsTx(tx) != nil {
+		t.Fatalf("FundsTx of an Ed25519 account could not be verified: %v\n", err)
+	}
+
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("FundsTx of an Ed25519 account could not be added: %v\n", err)
+	}
+
+	//The P-256 key of another account cannot sign for the Ed25519 account.
+	forgedTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, address, accB.Address, PrivKeyAccA, nil)
+	if err := verifyFundsTx(forgedTx); protocol.RejectionCode(err) != protocol.TXREJECT_BAD_SIGNATURE {
+		t.Errorf("FundsTx of an Ed25519 account signed with a P-256 key was not rejected for its signature: %v\n", err)
+	}
+}
+
 func TestContractTx(t *testing.T) {
 	randVar := rand.New(rand.NewSource(time.Now().Unix()))
 
@@ -27,7 +58,7 @@ func TestContractTx(t *testing.T) {
 	loopMax := int(randVar.Uint64() % 1000)
 	for i := 0; i <= loopMax; i++ {
 		tx, _, _ := protocol.ConstrContractTx(0, randVar.Uint64()%100+1, PrivKeyRoot, nil, nil)
-		if verifyContractTx(tx) == false {
+		if verifyContractTx(tx) != nil {
 			t.Errorf("ContractTx could not be verified: %v\n", tx)
 		}
 	}
@@ -46,12 +77,206 @@ func TestConfigTx(t *testing.T) {
 	//Add an invalid configTx, should not be accepted
 	txfail, err6 := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 20, 5000, randVar.Uint64(), 0, PrivKeyRoot)
 
-	if (verifyConfigTx(tx) == false || err != nil) &&
-		(verifyConfigTx(tx2) == false || err2 != nil) &&
-		(verifyConfigTx(tx3) == false || err3 != nil) &&
-		(verifyConfigTx(tx4) == false || err4 != nil) &&
-		(verifyConfigTx(tx5) == false || err5 != nil) &&
-		(verifyConfigTx(txfail) == true || err6 != nil) {
+	if (verifyConfigTx(tx) != nil || err != nil) &&
+		(verifyConfigTx(tx2) != nil || err2 != nil) &&
+		(verifyConfigTx(tx3) != nil || err3 != nil) &&
+		(verifyConfigTx(tx4) != nil || err4 != nil) &&
+		(verifyConfigTx(tx5) != nil || err5 != nil) &&
+		(verifyConfigTx(txfail) == nil || err6 != nil) {
 		t.Error("ConfigTx verification malfunctioning!")
 	}
 }
+
+func TestStateTransitionVerification(t *testing.T) {
+	cleanAndPrepare()
+
+	prevValidatorShardMap := ValidatorShardMap
+	defer func() { ValidatorShardMap = prevValidatorShardMap }()
+
+	ValidatorShardMap = protocol.NewMapping()
+	ValidatorShardMap.ValMapping[accA.Address] = 2
+
+	relativeAcc := protocol.NewRelativeAccount(accB.Address, [64]byte{}, 100, false, accB.CommitmentKey, nil, nil)
+	st := protocol.NewStateTransition(map[[64]byte]*protocol.RelativeAccount{accB.Address: &relativeAcc}, 10, 2,
+		[32]byte{'1'}, nil, nil, nil, nil)
+	st.Validator = accA.Address
+	stHash := st.HashTransition()
+	st.CommitmentSig, _ = crypto.SignMessageWithRSAKey(CommPrivKeyAccA, fmt.Sprintf("%x", stHash))
+
+	if err := verifyStateTransition(st); err != nil {
+		t.Errorf("State transition could not be verified: %v\n", err)
+	}
+
+	//Manipulating the relative state change invalidates the signature.
+	relativeAcc.Balance = 1000
+	if err := verifyStateTransition(st); err == nil {
+		t.Error("Manipulated state transition has been verified.")
+	}
+	relativeAcc.Balance = 100
+
+	//The validator must be assigned to the shard of the state transition.
+	ValidatorShardMap.ValMapping[accA.Address] = 3
+	if err := verifyStateTransition(st); err == nil {
+		t.Error("State transition of a validator assigned to another shard has been verified.")
+	}
+
+	//Validators which are not part of the current epoch are rejected.
+	delete(ValidatorShardMap.ValMapping, accA.Address)
+	if err := verifyStateTransition(st); err == nil {
+		t.Error("State transition of an unknown validator has been verified.")
+	}
+}
+
+func TestMultiSigFundsTx(t *testing.T) {
+	cleanAndPrepare()
+	//The treasury is credited in this shard.
+	NumberOfShards = 1
+	ValidatorShardMap.ValMapping[validatorAccAddress] = 1
+
+	//Root creates a 2-of-2 treasury account controlled by the keys of A and B.
+	contractTx, treasuryKey, _ := protocol.ConstrContractTx(0, 1, PrivKeyRoot, nil, nil)
+	if err := contractTx.SetMultiSig([][64]byte{accA.Address, accA.Address}, 2, PrivKeyRoot); err != nil || verifyContractTx(contractTx) == nil {
+		t.Error("ContractTx with duplicate multi-signature keys has been verified.")
+	}
+	if err := contractTx.SetMultiSig([][64]byte{accA.Address, accB.Address}, 2, PrivKeyRoot); err != nil || verifyContractTx(contractTx) != nil {
+		t.Fatalf("Multi-signature ContractTx could not be verified: %v\n", err)
+	}
+
+	//The account is created without balance, it must survive the block.
+	b := newBlock(lastBlock.HashBlock(), [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	store.WriteOpenTx(contractTx)
+	if err := addTx(b, contractTx); err != nil {
+		t.Fatalf("Multi-signature ContractTx could not be added: %v\n", err)
+	}
+	finalizeBlock(b)
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Block creating the multi-signature account could not be validated: %v\n", err)
+	}
+	treasury := storage.State[contractTx.PubKey]
+	if treasury == nil || !treasury.IsMultiSig() {
+		t.Fatal("Multi-signature account has not been created.")
+	}
+
+	fundingTx, _ := protocol.ConstrFundsTx(0x01, 1000, 1, accA.TxCnt, accA.Address, treasury.Address, PrivKeyAccA, nil)
+	b = newBlock(b.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b.Height+1)
+	store.WriteOpenTx(fundingTx)
+	if err := addTx(b, fundingTx); err != nil {
+		t.Fatalf("FundsTx to the multi-signature account could not be added: %v\n", err)
+	}
+	finalizeBlock(b)
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Block funding the multi-signature account could not be validated: %v\n", err)
+	}
+	treasury = storage.State[contractTx.PubKey]
+	if treasury.Balance != 1000 {
+		t.Fatalf("Multi-signature account has not been funded: %v\n", treasury)
+	}
+
+	b = newBlock(b.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, b.Height+1)
+
+	//The key of the account address alone is not sufficient.
+	singleSigTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, treasury.Address, accB.Address, treasuryKey, nil)
+	if err := addTx(b, singleSigTx); err == nil {
+		t.Error("FundsTx spending from a multi-signature account with a single signature has been added.")
+	}
+
+	for _, sigKeys := range [][]crypto.PrivateKey{{PrivKeyAccA}, {PrivKeyAccA, PrivKeyAccA}, {PrivKeyAccA, PrivKeyRoot}} {
+		tx, _ := protocol.ConstrMultiSigFundsTx(0x01, 10, 1, 0, treasury.Address, accB.Address, sigKeys, nil)
+		if err := addTx(b, tx); err == nil {
+			t.Errorf("FundsTx with %d insufficient signatures has been added.", len(sigKeys))
+		}
+	}
+
+	//Single-signature accounts must not carry multi-signatures.
+	tx, _ := protocol.ConstrMultiSigFundsTx(0x01, 10, 1, 0, accA.Address, accB.Address, []crypto.PrivateKey{PrivKeyAccA}, nil)
+	if err := addTx(b, tx); err == nil {
+		t.Error("FundsTx with multi-signatures of a single-signature account has been added.")
+	}
+
+	tx, _ = protocol.ConstrMultiSigFundsTx(0x01, 10, 1, 0, treasury.Address, accB.Address, []crypto.PrivateKey{PrivKeyAccB, PrivKeyAccA}, nil)
+	store.WriteOpenTx(tx)
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("FundsTx signed by 2 of 2 keys could not be added: %v\n", err)
+	}
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Block finalization failed. (%v)\n", err)
+	}
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Block with a multi-signature FundsTx could not be validated: %v\n", err)
+	}
+
+	if treasury.Balance != 1000-10-1 || treasury.TxCnt != 1 {
+		t.Errorf("Multi-signature account has not been debited: %v\n", treasury)
+	}
+}
+
+func TestBatchFundsTxVerification(t *testing.T) {
+	outputs := []protocol.FundsTxOutput{{To: accB.Address, Amount: 10}, {To: validatorAcc.Address, Amount: 20}}
+	tx, _ := protocol.ConstrBatchFundsTx(0x01, 1, 0, accA.Address, outputs, PrivKeyAccA)
+	if verifyFundsTx(tx) != nil {
+		t.Errorf("Batch FundsTx could not be verified: %v\n", tx)
+	}
+
+	invalidOutputs := [][]protocol.FundsTxOutput{
+		{{To: accB.Address, Amount: 10}, {To: accB.Address, Amount: 20}},
+		{{To: accB.Address, Amount: 10}, {To: accA.Address, Amount: 20}},
+		{{To: accB.Address, Amount: 10}, {To: validatorAcc.Address, Amount: 0}},
+	}
+	for _, outputs := range invalidOutputs {
+		tx, _ := protocol.ConstrBatchFundsTx(0x01, 1, 0, accA.Address, outputs, PrivKeyAccA)
+		if err := verifyFundsTx(tx); protocol.RejectionCode(err) != protocol.TXREJECT_BOUNDS_VIOLATION {
+			t.Errorf("Batch FundsTx with invalid outputs was not rejected for a bounds violation: %v\n", outputs)
+		}
+	}
+
+	//The amount must be the sum of the outputs.
+	tx.Amount = 31
+	tx.SetValidity(0, 0, PrivKeyAccA)
+	if verifyFundsTx(tx) == nil {
+		t.Error("Batch FundsTx with an amount other than the sum of its outputs has been verified.")
+	}
+}
+
+//Txs which are not signed by their sender or which the sender cannot pay are not admitted to the mempool, they can
+//neither replace nor evict another tx.
+func TestVerifyOpenTx(t *testing.T) {
+	cleanAndPrepare()
+	store.SetTxVerifier(verifyOpenTx)
+	defer store.SetTxVerifier(nil)
+	accA.TxCnt = 5
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 10, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	if admission := store.WriteOpenTx(tx); !admission.Admitted {
+		t.Fatalf("Signed tx was not admitted: %v\n", admission)
+	}
+
+	unsignedTx, _ := protocol.ConstrFundsTx(0x01, 10, 100, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	unsignedTx.Sig = [64]byte{}
+	admission := store.WriteOpenTx(unsignedTx)
+	if admission.Admitted || admission.Code != protocol.TXREJECT_BAD_SIGNATURE {
+		t.Errorf("Unsigned replacement was not rejected for its signature: %v\n", admission)
+	}
+	if store.ReadOpenTx(tx.Hash()) == nil {
+		t.Error("Unsigned replacement evicted the signed tx.")
+	}
+
+	overdrawnTx, _ := protocol.ConstrFundsTx(0x01, accA.Balance, 100, accA.TxCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+	if admission := store.WriteOpenTx(overdrawnTx); admission.Admitted || admission.Code != protocol.TXREJECT_INSUFFICIENT_FUNDS {
+		t.Errorf("Replacement the sender cannot pay was not rejected: %v\n", admission)
+	}
+
+	usedNonceTx, _ := protocol.ConstrFundsTx(0x01, 10, 10, accA.TxCnt-1, accA.Address, accB.Address, PrivKeyAccA, nil)
+	if admission := store.WriteOpenTx(usedNonceTx); admission.Admitted || admission.Code != protocol.TXREJECT_NONCE_MISMATCH {
+		t.Errorf("Tx with a used nonce was not rejected: %v\n", admission)
+	}
+
+	//The verification of a StakeTx of an unknown account does not create the account.
+	unknownAddress := [64]byte{'u'}
+	stakeTx, _ := protocol.ConstrStakeTx(0x01, 10, true, unknownAddress, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	if admission := store.WriteOpenTx(stakeTx); admission.Admitted || admission.Code != protocol.TXREJECT_BAD_SIGNATURE {
+		t.Errorf("StakeTx of an unknown account with an invalid signature was not rejected: %v\n", admission)
+	}
+	if _, exists := storage.State[unknownAddress]; exists {
+		t.Error("Verification of a StakeTx added its unknown account to the state.")
+	}
+}
@@ -0,0 +1,260 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"io/ioutil"
+	"log"
+)
+
+/**
+	The result of the verification of the chain of a database. Blocks of epochs with several shards are checked
+	structurally only, their state cannot be replayed since the state transitions of the other shards are not stored.
+ */
+type ChainReport struct {
+	From        uint32
+	To          uint32
+	EpochBlocks int
+	Blocks      int
+	Replayed    int
+}
+
+func (report *ChainReport) String() string {
+	return fmt.Sprintf("Verified heights %d to %d: %d epoch block(s), %d block(s) of which %d replayed against the state",
+		report.From, report.To, report.EpochBlocks, report.Blocks, report.Replayed)
+}
+
+//The first block of a chain which does not verify.
+type ChainMismatch struct {
+	Height       uint32
+	Hash         [32]byte
+	IsEpochBlock bool
+	Reason       string
+}
+
+func (mismatch *ChainMismatch) Error() string {
+	kind := "Block"
+	if mismatch.IsEpochBlock {
+		kind = "Epoch block"
+	}
+	return fmt.Sprintf("%v (%x) at height %d: %v", kind, mismatch.Hash[0:8], mismatch.Height, mismatch.Reason)
+}
+
+//Walks the chain of the store from the genesis (or the first epoch block kept in pruned mode) to the last closed block
+//and checks every epoch block and block as the miner does when it resumes. The chain is replayed on a fresh state with
+//the default system parameters, the store is not written to. Returns a *ChainMismatch for the first block which does
+//not verify. The chain is replayed on the package state of the miner, it cannot be verified next to a running miner.
+func VerifyChain(nodeStore storage.Store) (*ChainReport, error) {
+	if store != nil {
+		return nil, errors.New("Chain cannot be verified while the miner runs.")
+	}
+	store = nodeStore
+	defer func() {
+		store = nil
+	}()
+
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+	if FileLogger == nil {
+		FileLogger = log.New(ioutil.Discard, "", 0)
+	}
+
+	parameterSlice = []Parameters{NewDefaultParameters()}
+	activeParameters = &parameterSlice[0]
+	currentTargetTime = new(timerange)
+	target = []uint8{15}
+	globalBlockCount, localBlockCount = -1, -1
+
+	storage.State = make(map[[64]byte]*protocol.Account)
+	storage.RootKeys = make(map[[64]byte]*protocol.Account)
+
+	return verifyChain()
+}
+
+func verifyChain() (*ChainReport, error) {
+	genesis, err := store.ReadGenesis()
+	if err != nil {
+		return nil, err
+	}
+	if genesis == nil {
+		return nil, errors.New("The database holds no genesis.")
+	}
+
+	lastEpoch := store.ReadLastClosedEpochBlock()
+	if lastEpoch == nil {
+		return nil, errors.New("The database holds no epoch block.")
+	}
+
+	//In pruned mode, the chain starts at the first epoch block which was kept.
+	var first *protocol.EpochBlock
+	store.ForEachClosedEpochBlock(store.ReadPruneHeight(), lastEpoch.Height, func(epochBlock *protocol.EpochBlock) bool {
+		first = epochBlock
+		return false
+	})
+	if first == nil {
+		return nil, errors.New(fmt.Sprintf("The database holds no epoch block from height %d on.", store.ReadPruneHeight()))
+	}
+
+	report := &ChainReport{From: first.Height, To: lastEpoch.Height}
+	if lastClosed := store.ReadLastClosedBlock(); lastClosed != nil && lastClosed.Height > report.To {
+		report.To = lastClosed.Height
+	}
+
+	var prevHash [32]byte
+	for height := report.From; height <= report.To; height++ {
+		if epochBlock := store.ReadClosedEpochBlockByHeight(height); epochBlock != nil {
+			if reason := verifyChainEpochBlock(epochBlock, genesis, prevHash, height == report.From); reason != "" {
+				return report, &ChainMismatch{height, epochBlock.Hash, true, reason}
+			}
+			//The blocks of a single-shard epoch were replayed, their state has to be the one the epoch block carries.
+			if height != report.From && NumberOfShards <= 1 && protocol.StateRoot(storage.State) != epochBlock.MerklePatriciaRoot {
+				return report, &ChainMismatch{height, epochBlock.Hash, true, "Replayed state does not match the state of the epoch block."}
+			}
+			adoptEpochState(epochBlock, genesis)
+			report.EpochBlocks++
+			prevHash = epochBlock.Hash
+			continue
+		}
+
+		block := store.ReadClosedBlockByHeight(height)
+		if block == nil {
+			return report, &ChainMismatch{Height: height, Reason: "Neither a block nor an epoch block is stored at this height."}
+		}
+
+		replayed, reason := verifyChainBlock(block, prevHash)
+		if reason != "" {
+			return report, &ChainMismatch{height, block.Hash, false, reason}
+		}
+		report.Blocks++
+		if replayed {
+			report.Replayed++
+		}
+		prevHash = block.Hash
+	}
+
+	return report, nil
+}
+
+//Returns the reason why the epoch block does not verify, an empty string if it does. The first epoch block of a
+//pruned chain follows a pruned block.
+func verifyChainEpochBlock(epochBlock *protocol.EpochBlock, genesis *protocol.Genesis, prevHash [32]byte, isFirst bool) string {
+	if epochBlock.Height == 0 {
+		if len(epochBlock.PrevShardHashes) != 1 || epochBlock.PrevShardHashes[0] != genesis.Hash() {
+			return "Epoch block does not link to the genesis."
+		}
+		if epochBlock.Hash != protocol.NewEpochBlock(epochBlock.PrevShardHashes, 0).HashEpochBlock() {
+			return "Epoch block does not match its hash."
+		}
+		return ""
+	}
+
+	linked := false
+	for _, prevShardHash := range epochBlock.PrevShardHashes {
+		if isFirst {
+			height, pruned := store.ReadPrunedBlockHeight(prevShardHash)
+			linked = linked || (pruned && height == epochBlock.Height-1)
+		} else {
+			linked = linked || prevShardHash == prevHash
+		}
+	}
+	if !linked {
+		return fmt.Sprintf("Epoch block does not link to the previous block (%x).", prevHash[0:8])
+	}
+
+	if !verifyEpochBlockHash(epochBlock) {
+		return "Epoch block does not match its hash."
+	}
+
+	if protocol.StateRoot(epochBlock.State) != epochBlock.MerklePatriciaRoot {
+		return "State does not match the Merkle Patricia Root."
+	}
+
+	if epochBlock.ValMapping == nil || epochBlock.ValMapping.EpochHeight != int(epochBlock.Height) {
+		return fmt.Sprintf("Validator mapping does not belong to height %d.", epochBlock.Height)
+	}
+
+	return ""
+}
+
+//The blocks following an epoch block are replayed on its state, as in resumeState(). The state of the first epoch
+//block is empty, the root account is added as on the first start.
+func adoptEpochState(epochBlock *protocol.EpochBlock, genesis *protocol.Genesis) {
+	lastEpochBlock = epochBlock
+	ValidatorShardMap = epochBlock.ValMapping
+	NumberOfShards = epochBlock.NofShards
+
+	storage.State = epochBlock.State
+	if storage.State == nil {
+		storage.State = make(map[[64]byte]*protocol.Account)
+	}
+
+	storage.RootKeys = make(map[[64]byte]*protocol.Account)
+	if rootAcc, exists := storage.State[genesis.RootAddress]; exists {
+		storage.RootKeys[genesis.RootAddress] = rootAcc
+	} else {
+		initRootAccounts(genesis)
+	}
+}
+
+//Returns whether the block was replayed against the state and the reason why it does not verify, an empty string
+//if it does.
+func verifyChainBlock(block *protocol.Block, prevHash [32]byte) (replayed bool, reason string) {
+	if block.PrevHash != prevHash {
+		return false, fmt.Sprintf("Block does not link to the previous block (%x).", prevHash[0:8])
+	}
+
+	//The first block after the genesis is not proposed by a validator, see getInitialBlock().
+	if block.Height == 1 {
+		if block.Hash != block.HashBlock() {
+			return false, "Block does not match its hash."
+		}
+		postValidate(blockData{nil, block}, true)
+		return false, ""
+	}
+
+	if !verifyBlockHash(block) {
+		return false, "Block does not match its hash."
+	}
+
+	if protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
+		return false, "Merkle Root is incorrect."
+	}
+
+	//Txs missing in the database would be requested from the network by preValidate().
+	for _, txType := range protocol.TxTypes() {
+		for _, txHash := range block.TxHashes(txType.Id) {
+			if tx := store.ReadClosedTx(txHash); tx == nil {
+				return false, fmt.Sprintf("%v (%x) is not in the database.", txType.Name, txHash[0:8])
+			} else if tx.Hash() != txHash {
+				return false, fmt.Sprintf("%v (%x) in the database does not match its hash.", txType.Name, txHash[0:8])
+			}
+		}
+	}
+
+	if NumberOfShards > 1 {
+		collectStatistics(block)
+		return false, ""
+	}
+
+	txs, err := preValidate(block, true)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	data := blockData{txs, block}
+	if err := validateState(data); err != nil {
+		return false, err.Error()
+	}
+
+	if protocol.StateRoot(storage.State) != block.MerklePatriciaRoot {
+		return false, "Merkle Patricia Root is incorrect."
+	}
+
+	postValidate(data, true)
+	deleteZeroBalanceAccounts()
+
+	return true, ""
+}
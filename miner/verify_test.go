@@ -0,0 +1,123 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+	"testing"
+)
+
+func TestVerifyChain(t *testing.T) {
+	cleanAndPrepare()
+
+	genesis := protocol.NewGenesis(
+		crypto.GetAddressFromPubKey(&PrivKeyRoot.PublicKey),
+		crypto.GetBytesFromRSAPubKey(&CommPrivKeyRoot.PublicKey))
+	store.WriteGenesis(&genesis)
+
+	//The chain is replayed on the state the blocks are validated on.
+	lastEpochBlock.Hash = lastEpochBlock.HashEpochBlock()
+	lastEpochBlock.State = storage.State
+	store.WriteClosedEpochBlock(lastEpochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(lastEpochBlock)
+
+	var blocks []*protocol.Block
+	txCnt := accA.TxCnt
+	for height := uint32(2); height <= 4; height++ {
+		b := newBlock(lastBlock.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		for cnt := 0; cnt < 3; cnt++ {
+			tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, txCnt, accA.Address, accB.Address, PrivKeyAccA, nil)
+			addTx(b, tx)
+			store.WriteOpenTx(tx)
+			txCnt++
+		}
+		finalizeBlock(b)
+		if err := validate(b, false); err != nil {
+			t.Fatalf("Block validation failed: %v\n", err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	//The epoch block takes the roots of the shard states from the state transitions of the last blocks.
+	storage.OwnStateTransitionStash = nil
+	storage.WriteToOwnStateTransitionkStash(protocol.NewStateTransition(nil, int(lastBlock.Height), 1, lastBlock.Hash, nil, nil, nil, nil))
+	for shardID := 2; shardID <= NumberOfShards; shardID++ {
+		st := protocol.NewStateTransition(nil, int(lastBlock.Height), shardID, [32]byte{byte(shardID)}, nil, nil, nil, nil)
+		storage.ReceivedStateStash.Set(st.HashTransition(), st)
+	}
+	epochBlock := protocol.NewEpochBlock([][32]byte{lastBlock.Hash}, lastBlock.Height+1)
+	if err := finalizeEpochBlock(epochBlock); err != nil {
+		t.Fatalf("Epoch block finalization failed: %v\n", err)
+	}
+	store.WriteClosedEpochBlock(epochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(epochBlock)
+
+	//The parameters of the test setup are kept, VerifyChain() would start with the default ones.
+	report, err := verifyChain()
+	if err != nil {
+		t.Fatalf("Chain did not verify: %v\n", err)
+	}
+	if report.From != 0 || report.To != 5 || report.EpochBlocks != 2 || report.Blocks != 4 || report.Replayed != 3 {
+		t.Errorf("Wrong report: %v\n", report)
+	}
+
+	//An epoch block whose state matches its root but not the replayed blocks is reported.
+	tamperedState := make(map[[64]byte]*protocol.Account)
+	for address, acc := range epochBlock.State {
+		accCopy := *acc
+		tamperedState[address] = &accCopy
+	}
+	tamperedState[accB.Address].Balance += 10
+	prevState := storage.State
+	storage.State = tamperedState
+	tamperedEpochBlock := protocol.NewEpochBlock([][32]byte{lastBlock.Hash}, lastBlock.Height+1)
+	err = finalizeEpochBlock(tamperedEpochBlock)
+	storage.State = prevState
+	if err != nil {
+		t.Fatalf("Epoch block finalization failed: %v\n", err)
+	}
+	store.DeleteClosedEpochBlock(epochBlock.Hash)
+	store.WriteClosedEpochBlock(tamperedEpochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(tamperedEpochBlock)
+
+	_, err = verifyChain()
+	if mismatch, ok := err.(*ChainMismatch); !ok || mismatch.Height != 5 || mismatch.Hash != tamperedEpochBlock.Hash || !mismatch.IsEpochBlock {
+		t.Errorf("Epoch block with a tampered state at height 5 was not reported: %v\n", err)
+	}
+	store.DeleteClosedEpochBlock(tamperedEpochBlock.Hash)
+	store.WriteClosedEpochBlock(epochBlock)
+	store.DeleteAllLastClosedEpochBlock()
+	store.WriteLastClosedEpochBlock(epochBlock)
+
+	//A block with a wrong Merkle Patricia root which matches its hash is only detected by the replay.
+	tampered := *blocks[2]
+	tampered.MerklePatriciaRoot[0] ^= 0xff
+	partial := tampered
+	partial.CommitmentProof = [crypto.COMM_PROOF_LENGTH]byte{}
+	partial.Timestamp = 0
+	partialHash := partial.HashBlock()
+	tampered.Hash = sha3.Sum256(append(tampered.Nonce[:], partialHash[:]...))
+	store.WriteClosedBlock(&tampered)
+
+	_, err = verifyChain()
+	if mismatch, ok := err.(*ChainMismatch); !ok || mismatch.Height != 4 || mismatch.Hash != tampered.Hash || mismatch.IsEpochBlock {
+		t.Errorf("Tampered block at height 4 was not reported: %v\n", err)
+	}
+
+	//A tx missing in the database is reported before its block is replayed.
+	store.DeleteClosedTx(store.ReadClosedTx(blocks[1].TxHashes(protocol.FUNDSTX_TYPE)[0]))
+	_, err = verifyChain()
+	if mismatch, ok := err.(*ChainMismatch); !ok || mismatch.Height != 3 {
+		t.Errorf("Block at height 3 with a missing tx was not reported: %v\n", err)
+	}
+
+	//The store of the running miner is not replaced.
+	minerStore := store
+	if _, err := VerifyChain(storage.NewMemoryStore()); err == nil || store != minerStore {
+		t.Error("Chain was verified while the miner runs.")
+	}
+}
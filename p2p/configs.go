@@ -16,6 +16,8 @@ const (
 	TIME_BRDCST_INTERVAL = 60
 	//Calculate system time every UPDATE_SYS_TIME seconds
 	UPDATE_SYS_TIME = 90
+	//Upper bound of the number of entries of an account history sent in one response
+	MAX_ACC_TX_HISTORY = 100
 
 	//Protocol constants
 	IPV4ADDR_SIZE = 4
@@ -1,46 +1,46 @@
 package p2p
 
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
 //All incoming messages are processed here and acted upon accordingly
 func processIncomingMsg(p *peer, header *Header, payload []byte) {
+	//Tx broadcasts, requests and responses are dispatched through the registered tx types.
+	if processTxMsg(p, header, payload) {
+		return
+	}
 
 	switch header.TypeID {
 	//BROADCASTING
-	case FUNDSTX_BRDCST:
-		processTxBrdcst(p, payload, FUNDSTX_BRDCST)
-	case ACCTX_BRDCST:
-		processTxBrdcst(p, payload, ACCTX_BRDCST)
-	case CONFIGTX_BRDCST:
-		processTxBrdcst(p, payload, CONFIGTX_BRDCST)
-	case STAKETX_BRDCST:
-		processTxBrdcst(p, payload, STAKETX_BRDCST)
 	case BLOCK_BRDCST:
 		forwardBlockToMiner(p, payload)
 	case EPOCH_BLOCK_BRDCST:
 		forwardEpochBlockToMinerIn(p, payload)
 	case STATE_TRANSITION_BRDCST:
 		forwardStateTransitionToMiner(p,payload)
+	case FRAUD_PROOF_BRDCST:
+		forwardFraudProofToMiner(p, payload)
 	case TIME_BRDCST:
 		processTimeRes(p, payload)
 
 		//REQUESTS
-	case FUNDSTX_REQ:
-		txRes(p, payload, FUNDSTX_REQ)
-	case CONTRACTTX_REQ:
-		txRes(p, payload, CONTRACTTX_REQ)
-	case CONFIGTX_REQ:
-		txRes(p, payload, CONFIGTX_REQ)
-	case STAKETX_REQ:
-		txRes(p, payload, STAKETX_REQ)
 	case BLOCK_REQ:
 		blockRes(p, payload)
 	case STATE_TRANSITION_REQ:
 		stateTransitionRes(p,payload)
+	case STATE_REQ:
+		shardStateRes(p, payload)
 	case BLOCK_HEADER_REQ:
 		blockHeaderRes(p, payload)
 	case ACC_REQ:
 		accRes(p, payload)
 	case ROOTACC_REQ:
 		rootAccRes(p, payload)
+	case ACC_TX_HISTORY_REQ:
+		accTxHistoryRes(p, payload)
+	case TX_STATUS_REQ:
+		txStatusRes(p, payload)
 	case MINER_PING:
 		pongRes(p, payload, MINER_PING)
 	case CLIENT_PING:
@@ -65,16 +65,12 @@ func processIncomingMsg(p *peer, header *Header, payload []byte) {
 		processNeighborRes(p, payload)
 	case BLOCK_RES:
 		forwardBlockReqToMiner(p, payload)
+	case BLOCK_PRUNED:
+		processBlockPrunedRes(p, payload)
 	case STATE_TRANSITION_RES:
 		forwardStateTransitionShardReqToMiner(p,payload)
-	case FUNDSTX_RES:
-		forwardTxReqToMiner(p, payload, FUNDSTX_RES)
-	case CONTRACTTX_RES:
-		forwardTxReqToMiner(p, payload, CONTRACTTX_RES)
-	case CONFIGTX_RES:
-		forwardTxReqToMiner(p, payload, CONFIGTX_RES)
-	case STAKETX_RES:
-		forwardTxReqToMiner(p, payload, STAKETX_RES)
+	case STATE_RES:
+		forwardShardStateToMiner(p, payload)
 	case GENESIS_RES:
 		forwardGenesisReqToMiner(p, payload)
 	case FIRST_EPOCH_BLOCK_RES:
@@ -87,3 +83,22 @@ func processIncomingMsg(p *peer, header *Header, payload []byte) {
 		FileLogger.Printf("Incoming message with unrecognized header Type ID: %d - Payload Len: %d\n",header.TypeID,len(payload))
 	}
 }
+
+//Returns false if the message does not belong to any registered tx type.
+func processTxMsg(p *peer, header *Header, payload []byte) bool {
+	for _, txType := range protocol.TxTypes() {
+		switch header.TypeID {
+		case txType.BrdcstMsg:
+			processTxBrdcst(p, payload, txType)
+		case txType.ReqMsg:
+			txRes(p, payload, txType)
+		case txType.ResMsg:
+			forwardTxReqToMiner(p, payload, txType)
+		default:
+			continue
+		}
+		return true
+	}
+
+	return false
+}
@@ -85,4 +85,10 @@ func InitLogging() {
 	LogMapping[133] = "STATE_TRANSITION_BRDCST"
 	LogMapping[136] = "STATE_TRANSITION_REQ"
 	LogMapping[137] = "STATE_TRANSITION_RES"
+	LogMapping[138] = "FRAUD_PROOF_BRDCST"
+	LogMapping[139] = "ACC_TX_HISTORY_REQ"
+	LogMapping[140] = "ACC_TX_HISTORY_RES"
+	LogMapping[141] = "BLOCK_PRUNED"
+	LogMapping[142] = "TX_STATUS_REQ"
+	LogMapping[143] = "TX_STATUS_RES"
 }
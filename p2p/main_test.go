@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"github.com/bazo-blockchain/bazo-miner/storage"
 	"os"
 	"testing"
 )
@@ -13,6 +14,7 @@ var (
 func TestMain(m *testing.M) {
 	//Used for some tests, the bootstarp server is listening at 8000 at the same time
 	Ipport = "127.0.0.1:9000"
+	store = storage.NewMemoryStore()
 	InitLogging()
 
 	peers.minerConns = make(map[*peer]bool)
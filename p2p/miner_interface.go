@@ -1,7 +1,9 @@
 package p2p
 
 import (
+	"encoding/binary"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"sync"
 )
 
 var (
@@ -16,6 +18,12 @@ var (
 	//State transition from the network to the miner
 	StateTransitionIn = make(chan []byte)
 
+	//Fraud proof from the miner to the network
+	FraudProofOut = make(chan []byte)
+
+	//Fraud proof from the network to the miner
+	FraudProofIn = make(chan []byte)
+
 	//EpochBlock from the network, to the miner
 	EpochBlockIn = make(chan []byte)
 	//EpochBlock from the miner, to the network
@@ -26,15 +34,14 @@ var (
 
 	VerifiedTxsOut = make(chan []byte)
 
-	//Data requested by miner, to allow parallelism, we have a chan for every tx type.
-	FundsTxChan  = make(chan *protocol.FundsTx)
-	ContractTxChan    = make(chan *protocol.ContractTx)
-	ConfigTxChan = make(chan *protocol.ConfigTx)
-	StakeTxChan  = make(chan *protocol.StakeTx)
+	//Data requested by miner, to allow parallelism, we have a chan for every tx type (see TxResChan).
+	txResChans     = make(map[byte]chan protocol.Transaction)
+	txResChanMutex = &sync.Mutex{}
 
 	BlockReqChan 	= make(chan []byte)
 	StateTransitionShardReqChan 	= make(chan []byte)
 	StateTransitionShardOut 		= make(chan []byte)
+	ShardStateReqChan 	= make(chan []byte)
 
 	GenesisReqChan 	= make(chan []byte)
 	FirstEpochBlockReqChan 	= make(chan []byte)
@@ -43,7 +50,7 @@ var (
 
 	ValidatorShardMapReq 	= make(chan []byte)
 
-	receivedTXStash = make([]*protocol.FundsTx, 0)
+	receivedTXStash = make([]protocol.Transaction, 0)
 )
 
 //This is for blocks and txs that the miner successfully validated.
@@ -73,6 +80,14 @@ func forwardStateTransitionBrdcstToMiner()  {
 	}
 }
 
+func forwardFraudProofBrdcstToMiner() {
+	for {
+		proof := <-FraudProofOut
+		toBrdcst := BuildPacket(FRAUD_PROOF_BRDCST, proof)
+		minerBrdcstMsg <- toBrdcst
+	}
+}
+
 func forwardEpochBlockBrdcstToMiner() {
 	for {
 		epochBlock := <-EpochBlockOut
@@ -100,7 +115,7 @@ func forwardBlockToMiner(p *peer, payload []byte) {
 	BlockIn <- payload
 }
 
-func txAlreadyInStash(slice []*protocol.FundsTx, newTXHash [32]byte) bool {
+func txAlreadyInStash(slice []protocol.Transaction, newTXHash [32]byte) bool {
 	for _, txInStash := range slice {
 		if txInStash.Hash() == newTXHash {
 			return true
@@ -110,65 +125,68 @@ func txAlreadyInStash(slice []*protocol.FundsTx, newTXHash [32]byte) bool {
 }
 
 //These are transactions the miner specifically requested.
-func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
+func forwardTxReqToMiner(p *peer, payload []byte, txType *protocol.TxType) {
 	if payload == nil {
 		return
 	}
 
-	switch txType {
-	case FUNDSTX_RES:
-		var fundsTx *protocol.FundsTx
-		fundsTx = fundsTx.Decode(payload)
-		if fundsTx == nil {
-			return
-		}
-		/*My version: fundstx_res*/
-		//FundsTxChan <- fundsTx
-
-		// If TX is not received with the last 1000 Transaction, send it through the channel to the TX_FETCH.
-		// Otherwise send nothing. This means, that the TX was sent before and we ensure, that only one TX per Broadcast
-		// request is going through to the FETCH Request. This should prevent the "Received txHash did not correspond to
-		// our request." error
-		if !txAlreadyInStash(receivedTXStash, fundsTx.Hash()) {
-			receivedTXStash = append(receivedTXStash, fundsTx)
-			FundsTxChan <- fundsTx
-			if len(receivedTXStash) > 1000 {
-				receivedTXStash = append(receivedTXStash[:0], receivedTXStash[1:]...)
-			}
-		}
-	case CONTRACTTX_RES:
-		var contractTx *protocol.ContractTx
-		contractTx = contractTx.Decode(payload)
-		if contractTx == nil {
-			return
-		}
-		ContractTxChan <- contractTx
-	case CONFIGTX_RES:
-		var configTx *protocol.ConfigTx
-		configTx = configTx.Decode(payload)
-		if configTx == nil {
-			return
-		}
-		ConfigTxChan <- configTx
-	case STAKETX_RES:
-		var stakeTx *protocol.StakeTx
-		stakeTx = stakeTx.Decode(payload)
-		if stakeTx == nil {
-			return
+	tx, err := txType.Decode(payload)
+	if err != nil {
+		return
+	}
+
+	// If TX is not received with the last 1000 Transaction, send it through the channel to the TX_FETCH.
+	// Otherwise send nothing. This means, that the TX was sent before and we ensure, that only one TX per Broadcast
+	// request is going through to the FETCH Request. This should prevent the "Received txHash did not correspond to
+	// our request." error
+	if !txAlreadyInStash(receivedTXStash, tx.Hash()) {
+		receivedTXStash = append(receivedTXStash, tx)
+		TxResChan(txType) <- tx
+		if len(receivedTXStash) > 1000 {
+			receivedTXStash = append(receivedTXStash[:0], receivedTXStash[1:]...)
 		}
-		StakeTxChan <- stakeTx
 	}
 }
 
+//Returns the chan the requested txs of the given type are sent to the miner with.
+func TxResChan(txType *protocol.TxType) chan protocol.Transaction {
+	txResChanMutex.Lock()
+	defer txResChanMutex.Unlock()
+
+	if _, exists := txResChans[txType.Id]; !exists {
+		txResChans[txType.Id] = make(chan protocol.Transaction)
+	}
+
+	return txResChans[txType.Id]
+}
+
 func forwardBlockReqToMiner(p *peer, payload []byte) {
 	BlockReqChan <- payload
 }
 
+//A pruned miner no longer serves the block, the miner keeps waiting for the answers of the other miners.
+func processBlockPrunedRes(p *peer, payload []byte) {
+	if len(payload) != 40 {
+		return
+	}
+	FileLogger.Printf("Block (%x) at height %d was pruned by miner %v, it keeps the blocks from height %d\n",
+		payload[0:8], binary.BigEndian.Uint32(payload[32:36]), p.getIPPort(), binary.BigEndian.Uint32(payload[36:40]))
+}
+
 func forwardStateTransitionShardReqToMiner(p *peer, payload []byte) {
 	FileLogger.Printf("received state transition response..\n")
 	StateTransitionShardReqChan <- payload
 }
 
+//Every miner answers a shard state request, responses are dropped if the miner is not waiting for them (anymore).
+func forwardShardStateToMiner(p *peer, payload []byte) {
+	select {
+	case ShardStateReqChan <- payload:
+	default:
+		FileLogger.Printf("Dropped shard state response of miner %v\n", p.getIPPort())
+	}
+}
+
 func forwardGenesisReqToMiner(p *peer, payload []byte) {
 	GenesisReqChan <- payload
 }
@@ -190,6 +208,10 @@ func forwardStateTransitionToMiner(p *peer, payload []byte) () {
 	StateTransitionIn <- payload
 }
 
+func forwardFraudProofToMiner(p *peer, payload []byte) {
+	FraudProofIn <- payload
+}
+
 
 func forwardLastEpochBlockToMiner(p *peer, payload []byte)  {
 	LastEpochBlockReqChan <- payload
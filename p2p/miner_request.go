@@ -37,6 +37,23 @@ func StateTransitionReqShard(shardID int,height int) {
 	StateTransitionShardOut <- []byte(strRequest)
 }
 
+//Requests the accounts of the shards at the height of an epoch block from all miners, every miner answers with the
+//accounts of the shard it held in the previous epoch.
+func ShardStateReq(height int) error {
+	strRequest := strconv.Itoa(height)
+
+	for p := range peers.minerConns {
+		if p == nil {
+			return errors.New("Couldn't get a connection, request not transmitted.")
+		}
+
+		packet := BuildPacket(STATE_REQ, []byte(strRequest))
+		sendData(p, packet)
+	}
+
+	return nil
+}
+
 func LastBlockReq() error {
 
 	p := peers.getRandomPeer(PEERTYPE_MINER)
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
 	"strconv"
 	"time"
 )
@@ -16,38 +15,13 @@ var(
 
 //Process tx broadcasts from other miners. We can't broadcast incoming messages directly, first check if
 //the tx has already been broadcast before, whether it is a valid tx etc.
-func processTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
-	var tx protocol.Transaction
+func processTxBrdcst(p *peer, payload []byte, txType *protocol.TxType) {
 	//Make sure the transaction can be properly decoded, verification is done at a later stage to reduce latency
-	switch brdcstType {
-	case FUNDSTX_BRDCST:
-		var fTx *protocol.FundsTx
-		fTx = fTx.Decode(payload)
-		if fTx == nil {
-			return
-		}
-		tx = fTx
-	case ACCTX_BRDCST:
-		var aTx *protocol.ContractTx
-		aTx = aTx.Decode(payload)
-		if aTx == nil {
-			return
-		}
-		tx = aTx
-	case CONFIGTX_BRDCST:
-		var cTx *protocol.ConfigTx
-		cTx = cTx.Decode(payload)
-		if cTx == nil {
-			return
-		}
-		tx = cTx
-	case STAKETX_BRDCST:
-		var sTx *protocol.StakeTx
-		sTx = sTx.Decode(payload)
-		if sTx == nil {
-			return
-		}
-		tx = sTx
+	tx, err := txType.Decode(payload)
+	if err != nil {
+		logger.Printf("Received transaction could not be decoded: %v\n", err)
+		FileLogger.Printf("Received transaction could not be decoded: %v\n", err)
+		return
 	}
 
 	//Response tx acknowledgment if the peer is a client
@@ -56,31 +30,38 @@ func processTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
 		sendData(p, packet)
 	}
 
-	if storage.ReadOpenTx(tx.Hash()) != nil {
+	if store.ReadOpenTx(tx.Hash()) != nil {
 		logger.Printf("Received transaction (%x) already in the mempool.\n", tx.Hash())
 		FileLogger.Printf("Received transaction (%x) already in the mempool.\n", tx.Hash())
 		return
 	}
-	if storage.ReadClosedTx(tx.Hash()) != nil {
+	if store.ReadClosedTx(tx.Hash()) != nil {
 		logger.Printf("Received transaction (%x) already validated.\n", tx.Hash())
 		FileLogger.Printf("Received transaction (%x) already validated.\n", tx.Hash())
 		return
 	}
 
-	//Write to mempool and rebroadcast
+	//Write to mempool and rebroadcast, txs the mempool rejects are not passed on.
 	logger.Printf("Writing transaction (%x) in the mempool.\n", tx.Hash())
 	FileLogger.Printf("Writing transaction (%x) in the mempool.\n", tx.Hash())
 	logger.Printf("Writing transaction at time: %d\n", time.Now().Unix())
 	FileLogger.Printf("Writing transaction at time: %d\n", time.Now().Unix())
 
+	admission := store.WriteOpenTx(tx)
+	if !admission.Admitted || admission.Queued || admission.Promoted > 0 || len(admission.Evicted) > 0 {
+		logger.Printf("%v\n", admission)
+		FileLogger.Printf("%v\n", admission)
+	}
+	if !admission.Admitted {
+		return
+	}
+
 	writtenTXCount += 1
 
 	logger.Printf("Written tx count: %d\n", writtenTXCount)
 	FileLogger.Printf("Written tx count: %d\n", writtenTXCount)
 
-	storage.WriteOpenTx(tx)
-
-	toBrdcst := BuildPacket(brdcstType, payload)
+	toBrdcst := BuildPacket(txType.BrdcstMsg, payload)
 	minerBrdcstMsg <- toBrdcst
 }
 
@@ -1,24 +1,28 @@
 package p2p
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
 
 const HEADER_LEN = 5
 
 //Mapping constants, used to parse incoming messages
 const (
-	FUNDSTX_BRDCST      = 1
-	ACCTX_BRDCST        = 2
-	CONFIGTX_BRDCST     = 3
-	STAKETX_BRDCST      = 4
+	//The messages of the built-in tx types are declared with their types, see protocol.TxType.
+	FUNDSTX_BRDCST      = protocol.FUNDSTX_BRDCST
+	ACCTX_BRDCST        = protocol.CONTRACTTX_BRDCST
+	CONFIGTX_BRDCST     = protocol.CONFIGTX_BRDCST
+	STAKETX_BRDCST      = protocol.STAKETX_BRDCST
 	VERIFIEDTX_BRDCST   = 5
 	BLOCK_BRDCST        = 6
 	BLOCK_HEADER_BRDCST = 7
 	TX_BRDCST_ACK       = 8
 
-	FUNDSTX_REQ            = 10
-	CONTRACTTX_REQ              = 11
-	CONFIGTX_REQ           = 12
-	STAKETX_REQ            = 13
+	FUNDSTX_REQ            = protocol.FUNDSTX_REQ
+	CONTRACTTX_REQ         = protocol.CONTRACTTX_REQ
+	CONFIGTX_REQ           = protocol.CONFIGTX_REQ
+	STAKETX_REQ            = protocol.STAKETX_REQ
 	BLOCK_REQ              = 14
 	BLOCK_HEADER_REQ       = 15
 	ACC_REQ                = 16
@@ -26,10 +30,10 @@ const (
 	INTERMEDIATE_NODES_REQ = 18
 	GENESIS_REQ			   = 19
 
-	FUNDSTX_RES            = 20
-	CONTRACTTX_RES              = 21
-	CONFIGTX_RES           = 22
-	STAKETX_RES            = 23
+	FUNDSTX_RES            = protocol.FUNDSTX_RES
+	CONTRACTTX_RES         = protocol.CONTRACTTX_RES
+	CONFIGTX_RES           = protocol.CONFIGTX_RES
+	STAKETX_RES            = protocol.STAKETX_RES
 	BLOCK_RES              = 24
 	BlOCK_HEADER_RES       = 25
 	ACC_RES                = 26
@@ -66,6 +70,13 @@ const (
 	STATE_TRANSITION_BRDCST = 133
 	STATE_TRANSITION_REQ = 136
 	STATE_TRANSITION_RES = 137
+	FRAUD_PROOF_BRDCST = 138
+	ACC_TX_HISTORY_REQ = 139
+	ACC_TX_HISTORY_RES = 140
+	//Used to signal that the requested block was pruned
+	BLOCK_PRUNED = 141
+	TX_STATUS_REQ = 142
+	TX_STATUS_RES = 143
 )
 
 type Header struct {
@@ -10,14 +10,14 @@ import (
 )
 
 //This file responds to incoming requests from miners in a synchronous fashion
-func txRes(p *peer, payload []byte, txKind uint8) {
+func txRes(p *peer, payload []byte, txType *protocol.TxType) {
 	var txHash [32]byte
 	copy(txHash[:], payload[0:32])
 
 	var tx protocol.Transaction
 	//Check closed and open storage if the tx is available
-	openTx := storage.ReadOpenTx(txHash)
-	closedTx := storage.ReadClosedTx(txHash)
+	openTx := store.ReadOpenTx(txHash)
+	closedTx := store.ReadClosedTx(txHash)
 
 	if openTx != nil {
 		tx = openTx
@@ -32,47 +32,51 @@ func txRes(p *peer, payload []byte, txKind uint8) {
 		return
 	}
 
-	var packet []byte
-	switch txKind {
-	case FUNDSTX_REQ:
-		packet = BuildPacket(FUNDSTX_RES, tx.Encode())
-	case CONTRACTTX_REQ:
-		packet = BuildPacket(CONTRACTTX_RES, tx.Encode())
-	case CONFIGTX_REQ:
-		packet = BuildPacket(CONFIGTX_RES, tx.Encode())
-	case STAKETX_REQ:
-		packet = BuildPacket(STAKETX_RES, tx.Encode())
-	}
-
+	packet := BuildPacket(txType.ResMsg, tx.Encode())
 	sendData(p, packet)
 }
 
 //Here as well, checking open and closed block storage
 func blockRes(p *peer, payload []byte) {
 	FileLogger.Printf("Incoming block request of miner %v\n",p.getIPPort())
-	var packet []byte
+	typeID, data := _blockRes(payload)
+	if typeID == BLOCK_RES {
+		FileLogger.Printf("Sending following data for block req - %v\n", data)
+	}
+	sendData(p, BuildPacket(typeID, data))
+}
+
+//If no specific block is requested, the latest is returned. Requests for pruned blocks are answered with the hash
+//(32 Byte) | height of the block (4 Byte) | prune height of this miner (4 Byte).
+func _blockRes(payload []byte) (typeID uint8, data []byte) {
 	var block *protocol.Block
 	var blockHash [32]byte
 
-	//If no specific block is requested, send latest
 	if len(payload) > 0 {
-		copy(blockHash[:], payload[:32])
+		copy(blockHash[:], payload)
 		FileLogger.Printf("Checking block for hash (%x) \n",blockHash[0:8])
-		if block = storage.ReadClosedBlock(blockHash); block == nil {
-			block = storage.ReadOpenBlock(blockHash)
+		if block = store.ReadClosedBlock(blockHash); block == nil {
+			block = store.ReadOpenBlock(blockHash)
 		}
 	} else {
-		block = storage.ReadLastClosedBlock()
+		block = store.ReadLastClosedBlock()
 	}
 
 	if block != nil {
 		FileLogger.Printf("Returning block with hash (%x)\n",block.Hash[0:8])
-		packet = BuildPacket(BLOCK_RES, block.Encode())
-		FileLogger.Printf("Sending following data for block req (%x) - %v\n",block.Hash[0:8],packet)
-	} else {
-		packet = BuildPacket(NOT_FOUND, nil)
+		return BLOCK_RES, block.Encode()
 	}
-	sendData(p, packet)
+
+	if height, pruned := store.ReadPrunedBlockHeight(blockHash); len(payload) > 0 && pruned {
+		FileLogger.Printf("Block (%x) was pruned\n", blockHash[0:8])
+		data = make([]byte, 40)
+		copy(data[:32], blockHash[:])
+		binary.BigEndian.PutUint32(data[32:36], height)
+		binary.BigEndian.PutUint32(data[36:40], store.ReadPruneHeight())
+		return BLOCK_PRUNED, data
+	}
+
+	return NOT_FOUND, nil
 }
 
 func stateTransitionRes(p *peer, payload []byte) {
@@ -102,9 +106,27 @@ func stateTransitionRes(p *peer, payload []byte) {
 	sendData(p, packet)
 }
 
+func shardStateRes(p *peer, payload []byte) {
+	var packet []byte
+
+	height, err := strconv.Atoi(string(payload))
+	if err != nil {
+		return
+	}
+
+	if shardState := storage.ReadShardStateSnapshot(height); shardState != nil {
+		packet = BuildPacket(STATE_RES, shardState.Encode())
+		FileLogger.Printf("sent %d accounts of shard %d for epoch height: %d\n", len(shardState.Accounts), shardState.ShardID, height)
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
 func genesisRes(p *peer, payload []byte) {
 	var packet []byte
-	genesis, err := storage.ReadGenesis()
+	genesis, err := store.ReadGenesis()
 	if err == nil && genesis != nil {
 		packet = BuildPacket(GENESIS_RES, genesis.Encode())
 	} else {
@@ -116,7 +138,7 @@ func genesisRes(p *peer, payload []byte) {
 
 func FirstEpochBlockRes(p *peer, payload []byte) {
 	var packet []byte
-	firstEpochBlock, err := storage.ReadFirstEpochBlock()
+	firstEpochBlock, err := store.ReadFirstEpochBlock()
 
 	if err == nil && firstEpochBlock != nil {
 		packet = BuildPacket(FIRST_EPOCH_BLOCK_RES, firstEpochBlock.Encode())
@@ -131,7 +153,7 @@ func LastEpochBlockRes(p *peer, payload []byte) {
 	var packet []byte
 
 	var lastEpochBlock *protocol.EpochBlock
-	lastEpochBlock = storage.ReadLastClosedEpochBlock()
+	lastEpochBlock = store.ReadLastClosedEpochBlock()
 
 	if lastEpochBlock != nil {
 		packet = BuildPacket(LAST_EPOCH_BLOCK_RES, lastEpochBlock.Encode())
@@ -147,7 +169,7 @@ func EpochBlockRes(p *peer, payload []byte) {
 	copy(ebHash[:], payload[0:32])
 
 	var eb *protocol.EpochBlock
-	closedEb := storage.ReadClosedEpochBlock(ebHash)
+	closedEb := store.ReadClosedEpochBlock(ebHash)
 
 	if closedEb != nil {
 		eb = closedEb
@@ -172,14 +194,14 @@ func blockHeaderRes(p *peer, payload []byte) {
 	//If no specific header is requested, send latest
 	if len(payload) > 0 {
 		var blockHash [32]byte
-		copy(blockHash[:], payload[:32])
-		if block := storage.ReadClosedBlock(blockHash); block != nil {
-			block.InitBloomFilter(append(storage.GetTxPubKeys(block)))
+		copy(blockHash[:], payload)
+		if block := store.ReadClosedBlock(blockHash); block != nil {
+			block.InitBloomFilter(append(storage.GetTxPubKeys(store, block)))
 			encodedHeader = block.EncodeHeader()
 		}
 	} else {
-		if block := storage.ReadLastClosedBlock(); block != nil {
-			block.InitBloomFilter(append(storage.GetTxPubKeys(block)))
+		if block := store.ReadLastClosedBlock(); block != nil {
+			block.InitBloomFilter(append(storage.GetTxPubKeys(store, block)))
 			encodedHeader = block.EncodeHeader()
 		}
 	}
@@ -199,7 +221,7 @@ func accRes(p *peer, payload []byte) {
 	var pubKey [64]byte
 	copy(pubKey[:], payload[0:64])
 
-	acc, _ := storage.ReadAccount(pubKey)
+	acc, _ := store.ReadAccount(pubKey)
 	packet = BuildPacket(ACC_RES, acc.Encode())
 
 	sendData(p, packet)
@@ -210,12 +232,87 @@ func rootAccRes(p *peer, payload []byte) {
 	var pubKey [64]byte
 	copy(pubKey[:], payload[0:64])
 
-	acc, _ := storage.ReadRootAccount(pubKey)
+	acc, _ := store.ReadRootAccount(pubKey)
 	packet = BuildPacket(ROOTACC_RES, acc.Encode())
 
 	sendData(p, packet)
 }
 
+//Lists the tx history of an account, e.g. for wallets.
+func accTxHistoryRes(p *peer, payload []byte) {
+	var packet []byte
+
+	if history := _accTxHistoryRes(payload); history != nil {
+		packet = BuildPacket(ACC_TX_HISTORY_RES, history)
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+//The payload consists of the address (64 Byte) | offset (4 Byte) | limit (4 Byte). The response lists the entries
+//newest first, each encoded as block height (4 Byte) | tx type (1 Byte) | encoded tx.
+func _accTxHistoryRes(payload []byte) []byte {
+	if len(payload) != 72 {
+		return nil
+	}
+
+	var address [64]byte
+	copy(address[:], payload[:64])
+	offset := binary.BigEndian.Uint32(payload[64:68])
+	limit := binary.BigEndian.Uint32(payload[68:72])
+	if limit > MAX_ACC_TX_HISTORY {
+		limit = MAX_ACC_TX_HISTORY
+	}
+
+	var entries [][]byte
+	for _, accountTx := range store.ReadAccountTxs(address, int(offset), int(limit)) {
+		//Cross-shard txs credited to the account are listed by their receipt.
+		tx := store.ReadClosedTx(accountTx.TxHash)
+		if tx == nil {
+			if receipt := store.ReadReceipt(accountTx.TxHash); receipt != nil {
+				tx = receipt.Tx
+			}
+		}
+		if tx == nil {
+			continue
+		}
+
+		entry := make([]byte, 4)
+		binary.BigEndian.PutUint32(entry, accountTx.Height)
+		entry = append(entry, tx.Type())
+		entries = append(entries, append(entry, tx.Encode()...))
+	}
+
+	return protocol.EncodeList(entries)
+}
+
+//Tells clients whether a tx is pending, queued, included or why it was rejected.
+func txStatusRes(p *peer, payload []byte) {
+	var packet []byte
+
+	if status := _txStatusRes(payload); status != nil {
+		packet = BuildPacket(TX_STATUS_RES, status)
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+//The payload consists of the tx hash (32 Byte), the response is the encoded protocol.TxStatus.
+func _txStatusRes(payload []byte) []byte {
+	if len(payload) != 32 {
+		return nil
+	}
+
+	var txHash [32]byte
+	copy(txHash[:], payload)
+
+	return store.ReadTxStatus(txHash).Encode()
+}
+
 //Completes the handshake with another miner.
 func pongRes(p *peer, payload []byte, peerType uint) {
 	//Payload consists of a 2 bytes array (port number [big endian encoded]).
@@ -311,10 +408,10 @@ func intermediateNodesRes(p *peer, payload []byte) {
 	var nodeHashes [][]byte
 	var packet []byte
 
-	copy(blockHash[:], payload[:32])
+	copy(blockHash[:], payload)
 	copy(txHash[:], payload[32:64])
 
-	merkleTree := protocol.BuildMerkleTree(storage.ReadClosedBlock(blockHash))
+	merkleTree := protocol.BuildMerkleTree(store.ReadClosedBlock(blockHash))
 
 	if intermediates, _ := protocol.GetIntermediate(protocol.GetLeaf(merkleTree, txHash)); intermediates != nil {
 		for _, node := range intermediates {
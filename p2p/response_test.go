@@ -1,7 +1,13 @@
 package p2p
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/binary"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"strconv"
 	"testing"
 )
@@ -61,3 +67,90 @@ func Test_PongRes(t *testing.T) {
 		t.Errorf("Failed to extract IP:Port: (%v) vs. (%v)\n", "8000", ipportRet)
 	}
 }
+
+func Test_AccTxHistoryRes(t *testing.T) {
+	privKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	from := crypto.GetAddressFromPubKey(&privKey.PublicKey)
+	to := [64]byte{0x01}
+
+	var txs []*protocol.FundsTx
+	for i := 0; i < 3; i++ {
+		tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, uint32(i), from, to, privKey, nil)
+		store.WriteClosedTx(tx, uint32(i+1))
+		txs = append(txs, tx)
+	}
+
+	payload := make([]byte, 72)
+	copy(payload[:64], from[:])
+	binary.BigEndian.PutUint32(payload[64:68], 1)
+	binary.BigEndian.PutUint32(payload[68:72], 10)
+
+	entries, err := protocol.DecodeList(_accTxHistoryRes(payload))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("History response could not be decoded: %v, %d entries\n", err, len(entries))
+	}
+
+	//Newest first, the newest one is skipped.
+	for i, entry := range entries {
+		height := binary.BigEndian.Uint32(entry[:4])
+		tx, err := protocol.GetTxType(entry[4]).Decode(entry[5:])
+		if err != nil || height != uint32(2-i) || tx.Hash() != txs[1-i].Hash() {
+			t.Errorf("Entry %d of the history response is wrong: height %d, %v\n", i, height, err)
+		}
+	}
+
+	if _accTxHistoryRes(payload[:64]) != nil {
+		t.Error("Malformed history request was answered.")
+	}
+}
+
+func Test_TxStatusRes(t *testing.T) {
+	privKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	from := crypto.GetAddressFromPubKey(&privKey.PublicKey)
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, from, [64]byte{0x02}, privKey, nil)
+	store.WriteINVALIDOpenTx(tx, protocol.NewTxRejection(protocol.TXREJECT_INSUFFICIENT_FUNDS, "Not enough funds to complete the transaction!"))
+	txHash := tx.Hash()
+
+	var status *protocol.TxStatus
+	status, err := status.Decode(_txStatusRes(txHash[:]))
+	if err != nil || status.TxHash != txHash || status.Status != protocol.TXSTATUS_REJECTED || status.Code != protocol.TXREJECT_INSUFFICIENT_FUNDS {
+		t.Errorf("Wrong status of a rejected tx: %v, %v\n", status, err)
+	}
+
+	store.WriteClosedTx(tx, 7)
+	status, err = status.Decode(_txStatusRes(txHash[:]))
+	if err != nil || status.Status != protocol.TXSTATUS_INCLUDED || status.Height != 7 {
+		t.Errorf("Wrong status of an included tx: %v, %v\n", status, err)
+	}
+
+	if _txStatusRes(txHash[:8]) != nil {
+		t.Error("Malformed status request was answered.")
+	}
+}
+
+func Test_BlockRes(t *testing.T) {
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'r', 1}
+	b.Height = 1
+	store.WriteClosedBlock(b)
+
+	if typeID, data := _blockRes(b.Hash[:]); typeID != BLOCK_RES || data == nil {
+		t.Errorf("Closed block was not returned: %v\n", typeID)
+	}
+
+	store.PruneClosedBlocks(2)
+
+	typeID, data := _blockRes(b.Hash[:])
+	if typeID != BLOCK_PRUNED || len(data) != 40 {
+		t.Fatalf("Request for a pruned block was answered with %v\n", typeID)
+	}
+	if !bytes.Equal(data[:32], b.Hash[:]) || binary.BigEndian.Uint32(data[32:36]) != 1 || binary.BigEndian.Uint32(data[36:40]) != 2 {
+		t.Errorf("Pruned response is wrong: %v\n", data)
+	}
+
+	unknownHash := [32]byte{'u'}
+	if typeID, _ := _blockRes(unknownHash[:]); typeID != NOT_FOUND {
+		t.Errorf("Request for an unknown block was answered with %v\n", typeID)
+	}
+}
@@ -17,6 +17,8 @@ var (
 	//monitor triggers.
 	Ipport string
 	peers  peersStruct
+	//The store of the node, the blocks and txs requested by peers are served from it.
+	store  storage.Store
 
 	iplistChan      = make(chan string, MIN_MINERS)
 	minerBrdcstMsg  = make(chan []byte)
@@ -26,8 +28,9 @@ var (
 )
 
 //Entry point for p2p package
-func Init(ipport string) {
+func Init(ipport string, nodeStore storage.Store) {
 	Ipport = ipport
+	store = nodeStore
 	InitLogging()
 
 	//Initialize peer map
@@ -41,6 +44,7 @@ func Init(ipport string) {
 	go forwardBlockBrdcstToMiner()
 	go forwardStateTransitionShardToMiner()
 	go forwardStateTransitionBrdcstToMiner()
+	go forwardFraudProofBrdcstToMiner()
 	go forwardEpochBlockBrdcstToMiner()
 	go forwardBlockHeaderBrdcstToMiner()
 	go forwardVerifiedTxsToMiner()
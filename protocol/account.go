@@ -1,8 +1,7 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
@@ -17,6 +16,8 @@ type Account struct {
 	StakingBlockHeight uint32                // 4 Byte
 	Contract           []byte                // Arbitrary length
 	ContractVariables  []ByteArray           // Arbitrary length
+	MultiSigKeys       [][64]byte            // Arbitrary length, keys authorizing the fundsTxs of a multi-signature account
+	MultiSigThreshold  uint32                // 4 Byte, number of keys required, 0 for single-signature accounts
 }
 
 func NewAccount(address [64]byte,
@@ -37,6 +38,8 @@ func NewAccount(address [64]byte,
 		0,
 		contract,
 		contractVariables,
+		nil,
+		0,
 	}
 
 	return newAcc
@@ -50,34 +53,111 @@ func (acc *Account) Hash() [32]byte {
 	return SerializeHashContent(acc.Address)
 }
 
+//The hash content of an account in the epoch block state, it is the one accounts had before multi-signature accounts
+//were introduced. The key sets of multi-signature accounts are hashed separately (see stateHashContent).
+type accountHashContent struct {
+	Address            [64]byte
+	Issuer             [64]byte
+	Balance            uint64
+	TxCnt              uint32
+	IsStaking          bool
+	CommitmentKey      [crypto.COMM_KEY_LENGTH]byte
+	StakingBlockHeight uint32
+	Contract           []byte
+	ContractVariables  []ByteArray
+}
+
+type multiSigHashContent struct {
+	MultiSigKeys      [][64]byte
+	MultiSigThreshold uint32
+}
+
+func stateHashContent(state map[[64]byte]*Account) (accounts map[[64]byte]*accountHashContent, multiSigs map[[64]byte]multiSigHashContent) {
+	if state == nil {
+		return nil, nil
+	}
+
+	accounts = make(map[[64]byte]*accountHashContent)
+	multiSigs = make(map[[64]byte]multiSigHashContent)
+	for address, acc := range state {
+		if acc == nil {
+			accounts[address] = nil
+			continue
+		}
+
+		accounts[address] = &accountHashContent{
+			acc.Address,
+			acc.Issuer,
+			acc.Balance,
+			acc.TxCnt,
+			acc.IsStaking,
+			acc.CommitmentKey,
+			acc.StakingBlockHeight,
+			acc.Contract,
+			acc.ContractVariables,
+		}
+		if acc.IsMultiSig() {
+			multiSigs[address] = multiSigHashContent{acc.MultiSigKeys, acc.MultiSigThreshold}
+		}
+	}
+
+	return accounts, multiSigs
+}
+
+//Returns whether fundsTxs spending from this account need to be signed by MultiSigThreshold of the MultiSigKeys.
+func (acc *Account) IsMultiSig() bool {
+	return acc.MultiSigThreshold > 0
+}
+
+//Address | Issuer | Balance | TxCnt | IsStaking | CommitmentKey | StakingBlockHeight | Contract | ContractVariables |
+//MultiSigKeys | MultiSigThreshold
 func (acc *Account) Encode() []byte {
 	if acc == nil {
 		return nil
 	}
 
-	encoded := Account{
-		Address:            acc.Address,
-		Issuer:             acc.Issuer,
-		Balance:            acc.Balance,
-		TxCnt:              acc.TxCnt,
-		IsStaking:          acc.IsStaking,
-		CommitmentKey:   	acc.CommitmentKey,
-		StakingBlockHeight: acc.StakingBlockHeight,
-		Contract:           acc.Contract,
-		ContractVariables:  acc.ContractVariables,
-	}
+	enc := newEncoder()
+	enc.putFixed(acc.Address[:])
+	enc.putFixed(acc.Issuer[:])
+	enc.putUint64(acc.Balance)
+	enc.putUint32(acc.TxCnt)
+	enc.putBool(acc.IsStaking)
+	enc.putFixed(acc.CommitmentKey[:])
+	enc.putUint32(acc.StakingBlockHeight)
+	enc.putBytes(acc.Contract)
+	enc.putByteArrays(acc.ContractVariables)
+	enc.putKeys(acc.MultiSigKeys)
+	enc.putUint32(acc.MultiSigThreshold)
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return enc.bytes()
 }
 
-func (*Account) Decode(encoded []byte) (acc *Account) {
-	var decoded Account
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+func (*Account) Decode(encoded []byte) (*Account, error) {
+	if encoded == nil {
+		return nil, errors.New("Account encoding is empty.")
+	}
+
+	acc := new(Account)
+	dec := newDecoder(encoded, "Account")
+	dec.getFixed(acc.Address[:])
+	dec.getFixed(acc.Issuer[:])
+	acc.Balance = dec.getUint64()
+	acc.TxCnt = dec.getUint32()
+	acc.IsStaking = dec.getBool()
+	dec.getFixed(acc.CommitmentKey[:])
+	acc.StakingBlockHeight = dec.getUint32()
+	acc.Contract = dec.getBytes()
+	acc.ContractVariables = dec.getByteArrays()
+	if dec.version >= 4 {
+		acc.MultiSigKeys = dec.getKeys()
+		acc.MultiSigThreshold = dec.getUint32()
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return acc, nil
 }
 
 func (acc Account) String() string {
@@ -92,7 +172,8 @@ func (acc Account) String() string {
 			"CommitmentKey: %x, " +
 			"StakingBlockHeight: %v, " +
 			"Contract: %v, " +
-			"ContractVariables: %v",
+			"ContractVariables: %v, " +
+			"MultiSig: %v of %v keys",
 		addressHash[0:8],
 		acc.Address[0:8],
 		acc.Issuer[0:8],
@@ -102,5 +183,7 @@ func (acc Account) String() string {
 		acc.CommitmentKey[0:8],
 		acc.StakingBlockHeight,
 		acc.Contract,
-		acc.ContractVariables)
+		acc.ContractVariables,
+		acc.MultiSigThreshold,
+		len(acc.MultiSigKeys))
 }
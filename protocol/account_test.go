@@ -66,7 +66,10 @@ func TestAccountSerialization(t *testing.T) {
 
 	var compareAcc *Account
 	encodedAcc := accA.Encode()
-	compareAcc = compareAcc.Decode(encodedAcc)
+	compareAcc, err := compareAcc.Decode(encodedAcc)
+	if err != nil {
+		t.Fatalf("Account decoding failed: %v\n", err)
+	}
 
 	if !reflect.DeepEqual(accA, compareAcc) {
 		t.Error("Account encoding/decoding failed!")
@@ -1,9 +1,9 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/willf/bloom"
@@ -43,10 +43,8 @@ type Block struct {
 	ConflictingBlockHash2 [32]byte
 	StateCopy             map[[64]byte]*Account //won't be serialized, just keeping track of local state changes
 
-	ContractTxData  [][32]byte
-	FundsTxData  	[][32]byte
-	ConfigTxData 	[][32]byte
-	StakeTxData  	[][32]byte
+	TxData			map[byte][][32]byte //Hashes of the included txs per registered tx type, keyed by its id
+	Receipts		[]*Receipt //Cross-shard FundsTxs of other shards credited in this block
 }
 
 func NewBlock(prevHash [32]byte, height uint32) *Block {
@@ -56,10 +54,23 @@ func NewBlock(prevHash [32]byte, height uint32) *Block {
 	}
 
 	newBlock.StateCopy = make(map[[64]byte]*Account)
+	newBlock.TxData = make(map[byte][][32]byte)
 
 	return &newBlock
 }
 
+//The hashes of the txs of the given type the block includes.
+func (block *Block) TxHashes(txType byte) [][32]byte {
+	return block.TxData[txType]
+}
+
+func (block *Block) AddTxHash(txType byte, txHash [32]byte) {
+	if block.TxData == nil {
+		block.TxData = make(map[byte][][32]byte)
+	}
+	block.TxData[txType] = append(block.TxData[txType], txHash)
+}
+
 func (block *Block) HashBlock() [32]byte {
 	if block == nil {
 		return [32]byte{}
@@ -111,58 +122,88 @@ func (block *Block) GetSize() uint64 {
 			int(block.NrConfigTx)*TXHASH_LEN +
 			int(block.NrStakeTx)*TXHASH_LEN
 
+	//Other tx types have no counter in the header.
+	for _, txType := range block.otherTxTypes() {
+		size += len(block.TxHashes(txType)) * TXHASH_LEN
+	}
+
 	if block.BloomFilter != nil {
 		encodedBF, _ := block.BloomFilter.GobEncode()
 		size += len(encodedBF)
 	}
 
+	for _, receipt := range block.Receipts {
+		size += int(receipt.Size())
+	}
+
 	return uint64(size)
 }
 
+//Header | ShardId | Hash | PrevHash | NrConfigTx | NrElementsBF | BloomFilter | Height | Beneficiary | Nonce |
+//Timestamp | MerkleRoot | MerklePatriciaRoot | NrContractTx | NrFundsTx | NrStakeTx | SlashedAddress |
+//CommitmentProof | ConflictingBlockHash1 | ConflictingBlockHash2 | ContractTxData | FundsTxData | ConfigTxData |
+//StakeTxData | Receipts | OtherTxData
+//The bloom filter is encoded as []byte in the binary format of github.com/willf/bloom, an empty one means no filter.
+//The receipts are prefixed with their number, each of them is a nested object. Version 1 encodings have no receipts.
+//The tx hashes of the built-in tx types keep their fields, the ones of other registered types are encoded as a map
+//from the type id (1 Byte) to the hashes. Encodings before version 8 have no other tx types.
 func (block *Block) Encode() []byte {
 	if block == nil {
 		return nil
 	}
 
-	encoded := Block{
-		Header:                block.Header,
-		ShardId:			   block.ShardId,
-		Hash:                  block.Hash,
-		PrevHash:              block.PrevHash,
-		Nonce:                 block.Nonce,
-		Timestamp:             block.Timestamp,
-		MerkleRoot:            block.MerkleRoot,
-		MerklePatriciaRoot:    block.MerklePatriciaRoot,
-		Beneficiary:           block.Beneficiary,
-		NrContractTx:          block.NrContractTx,
-		NrFundsTx:             block.NrFundsTx,
-		NrConfigTx:            block.NrConfigTx,
-		NrStakeTx:             block.NrStakeTx,
-		NrElementsBF:          block.NrElementsBF,
-		BloomFilter:           block.BloomFilter,
-		SlashedAddress:        block.SlashedAddress,
-		Height:                block.Height,
-		CommitmentProof:       block.CommitmentProof,
-		ConflictingBlockHash1: block.ConflictingBlockHash1,
-		ConflictingBlockHash2: block.ConflictingBlockHash2,
-
-		ContractTxData:        block.ContractTxData,
-		FundsTxData:           block.FundsTxData,
-		ConfigTxData:          block.ConfigTxData,
-		StakeTxData:           block.StakeTxData,
-	}
-
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	var bloomFilter []byte
+	if block.BloomFilter != nil {
+		bloomFilter, _ = block.BloomFilter.GobEncode()
+	}
+
+	enc := newEncoder()
+	enc.putByte(block.Header)
+	enc.putInt64(int64(block.ShardId))
+	enc.putFixed(block.Hash[:])
+	enc.putFixed(block.PrevHash[:])
+	enc.putByte(block.NrConfigTx)
+	enc.putUint16(block.NrElementsBF)
+	enc.putBytes(bloomFilter)
+	enc.putUint32(block.Height)
+	enc.putFixed(block.Beneficiary[:])
+	enc.putFixed(block.Nonce[:])
+	enc.putInt64(block.Timestamp)
+	enc.putFixed(block.MerkleRoot[:])
+	enc.putFixed(block.MerklePatriciaRoot[:])
+	enc.putUint16(block.NrContractTx)
+	enc.putUint16(block.NrFundsTx)
+	enc.putUint16(block.NrStakeTx)
+	enc.putFixed(block.SlashedAddress[:])
+	enc.putFixed(block.CommitmentProof[:])
+	enc.putFixed(block.ConflictingBlockHash1[:])
+	enc.putFixed(block.ConflictingBlockHash2[:])
+	for _, txType := range builtInTxTypes {
+		enc.putHashes(block.TxHashes(txType))
+	}
+
+	enc.putUint32(uint32(len(block.Receipts)))
+	for _, receipt := range block.Receipts {
+		enc.putBytes(receipt.Encode())
+	}
+
+	otherTxTypes := block.otherTxTypes()
+	enc.putUint32(uint32(len(otherTxTypes)))
+	for _, txType := range otherTxTypes {
+		enc.putByte(txType)
+		enc.putHashes(block.TxHashes(txType))
+	}
+
+	return enc.bytes()
 }
 
+//The header is encoded in the same format as the whole block, the body fields are left empty.
 func (block *Block) EncodeHeader() []byte {
 	if block == nil {
 		return nil
 	}
 
-	encoded := Block{
+	header := Block{
 		Header:       block.Header,
 		ShardId:	  block.ShardId,
 		Hash:         block.Hash,
@@ -174,21 +215,72 @@ func (block *Block) EncodeHeader() []byte {
 		Beneficiary:  block.Beneficiary,
 	}
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return header.Encode()
 }
 
-func (block *Block) Decode(encoded []byte) (b *Block) {
+func (*Block) Decode(encoded []byte) (*Block, error) {
 	if encoded == nil {
-		return nil
+		return nil, errors.New("Block encoding is empty.")
 	}
 
-	var decoded Block
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	block := new(Block)
+	dec := newDecoder(encoded, "Block")
+	block.Header = dec.getByte()
+	block.ShardId = int(dec.getInt64())
+	dec.getFixed(block.Hash[:])
+	dec.getFixed(block.PrevHash[:])
+	block.NrConfigTx = dec.getByte()
+	block.NrElementsBF = dec.getUint16()
+	bloomFilter := dec.getBytes()
+	block.Height = dec.getUint32()
+	dec.getFixed(block.Beneficiary[:])
+	dec.getFixed(block.Nonce[:])
+	block.Timestamp = dec.getInt64()
+	dec.getFixed(block.MerkleRoot[:])
+	dec.getFixed(block.MerklePatriciaRoot[:])
+	block.NrContractTx = dec.getUint16()
+	block.NrFundsTx = dec.getUint16()
+	block.NrStakeTx = dec.getUint16()
+	dec.getFixed(block.SlashedAddress[:])
+	dec.getFixed(block.CommitmentProof[:])
+	dec.getFixed(block.ConflictingBlockHash1[:])
+	dec.getFixed(block.ConflictingBlockHash2[:])
+	for _, txType := range builtInTxTypes {
+		block.putTxHashes(txType, dec.getHashes())
+	}
+
+	if dec.version >= 2 {
+		nrReceipts := dec.getLength(4)
+		for i := 0; i < nrReceipts && dec.err == nil; i++ {
+			var receipt *Receipt
+			receipt, err := receipt.Decode(dec.getBytes())
+			if err != nil {
+				return nil, err
+			}
+			block.Receipts = append(block.Receipts, receipt)
+		}
+	}
+
+	if dec.version >= 8 {
+		nrTxTypes := dec.getLength(5)
+		for i := 0; i < nrTxTypes && dec.err == nil; i++ {
+			txType := dec.getByte()
+			block.putTxHashes(txType, dec.getHashes())
+		}
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	if bloomFilter != nil {
+		block.BloomFilter = new(bloom.BloomFilter)
+		if err := block.BloomFilter.GobDecode(bloomFilter); err != nil {
+			return nil, errors.New(fmt.Sprintf("Block encoding contains an invalid bloom filter: %v", err))
+		}
+	}
+
+	return block, nil
 }
 
 func (block Block) String() string {
@@ -204,6 +296,7 @@ func (block Block) String() string {
 		"Amount of contractTx: %v\n"+
 		"Amount of configTx: %v\n"+
 		"Amount of stakeTx: %v\n"+
+		"Amount of receipts: %v\n"+
 		"Height: %d\n"+
 		"Commitment Proof: %x\n"+
 		"Slashed Address:%x\n"+
@@ -221,6 +314,7 @@ func (block Block) String() string {
 		block.NrContractTx,
 		block.NrConfigTx,
 		block.NrStakeTx,
+		len(block.Receipts),
 		block.Height,
 		block.CommitmentProof[0:8],
 		block.SlashedAddress[0:8],
@@ -228,3 +322,33 @@ func (block Block) String() string {
 		block.ConflictingBlockHash2[0:8],
 	)
 }
+
+//The order in which the hashes of the built-in tx types are encoded.
+var builtInTxTypes = []byte{CONTRACTTX_TYPE, FUNDSTX_TYPE, CONFIGTX_TYPE, STAKETX_TYPE}
+
+//The tx types with hashes in the block other than the built-in ones, sorted by id.
+func (block *Block) otherTxTypes() (txTypes []byte) {
+	for txType, txHashes := range block.TxData {
+		if len(txHashes) > 0 && !isBuiltInTxType(txType) {
+			txTypes = append(txTypes, txType)
+		}
+	}
+	sort.Slice(txTypes, func(i, j int) bool { return txTypes[i] < txTypes[j] })
+	return txTypes
+}
+
+func isBuiltInTxType(txType byte) bool {
+	for _, builtIn := range builtInTxTypes {
+		if txType == builtIn {
+			return true
+		}
+	}
+	return false
+}
+
+//Decoded blocks only hold the tx types they include, like the blocks the miner assembles.
+func (block *Block) putTxHashes(txType byte, txHashes [][32]byte) {
+	for _, txHash := range txHashes {
+		block.AddTxHash(txType, txHash)
+	}
+}
@@ -76,11 +76,14 @@ func TestBlockSerialization(t *testing.T) {
 	rand.Read(block.ConflictingBlockHash1[:])
 	rand.Read(block.ConflictingBlockHash2[:])
 
-	var compareBlock Block
+	var compareBlock *Block
 	encodedBlock := block.Encode()
-	compareBlock = *compareBlock.Decode(encodedBlock)
+	compareBlock, err := compareBlock.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Block decoding failed: %v\n", err)
+	}
 
-	if !reflect.DeepEqual(block, compareBlock) {
+	if !reflect.DeepEqual(block, *compareBlock) {
 		t.Error("Block encoding/decoding failed!")
 	}
 }
@@ -106,11 +109,14 @@ func TestBlockHeaderSerialization(t *testing.T) {
 	blockHeader.Height = uint32(randVar.Uint32())
 	rand.Read(blockHeader.Beneficiary[:])
 
-	var compareBlockHeader Block
+	var compareBlockHeader *Block
 	encodedBlock := blockHeader.EncodeHeader()
-	compareBlockHeader = *compareBlockHeader.Decode(encodedBlock)
+	compareBlockHeader, err := compareBlockHeader.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Block header decoding failed: %v\n", err)
+	}
 
-	if !reflect.DeepEqual(blockHeader, compareBlockHeader) {
+	if !reflect.DeepEqual(blockHeader, *compareBlockHeader) {
 		t.Error("Block encoding/decoding failed!")
 	}
 
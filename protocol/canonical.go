@@ -0,0 +1,110 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//The canonical encoding is the input of all hashes (see SerializeHashContent). It only depends on the values, never on
+//memory addresses, map iteration order or the platform, so every node calculates the same hash for the same content:
+//
+//  bool                    1 Byte (0x00 or 0x01)
+//  (u)int8/16/32/64        big endian, 1/2/4/8 Byte
+//  int, uint               big endian, always 8 Byte
+//  string                  length (4 Byte) | bytes
+//  array                   elements in order, no length (the length is part of the type)
+//  slice                   length (4 Byte) | elements in order, nil and empty slices are equal
+//  map                     length (4 Byte) | (key | value) entries, sorted by the encoding of the key
+//  pointer                 0x00 if nil, else 0x01 | pointee
+//  struct                  all fields (exported and unexported) in declaration order
+//
+//All other kinds (e.g. floats, interfaces, channels) are not supported and lead to a panic, since they either have
+//no canonical representation or should never be part of a hash.
+func canonicalEncode(buf *bytes.Buffer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case reflect.Int8:
+		buf.WriteByte(byte(v.Int()))
+	case reflect.Int16:
+		writeUint(buf, uint64(v.Int()), 2)
+	case reflect.Int32:
+		writeUint(buf, uint64(v.Int()), 4)
+	case reflect.Int, reflect.Int64:
+		writeUint(buf, uint64(v.Int()), 8)
+	case reflect.Uint8:
+		buf.WriteByte(byte(v.Uint()))
+	case reflect.Uint16:
+		writeUint(buf, v.Uint(), 2)
+	case reflect.Uint32:
+		writeUint(buf, v.Uint(), 4)
+	case reflect.Uint, reflect.Uint64:
+		writeUint(buf, v.Uint(), 8)
+	case reflect.String:
+		writeUint(buf, uint64(v.Len()), 4)
+		buf.WriteString(v.String())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			canonicalEncode(buf, v.Index(i))
+		}
+	case reflect.Slice:
+		writeUint(buf, uint64(v.Len()), 4)
+		for i := 0; i < v.Len(); i++ {
+			canonicalEncode(buf, v.Index(i))
+		}
+	case reflect.Map:
+		type entry struct {
+			key   []byte
+			value []byte
+		}
+		entries := make([]entry, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			var keyBuf, valueBuf bytes.Buffer
+			canonicalEncode(&keyBuf, key)
+			canonicalEncode(&valueBuf, v.MapIndex(key))
+			entries = append(entries, entry{keyBuf.Bytes(), valueBuf.Bytes()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		writeUint(buf, uint64(len(entries)), 4)
+		for _, e := range entries {
+			buf.Write(e.key)
+			buf.Write(e.value)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(0)
+		} else {
+			buf.WriteByte(1)
+			canonicalEncode(buf, v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			canonicalEncode(buf, v.Field(i))
+		}
+	default:
+		panic(fmt.Sprintf("Type %v has no canonical encoding.", v.Type()))
+	}
+}
+
+func writeUint(buf *bytes.Buffer, value uint64, size int) {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], value)
+	buf.Write(encoded[8-size:])
+}
+
+//Returns the canonical encoding of data, which is the input for hashing.
+func CanonicalEncoding(data interface{}) []byte {
+	var buf bytes.Buffer
+	canonicalEncode(&buf, reflect.ValueOf(data))
+	return buf.Bytes()
+}
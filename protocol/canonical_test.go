@@ -0,0 +1,181 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+//Fixed test data for the golden vectors below. The vectors pin the hashes, changing them breaks consensus with
+//existing nodes. Signatures are not part of the hashes and are therefore left empty.
+func goldenAddress(seed byte) (address [64]byte) {
+	for i := range address {
+		address[i] = seed + byte(i)
+	}
+	return address
+}
+
+func goldenHash(seed byte) (hash [32]byte) {
+	for i := range hash {
+		hash[i] = seed ^ byte(i)
+	}
+	return hash
+}
+
+func goldenCommitment(seed byte) (commitment [256]byte) {
+	for i := range commitment {
+		commitment[i] = seed * byte(i)
+	}
+	return commitment
+}
+
+func goldenAccount(seed byte) *Account {
+	acc := NewAccount(goldenAddress(seed), goldenAddress(seed+1), 1000*uint64(seed), true, goldenCommitment(seed), []byte{seed, 0x02}, []ByteArray{{seed}})
+	acc.TxCnt = uint32(seed)
+	acc.StakingBlockHeight = 7
+	return &acc
+}
+
+func goldenBlock() *Block {
+	return &Block{
+		Header:                0x01,
+		ShardId:               2,
+		PrevHash:              goldenHash(1),
+		Height:                42,
+		Beneficiary:           goldenAddress(3),
+		Timestamp:             1546300800,
+		MerkleRoot:            goldenHash(4),
+		MerklePatriciaRoot:    goldenHash(5),
+		CommitmentProof:       goldenCommitment(6),
+		SlashedAddress:        goldenAddress(7),
+		ConflictingBlockHash1: goldenHash(8),
+		ConflictingBlockHash2: goldenHash(9),
+		TxData:                map[byte][][32]byte{FUNDSTX_TYPE: [][32]byte{goldenHash(10)}},
+	}
+}
+
+func goldenEpochBlock() *EpochBlock {
+	epochBlock := NewEpochBlock([][32]byte{goldenHash(1), goldenHash(2)}, 100)
+	epochBlock.Timestamp = 1546300800
+	epochBlock.MerkleRoot = goldenHash(3)
+	epochBlock.MerklePatriciaRoot = goldenHash(4)
+	epochBlock.CommitmentProof = goldenCommitment(5)
+	epochBlock.State = map[[64]byte]*Account{
+		goldenAddress(6): goldenAccount(6),
+		goldenAddress(7): goldenAccount(7),
+		goldenAddress(8): goldenAccount(8),
+	}
+	epochBlock.ValMapping = NewMapping()
+	epochBlock.ValMapping.ValMapping[goldenAddress(6)] = 1
+	epochBlock.ValMapping.ValMapping[goldenAddress(7)] = 2
+	epochBlock.ValMapping.EpochHeight = 100
+	epochBlock.NofShards = 2
+	return epochBlock
+}
+
+func goldenStateTransition() *StateTransition {
+	relativeAcc := NewRelativeAccount(goldenAddress(1), goldenAddress(2), -300, false, goldenCommitment(3), nil, nil)
+	st := NewStateTransition(map[[64]byte]*RelativeAccount{goldenAddress(1): &relativeAcc}, 42, 2, goldenHash(4),
+		nil, [][32]byte{goldenHash(5)}, nil, nil)
+	st.Validator = goldenAddress(6)
+	return st
+}
+
+func TestGoldenHashes(t *testing.T) {
+	genesis := NewGenesis(goldenAddress(1), goldenCommitment(2))
+
+	testCases := []struct {
+		name     string
+		hash     [32]byte
+		expected string
+	}{
+		{"Block", goldenBlock().HashBlock(),
+			"efb3eca48e8f77bcb7743bdf950d9e84b623d2e4d400157bedf3f520bd38af6f"},
+		{"EpochBlock", goldenEpochBlock().HashEpochBlock(),
+			"e41c704c8eb3ff1731eb58807f8ff5b8715e96d9987c776965ba7c514334e271"},
+		{"FundsTx", (&FundsTx{Header: 0x01, Amount: 100, Fee: 2, TxCnt: 3, From: goldenAddress(1), To: goldenAddress(2), Data: []byte{0x04}}).Hash(),
+			"afbbc155b5d585b99ea51ac092da1aca8452c0fe815d041a8670d7b38ebc6fa6"},
+		{"ContractTx", (&ContractTx{Header: 0x01, Issuer: goldenAddress(1), Fee: 2, PubKey: goldenAddress(3), Contract: []byte{0x04}, ContractVariables: []ByteArray{{0x05}}}).Hash(),
+			"be1729871e14ccb5ba8f7d8272a109aac6f0e2c1af4a90691ba40338abe5ae55"},
+		{"ConfigTx", (&ConfigTx{Header: 0x01, Id: 2, Payload: 3, Fee: 4, TxCnt: 5}).Hash(),
+			"3c9adc603b00c78e54ef0ea9a3bc4264430851f16e11a94ba7182ac4df2d0f8c"},
+		{"StakeTx", (&StakeTx{Header: 0x01, Fee: 2, IsStaking: true, Account: goldenAddress(3), CommitmentKey: goldenCommitment(4)}).Hash(),
+			"c94e9e1f055b71b2ea3e236bcab1dc83ac0f91ebb6c2ed29e961eaf9de261e79"},
+		{"StateTransition", goldenStateTransition().HashTransition(),
+			"e0957abdd2f7aa55b3a17f1bfd77e0f507a8b5f1caa63654ffa0853a52e6d1cb"},
+		{"Genesis", genesis.Hash(),
+			"26dde6a7e3b5919293ad61be7603c035cf070c8c650e531a6ff7370d4d7f8d8d"},
+	}
+
+	for _, testCase := range testCases {
+		if fmt.Sprintf("%x", testCase.hash) != testCase.expected {
+			t.Errorf("%v hash does not match the golden vector: %x vs. %v\n", testCase.name, testCase.hash, testCase.expected)
+		}
+	}
+}
+
+func TestExtendHash(t *testing.T) {
+	hash := goldenHash(1)
+	if extendHash(hash, false, uint32(2)) != hash {
+		t.Error("Hash without the new fields has changed.")
+	}
+
+	extendedHash := struct {
+		Hash       [32]byte
+		ValidFrom  uint32
+		ValidUntil uint32
+	}{hash, 2, 3}
+	if extendHash(hash, true, uint32(2), uint32(3)) != SerializeHashContent(extendedHash) {
+		t.Error("Extended hash does not equal the hash of the original hash followed by the new fields.")
+	}
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	//The encoding of a map must not depend on the insertion (and therefore iteration) order.
+	mapA, mapB := make(map[[64]byte]int), make(map[[64]byte]int)
+	for i := 0; i < 100; i++ {
+		mapA[goldenAddress(byte(i))] = i
+		mapB[goldenAddress(byte(99-i))] = 99 - i
+	}
+	if !bytes.Equal(CanonicalEncoding(mapA), CanonicalEncoding(mapB)) {
+		t.Error("Canonical encoding of equal maps differs.")
+	}
+
+	//Pointers are encoded by their content, not by their address.
+	accA, accB := goldenAccount(1), goldenAccount(1)
+	if !bytes.Equal(CanonicalEncoding(accA), CanonicalEncoding(accB)) {
+		t.Error("Canonical encoding of equal pointees differs.")
+	}
+
+	//Nil and empty slices are equal, a missing pointer is not.
+	if !bytes.Equal(CanonicalEncoding([]byte(nil)), CanonicalEncoding([]byte{})) {
+		t.Error("Canonical encoding of nil and empty slices differs.")
+	}
+	if bytes.Equal(CanonicalEncoding((*Account)(nil)), CanonicalEncoding(&Account{})) {
+		t.Error("Canonical encoding of a nil pointer equals the encoding of a zero value.")
+	}
+
+	expected := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xff, 0xfe, 0x01}
+	encoded := CanonicalEncoding(struct {
+		a int
+		b int16
+		c bool
+	}{5, -2, true})
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("Canonical encoding of primitives is wrong: %x vs. %x\n", encoded, expected)
+	}
+}
+
+func TestEpochBlockHashMultiSig(t *testing.T) {
+	epochBlock := goldenEpochBlock()
+	singleSigHash := epochBlock.HashEpochBlock()
+
+	epochBlock.State[goldenAddress(6)].MultiSigKeys = [][64]byte{goldenAddress(1), goldenAddress(2)}
+	epochBlock.State[goldenAddress(6)].MultiSigThreshold = 2
+	multiSigHash := epochBlock.HashEpochBlock()
+
+	epochBlock.State[goldenAddress(6)].MultiSigThreshold = 1
+	if multiSigHash == singleSigHash || epochBlock.HashEpochBlock() == multiSigHash {
+		t.Error("Key sets of multi-signature accounts are not part of the EpochBlock hash.")
+	}
+}
@@ -1,15 +1,13 @@
 package protocol
 
 import (
-	"bytes"
-	"crypto/ecdsa"
-	"crypto/rand"
-	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
 
 const (
-	CONFIGTX_SIZE = 83
+	CONFIGTX_SIZE = 92
 
 	BLOCK_SIZE_ID           = 1
 	DIFF_INTERVAL_ID        = 2
@@ -62,9 +60,11 @@ type ConfigTx struct {
 	Fee     uint64
 	TxCnt   uint8
 	Sig     [64]byte
+	ValidFrom  uint32
+	ValidUntil uint32
 }
 
-func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uint8, rootPrivKey *ecdsa.PrivateKey) (tx *ConfigTx, err error) {
+func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uint8, rootPrivKey crypto.PrivateKey) (tx *ConfigTx, err error) {
 
 	tx = new(ConfigTx)
 	tx.Header = header
@@ -75,15 +75,10 @@ func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uin
 
 	txHash := tx.Hash()
 
-	r, s, err := ecdsa.Sign(rand.Reader, rootPrivKey, txHash[:])
-
-	if err != nil {
+	if tx.Sig, err = signTxHash(txHash, rootPrivKey); err != nil {
 		return nil, err
 	}
 
-	copy(tx.Sig[32-len(r.Bytes()):32], r.Bytes())
-	copy(tx.Sig[64-len(s.Bytes()):], s.Bytes())
-
 	return tx, nil
 }
 
@@ -106,56 +101,65 @@ func (tx *ConfigTx) Hash() (hash [32]byte) {
 		tx.Fee,
 		tx.TxCnt,
 	}
-	return SerializeHashContent(txHash)
+	return hashWithValidity(SerializeHashContent(txHash), tx.ValidFrom, tx.ValidUntil)
 }
 
-func (tx *ConfigTx) Encode() (encodedTx []byte) {
+//Restricts the block heights the tx can be included at and signs it again.
+func (tx *ConfigTx) SetValidity(validFrom uint32, validUntil uint32, rootPrivKey crypto.PrivateKey) (err error) {
+	tx.ValidFrom, tx.ValidUntil = validFrom, validUntil
+	tx.Sig, err = signTxHash(tx.Hash(), rootPrivKey)
+	return err
+}
 
+//Header | Id | Payload | Fee | TxCnt | Sig | ValidFrom | ValidUntil
+func (tx *ConfigTx) Encode() (encodedTx []byte) {
 	if tx == nil {
 		return nil
 	}
 
-	var buf bytes.Buffer
-	var payloadBuf [8]byte
-	var feeBuf [8]byte
-
-	binary.Write(&buf, binary.BigEndian, tx.Payload)
-	copy(payloadBuf[:], buf.Bytes())
-	buf.Reset()
-	binary.Write(&buf, binary.BigEndian, tx.Fee)
-	copy(feeBuf[:], buf.Bytes())
-	buf.Reset()
-
-	encodedTx = make([]byte, CONFIGTX_SIZE)
-	encodedTx[0] = tx.Header
-	encodedTx[1] = tx.Id
-	copy(encodedTx[2:10], payloadBuf[:])
-	copy(encodedTx[10:18], feeBuf[:])
-	encodedTx[18] = byte(tx.TxCnt)
-	copy(encodedTx[19:83], tx.Sig[:])
-
-	return encodedTx
+	enc := newEncoder()
+	enc.putByte(tx.Header)
+	enc.putByte(tx.Id)
+	enc.putUint64(tx.Payload)
+	enc.putUint64(tx.Fee)
+	enc.putByte(tx.TxCnt)
+	enc.putFixed(tx.Sig[:])
+	enc.putUint32(tx.ValidFrom)
+	enc.putUint32(tx.ValidUntil)
+
+	return enc.bytes()
 }
 
-func (*ConfigTx) Decode(encodedTx []byte) (tx *ConfigTx) {
+func (*ConfigTx) Decode(encodedTx []byte) (*ConfigTx, error) {
+	//Encodings before version 3 have no validity window.
+	if len(encodedTx) != CONFIGTX_SIZE && len(encodedTx) != CONFIGTX_SIZE-VALIDITY_SIZE {
+		return nil, errors.New(fmt.Sprintf("ConfigTx encoding has size %d, expected %d.", len(encodedTx), CONFIGTX_SIZE))
+	}
+
+	tx := new(ConfigTx)
+	dec := newDecoder(encodedTx, "ConfigTx")
+	tx.Header = dec.getByte()
+	tx.Id = dec.getByte()
+	tx.Payload = dec.getUint64()
+	tx.Fee = dec.getUint64()
+	tx.TxCnt = dec.getByte()
+	dec.getFixed(tx.Sig[:])
+	if dec.version >= 3 {
+		tx.ValidFrom = dec.getUint32()
+		tx.ValidUntil = dec.getUint32()
+	}
 
-	if len(encodedTx) != CONFIGTX_SIZE {
-		return nil
+	if err := dec.finish(); err != nil {
+		return nil, err
 	}
 
-	tx = new(ConfigTx)
-	tx.Header = encodedTx[0]
-	tx.Id = encodedTx[1]
-	tx.Payload = binary.BigEndian.Uint64(encodedTx[2:10])
-	tx.Fee = binary.BigEndian.Uint64(encodedTx[10:18])
-	tx.TxCnt = uint8(encodedTx[18])
-	copy(tx.Sig[:], encodedTx[19:83])
-
-	return tx
+	return tx, nil
 }
 
 func (tx *ConfigTx) TxFee() uint64 { return tx.Fee }
 func (tx *ConfigTx) Size() uint64  { return CONFIGTX_SIZE }
+func (tx *ConfigTx) Validity() (uint32, uint32) { return tx.ValidFrom, tx.ValidUntil }
+func (tx *ConfigTx) Type() byte { return CONFIGTX_TYPE }
 
 func (tx ConfigTx) String() string {
 	return fmt.Sprintf(
@@ -163,10 +167,13 @@ func (tx ConfigTx) String() string {
 			"Id: %v\n"+
 			"Payload: %v\n"+
 			"Fee: %v\n"+
-			"TxCnt: %v\n",
+			"TxCnt: %v\n"+
+			"Valid: %v - %v\n",
 		tx.Id,
 		tx.Payload,
 		tx.Fee,
 		tx.TxCnt,
+		tx.ValidFrom,
+		tx.ValidUntil,
 	)
 }
@@ -15,8 +15,8 @@ func TestConfigTxSerialization(t *testing.T) {
 		tx, err := ConstrConfigTx(uint8(rand.Uint32()%256), uint8(rand.Uint32()%256), rand.Uint64(), rand.Uint64(), uint8(i), RootPrivKey)
 		data := tx.Encode()
 		var decodedTx *ConfigTx
-		decodedTx = decodedTx.Decode(data)
-		if !reflect.DeepEqual(tx, decodedTx) || err != nil {
+		decodedTx, decodeErr := decodedTx.Decode(data)
+		if !reflect.DeepEqual(tx, decodedTx) || err != nil || decodeErr != nil {
 			t.Errorf("ConfigTx Serialization failed (%v) vs. (%v)\n", tx, decodedTx)
 		}
 	}
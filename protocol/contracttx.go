@@ -1,16 +1,16 @@
 package protocol
 
 import (
-	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"encoding/gob"
+	"errors"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
 
 const (
-	CONTRACTTX_SIZE = 201
+	CONTRACTTX_SIZE = 226 //Without Contract, ContractVariables and MultiSigKeys
 )
 
 type ContractTx struct {
@@ -21,9 +21,13 @@ type ContractTx struct {
 	Sig               [64]byte
 	Contract          []byte
 	ContractVariables []ByteArray
+	ValidFrom         uint32
+	ValidUntil        uint32
+	MultiSigKeys      [][64]byte
+	MultiSigThreshold uint32
 }
 
-func ConstrContractTx(header byte, fee uint64, issuerSigKey *ecdsa.PrivateKey, contract []byte, contractVariables []ByteArray) (tx *ContractTx, newContractKey *ecdsa.PrivateKey, err error) {
+func ConstrContractTx(header byte, fee uint64, issuerSigKey crypto.PrivateKey, contract []byte, contractVariables []ByteArray) (tx *ContractTx, newContractKey *ecdsa.PrivateKey, err error) {
 	tx = new(ContractTx)
 	tx.Header = header
 	tx.Fee = fee
@@ -35,22 +39,16 @@ func ConstrContractTx(header byte, fee uint64, issuerSigKey *ecdsa.PrivateKey, c
 	copy(tx.PubKey[32-len(newAccPub1):32], newAccPub1)
 	copy(tx.PubKey[64-len(newAccPub2):], newAccPub2)
 
-	var issuerPublicKey [64]byte
-	issuerPubKey1, issuerPubKey2 := issuerSigKey.PublicKey.X.Bytes(), issuerSigKey.PublicKey.Y.Bytes()
-	copy(issuerPublicKey[32-len(issuerPubKey1):32], issuerPubKey1)
-	copy(issuerPublicKey[64-len(issuerPubKey2):], issuerPubKey2)
-	copy(tx.Issuer[:], issuerPublicKey[:])
+	if tx.Issuer, err = crypto.GetAddressFromPrivKey(issuerSigKey); err != nil {
+		return nil, nil, err
+	}
 
 	txHash := tx.Hash()
 
-	r, s, err := ecdsa.Sign(rand.Reader, issuerSigKey, txHash[:])
-	if err != nil {
+	if tx.Sig, err = signTxHash(txHash, issuerSigKey); err != nil {
 		return nil, nil, err
 	}
 
-	copy(tx.Sig[32-len(r.Bytes()):32], r.Bytes())
-	copy(tx.Sig[64-len(s.Bytes()):], s.Bytes())
-
 	return tx, newContractKey, nil
 }
 
@@ -75,38 +73,92 @@ func (tx *ContractTx) Hash() [32]byte {
 		tx.ContractVariables,
 	}
 
-	return SerializeHashContent(txHash)
+	//The multi-signature keys extend the hash (see extendHash).
+	contractHash := extendHash(SerializeHashContent(txHash), tx.MultiSigThreshold != 0 || len(tx.MultiSigKeys) != 0,
+		tx.MultiSigKeys, tx.MultiSigThreshold)
+
+	return hashWithValidity(contractHash, tx.ValidFrom, tx.ValidUntil)
 }
 
+//Restricts the block heights the tx can be included at and signs it again.
+func (tx *ContractTx) SetValidity(validFrom uint32, validUntil uint32, issuerSigKey crypto.PrivateKey) (err error) {
+	tx.ValidFrom, tx.ValidUntil = validFrom, validUntil
+	tx.Sig, err = signTxHash(tx.Hash(), issuerSigKey)
+	return err
+}
+
+//Makes the new account a multi-signature account, whose fundsTxs need to be signed by threshold of the keys, and signs
+//the tx again.
+func (tx *ContractTx) SetMultiSig(keys [][64]byte, threshold uint32, issuerSigKey crypto.PrivateKey) (err error) {
+	tx.MultiSigKeys, tx.MultiSigThreshold = keys, threshold
+	tx.Sig, err = signTxHash(tx.Hash(), issuerSigKey)
+	return err
+}
+
+//Header | Issuer | Fee | PubKey | Sig | Contract | ContractVariables | ValidFrom | ValidUntil | MultiSigKeys |
+//MultiSigThreshold
 func (tx *ContractTx) Encode() []byte {
 	if tx == nil {
 		return nil
 	}
 
-	encoded := ContractTx{
-		Header: tx.Header,
-		Issuer: tx.Issuer,
-		Fee:    tx.Fee,
-		PubKey: tx.PubKey,
-		Sig:    tx.Sig,
+	enc := newEncoder()
+	enc.putByte(tx.Header)
+	enc.putFixed(tx.Issuer[:])
+	enc.putUint64(tx.Fee)
+	enc.putFixed(tx.PubKey[:])
+	enc.putFixed(tx.Sig[:])
+	enc.putBytes(tx.Contract)
+	enc.putByteArrays(tx.ContractVariables)
+	enc.putUint32(tx.ValidFrom)
+	enc.putUint32(tx.ValidUntil)
+	enc.putKeys(tx.MultiSigKeys)
+	enc.putUint32(tx.MultiSigThreshold)
+
+	return enc.bytes()
+}
+
+func (*ContractTx) Decode(encoded []byte) (*ContractTx, error) {
+	if encoded == nil {
+		return nil, errors.New("ContractTx encoding is empty.")
 	}
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
-}
+	tx := new(ContractTx)
+	dec := newDecoder(encoded, "ContractTx")
+	tx.Header = dec.getByte()
+	dec.getFixed(tx.Issuer[:])
+	tx.Fee = dec.getUint64()
+	dec.getFixed(tx.PubKey[:])
+	dec.getFixed(tx.Sig[:])
+	tx.Contract = dec.getBytes()
+	tx.ContractVariables = dec.getByteArrays()
+	if dec.version >= 3 {
+		tx.ValidFrom = dec.getUint32()
+		tx.ValidUntil = dec.getUint32()
+	}
+	if dec.version >= 4 {
+		tx.MultiSigKeys = dec.getKeys()
+		tx.MultiSigThreshold = dec.getUint32()
+	}
 
-func (*ContractTx) Decode(encoded []byte) (tx *ContractTx) {
-	var decoded ContractTx
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
 }
 
 func (tx *ContractTx) TxFee() uint64 { return tx.Fee }
+func (tx *ContractTx) Validity() (uint32, uint32) { return tx.ValidFrom, tx.ValidUntil }
+func (tx *ContractTx) Type() byte { return CONTRACTTX_TYPE }
 
-func (tx *ContractTx) Size() uint64 { return CONTRACTTX_SIZE }
+func (tx *ContractTx) Size() uint64 {
+	size := CONTRACTTX_SIZE + uint64(len(tx.Contract)) + uint64(len(tx.MultiSigKeys))*64
+	for _, variable := range tx.ContractVariables {
+		size += 4 + uint64(len(variable))
+	}
+	return size
+}
 
 func (tx ContractTx) String() string {
 	return fmt.Sprintf(
@@ -117,7 +169,9 @@ func (tx ContractTx) String() string {
 			"PubKey: %x\n"+
 			"Sig: %x\n"+
 			"Contract: %v\n"+
-			"ContractVariables: %v\n",
+			"ContractVariables: %v\n"+
+			"Valid: %v - %v\n"+
+			"MultiSig: %v of %v keys\n",
 		tx.Header,
 		tx.Issuer[0:8],
 		tx.Fee,
@@ -125,5 +179,9 @@ func (tx ContractTx) String() string {
 		tx.Sig[0:8],
 		tx.Contract[:],
 		tx.ContractVariables[:],
+		tx.ValidFrom,
+		tx.ValidUntil,
+		tx.MultiSigThreshold,
+		len(tx.MultiSigKeys),
 	)
 }
@@ -61,20 +61,20 @@ func TestContractTxSerialization(t *testing.T) {
 
 	var decodedTx *ContractTx
 	encodedTx := tx.Encode()
-	decodedTx = decodedTx.Decode(encodedTx)
+	decodedTx, err := decodedTx.Decode(encodedTx)
 
-	if !reflect.DeepEqual(tx, decodedTx) {
+	if !reflect.DeepEqual(tx, decodedTx) || err != nil {
 		t.Errorf("ContractTx serialization failed: %v vs. %v\n", tx, decodedTx)
 	}
 
 	header = byte(1)
 	fee = uint64(2)
-	tx, _, _ = ConstrContractTx(header, fee, RootPrivKey, nil, nil)
+	tx, _, _ = ConstrContractTx(header, fee, RootPrivKey, []byte{0x01, 0x02}, []ByteArray{{0x03}, {0x04, 0x05}})
 
 	encodedTx = tx.Encode()
-	decodedTx = decodedTx.Decode(encodedTx)
+	decodedTx, err = decodedTx.Decode(encodedTx)
 
-	if !reflect.DeepEqual(tx, decodedTx) {
+	if !reflect.DeepEqual(tx, decodedTx) || err != nil {
 		t.Errorf("ContractTx serialization failed: %v vs. %v\n", tx, decodedTx)
 	}
 }
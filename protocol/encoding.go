@@ -0,0 +1,286 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+//Wire format of all blocks, transactions and the data structures exchanged between miners. The same encoding is used
+//for the p2p messages and for storage, which allows clients written in any language to talk to the miner.
+//
+//Every encoded object starts with a version byte, followed by its fields in the order documented at the respective
+//Encode() function. The fields are encoded as follows:
+//
+//  byte, bool              1 Byte (bool is 0x00 or 0x01)
+//  uint16/32/64            big endian, 2/4/8 Byte
+//  int, int64              big endian two's complement, 8 Byte
+//  [n]byte                 n Byte, no length
+//  []byte                  length (4 Byte) | bytes
+//  [][32]byte              number of hashes (4 Byte) | hashes (32 Byte each)
+//  [][64]byte              number of keys or signatures (4 Byte) | entries (64 Byte each)
+//  []ByteArray             number of entries (4 Byte) | entries encoded as []byte
+//  nested objects          length (4 Byte) | encoding of the object (including its own version byte)
+//  maps                    number of entries (4 Byte) | entries sorted by key
+//
+//Decoders are strict: unknown versions, truncated payloads and trailing bytes are rejected with an error.
+//
+//Versions:
+//  1   initial format
+//  2   blocks carry the receipts of cross-shard FundsTxs
+//  3   all transactions carry a validity window (ValidFrom | ValidUntil)
+//  4   multi-signature accounts, contractTxs creating them and fundsTxs spending from them
+//  5   batch fundsTxs with several outputs
+//  6   state transitions carry the validator and its commitment signature
+//  7   state transitions and epoch blocks carry the roots of the shard states
+//  8   blocks carry the tx hashes of registered tx types other than the built-in ones
+const (
+	ENCODING_VERSION = 8
+)
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func newEncoder() *encoder {
+	enc := new(encoder)
+	enc.buf.WriteByte(ENCODING_VERSION)
+	return enc
+}
+
+func (enc *encoder) putByte(value byte) {
+	enc.buf.WriteByte(value)
+}
+
+func (enc *encoder) putBool(value bool) {
+	if value {
+		enc.buf.WriteByte(1)
+	} else {
+		enc.buf.WriteByte(0)
+	}
+}
+
+func (enc *encoder) putUint16(value uint16) {
+	var encoded [2]byte
+	binary.BigEndian.PutUint16(encoded[:], value)
+	enc.buf.Write(encoded[:])
+}
+
+func (enc *encoder) putUint32(value uint32) {
+	var encoded [4]byte
+	binary.BigEndian.PutUint32(encoded[:], value)
+	enc.buf.Write(encoded[:])
+}
+
+func (enc *encoder) putUint64(value uint64) {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], value)
+	enc.buf.Write(encoded[:])
+}
+
+func (enc *encoder) putInt64(value int64) {
+	enc.putUint64(uint64(value))
+}
+
+func (enc *encoder) putFixed(value []byte) {
+	enc.buf.Write(value)
+}
+
+func (enc *encoder) putBytes(value []byte) {
+	enc.putUint32(uint32(len(value)))
+	enc.buf.Write(value)
+}
+
+func (enc *encoder) putHashes(hashes [][32]byte) {
+	enc.putUint32(uint32(len(hashes)))
+	for _, hash := range hashes {
+		enc.buf.Write(hash[:])
+	}
+}
+
+func (enc *encoder) putKeys(keys [][64]byte) {
+	enc.putUint32(uint32(len(keys)))
+	for _, key := range keys {
+		enc.buf.Write(key[:])
+	}
+}
+
+func (enc *encoder) putByteArrays(arrays []ByteArray) {
+	enc.putUint32(uint32(len(arrays)))
+	for _, array := range arrays {
+		enc.putBytes(array)
+	}
+}
+
+func (enc *encoder) bytes() []byte {
+	return enc.buf.Bytes()
+}
+
+//The decoder keeps the first error that occurred, all subsequent reads return zero values. This way the decoding
+//functions can read all fields and check the error once at the end (see finish()).
+type decoder struct {
+	data    []byte
+	index   int
+	version byte
+	name    string
+	err     error
+}
+
+func newDecoder(encoded []byte, name string) *decoder {
+	dec := &decoder{data: encoded, name: name}
+	dec.version = dec.getByte()
+	if dec.err == nil && (dec.version == 0 || dec.version > ENCODING_VERSION) {
+		dec.err = errors.New(fmt.Sprintf("%v encoding version %d is not supported.", name, dec.version))
+	}
+
+	return dec
+}
+
+func (dec *decoder) read(length int) []byte {
+	if dec.err != nil {
+		return nil
+	}
+
+	if length < 0 || len(dec.data)-dec.index < length {
+		dec.err = errors.New(fmt.Sprintf("%v encoding is truncated.", dec.name))
+		return nil
+	}
+
+	value := dec.data[dec.index : dec.index+length]
+	dec.index += length
+	return value
+}
+
+//Checks that a length read from the payload is satisfiable, such that a manipulated length cannot trigger huge
+//allocations.
+func (dec *decoder) getLength(elementSize int) int {
+	length := int(dec.getUint32())
+	if dec.err == nil && length*elementSize > len(dec.data)-dec.index {
+		dec.err = errors.New(fmt.Sprintf("%v encoding is truncated.", dec.name))
+		return 0
+	}
+
+	return length
+}
+
+func (dec *decoder) getByte() byte {
+	if value := dec.read(1); value != nil {
+		return value[0]
+	}
+	return 0
+}
+
+func (dec *decoder) getBool() bool {
+	value := dec.getByte()
+	if value > 1 && dec.err == nil {
+		dec.err = errors.New(fmt.Sprintf("%v encoding contains an invalid bool.", dec.name))
+	}
+	return value == 1
+}
+
+func (dec *decoder) getUint16() uint16 {
+	if value := dec.read(2); value != nil {
+		return binary.BigEndian.Uint16(value)
+	}
+	return 0
+}
+
+func (dec *decoder) getUint32() uint32 {
+	if value := dec.read(4); value != nil {
+		return binary.BigEndian.Uint32(value)
+	}
+	return 0
+}
+
+func (dec *decoder) getUint64() uint64 {
+	if value := dec.read(8); value != nil {
+		return binary.BigEndian.Uint64(value)
+	}
+	return 0
+}
+
+func (dec *decoder) getInt64() int64 {
+	return int64(dec.getUint64())
+}
+
+func (dec *decoder) getFixed(dst []byte) {
+	copy(dst, dec.read(len(dst)))
+}
+
+//Empty byte slices are decoded as nil.
+func (dec *decoder) getBytes() []byte {
+	length := dec.getLength(1)
+	if value := dec.read(length); len(value) > 0 {
+		decoded := make([]byte, length)
+		copy(decoded, value)
+		return decoded
+	}
+	return nil
+}
+
+func (dec *decoder) getHashes() (hashes [][32]byte) {
+	length := dec.getLength(32)
+	for i := 0; i < length && dec.err == nil; i++ {
+		var hash [32]byte
+		dec.getFixed(hash[:])
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func (dec *decoder) getKeys() (keys [][64]byte) {
+	length := dec.getLength(64)
+	for i := 0; i < length && dec.err == nil; i++ {
+		var key [64]byte
+		dec.getFixed(key[:])
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (dec *decoder) getByteArrays() (arrays []ByteArray) {
+	length := dec.getLength(4)
+	for i := 0; i < length && dec.err == nil; i++ {
+		arrays = append(arrays, dec.getBytes())
+	}
+	return arrays
+}
+
+//Returns the first error during decoding or an error if there are bytes left in the payload.
+func (dec *decoder) finish() error {
+	if dec.err == nil && dec.index != len(dec.data) {
+		dec.err = errors.New(fmt.Sprintf("%v encoding has %d trailing bytes.", dec.name, len(dec.data)-dec.index))
+	}
+	return dec.err
+}
+
+//Maps keyed by address are encoded in the order of their addresses.
+func sortAddresses(addresses [][64]byte) {
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+}
+
+//Encodes a list of variable-sized items (e.g., encoded transactions): number of items (4 Byte) | items encoded as []byte
+func EncodeList(items [][]byte) []byte {
+	enc := newEncoder()
+	enc.putUint32(uint32(len(items)))
+	for _, item := range items {
+		enc.putBytes(item)
+	}
+	return enc.bytes()
+}
+
+func DecodeList(encoded []byte) (items [][]byte, err error) {
+	dec := newDecoder(encoded, "List")
+	length := dec.getLength(4)
+	for i := 0; i < length && dec.err == nil; i++ {
+		items = append(items, dec.getBytes())
+	}
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,198 @@
+package protocol
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRejectsTruncatedEncoding(t *testing.T) {
+	tx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, []byte{0x01, 0x02, 0x03})
+	encodedTx := tx.Encode()
+
+	var decodedTx *FundsTx
+	for i := 0; i < len(encodedTx); i++ {
+		if _, err := decodedTx.Decode(encodedTx[:i]); err == nil {
+			t.Errorf("FundsTx truncated to %d bytes has been decoded.\n", i)
+		}
+	}
+
+	block := NewBlock([32]byte{'0', '1'}, 10)
+	block.TxData[FUNDSTX_TYPE] = [][32]byte{tx.Hash()}
+	encodedBlock := block.Encode()
+
+	var decodedBlock *Block
+	if _, err := decodedBlock.Decode(encodedBlock[:len(encodedBlock)-1]); err == nil {
+		t.Error("Truncated block has been decoded.")
+	}
+}
+
+func TestDecodeRejectsTrailingBytes(t *testing.T) {
+	encodedAcc := accA.Encode()
+	encodedAcc = append(encodedAcc, 0x00)
+
+	var decodedAcc *Account
+	if _, err := decodedAcc.Decode(encodedAcc); err == nil {
+		t.Error("Account with trailing bytes has been decoded.")
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	genesis := NewGenesis(accA.Address, accA.CommitmentKey)
+	encodedGenesis := genesis.Encode()
+
+	var decodedGenesis *Genesis
+	for _, version := range []byte{0, ENCODING_VERSION + 1} {
+		encodedGenesis[0] = version
+		if _, err := decodedGenesis.Decode(encodedGenesis); err == nil {
+			t.Errorf("Genesis with encoding version %d has been decoded.\n", version)
+		}
+	}
+}
+
+func TestDecodeVersion5StateTransition(t *testing.T) {
+	relativeAcc := NewRelativeAccount(accA.Address, [64]byte{}, 100, false, accA.CommitmentKey, nil, nil)
+	st := NewStateTransition(map[[64]byte]*RelativeAccount{accA.Address: &relativeAcc}, 10, 2, [32]byte{'1'}, nil, [][32]byte{{'2'}}, nil, nil)
+	st.Validator = accA.Address
+	st.CommitmentSig[0] = 0x01
+
+	encodedSt := st.EncodeTransition()
+	var decodedSt *StateTransition
+	decodedSt, err := decodedSt.DecodeTransition(encodedSt)
+	if err != nil || decodedSt.Validator != st.Validator || decodedSt.CommitmentSig != st.CommitmentSig {
+		t.Fatalf("Version %d state transition decoding failed: %v\n", ENCODING_VERSION, err)
+	}
+
+	//Version 5 state transitions end after the StakeTxData, i.e. without the validator, its signature and the root.
+	encodedSt = encodedSt[:len(encodedSt)-len(st.Validator)-len(st.CommitmentSig)-len(st.ShardStateRoot)]
+	encodedSt[0] = 5
+
+	decodedSt, err = decodedSt.DecodeTransition(encodedSt)
+	if err != nil {
+		t.Fatalf("Version 5 state transition could not be decoded: %v\n", err)
+	}
+	if decodedSt.HashTransition() == st.HashTransition() || decodedSt.Validator != [64]byte{} || decodedSt.Height != st.Height ||
+		!reflect.DeepEqual(decodedSt.FundsTxData, st.FundsTxData) {
+		t.Error("Version 5 state transition decoding failed!")
+	}
+}
+
+func TestDecodeVersion1Block(t *testing.T) {
+	block := NewBlock([32]byte{'0', '1'}, 10)
+	block.TxData[FUNDSTX_TYPE] = [][32]byte{{'2'}}
+
+	//Version 1 blocks end after the StakeTxData, i.e. without the number of receipts and other tx types.
+	encodedBlock := block.Encode()
+	encodedBlock = encodedBlock[:len(encodedBlock)-8]
+	encodedBlock[0] = 1
+
+	var decodedBlock *Block
+	decodedBlock, err := decodedBlock.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Version 1 block could not be decoded: %v\n", err)
+	}
+
+	if decodedBlock.Height != block.Height || !reflect.DeepEqual(decodedBlock.TxHashes(FUNDSTX_TYPE), block.TxHashes(FUNDSTX_TYPE)) || decodedBlock.Receipts != nil {
+		t.Error("Version 1 block decoding failed!")
+	}
+}
+
+func TestDecodeVersion7Block(t *testing.T) {
+	block := NewBlock([32]byte{'0', '1'}, 10)
+	block.AddTxHash(STAKETX_TYPE, [32]byte{'2'})
+
+	//Version 7 blocks end after the receipts, i.e. without the number of other tx types.
+	encodedBlock := block.Encode()
+	encodedBlock = encodedBlock[:len(encodedBlock)-4]
+	encodedBlock[0] = 7
+
+	var decodedBlock *Block
+	decodedBlock, err := decodedBlock.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Version 7 block could not be decoded: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(decodedBlock.TxData, block.TxData) {
+		t.Error("Version 7 block decoding failed!")
+	}
+}
+
+func TestEncodeBlockOtherTxTypes(t *testing.T) {
+	block := NewBlock([32]byte{'0', '1'}, 10)
+	block.AddTxHash(FUNDSTX_TYPE, [32]byte{'2'})
+	block.AddTxHash(0x20, [32]byte{'3'})
+	block.AddTxHash(0x20, [32]byte{'4'})
+	block.AddTxHash(0x10, [32]byte{'5'})
+
+	var decodedBlock *Block
+	decodedBlock, err := decodedBlock.Decode(block.Encode())
+	if err != nil {
+		t.Fatalf("Block could not be decoded: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(decodedBlock.TxData, block.TxData) {
+		t.Errorf("Tx hashes of other tx types are not properly serialized: %v vs. %v\n", decodedBlock.TxData, block.TxData)
+	}
+
+	//The other tx types are encoded sorted by their id, independent of the order of the map.
+	if !bytes.Equal(block.Encode(), decodedBlock.Encode()) {
+		t.Error("Encoding of the other tx types is not deterministic!")
+	}
+}
+
+func TestDecodeVersion2Tx(t *testing.T) {
+	tx, _ := ConstrStakeTx(0x01, 5, true, accA.Address, PrivKeyA, &CommitmentKeyA.PublicKey)
+	configTx, _ := ConstrConfigTx(0x01, 1, 5000, 2, 0, PrivKeyA)
+
+	//Version 2 transactions end before the validity window.
+	encodedTx := tx.Encode()
+	encodedTx = encodedTx[:len(encodedTx)-VALIDITY_SIZE]
+	encodedTx[0] = 2
+
+	var decodedTx *StakeTx
+	decodedTx, err := decodedTx.Decode(encodedTx)
+	if err != nil {
+		t.Fatalf("Version 2 StakeTx could not be decoded: %v\n", err)
+	}
+
+	//Without validity window, the hash (and thus the signature) stays the same.
+	if decodedTx.Hash() != tx.Hash() || decodedTx.ValidFrom != 0 || decodedTx.ValidUntil != 0 {
+		t.Error("Version 2 StakeTx decoding failed!")
+	}
+
+	encodedConfigTx := configTx.Encode()
+	encodedConfigTx = encodedConfigTx[:len(encodedConfigTx)-VALIDITY_SIZE]
+	encodedConfigTx[0] = 2
+
+	var decodedConfigTx *ConfigTx
+	if decodedConfigTx, err = decodedConfigTx.Decode(encodedConfigTx); err != nil || decodedConfigTx.Hash() != configTx.Hash() {
+		t.Errorf("Version 2 ConfigTx decoding failed: %v\n", err)
+	}
+}
+
+func TestDecodeRejectsInvalidLength(t *testing.T) {
+	tx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, nil)
+	encodedTx := tx.Encode()
+
+	//The length of Data is followed by the validity window and the numbers of multi-signatures and outputs, claim a huge
+	//payload without providing it.
+	copy(encodedTx[len(encodedTx)-4-VALIDITY_SIZE-8:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	var decodedTx *FundsTx
+	if _, err := decodedTx.Decode(encodedTx); err == nil {
+		t.Error("FundsTx with invalid data length has been decoded.")
+	}
+}
+
+func TestEncodeList(t *testing.T) {
+	items := [][]byte{{0x01}, {0x02, 0x03}, {0x04, 0x05, 0x06}}
+
+	decodedItems, err := DecodeList(EncodeList(items))
+	if err != nil {
+		t.Fatalf("List decoding failed: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(items, decodedItems) {
+		t.Errorf("List encoding/decoding failed: %v vs. %v\n", items, decodedItems)
+	}
+}
@@ -1,8 +1,7 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
@@ -22,6 +21,7 @@ type EpochBlock struct {
 	State				  map[[64]byte]*Account
 	ValMapping			  *ValShardMapping
 	NofShards			  int
+	ShardStateRoots		  [][32]byte	//Roots of the shard states of the previous epoch, see StateTransition.ShardStateRoot
 }
 
 func NewEpochBlock(prevShardHashes [][32]byte, height uint32) *EpochBlock {
@@ -38,6 +38,7 @@ func (epochBlock *EpochBlock) HashEpochBlock() [32]byte {
 		return [32]byte{}
 	}
 
+	state, multiSigs := stateHashContent(epochBlock.State)
 	blockHash := struct {
 		prevShardHashes               [][32]byte
 		timestamp             		  int64
@@ -45,7 +46,7 @@ func (epochBlock *EpochBlock) HashEpochBlock() [32]byte {
 		merklePatriciaRoot	  		  [32]byte
 		height				  		  uint32
 		commitmentProof       		  [crypto.COMM_PROOF_LENGTH]byte
-		state					      map[[64]byte]*Account
+		state					      map[[64]byte]*accountHashContent
 		valmapping					  *ValShardMapping
 		noshards					  int
 	}{
@@ -55,64 +56,114 @@ func (epochBlock *EpochBlock) HashEpochBlock() [32]byte {
 		epochBlock.MerklePatriciaRoot,
 		epochBlock.Height,
 		epochBlock.CommitmentProof,
-		epochBlock.State,
+		state,
 		epochBlock.ValMapping,
 		epochBlock.NofShards,
 	}
-	return SerializeHashContent(blockHash)
+	//The key sets of multi-signature accounts and the roots of the shard states extend the hash (see extendHash).
+	epochBlockHash := extendHash(SerializeHashContent(blockHash), len(multiSigs) > 0, multiSigs)
+	return extendHash(epochBlockHash, len(epochBlock.ShardStateRoots) > 0, epochBlock.ShardStateRoots)
 }
 
+//Header | Hash | PrevShardHashes | Height | Timestamp | MerkleRoot | MerklePatriciaRoot | CommitmentProof | State |
+//ValMapping | NofShards | ShardStateRoots
+//The state is encoded as a map of accounts (nested objects), sorted by address. The validator mapping is a nested
+//object, an empty one means no mapping.
 func (epochBlock *EpochBlock) Encode() []byte {
 	if epochBlock == nil {
 		return nil
 	}
 
-	encoded := EpochBlock{
-		Header:                epochBlock.Header,
-		Hash:                  epochBlock.Hash,
-		PrevShardHashes:       epochBlock.PrevShardHashes,
-		Timestamp:             epochBlock.Timestamp,
-		MerkleRoot:            epochBlock.MerkleRoot,
-		MerklePatriciaRoot:    epochBlock.MerklePatriciaRoot,
-		Height:                epochBlock.Height,
-		CommitmentProof:	   epochBlock.CommitmentProof,
-		State:				   epochBlock.State,
-		ValMapping:			   epochBlock.ValMapping,
-		NofShards:			   epochBlock.NofShards,
+	enc := newEncoder()
+	enc.putByte(epochBlock.Header)
+	enc.putFixed(epochBlock.Hash[:])
+	enc.putHashes(epochBlock.PrevShardHashes)
+	enc.putUint32(epochBlock.Height)
+	enc.putInt64(epochBlock.Timestamp)
+	enc.putFixed(epochBlock.MerkleRoot[:])
+	enc.putFixed(epochBlock.MerklePatriciaRoot[:])
+	enc.putFixed(epochBlock.CommitmentProof[:])
+
+	var addresses [][64]byte
+	for address := range epochBlock.State {
+		addresses = append(addresses, address)
 	}
+	sortAddresses(addresses)
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	enc.putUint32(uint32(len(addresses)))
+	for _, address := range addresses {
+		enc.putBytes(epochBlock.State[address].Encode())
+	}
+
+	enc.putBytes(epochBlock.ValMapping.Encode())
+	enc.putInt64(int64(epochBlock.NofShards))
+	enc.putHashes(epochBlock.ShardStateRoots)
+
+	return enc.bytes()
 }
 
+//The header is encoded in the same format as the whole epoch block, the body fields are left empty.
 func (epochBlock *EpochBlock) EncodeHeader() []byte {
 	if epochBlock == nil {
 		return nil
 	}
 
-	encoded := EpochBlock{
+	header := EpochBlock{
 		Header:       		 epochBlock.Header,
 		Hash:         		 epochBlock.Hash,
 		PrevShardHashes:     epochBlock.PrevShardHashes,
 		Height:       		 epochBlock.Height,
 	}
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return header.Encode()
 }
 
-func (epochBlock *EpochBlock) Decode(encoded []byte) (b *EpochBlock) {
+func (*EpochBlock) Decode(encoded []byte) (*EpochBlock, error) {
 	if encoded == nil {
-		return nil
+		return nil, errors.New("EpochBlock encoding is empty.")
+	}
+
+	epochBlock := new(EpochBlock)
+	dec := newDecoder(encoded, "EpochBlock")
+	epochBlock.Header = dec.getByte()
+	dec.getFixed(epochBlock.Hash[:])
+	epochBlock.PrevShardHashes = dec.getHashes()
+	epochBlock.Height = dec.getUint32()
+	epochBlock.Timestamp = dec.getInt64()
+	dec.getFixed(epochBlock.MerkleRoot[:])
+	dec.getFixed(epochBlock.MerklePatriciaRoot[:])
+	dec.getFixed(epochBlock.CommitmentProof[:])
+
+	nrAccounts := dec.getLength(4)
+	for i := 0; i < nrAccounts && dec.err == nil; i++ {
+		var acc *Account
+		acc, err := acc.Decode(dec.getBytes())
+		if err != nil {
+			return nil, err
+		}
+		if epochBlock.State == nil {
+			epochBlock.State = make(map[[64]byte]*Account)
+		}
+		epochBlock.State[acc.Address] = acc
+	}
+
+	if encodedMapping := dec.getBytes(); encodedMapping != nil {
+		var err error
+		if epochBlock.ValMapping, err = epochBlock.ValMapping.Decode(encodedMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	epochBlock.NofShards = int(dec.getInt64())
+	if dec.version >= 7 {
+		epochBlock.ShardStateRoots = dec.getHashes()
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
 	}
 
-	var decoded EpochBlock
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	return epochBlock, nil
 }
 
 func (epochBlock EpochBlock) String() string {
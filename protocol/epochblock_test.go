@@ -142,14 +142,26 @@ func TestEpochBlockSerialization(t *testing.T) {
 	epochBlock.State = stateMapping
 	epochBlock.ValMapping = valMapping
 	epochBlock.NofShards = numberofshards
+	epochBlock.ShardStateRoots = [][32]byte{{'4'}, {'5'}}
 
-	var compareBlock EpochBlock
+	var compareBlock *EpochBlock
 	encodedBlock := epochBlock.Encode()
-	compareBlock = *compareBlock.Decode(encodedBlock)
+	compareBlock, err := compareBlock.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Epoch block decoding failed: %v\n", err)
+	}
 
-	if !reflect.DeepEqual(epochBlock, compareBlock) {
+	if !reflect.DeepEqual(epochBlock, *compareBlock) {
 		t.Error("Block encoding/decoding failed!")
 	}
+
+	//Version 6 epoch blocks end after the number of shards, i.e. without the roots of the shard states.
+	encodedBlock = encodedBlock[:len(encodedBlock)-4-2*32]
+	encodedBlock[0] = 6
+	compareBlock, err = compareBlock.Decode(encodedBlock)
+	if err != nil || compareBlock.ShardStateRoots != nil || compareBlock.NofShards != numberofshards {
+		t.Errorf("Version 6 epoch block decoding failed: %v\n", err)
+	}
 }
 
 func TestEpochBlockHeaderSerialization(t *testing.T) {
@@ -179,11 +191,14 @@ func TestEpochBlockHeaderSerialization(t *testing.T) {
 	epochBlockHeader.Height = height
 
 
-	var compareEpochBlockHeader EpochBlock
+	var compareEpochBlockHeader *EpochBlock
 	encodedBlock := epochBlockHeader.EncodeHeader()
-	compareEpochBlockHeader = *compareEpochBlockHeader.Decode(encodedBlock)
+	compareEpochBlockHeader, err := compareEpochBlockHeader.Decode(encodedBlock)
+	if err != nil {
+		t.Fatalf("Epoch block header decoding failed: %v\n", err)
+	}
 
-	if !reflect.DeepEqual(epochBlockHeader, compareEpochBlockHeader) {
+	if !reflect.DeepEqual(epochBlockHeader, *compareEpochBlockHeader) {
 		t.Error("Block encoding/decoding failed!")
 	}
 }
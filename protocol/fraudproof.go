@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+/**
+	A fraud proof challenges the state transition of a foreign shard block. It carries everything a validator needs to
+	re-execute the block without having access to the state of the other shard: the block, its transactions and the signed
+	state transition. The disputed accounts are the ones for which the relative state change of the state transition
+	does not match the re-execution of the block.
+ */
+type FraudProof struct {
+	Block				*Block
+	StateTransition		*StateTransition
+	ContractTxs			[]*ContractTx
+	FundsTxs			[]*FundsTx
+	ConfigTxs			[]*ConfigTx
+	StakeTxs			[]*StakeTx
+	DisputedAccounts	[][64]byte
+}
+
+func NewFraudProof(block *Block, st *StateTransition, contractTxs []*ContractTx, fundsTxs []*FundsTx,
+	configTxs []*ConfigTx, stakeTxs []*StakeTx, disputedAccounts [][64]byte) *FraudProof {
+	return &FraudProof{
+		Block:				block,
+		StateTransition:	st,
+		ContractTxs:		contractTxs,
+		FundsTxs:			fundsTxs,
+		ConfigTxs:			configTxs,
+		StakeTxs:			stakeTxs,
+		DisputedAccounts:	disputedAccounts,
+	}
+}
+
+//The transactions are already committed to by the block (Merkle root) and the state transition, hence they are not
+//part of the hash.
+func (proof *FraudProof) Hash() [32]byte {
+	if proof == nil {
+		return [32]byte{}
+	}
+
+	proofHash := struct {
+		BlockHash			[32]byte
+		TransitionHash		[32]byte
+		DisputedAccounts	[][64]byte
+	}{
+		proof.Block.Hash,
+		proof.StateTransition.HashTransition(),
+		proof.DisputedAccounts,
+	}
+	return SerializeHashContent(proofHash)
+}
+
+//Block | StateTransition | ContractTxs | FundsTxs | ConfigTxs | StakeTxs | DisputedAccounts
+//The block, the state transition and the transactions are nested objects, the lists of transactions and the disputed
+//accounts are prefixed with the number of entries.
+func (proof *FraudProof) Encode() []byte {
+	if proof == nil {
+		return nil
+	}
+
+	enc := newEncoder()
+	enc.putBytes(proof.Block.Encode())
+	enc.putBytes(proof.StateTransition.EncodeTransition())
+
+	enc.putUint32(uint32(len(proof.ContractTxs)))
+	for _, tx := range proof.ContractTxs {
+		enc.putBytes(tx.Encode())
+	}
+	enc.putUint32(uint32(len(proof.FundsTxs)))
+	for _, tx := range proof.FundsTxs {
+		enc.putBytes(tx.Encode())
+	}
+	enc.putUint32(uint32(len(proof.ConfigTxs)))
+	for _, tx := range proof.ConfigTxs {
+		enc.putBytes(tx.Encode())
+	}
+	enc.putUint32(uint32(len(proof.StakeTxs)))
+	for _, tx := range proof.StakeTxs {
+		enc.putBytes(tx.Encode())
+	}
+
+	enc.putUint32(uint32(len(proof.DisputedAccounts)))
+	for _, address := range proof.DisputedAccounts {
+		enc.putFixed(address[:])
+	}
+
+	return enc.bytes()
+}
+
+func (*FraudProof) Decode(encoded []byte) (*FraudProof, error) {
+	if encoded == nil {
+		return nil, errors.New("FraudProof encoding is empty.")
+	}
+
+	var err error
+	proof := new(FraudProof)
+	dec := newDecoder(encoded, "FraudProof")
+
+	if proof.Block, err = proof.Block.Decode(dec.getBytes()); err != nil {
+		return nil, err
+	}
+	if proof.StateTransition, err = proof.StateTransition.DecodeTransition(dec.getBytes()); err != nil {
+		return nil, err
+	}
+
+	nrTxs := dec.getLength(4)
+	for i := 0; i < nrTxs && dec.err == nil; i++ {
+		var tx *ContractTx
+		if tx, err = tx.Decode(dec.getBytes()); err != nil {
+			return nil, err
+		}
+		proof.ContractTxs = append(proof.ContractTxs, tx)
+	}
+	nrTxs = dec.getLength(4)
+	for i := 0; i < nrTxs && dec.err == nil; i++ {
+		var tx *FundsTx
+		if tx, err = tx.Decode(dec.getBytes()); err != nil {
+			return nil, err
+		}
+		proof.FundsTxs = append(proof.FundsTxs, tx)
+	}
+	nrTxs = dec.getLength(4)
+	for i := 0; i < nrTxs && dec.err == nil; i++ {
+		var tx *ConfigTx
+		if tx, err = tx.Decode(dec.getBytes()); err != nil {
+			return nil, err
+		}
+		proof.ConfigTxs = append(proof.ConfigTxs, tx)
+	}
+	nrTxs = dec.getLength(4)
+	for i := 0; i < nrTxs && dec.err == nil; i++ {
+		var tx *StakeTx
+		if tx, err = tx.Decode(dec.getBytes()); err != nil {
+			return nil, err
+		}
+		proof.StakeTxs = append(proof.StakeTxs, tx)
+	}
+
+	nrAccounts := dec.getLength(64)
+	for i := 0; i < nrAccounts && dec.err == nil; i++ {
+		var address [64]byte
+		dec.getFixed(address[:])
+		proof.DisputedAccounts = append(proof.DisputedAccounts, address)
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+func (proof FraudProof) String() string {
+	proofHash := proof.Hash()
+	return fmt.Sprintf(
+		"\nHash: %x\n"+
+			"Block: %x\n"+
+			"Shard: %v\n"+
+			"Height: %v\n"+
+			"Validator: %x\n"+
+			"Disputed accounts: %v\n",
+		proofHash[0:8],
+		proof.Block.Hash[0:8],
+		proof.StateTransition.ShardID,
+		proof.StateTransition.Height,
+		proof.StateTransition.Validator[0:8],
+		len(proof.DisputedAccounts),
+	)
+}
@@ -0,0 +1,61 @@
+package protocol
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"reflect"
+	"testing"
+)
+
+func TestFraudProofSerialization(t *testing.T) {
+	fundsTx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, nil)
+	stakeTx, _ := ConstrStakeTx(0x01, 5, true, accA.Address, PrivKeyA, &CommitmentKeyA.PublicKey)
+
+	block := NewBlock([32]byte{'0', '1'}, 10)
+	block.Hash = [32]byte{'2'}
+	block.TxData[FUNDSTX_TYPE] = [][32]byte{fundsTx.Hash()}
+	block.TxData[STAKETX_TYPE] = [][32]byte{stakeTx.Hash()}
+
+	stateChange := make(map[[64]byte]*RelativeAccount)
+	accRel := NewRelativeAccount(accA.Address, [64]byte{}, 50, true, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	stateChange[accA.Address] = &accRel
+
+	st := NewStateTransition(stateChange, 10, 2, block.Hash, nil, block.TxHashes(FUNDSTX_TYPE), nil, block.TxHashes(STAKETX_TYPE))
+	st.Validator = accB.Address
+
+	proof := NewFraudProof(block, st, nil, []*FundsTx{fundsTx}, nil, []*StakeTx{stakeTx}, [][64]byte{accA.Address})
+
+	var decodedProof *FraudProof
+	decodedProof, err := decodedProof.Decode(proof.Encode())
+	if err != nil {
+		t.Fatalf("Fraud proof decoding failed: %v\n", err)
+	}
+
+	if decodedProof.Hash() != proof.Hash() {
+		t.Errorf("Fraud proof encoding/decoding failed: Hash does not match: %x vs. %x\n", decodedProof.Hash(), proof.Hash())
+	}
+
+	if decodedProof.Block.Hash != block.Hash || decodedProof.StateTransition.HashTransition() != st.HashTransition() {
+		t.Error("Fraud proof encoding/decoding failed: Block or state transition does not match!")
+	}
+
+	if len(decodedProof.FundsTxs) != 1 || decodedProof.FundsTxs[0].Hash() != fundsTx.Hash() ||
+		len(decodedProof.StakeTxs) != 1 || decodedProof.StakeTxs[0].Hash() != stakeTx.Hash() ||
+		len(decodedProof.ContractTxs) != 0 || len(decodedProof.ConfigTxs) != 0 {
+		t.Error("Fraud proof encoding/decoding failed: Transactions do not match!")
+	}
+
+	if !reflect.DeepEqual(proof.DisputedAccounts, decodedProof.DisputedAccounts) {
+		t.Error("Fraud proof encoding/decoding failed: Disputed accounts do not match!")
+	}
+
+	//Proofs disputing different accounts are different proofs
+	otherProof := NewFraudProof(block, st, nil, []*FundsTx{fundsTx}, nil, []*StakeTx{stakeTx}, [][64]byte{accB.Address})
+	if otherProof.Hash() == proof.Hash() {
+		t.Error("Fraud proofs with different disputed accounts have the same hash.")
+	}
+
+	encodedProof := proof.Encode()
+	if _, err := decodedProof.Decode(encodedProof[:len(encodedProof)-1]); err == nil {
+		t.Error("Truncated fraud proof has been decoded.")
+	}
+}
@@ -1,15 +1,14 @@
 package protocol
 
 import (
-	"bytes"
-	"crypto/ecdsa"
-	"crypto/rand"
-	"encoding/gob"
+	"errors"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
 
 const (
-	FUNDSTX_SIZE = 213
+	FUNDSTX_SIZE        = 234 //Without Data, MultiSigs and Outputs
+	FUNDSTX_OUTPUT_SIZE = 72  //To | Amount
 )
 
 //when we broadcast transactions we need a way to distinguish with a type
@@ -23,9 +22,18 @@ type FundsTx struct {
 	To     [64]byte
 	Sig    [64]byte
 	Data   []byte
+	ValidFrom  uint32
+	ValidUntil uint32
+	MultiSigs  [][64]byte      //Signatures of the key holders if From is a multi-signature account, Sig is not used then
+	Outputs    []FundsTxOutput //Recipients of a batch transfer, Amount is their sum and To is not used then
 }
 
-func ConstrFundsTx(header byte, amount uint64, fee uint64, txCnt uint32, from, to [64]byte, sigKey *ecdsa.PrivateKey, data []byte) (tx *FundsTx, err error) {
+type FundsTxOutput struct {
+	To     [64]byte
+	Amount uint64
+}
+
+func ConstrFundsTx(header byte, amount uint64, fee uint64, txCnt uint32, from, to [64]byte, sigKey crypto.PrivateKey, data []byte) (tx *FundsTx, err error) {
 	tx = new(FundsTx)
 	tx.Header = header
 	tx.From = from
@@ -37,17 +45,84 @@ func ConstrFundsTx(header byte, amount uint64, fee uint64, txCnt uint32, from, t
 
 	txHash := tx.Hash()
 
-	r, s, err := ecdsa.Sign(rand.Reader, sigKey, txHash[:])
-	if err != nil {
+	if tx.Sig, err = signTxHash(txHash, sigKey); err != nil {
 		return nil, err
 	}
 
-	copy(tx.Sig[32-len(r.Bytes()):32], r.Bytes())
-	copy(tx.Sig[64-len(s.Bytes()):], s.Bytes())
+	return tx, nil
+}
+
+//Constructs a batch transfer paying all outputs with one signature and one fee.
+func ConstrBatchFundsTx(header byte, fee uint64, txCnt uint32, from [64]byte, outputs []FundsTxOutput, sigKey crypto.PrivateKey) (tx *FundsTx, err error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("Batch transfer has no outputs.")
+	}
+
+	tx = new(FundsTx)
+	tx.Header = header
+	tx.From = from
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.Outputs = outputs
+
+	for _, output := range outputs {
+		if tx.Amount+output.Amount < tx.Amount {
+			return nil, errors.New("Sum of the batch transfer outputs overflows.")
+		}
+		tx.Amount += output.Amount
+	}
+
+	if tx.Sig, err = signTxHash(tx.Hash(), sigKey); err != nil {
+		return nil, err
+	}
 
 	return tx, nil
 }
 
+func (tx *FundsTx) IsBatch() bool {
+	return len(tx.Outputs) > 0
+}
+
+//Returns the outputs of a batch transfer or the single recipient of all other fundsTxs.
+func (tx *FundsTx) Recipients() []FundsTxOutput {
+	if tx.IsBatch() {
+		return tx.Outputs
+	}
+
+	return []FundsTxOutput{{tx.To, tx.Amount}}
+}
+
+//Constructs a fundsTx spending from a multi-signature account, signed by each of the given keys.
+func ConstrMultiSigFundsTx(header byte, amount uint64, fee uint64, txCnt uint32, from, to [64]byte, sigKeys []crypto.PrivateKey, data []byte) (tx *FundsTx, err error) {
+	tx = new(FundsTx)
+	tx.Header = header
+	tx.From = from
+	tx.To = to
+	tx.Amount = amount
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.Data = data
+
+	for _, sigKey := range sigKeys {
+		if err = tx.AddMultiSig(sigKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+//The signatures are not part of the hash, such that the key holders of a multi-signature account can sign independently.
+func (tx *FundsTx) AddMultiSig(sigKey crypto.PrivateKey) error {
+	sig, err := signTxHash(tx.Hash(), sigKey)
+	if err != nil {
+		return err
+	}
+
+	tx.MultiSigs = append(tx.MultiSigs, sig)
+	return nil
+}
+
 func (tx *FundsTx) Hash() (hash [32]byte) {
 	if tx == nil {
 		//is returning nil better?
@@ -72,42 +147,94 @@ func (tx *FundsTx) Hash() (hash [32]byte) {
 		tx.Data,
 	}
 
-	return SerializeHashContent(txHash)
+	//The outputs of batch transfers extend the hash (see extendHash).
+	fundsHash := extendHash(SerializeHashContent(txHash), tx.IsBatch(), tx.Outputs)
+
+	return hashWithValidity(fundsHash, tx.ValidFrom, tx.ValidUntil)
 }
 
-//when we serialize the struct with binary.Write, unexported field get serialized as well, undesired
-//behavior. Therefore, writing own encoder/decoder
-func (tx *FundsTx) Encode() (encodedTx []byte) {
+//Restricts the block heights the tx can be included at and signs it again. The signatures of multi-signature key
+//holders are discarded, they have to be added again.
+func (tx *FundsTx) SetValidity(validFrom uint32, validUntil uint32, sigKey crypto.PrivateKey) (err error) {
+	tx.ValidFrom, tx.ValidUntil = validFrom, validUntil
+	tx.MultiSigs = nil
+	tx.Sig, err = signTxHash(tx.Hash(), sigKey)
+	return err
+}
 
-	//gob.Register(&ethdb.MemDatabase{})
+//Header | Amount | Fee | TxCnt | From | To | Sig | Data | ValidFrom | ValidUntil | MultiSigs | Outputs
+//The outputs are prefixed with their number, each output is encoded as To | Amount.
+func (tx *FundsTx) Encode() (encodedTx []byte) {
+	if tx == nil {
+		return nil
+	}
 
-	// Encode
-	encodeData := FundsTx{
-		tx.Header,
-		tx.Amount,
-		tx.Fee,
-		tx.TxCnt,
-		tx.From,
-		tx.To,
-		tx.Sig,
-		tx.Data,
+	enc := newEncoder()
+	enc.putByte(tx.Header)
+	enc.putUint64(tx.Amount)
+	enc.putUint64(tx.Fee)
+	enc.putUint32(tx.TxCnt)
+	enc.putFixed(tx.From[:])
+	enc.putFixed(tx.To[:])
+	enc.putFixed(tx.Sig[:])
+	enc.putBytes(tx.Data)
+	enc.putUint32(tx.ValidFrom)
+	enc.putUint32(tx.ValidUntil)
+	enc.putKeys(tx.MultiSigs)
+	enc.putUint32(uint32(len(tx.Outputs)))
+	for _, output := range tx.Outputs {
+		enc.putFixed(output.To[:])
+		enc.putUint64(output.Amount)
 	}
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encodeData)
 
-	return buffer.Bytes()
+	return enc.bytes()
 }
 
-func (*FundsTx) Decode(encodedTx []byte) *FundsTx {
-	var decoded FundsTx
-	buffer := bytes.NewBuffer(encodedTx)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+func (*FundsTx) Decode(encodedTx []byte) (*FundsTx, error) {
+	if encodedTx == nil {
+		return nil, errors.New("FundsTx encoding is empty.")
+	}
+
+	tx := new(FundsTx)
+	dec := newDecoder(encodedTx, "FundsTx")
+	tx.Header = dec.getByte()
+	tx.Amount = dec.getUint64()
+	tx.Fee = dec.getUint64()
+	tx.TxCnt = dec.getUint32()
+	dec.getFixed(tx.From[:])
+	dec.getFixed(tx.To[:])
+	dec.getFixed(tx.Sig[:])
+	tx.Data = dec.getBytes()
+	if dec.version >= 3 {
+		tx.ValidFrom = dec.getUint32()
+		tx.ValidUntil = dec.getUint32()
+	}
+	if dec.version >= 4 {
+		tx.MultiSigs = dec.getKeys()
+	}
+	if dec.version >= 5 {
+		nrOutputs := dec.getLength(FUNDSTX_OUTPUT_SIZE)
+		for i := 0; i < nrOutputs && dec.err == nil; i++ {
+			var output FundsTxOutput
+			dec.getFixed(output.To[:])
+			output.Amount = dec.getUint64()
+			tx.Outputs = append(tx.Outputs, output)
+		}
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
 }
 
 func (tx *FundsTx) TxFee() uint64 { return tx.Fee }
-func (tx *FundsTx) Size() uint64  { return FUNDSTX_SIZE }
+func (tx *FundsTx) Size() uint64 {
+	return FUNDSTX_SIZE + uint64(len(tx.Data)) + uint64(len(tx.MultiSigs))*64 + uint64(len(tx.Outputs))*FUNDSTX_OUTPUT_SIZE
+}
+func (tx *FundsTx) Validity() (uint32, uint32) { return tx.ValidFrom, tx.ValidUntil }
+func (tx *FundsTx) Type() byte { return FUNDSTX_TYPE }
 
 func (tx FundsTx) String() string {
 	return fmt.Sprintf(
@@ -119,7 +246,10 @@ func (tx FundsTx) String() string {
 			"From: %x\n"+
 			"To: %x\n"+
 			"Sig: %x\n"+
-			"Data: %v\n",
+			"Data: %v\n"+
+			"Valid: %v - %v\n"+
+			"MultiSigs: %v\n"+
+			"Outputs: %v\n",
 		tx.Header,
 		tx.Hash(),
 		tx.Amount,
@@ -129,5 +259,9 @@ func (tx FundsTx) String() string {
 		tx.To[0:8],
 		tx.Sig[0:8],
 		tx.Data,
+		tx.ValidFrom,
+		tx.ValidUntil,
+		len(tx.MultiSigs),
+		len(tx.Outputs),
 	)
 }
@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
 
 func TestFundsTxSerialization(t *testing.T) {
@@ -14,7 +16,10 @@ func TestFundsTxSerialization(t *testing.T) {
 		tx, _ := ConstrFundsTx(0x01, rand.Uint64()%100000+1, rand.Uint64()%10+1, uint32(i), accA.Address, accB.Address, PrivKeyA, nil)
 		data := tx.Encode()
 		var decodedTx *FundsTx
-		decodedTx = decodedTx.Decode(data)
+		decodedTx, err := decodedTx.Decode(data)
+		if err != nil {
+			t.Fatalf("FundsTx decoding failed: %v\n", err)
+		}
 
 		//this is done by verify() which is outside protocol package, we're just testing serialization here
 		decodedTx.From = accA.Address
@@ -25,3 +30,100 @@ func TestFundsTxSerialization(t *testing.T) {
 		}
 	}
 }
+
+func TestFundsTxValidity(t *testing.T) {
+	tx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, nil)
+	hashWithoutWindow := tx.Hash()
+
+	if !IsValidAt(tx, 0) || !IsValidAt(tx, 1000) || IsExpired(tx, 1000) {
+		t.Error("FundsTx without validity window is not valid at every height.")
+	}
+
+	if err := tx.SetValidity(10, 20, PrivKeyA); err != nil {
+		t.Fatalf("Validity window could not be set: %v\n", err)
+	}
+
+	//The signature covers the validity window.
+	if tx.Hash() == hashWithoutWindow {
+		t.Error("Validity window is not part of the FundsTx hash.")
+	}
+
+	if IsValidAt(tx, 9) || !IsValidAt(tx, 10) || !IsValidAt(tx, 20) || IsValidAt(tx, 21) {
+		t.Error("FundsTx is valid outside of its validity window.")
+	}
+
+	if IsExpired(tx, 19) || !IsExpired(tx, 20) {
+		t.Error("FundsTx expiry does not match its validity window.")
+	}
+
+	var decodedTx *FundsTx
+	decodedTx, err := decodedTx.Decode(tx.Encode())
+	if err != nil {
+		t.Fatalf("FundsTx decoding failed: %v\n", err)
+	}
+
+	if decodedTx.ValidFrom != 10 || decodedTx.ValidUntil != 20 || decodedTx.Hash() != tx.Hash() {
+		t.Errorf("FundsTx validity window encoding/decoding failed: %v vs. %v\n", decodedTx, tx)
+	}
+}
+
+func TestMultiSigFundsTx(t *testing.T) {
+	tx, err := ConstrMultiSigFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, []crypto.PrivateKey{PrivKeyA, PrivKeyB}, nil)
+	if err != nil {
+		t.Fatalf("Multi-signature FundsTx could not be constructed: %v\n", err)
+	}
+
+	//The signatures are not part of the hash, every key holder signs the same hash.
+	singleSigTx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, nil)
+	if len(tx.MultiSigs) != 2 || tx.Hash() != singleSigTx.Hash() {
+		t.Errorf("Multi-signature FundsTx has not been signed by all keys: %v\n", tx)
+	}
+
+	if tx.Size() != FUNDSTX_SIZE+2*64 {
+		t.Errorf("Multi-signature FundsTx size is %v instead of %v\n", tx.Size(), FUNDSTX_SIZE+2*64)
+	}
+
+	var decodedTx *FundsTx
+	decodedTx, err = decodedTx.Decode(tx.Encode())
+	if err != nil {
+		t.Fatalf("FundsTx decoding failed: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(tx, decodedTx) {
+		t.Errorf("Multi-signature FundsTx encoding/decoding failed: %v vs. %v\n", decodedTx, tx)
+	}
+}
+
+func TestBatchFundsTx(t *testing.T) {
+	outputs := []FundsTxOutput{{accB.Address, 10}, {accA.Address, 20}, {[64]byte{0x01}, 30}}
+	tx, err := ConstrBatchFundsTx(0x01, 1, 0, accA.Address, outputs, PrivKeyA)
+	if err != nil {
+		t.Fatalf("Batch FundsTx could not be constructed: %v\n", err)
+	}
+
+	if !tx.IsBatch() || tx.Amount != 60 || len(tx.Recipients()) != 3 || tx.Size() != FUNDSTX_SIZE+3*FUNDSTX_OUTPUT_SIZE {
+		t.Errorf("Batch FundsTx construction failed: %v\n", tx)
+	}
+
+	//The outputs are covered by the signature.
+	hash := tx.Hash()
+	tx.Outputs[0].Amount = 11
+	if tx.Hash() == hash {
+		t.Error("Outputs are not part of the batch FundsTx hash.")
+	}
+	tx.Outputs[0].Amount = 10
+
+	var decodedTx *FundsTx
+	decodedTx, err = decodedTx.Decode(tx.Encode())
+	if err != nil {
+		t.Fatalf("FundsTx decoding failed: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(tx, decodedTx) {
+		t.Errorf("Batch FundsTx encoding/decoding failed: %v vs. %v\n", decodedTx, tx)
+	}
+
+	if _, err := ConstrBatchFundsTx(0x01, 1, 0, accA.Address, nil, PrivKeyA); err == nil {
+		t.Error("Batch FundsTx without outputs has been constructed.")
+	}
+}
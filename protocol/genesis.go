@@ -1,8 +1,7 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
@@ -36,31 +35,34 @@ func (genesis *Genesis) Hash() [32]byte {
 	return SerializeHashContent(input)
 }
 
+//RootAddress | RootCommitment
 func (genesis *Genesis) Encode() []byte {
 	if genesis == nil {
 		return nil
 	}
 
-	encoded := Genesis{
-		RootAddress:    genesis.RootAddress,
-		RootCommitment:	genesis.RootCommitment,
-	}
+	enc := newEncoder()
+	enc.putFixed(genesis.RootAddress[:])
+	enc.putFixed(genesis.RootCommitment[:])
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return enc.bytes()
 }
 
-func (*Genesis) Decode(encoded []byte) (acc *Genesis) {
+func (*Genesis) Decode(encoded []byte) (*Genesis, error) {
 	if encoded == nil {
-		return nil
+		return nil, errors.New("Genesis encoding is empty.")
+	}
+
+	genesis := new(Genesis)
+	dec := newDecoder(encoded, "Genesis")
+	dec.getFixed(genesis.RootAddress[:])
+	dec.getFixed(genesis.RootCommitment[:])
+
+	if err := dec.finish(); err != nil {
+		return nil, err
 	}
 
-	var decoded Genesis
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	return genesis, nil
 }
 
 func (genesis *Genesis) String() string {
@@ -24,11 +24,14 @@ func TestGenesisSerialization(t *testing.T) {
 	rand.Read(genesis.RootAddress[:])
 	rand.Read(genesis.RootCommitment[:])
 
-	var compareGenesis Genesis
+	var compareGenesis *Genesis
 	encodedGenesis := genesis.Encode()
-	compareGenesis = *compareGenesis.Decode(encodedGenesis)
+	compareGenesis, err := compareGenesis.Decode(encodedGenesis)
+	if err != nil {
+		t.Fatalf("Genesis decoding failed: %v\n", err)
+	}
 
-	if !reflect.DeepEqual(genesis, compareGenesis) {
+	if !reflect.DeepEqual(genesis, *compareGenesis) {
 		t.Error("Genesis encoding/decoding failed!")
 	}
 }
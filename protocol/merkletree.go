@@ -46,26 +46,13 @@ func BuildMerkleTree(b *Block) *MerkleTree {
 		return nil
 	}
 
-	if b.FundsTxData != nil {
-		for _, txHash := range b.FundsTxData {
-			txHashes = append(txHashes, txHash)
-		}
-	}
-	if b.ContractTxData != nil {
-		for _, txHash := range b.ContractTxData {
-			txHashes = append(txHashes, txHash)
-		}
-	}
-	if b.ConfigTxData != nil {
-		for _, txHash := range b.ConfigTxData {
-			txHashes = append(txHashes, txHash)
-		}
+	//The FundsTxs come first, as they did before the other tx types were added to the tree.
+	for _, txType := range append([]byte{FUNDSTX_TYPE, CONTRACTTX_TYPE, CONFIGTX_TYPE, STAKETX_TYPE}, b.otherTxTypes()...) {
+		txHashes = append(txHashes, b.TxHashes(txType)...)
 	}
 
-	if b.StakeTxData != nil {
-		for _, txHash := range b.StakeTxData {
-			txHashes = append(txHashes, txHash)
-		}
+	for _, receipt := range b.Receipts {
+		txHashes = append(txHashes, receipt.Hash())
 	}
 
 	//Merkle root for no transactions is 0 hash
@@ -207,6 +194,51 @@ func GetIntermediate(leaf *Node) (intermediate []*Node, err error) {
 	return intermediate, nil
 }
 
+//MerkleProof returns the hashes needed to verify that the tx is part of the Merkle tree of the block, i.e. the hash of
+//the sibling and the parent of every node on the path from the leaf up to the root.
+func MerkleProof(b *Block, txHash [32]byte) (proof [][32]byte, err error) {
+	merkleTree := BuildMerkleTree(b)
+	if merkleTree == nil {
+		return nil, errors.New("Block does not contain any transactions.")
+	}
+
+	leaf := GetLeaf(merkleTree, txHash)
+	if leaf == nil {
+		return nil, errors.New(fmt.Sprintf("Transaction %x is not part of the block.", txHash))
+	}
+
+	intermediates, err := GetIntermediate(leaf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range intermediates {
+		proof = append(proof, node.Hash)
+	}
+
+	return proof, nil
+}
+
+//VerifyMerkleProof returns true if the proof leads from the leaf hash to the Merkle root. The order of the siblings is
+//not part of the proof, hence both concatenations are checked on every level.
+func VerifyMerkleProof(merkleRoot [32]byte, leafHash [32]byte, proof [][32]byte) bool {
+	if len(proof) == 0 || len(proof)%2 != 0 {
+		return false
+	}
+
+	currentHash := leafHash
+	for i := 0; i < len(proof); i += 2 {
+		sibling, parent := proof[i], proof[i+1]
+		if sha3.Sum256(append(currentHash[:], sibling[:]...)) != parent &&
+			sha3.Sum256(append(sibling[:], currentHash[:]...)) != parent {
+			return false
+		}
+		currentHash = parent
+	}
+
+	return currentHash == merkleRoot
+}
+
 //String returns a string representation of the tree. Only leaf nodes are included
 //in the output.
 func (m *MerkleTree) String() string {
@@ -23,7 +23,7 @@ func TestBuildMerkleTree3N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -55,7 +55,7 @@ func TestBuildMerkleTree2N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -82,7 +82,7 @@ func TestBuildMerkleTree4N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -117,7 +117,7 @@ func TestBuildMerkleTree6N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -160,7 +160,7 @@ func TestBuildMerkleTree8N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -209,7 +209,7 @@ func TestBuildMerkleTree10N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -266,7 +266,7 @@ func TestBuildMerkleTree11N(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -329,7 +329,7 @@ func TestGetIntermediate(t *testing.T) {
 	}
 
 	b := Block{
-		FundsTxData: hashSlice,
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
 	}
 
 	concat12 := append(hashSlice[0][:], hashSlice[1][:]...)
@@ -392,3 +392,44 @@ func TestGetIntermediate(t *testing.T) {
 		t.Errorf("Hashes don't match: %x != %x\n", intermediates[4].Hash, hash12345678)
 	}
 }
+
+func TestMerkleProof(t *testing.T) {
+	var hashSlice [][32]byte
+	var tx *FundsTx
+
+	//Generating a private key and prepare data
+	privA, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	for i := 0; i < 11; i++ {
+		tx, _ = ConstrFundsTx(0, 10, 1, uint32(i), [64]byte{'1'}, [64]byte{'2'}, privA, nil)
+		hashSlice = append(hashSlice, tx.Hash())
+	}
+
+	b := Block{
+		TxData: map[byte][][32]byte{FUNDSTX_TYPE: hashSlice},
+	}
+	merkleRoot := BuildMerkleTree(&b).MerkleRoot()
+
+	for _, txHash := range hashSlice {
+		proof, err := MerkleProof(&b, txHash)
+		if err != nil {
+			t.Fatalf("Merkle proof could not be created: %v\n", err)
+		}
+
+		if !VerifyMerkleProof(merkleRoot, txHash, proof) {
+			t.Errorf("Merkle proof of %x could not be verified.\n", txHash)
+		}
+
+		if VerifyMerkleProof(merkleRoot, [32]byte{'3'}, proof) {
+			t.Error("Merkle proof has been verified for another hash.")
+		}
+
+		if VerifyMerkleProof([32]byte{'4'}, txHash, proof) {
+			t.Error("Merkle proof has been verified against another root.")
+		}
+	}
+
+	if _, err := MerkleProof(&b, [32]byte{'3'}); err == nil {
+		t.Error("Merkle proof created for a hash which is not part of the block.")
+	}
+}
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+/**
+	A receipt is the second phase of a cross-shard FundsTx. The shard of the sender debits the amount and includes the
+	transaction in one of its blocks. The shard of the receiver credits the amount as soon as one of its blocks includes
+	the receipt, which proves with a Merkle proof that the transaction is part of the block of the sender shard.
+ */
+type Receipt struct {
+	Tx          *FundsTx
+	ShardId     int        //Shard of the sender
+	BlockHash   [32]byte   //Block of the sender shard which includes the transaction
+	MerkleProof [][32]byte //Sibling and parent hash of every level of the Merkle tree, from the leaf up to the root
+}
+
+func NewReceipt(tx *FundsTx, shardId int, blockHash [32]byte, merkleProof [][32]byte) *Receipt {
+	return &Receipt{
+		Tx:          tx,
+		ShardId:     shardId,
+		BlockHash:   blockHash,
+		MerkleProof: merkleProof,
+	}
+}
+
+//The Merkle proof is not part of the hash, it can be recalculated from the block.
+func (receipt *Receipt) Hash() [32]byte {
+	if receipt == nil {
+		return [32]byte{}
+	}
+
+	receiptHash := struct {
+		TxHash    [32]byte
+		ShardId   int
+		BlockHash [32]byte
+	}{
+		receipt.Tx.Hash(),
+		receipt.ShardId,
+		receipt.BlockHash,
+	}
+	return SerializeHashContent(receiptHash)
+}
+
+func (receipt *Receipt) Size() uint64 {
+	return uint64(len(receipt.Encode()))
+}
+
+//Tx | ShardId | BlockHash | MerkleProof
+//The transaction is a nested object.
+func (receipt *Receipt) Encode() []byte {
+	if receipt == nil {
+		return nil
+	}
+
+	enc := newEncoder()
+	enc.putBytes(receipt.Tx.Encode())
+	enc.putInt64(int64(receipt.ShardId))
+	enc.putFixed(receipt.BlockHash[:])
+	enc.putHashes(receipt.MerkleProof)
+
+	return enc.bytes()
+}
+
+func (*Receipt) Decode(encoded []byte) (*Receipt, error) {
+	if encoded == nil {
+		return nil, errors.New("Receipt encoding is empty.")
+	}
+
+	var err error
+	receipt := new(Receipt)
+	dec := newDecoder(encoded, "Receipt")
+
+	if receipt.Tx, err = receipt.Tx.Decode(dec.getBytes()); err != nil {
+		return nil, err
+	}
+	receipt.ShardId = int(dec.getInt64())
+	dec.getFixed(receipt.BlockHash[:])
+	receipt.MerkleProof = dec.getHashes()
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func (receipt Receipt) String() string {
+	return fmt.Sprintf(
+		"\nTx: %x\n"+
+			"Shard: %v\n"+
+			"Block: %x\n"+
+			"To: %x\n"+
+			"Amount: %v\n",
+		receipt.Tx.Hash(),
+		receipt.ShardId,
+		receipt.BlockHash[0:8],
+		receipt.Tx.To[0:8],
+		receipt.Tx.Amount,
+	)
+}
@@ -0,0 +1,52 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReceiptSerialization(t *testing.T) {
+	tx, _ := ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, PrivKeyA, nil)
+	txB, _ := ConstrFundsTx(0x01, 200, 1, 1, accA.Address, accB.Address, PrivKeyA, nil)
+
+	senderBlock := NewBlock([32]byte{'0'}, 10)
+	senderBlock.TxData[FUNDSTX_TYPE] = [][32]byte{tx.Hash(), txB.Hash()}
+	merkleProof, err := MerkleProof(senderBlock, tx.Hash())
+	if err != nil {
+		t.Fatalf("Merkle proof could not be created: %v\n", err)
+	}
+
+	receipt := NewReceipt(tx, 2, [32]byte{'1'}, merkleProof)
+
+	var decodedReceipt *Receipt
+	decodedReceipt, err = decodedReceipt.Decode(receipt.Encode())
+	if err != nil {
+		t.Fatalf("Receipt decoding failed: %v\n", err)
+	}
+
+	if decodedReceipt.Hash() != receipt.Hash() || decodedReceipt.Tx.Hash() != tx.Hash() {
+		t.Error("Receipt encoding/decoding failed: Hash does not match!")
+	}
+
+	if !reflect.DeepEqual(receipt.MerkleProof, decodedReceipt.MerkleProof) {
+		t.Error("Receipt encoding/decoding failed: Merkle proof does not match!")
+	}
+
+	//Receipts are part of the block encoding and of its Merkle tree.
+	block := NewBlock([32]byte{'2'}, 11)
+	block.Receipts = []*Receipt{receipt}
+
+	var decodedBlock *Block
+	decodedBlock, err = decodedBlock.Decode(block.Encode())
+	if err != nil {
+		t.Fatalf("Block decoding failed: %v\n", err)
+	}
+
+	if len(decodedBlock.Receipts) != 1 || decodedBlock.Receipts[0].Hash() != receipt.Hash() {
+		t.Error("Block encoding/decoding failed: Receipts do not match!")
+	}
+
+	if BuildMerkleTree(block).MerkleRoot() == [32]byte{} {
+		t.Error("Receipts are not part of the Merkle tree.")
+	}
+}
@@ -102,6 +102,19 @@ func ReturnShardHashesForHeight(statestash *StateStash, height uint32) [][32]byt
 	return hashSlice
 }
 
+func ReturnStateTransitionForBlockHash(statestash *StateStash, blockHash [32]byte) *StateTransition {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	for _,st := range statestash.M {
+		if(st.BlockHash == blockHash){
+			return st
+		}
+	}
+
+	return nil
+}
+
 func ReturnStateTransitionForPosition(stateStash *StateStash, position int) (stateHash [32]byte, stateTransition *StateTransition) {
 	stateMutex.Lock()
 	defer stateMutex.Unlock()
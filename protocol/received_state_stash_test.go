@@ -185,7 +185,7 @@ func TestStateTransitionHash(t *testing.T){
 	var stateTransition1 = NewStateTransition(nil,10,5,[32]byte{'0'},nil,nil,
 	nil,nil)
 
-	var stateTransition2 = NewStateTransition(nil,10,5,[32]byte{'1'},nil,nil,
+	var stateTransition2 = NewStateTransition(nil,10,5,[32]byte{'0'},nil,nil,
 		nil,nil)
 
 	hashST1 := stateTransition1.HashTransition()
@@ -195,4 +195,19 @@ func TestStateTransitionHash(t *testing.T){
 		t.Errorf("Error hashing state transitions - ST1: (%x) vs. ST2: (%x)",hashST1[0:8],hashST2[0:8])
 	}
 
-}
\ No newline at end of file
+	//The hash covers the whole content, not only the height and the shard
+	stateTransition2.BlockHash = [32]byte{'1'}
+	hashST2 = stateTransition2.HashTransition()
+
+	if reflect.DeepEqual(hashST1, hashST2){
+		t.Errorf("State transitions with different content have the same hash (%x)",hashST1[0:8])
+	}
+
+	stateTransition2.BlockHash = stateTransition1.BlockHash
+	stateTransition2.FundsTxData = [][32]byte{{'2'}}
+	hashST2 = stateTransition2.HashTransition()
+
+	if reflect.DeepEqual(hashST1, hashST2){
+		t.Errorf("State transitions with different content have the same hash (%x)",hashST1[0:8])
+	}
+}
@@ -1,8 +1,7 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
@@ -20,6 +19,9 @@ type StateTransition struct {
 	FundsTxData  				[][32]byte
 	ConfigTxData 				[][32]byte
 	StakeTxData  				[][32]byte
+	Validator					[64]byte							//Address of the validator which produced the block
+	CommitmentSig				[crypto.COMM_PROOF_LENGTH]byte	//Signature of the hash with the commitment key of the validator
+	ShardStateRoot				[32]byte							//Root of the accounts of the shard, only set at the last height of an epoch
 }
 
 /**
@@ -40,14 +42,14 @@ type RelativeAccount struct {
 func NewStateTransition(stateChange map[[64]byte]*RelativeAccount, height int, shardid int, blockHash [32]byte, contractData [][32]byte,
 	fundsData [][32]byte, configData [][32]byte, stakeData [][32]byte) *StateTransition {
 	newTransition := StateTransition{
-		stateChange,
-		height,
-		shardid,
-		blockHash,
-		contractData,
-		fundsData,
-		configData,
-		stakeData,
+		RelativeStateChange:	stateChange,
+		Height:					height,
+		ShardID:				shardid,
+		BlockHash:				blockHash,
+		ContractTxData:			contractData,
+		FundsTxData:			fundsData,
+		ConfigTxData:			configData,
+		StakeTxData:			stakeData,
 	}
 
 	return &newTransition
@@ -81,14 +83,31 @@ func (st *StateTransition) HashTransition() [32]byte {
 		return [32]byte{}
 	}
 
+	//The hash covers the whole content except the signature, such that the relative state changes cannot be altered
+	//without invalidating the signature.
 	stHash := struct {
-		Height				  			  int
-		ShardID							  int
+		RelativeStateChange			map[[64]byte]*RelativeAccount
+		Height						int
+		ShardID						int
+		BlockHash					[32]byte
+		ContractTxData				[][32]byte
+		FundsTxData					[][32]byte
+		ConfigTxData				[][32]byte
+		StakeTxData					[][32]byte
+		Validator					[64]byte
 	}{
+		st.RelativeStateChange,
 		st.Height,
 		st.ShardID,
+		st.BlockHash,
+		st.ContractTxData,
+		st.FundsTxData,
+		st.ConfigTxData,
+		st.StakeTxData,
+		st.Validator,
 	}
-	return SerializeHashContent(stHash)
+	//The root of the shard state extends the hash (see extendHash).
+	return extendHash(SerializeHashContent(stHash), st.ShardStateRoot != [32]byte{}, st.ShardStateRoot)
 }
 
 
@@ -100,63 +119,121 @@ func (acc *RelativeAccount) Hash() [32]byte {
 	return SerializeHashContent(acc.Address)
 }
 
+//RelativeStateChange | Height | ShardID | BlockHash | ContractTxData | FundsTxData | ConfigTxData | StakeTxData |
+//Validator | CommitmentSig | ShardStateRoot
+//The relative state change is encoded as a map of relative accounts (nested objects), sorted by address.
 func (st *StateTransition) EncodeTransition() []byte {
 	if st == nil {
 		return nil
 	}
 
-	encoded := StateTransition{
-		RelativeStateChange:		st.RelativeStateChange,
-		Height:						st.Height,
-		ShardID:					st.ShardID,
-		BlockHash:					st.BlockHash,
-		ContractTxData:				st.ContractTxData,
-		FundsTxData:				st.FundsTxData,
-		ConfigTxData:				st.ConfigTxData,
-		StakeTxData:				st.StakeTxData,
+	var addresses [][64]byte
+	for address := range st.RelativeStateChange {
+		addresses = append(addresses, address)
 	}
+	sortAddresses(addresses)
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	enc := newEncoder()
+	enc.putUint32(uint32(len(addresses)))
+	for _, address := range addresses {
+		enc.putBytes(st.RelativeStateChange[address].Encode())
+	}
+	enc.putInt64(int64(st.Height))
+	enc.putInt64(int64(st.ShardID))
+	enc.putFixed(st.BlockHash[:])
+	enc.putHashes(st.ContractTxData)
+	enc.putHashes(st.FundsTxData)
+	enc.putHashes(st.ConfigTxData)
+	enc.putHashes(st.StakeTxData)
+	enc.putFixed(st.Validator[:])
+	enc.putFixed(st.CommitmentSig[:])
+	enc.putFixed(st.ShardStateRoot[:])
+
+	return enc.bytes()
 }
 
-func (*StateTransition) DecodeTransition(encoded []byte) (st *StateTransition) {
-	var decoded StateTransition
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+func (*StateTransition) DecodeTransition(encoded []byte) (*StateTransition, error) {
+	if encoded == nil {
+		return nil, errors.New("StateTransition encoding is empty.")
+	}
+
+	st := new(StateTransition)
+	st.RelativeStateChange = make(map[[64]byte]*RelativeAccount)
+	dec := newDecoder(encoded, "StateTransition")
+	nrAccounts := dec.getLength(4)
+	for i := 0; i < nrAccounts && dec.err == nil; i++ {
+		var acc *RelativeAccount
+		acc, err := acc.Decode(dec.getBytes())
+		if err != nil {
+			return nil, err
+		}
+		st.RelativeStateChange[acc.Address] = acc
+	}
+	st.Height = int(dec.getInt64())
+	st.ShardID = int(dec.getInt64())
+	dec.getFixed(st.BlockHash[:])
+	st.ContractTxData = dec.getHashes()
+	st.FundsTxData = dec.getHashes()
+	st.ConfigTxData = dec.getHashes()
+	st.StakeTxData = dec.getHashes()
+	//Earlier state transitions are unsigned, they are rejected by the verification.
+	if dec.version >= 6 {
+		dec.getFixed(st.Validator[:])
+		dec.getFixed(st.CommitmentSig[:])
+	}
+	if dec.version >= 7 {
+		dec.getFixed(st.ShardStateRoot[:])
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return st, nil
 }
 
+//Address | Issuer | Balance | TxCnt | IsStaking | CommitmentKey | StakingBlockHeight | Contract | ContractVariables
 func (acc *RelativeAccount) Encode() []byte {
 	if acc == nil {
 		return nil
 	}
 
-	encoded := RelativeAccount{
-		Address:            acc.Address,
-		Issuer:             acc.Issuer,
-		Balance:            acc.Balance,
-		TxCnt:              acc.TxCnt,
-		IsStaking:          acc.IsStaking,
-		CommitmentKey:   	acc.CommitmentKey,
-		StakingBlockHeight: acc.StakingBlockHeight,
-		Contract:           acc.Contract,
-		ContractVariables:  acc.ContractVariables,
-	}
+	enc := newEncoder()
+	enc.putFixed(acc.Address[:])
+	enc.putFixed(acc.Issuer[:])
+	enc.putInt64(acc.Balance)
+	enc.putUint32(uint32(acc.TxCnt))
+	enc.putBool(acc.IsStaking)
+	enc.putFixed(acc.CommitmentKey[:])
+	enc.putUint32(uint32(acc.StakingBlockHeight))
+	enc.putBytes(acc.Contract)
+	enc.putByteArrays(acc.ContractVariables)
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	return enc.bytes()
 }
 
-func (*RelativeAccount) Decode(encoded []byte) (acc *RelativeAccount) {
-	var decoded RelativeAccount
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+func (*RelativeAccount) Decode(encoded []byte) (*RelativeAccount, error) {
+	if encoded == nil {
+		return nil, errors.New("RelativeAccount encoding is empty.")
+	}
+
+	acc := new(RelativeAccount)
+	dec := newDecoder(encoded, "RelativeAccount")
+	dec.getFixed(acc.Address[:])
+	dec.getFixed(acc.Issuer[:])
+	acc.Balance = dec.getInt64()
+	acc.TxCnt = int32(dec.getUint32())
+	acc.IsStaking = dec.getBool()
+	dec.getFixed(acc.CommitmentKey[:])
+	acc.StakingBlockHeight = int32(dec.getUint32())
+	acc.Contract = dec.getBytes()
+	acc.ContractVariables = dec.getByteArrays()
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return acc, nil
 }
 
 func (acc RelativeAccount) String() string {
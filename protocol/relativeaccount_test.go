@@ -50,9 +50,15 @@ func TestStateTransition(t *testing.T) {
 	var stateTransition = NewStateTransition(stateRelative,10,3,[32]byte{'0','1','2'},hashAccSlice,hashFundsSlice,
 		hashConfigSlice,hashStakeSlice)
 
+	stateTransition.Validator = [64]byte{'7'}
+	stateTransition.CommitmentSig = [crypto.COMM_PROOF_LENGTH]byte{'8'}
+
 	var compareTransition *StateTransition
 	encodedAcc := stateTransition.EncodeTransition()
-	compareTransition = compareTransition.DecodeTransition(encodedAcc)
+	compareTransition, err := compareTransition.DecodeTransition(encodedAcc)
+	if err != nil {
+		t.Fatalf("State Transition decoding failed: %v\n", err)
+	}
 
 	if !reflect.DeepEqual(stateTransition.Height, compareTransition.Height) {
 		t.Error("State Transition encoding/decoding failed: Height does not match!")
@@ -82,6 +88,14 @@ func TestStateTransition(t *testing.T) {
 		t.Error("State Transition encoding/decoding failed: StakeTX not serialized!")
 	}
 
+	if stateTransition.Validator != compareTransition.Validator || stateTransition.CommitmentSig != compareTransition.CommitmentSig {
+		t.Error("State Transition encoding/decoding failed: Signature not serialized!")
+	}
+
+	if stateTransition.HashTransition() != compareTransition.HashTransition() {
+		t.Error("State Transition encoding/decoding failed: Hash does not match!")
+	}
+
 	for k, _ := range stateTransition.RelativeStateChange {
 		if _, ok := compareTransition.RelativeStateChange[k]; !ok {
 			t.Errorf("account not existing in serialized state")
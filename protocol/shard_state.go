@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+/**
+	The shard state is the set of accounts of one shard at the height of an epoch block. In partitioned mode, validators
+	only hold the accounts of their shard and fetch the accounts of a newly assigned shard from the validators which held
+	them in the previous epoch. ShardID and NumberOfShards denote the shard of the previous epoch the accounts belong to,
+	they are checked against the root of this shard committed in the epoch block.
+ */
+type ShardState struct {
+	Height         int
+	ShardID        int
+	NumberOfShards int
+	Accounts       []*Account
+}
+
+func NewShardState(height int, shardID int, numberOfShards int, accounts []*Account) *ShardState {
+	return &ShardState{
+		Height:         height,
+		ShardID:        shardID,
+		NumberOfShards: numberOfShards,
+		Accounts:       accounts,
+	}
+}
+
+//The root the accounts are committed to by the validator of the shard, see StateTransition.ShardStateRoot.
+func (shardState *ShardState) Root() [32]byte {
+	state := make(map[[64]byte]*Account)
+	for _, acc := range shardState.Accounts {
+		state[acc.Address] = acc
+	}
+	return StateRoot(state)
+}
+
+//Height | ShardID | NumberOfShards | Accounts
+//The accounts are nested objects prefixed with the number of accounts.
+func (shardState *ShardState) Encode() []byte {
+	if shardState == nil {
+		return nil
+	}
+
+	enc := newEncoder()
+	enc.putInt64(int64(shardState.Height))
+	enc.putInt64(int64(shardState.ShardID))
+	enc.putInt64(int64(shardState.NumberOfShards))
+
+	enc.putUint32(uint32(len(shardState.Accounts)))
+	for _, acc := range shardState.Accounts {
+		enc.putBytes(acc.Encode())
+	}
+
+	return enc.bytes()
+}
+
+func (*ShardState) Decode(encoded []byte) (*ShardState, error) {
+	if encoded == nil {
+		return nil, errors.New("ShardState encoding is empty.")
+	}
+
+	var err error
+	shardState := new(ShardState)
+	dec := newDecoder(encoded, "ShardState")
+
+	shardState.Height = int(dec.getInt64())
+	shardState.ShardID = int(dec.getInt64())
+	shardState.NumberOfShards = int(dec.getInt64())
+
+	nrAccounts := dec.getLength(4)
+	for i := 0; i < nrAccounts && dec.err == nil; i++ {
+		var acc *Account
+		if acc, err = acc.Decode(dec.getBytes()); err != nil {
+			return nil, err
+		}
+		shardState.Accounts = append(shardState.Accounts, acc)
+	}
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return shardState, nil
+}
+
+func (shardState ShardState) String() string {
+	return fmt.Sprintf(
+		"\nHeight: %v\n"+
+			"Shard: %v\n"+
+			"Number of shards: %v\n"+
+			"Accounts: %v\n",
+		shardState.Height,
+		shardState.ShardID,
+		shardState.NumberOfShards,
+		len(shardState.Accounts),
+	)
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func TestShardStateSerialization(t *testing.T) {
+	shardState := NewShardState(20, 2, 3, []*Account{accA, accB})
+
+	var decodedShardState *ShardState
+	decodedShardState, err := decodedShardState.Decode(shardState.Encode())
+	if err != nil {
+		t.Fatalf("Shard state decoding failed: %v\n", err)
+	}
+
+	if decodedShardState.Height != 20 || decodedShardState.ShardID != 2 || decodedShardState.NumberOfShards != 3 {
+		t.Errorf("Shard state encoding/decoding failed: %v vs. %v\n", decodedShardState, shardState)
+	}
+
+	if len(decodedShardState.Accounts) != 2 || decodedShardState.Accounts[0].Address != accA.Address ||
+		decodedShardState.Accounts[1].Balance != accB.Balance {
+		t.Error("Shard state encoding/decoding failed: Accounts do not match!")
+	}
+
+	encodedShardState := shardState.Encode()
+	if _, err := decodedShardState.Decode(encodedShardState[:len(encodedShardState)-1]); err == nil {
+		t.Error("Truncated shard state has been decoded.")
+	}
+}
+
+func TestShardStateRoot(t *testing.T) {
+	accountA, accountB := *accA, *accB
+	accountA.Balance, accountB.Balance = 100, 200
+	shardState := NewShardState(20, 2, 3, []*Account{&accountA, &accountB})
+	state := map[[64]byte]*Account{accountA.Address: &accountA, accountB.Address: &accountB}
+	if shardState.Root() != StateRoot(state) {
+		t.Error("Root of the shard state does not match the state root of its accounts.")
+	}
+
+	shardState.Accounts = shardState.Accounts[:1]
+	if shardState.Root() == StateRoot(state) {
+		t.Error("Root of an incomplete shard state matches the root of the complete one.")
+	}
+}
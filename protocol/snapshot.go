@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/sha3"
+)
+
+/**
+	A snapshot lets a validator start from a closed epoch block instead of replaying the chain from the bootstrap node.
+	It carries the epoch block, which holds the complete state and the validator mapping, together with the genesis.
+	The checksum protects the snapshot against corrupted files, the epoch block itself is checked by the miner against
+	its hash and its state against the Merkle Patricia root before it is imported.
+ */
+type Snapshot struct {
+	Genesis    *Genesis
+	EpochBlock *EpochBlock
+}
+
+func NewSnapshot(genesis *Genesis, epochBlock *EpochBlock) *Snapshot {
+	return &Snapshot{
+		Genesis:    genesis,
+		EpochBlock: epochBlock,
+	}
+}
+
+//The SHA3-256 hash of the encoded genesis and epoch block.
+func (snapshot *Snapshot) Checksum() [32]byte {
+	return sha3.Sum256(append(snapshot.Genesis.Encode(), snapshot.EpochBlock.Encode()...))
+}
+
+//Genesis | EpochBlock | Checksum
+//The genesis and the epoch block are nested objects.
+func (snapshot *Snapshot) Encode() []byte {
+	if snapshot == nil {
+		return nil
+	}
+
+	checksum := snapshot.Checksum()
+
+	enc := newEncoder()
+	enc.putBytes(snapshot.Genesis.Encode())
+	enc.putBytes(snapshot.EpochBlock.Encode())
+	enc.putFixed(checksum[:])
+
+	return enc.bytes()
+}
+
+func (*Snapshot) Decode(encoded []byte) (*Snapshot, error) {
+	if encoded == nil {
+		return nil, errors.New("Snapshot encoding is empty.")
+	}
+
+	dec := newDecoder(encoded, "Snapshot")
+	encodedGenesis := dec.getBytes()
+	encodedEpochBlock := dec.getBytes()
+	var checksum [32]byte
+	dec.getFixed(checksum[:])
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	snapshot := new(Snapshot)
+	var err error
+	if snapshot.Genesis, err = snapshot.Genesis.Decode(encodedGenesis); err != nil {
+		return nil, err
+	}
+	if snapshot.EpochBlock, err = snapshot.EpochBlock.Decode(encodedEpochBlock); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Checksum() != checksum {
+		return nil, errors.New(fmt.Sprintf("Snapshot checksum does not match: %x", checksum[0:8]))
+	}
+
+	return snapshot, nil
+}
+
+func (snapshot *Snapshot) String() string {
+	return fmt.Sprintf(
+		"Epoch Block: %x\n"+
+		"Height: %d\n"+
+		"MerklePatriciaRoot: %x\n"+
+		"Accounts: %d\n"+
+		"Number of Shards: %d\n"+
+		"Genesis: %x\n",
+		snapshot.EpochBlock.Hash,
+		snapshot.EpochBlock.Height,
+		snapshot.EpochBlock.MerklePatriciaRoot[0:8],
+		len(snapshot.EpochBlock.State),
+		snapshot.EpochBlock.NofShards,
+		snapshot.Genesis.Hash(),
+	)
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotSerialization(t *testing.T) {
+	genesis := NewGenesis(accA.Address, accA.CommitmentKey)
+
+	epochBlock := NewEpochBlock([][32]byte{{'0', '1'}}, 100)
+	epochBlock.State = map[[64]byte]*Account{accA.Address: accA, accB.Address: accB}
+	epochBlock.MerklePatriciaRoot = StateRoot(epochBlock.State)
+	epochBlock.ValMapping = NewMapping()
+	epochBlock.ValMapping.ValMapping[accA.Address] = 1
+	epochBlock.ValMapping.EpochHeight = 100
+	epochBlock.NofShards = 1
+	epochBlock.Hash = epochBlock.HashEpochBlock()
+
+	snapshot := NewSnapshot(&genesis, epochBlock)
+
+	var compareSnapshot *Snapshot
+	encodedSnapshot := snapshot.Encode()
+	compareSnapshot, err := compareSnapshot.Decode(encodedSnapshot)
+	if err != nil {
+		t.Fatalf("Snapshot decoding failed: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(snapshot.Genesis, compareSnapshot.Genesis) ||
+		!reflect.DeepEqual(snapshot.EpochBlock.Encode(), compareSnapshot.EpochBlock.Encode()) {
+		t.Error("Snapshot encoding/decoding failed!")
+	}
+
+	//A corrupted snapshot is rejected.
+	encodedSnapshot[len(encodedSnapshot)/2] ^= 0xff
+	if _, err := compareSnapshot.Decode(encodedSnapshot); err == nil {
+		t.Error("Corrupted snapshot was decoded.")
+	}
+
+	if _, err := compareSnapshot.Decode(encodedSnapshot[:len(encodedSnapshot)-1]); err == nil {
+		t.Error("Truncated snapshot was decoded.")
+	}
+}
@@ -1,16 +1,14 @@
 package protocol
 
 import (
-	"crypto/ecdsa"
-	"crypto/rand"
 	"crypto/rsa"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 )
 
 const (
-	STAKETX_SIZE = 138 + crypto.COMM_KEY_LENGTH
+	STAKETX_SIZE = 147 + crypto.COMM_KEY_LENGTH
 )
 
 //when we broadcast transactions we need a way to distinguish with a type
@@ -22,9 +20,11 @@ type StakeTx struct {
 	Account       [64]byte              // 64 Byte
 	Sig           [64]byte              // 64 Byte
 	CommitmentKey [crypto.COMM_KEY_LENGTH]byte // the modulus N of the RSA public key
+	ValidFrom     uint32                // 4 Byte
+	ValidUntil    uint32                // 4 Byte
 }
 
-func ConstrStakeTx(header byte, fee uint64, isStaking bool, account [64]byte, signKey *ecdsa.PrivateKey, commPubKey *rsa.PublicKey) (tx *StakeTx, err error) {
+func ConstrStakeTx(header byte, fee uint64, isStaking bool, account [64]byte, signKey crypto.PrivateKey, commPubKey *rsa.PublicKey) (tx *StakeTx, err error) {
 
 	tx = new(StakeTx)
 
@@ -37,14 +37,10 @@ func ConstrStakeTx(header byte, fee uint64, isStaking bool, account [64]byte, si
 
 	txHash := tx.Hash()
 
-	r, s, err := ecdsa.Sign(rand.Reader, signKey, txHash[:])
-	if err != nil {
+	if tx.Sig, err = signTxHash(txHash, signKey); err != nil {
 		return nil, err
 	}
 
-	copy(tx.Sig[32-len(r.Bytes()):32], r.Bytes())
-	copy(tx.Sig[64-len(s.Bytes()):], s.Bytes())
-
 	return tx, nil
 }
 
@@ -68,66 +64,65 @@ func (tx *StakeTx) Hash() (hash [32]byte) {
 		tx.CommitmentKey,
 	}
 
-	return SerializeHashContent(txHash)
+	return hashWithValidity(SerializeHashContent(txHash), tx.ValidFrom, tx.ValidUntil)
+}
+
+//Restricts the block heights the tx can be included at and signs it again.
+func (tx *StakeTx) SetValidity(validFrom uint32, validUntil uint32, signKey crypto.PrivateKey) (err error) {
+	tx.ValidFrom, tx.ValidUntil = validFrom, validUntil
+	tx.Sig, err = signTxHash(tx.Hash(), signKey)
+	return err
 }
 
-//when we serialize the struct with binary.Write, unexported field get serialized as well, undesired
-//behavior. Therefore, writing own encoder/decoder
+//Header | Fee | IsStaking | Account | Sig | CommitmentKey | ValidFrom | ValidUntil
 func (tx *StakeTx) Encode() (encodedTx []byte) {
 	if tx == nil {
 		return nil
 	}
 
-	var fee [8]byte
-	var isStaking byte
-
-	binary.BigEndian.PutUint64(fee[:], tx.Fee)
-
-	if tx.IsStaking == true {
-		isStaking = 1
-	} else {
-		isStaking = 0
-	}
-
-	encodedTx = make([]byte, STAKETX_SIZE)
-
-	encodedTx[0] = tx.Header
-	copy(encodedTx[1:9], fee[:])
-	encodedTx[9] = isStaking
-	copy(encodedTx[10:74], tx.Account[:])
-	copy(encodedTx[74:138], tx.Sig[:])
-	copy(encodedTx[138:138+crypto.COMM_KEY_LENGTH], tx.CommitmentKey[:])
-
-	return encodedTx
+	enc := newEncoder()
+	enc.putByte(tx.Header)
+	enc.putUint64(tx.Fee)
+	enc.putBool(tx.IsStaking)
+	enc.putFixed(tx.Account[:])
+	enc.putFixed(tx.Sig[:])
+	enc.putFixed(tx.CommitmentKey[:])
+	enc.putUint32(tx.ValidFrom)
+	enc.putUint32(tx.ValidUntil)
+
+	return enc.bytes()
 }
 
-func (*StakeTx) Decode(encodedTx []byte) (tx *StakeTx) {
-	tx = new(StakeTx)
-
-	if len(encodedTx) != STAKETX_SIZE {
-		return nil
+func (*StakeTx) Decode(encodedTx []byte) (*StakeTx, error) {
+	//Encodings before version 3 have no validity window.
+	if len(encodedTx) != STAKETX_SIZE && len(encodedTx) != STAKETX_SIZE-VALIDITY_SIZE {
+		return nil, errors.New(fmt.Sprintf("StakeTx encoding has size %d, expected %d.", len(encodedTx), STAKETX_SIZE))
 	}
 
-	var isStakingAsByte byte
-
-	tx.Header = encodedTx[0]
-	tx.Fee = binary.BigEndian.Uint64(encodedTx[1:9])
-	isStakingAsByte = encodedTx[9]
-	copy(tx.Account[:], encodedTx[10:74])
-	copy(tx.Sig[:], encodedTx[74:138])
-	copy(tx.CommitmentKey[:], encodedTx[138:138+crypto.COMM_KEY_LENGTH])
+	tx := new(StakeTx)
+	dec := newDecoder(encodedTx, "StakeTx")
+	tx.Header = dec.getByte()
+	tx.Fee = dec.getUint64()
+	tx.IsStaking = dec.getBool()
+	dec.getFixed(tx.Account[:])
+	dec.getFixed(tx.Sig[:])
+	dec.getFixed(tx.CommitmentKey[:])
+	if dec.version >= 3 {
+		tx.ValidFrom = dec.getUint32()
+		tx.ValidUntil = dec.getUint32()
+	}
 
-	if isStakingAsByte == 0 {
-		tx.IsStaking = false
-	} else {
-		tx.IsStaking = true
+	if err := dec.finish(); err != nil {
+		return nil, err
 	}
 
-	return tx
+	return tx, nil
 }
 
 func (tx *StakeTx) TxFee() uint64 { return tx.Fee }
 func (tx *StakeTx) Size() uint64  { return STAKETX_SIZE }
+func (tx *StakeTx) Validity() (uint32, uint32) { return tx.ValidFrom, tx.ValidUntil }
+func (tx *StakeTx) Type() byte { return STAKETX_TYPE }
 
 func (tx StakeTx) String() string {
 	return fmt.Sprintf(
@@ -136,12 +131,15 @@ func (tx StakeTx) String() string {
 			"IsStaking: %v\n"+
 			"Account: %x\n"+
 			"Sig: %x\n"+
-			"CommitmentKey: %x\n",
+			"CommitmentKey: %x\n"+
+			"Valid: %v - %v\n",
 		tx.Header,
 		tx.Fee,
 		tx.IsStaking,
 		tx.Account[0:8],
 		tx.Sig[0:8],
 		tx.CommitmentKey[0:8],
+		tx.ValidFrom,
+		tx.ValidUntil,
 	)
 }
@@ -17,7 +17,10 @@ func TestStakeTxSerialization(t *testing.T) {
 		tx, _ := ConstrStakeTx(0x01, fee, isStaking, accA.Address, PrivKeyA, &CommitmentKeyA.PublicKey)
 		data := tx.Encode()
 		var decodedTx *StakeTx
-		decodedTx = decodedTx.Decode(data)
+		decodedTx, err := decodedTx.Decode(data)
+		if err != nil {
+			t.Fatalf("StakeTx decoding failed: %v\n", err)
+		}
 
 		//this is done by verify() which is outside protocol package, we're just testing serialization here
 		//decodedTx.Fee = fee
@@ -0,0 +1,646 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"golang.org/x/crypto/sha3"
+)
+
+//The state trie is a hexary Merkle Patricia trie over all accounts of the state. The path of an account is the
+//sha3 hash of its address (64 nibbles), the value stored in the leaf is the account itself. Every node is stored
+//under the hash of its encoding, so the root hash commits to the whole state and a path from the root to a leaf
+//proves the content of a single account. Nodes are never overwritten, which keeps tries of older heights readable
+//as long as their nodes are not deleted from the database.
+const (
+	TRIE_LEAF_NODE      = 0x00
+	TRIE_EXTENSION_NODE = 0x01
+	TRIE_BRANCH_NODE    = 0x02
+
+	TRIE_PATH_LEN = 64
+)
+
+//TrieNodeDatabase is the content-addressed node store the state trie is read from and written to.
+type TrieNodeDatabase interface {
+	GetTrieNode(hash [32]byte) []byte
+	PutTrieNode(hash [32]byte, node []byte)
+}
+
+//MemoryTrieDatabase keeps the trie nodes in memory, e.g. to compute a root without persisting it or to verify a proof.
+type MemoryTrieDatabase map[[32]byte][]byte
+
+func NewMemoryTrieDatabase() MemoryTrieDatabase {
+	return make(MemoryTrieDatabase)
+}
+
+func (db MemoryTrieDatabase) GetTrieNode(hash [32]byte) []byte {
+	return db[hash]
+}
+
+func (db MemoryTrieDatabase) PutTrieNode(hash [32]byte, node []byte) {
+	db[hash] = node
+}
+
+type trieEntry struct {
+	path  []byte
+	value []byte
+}
+
+//Accounts without balance, contract and multi-signature keys are removed after every block (see
+//deleteZeroBalanceAccounts in the miner). They are not part of the state commitment, no matter whether they have been
+//deleted yet or not.
+func isCommittedAccount(acc *Account) bool {
+	return acc != nil && (acc.Balance > 0 || acc.Contract != nil || acc.IsMultiSig())
+}
+
+//BuildStateTrie writes the trie of the given state to db and returns its root. The root of an empty state is the
+//zero hash. The trie is canonical, i.e., the same state always results in the same root.
+func BuildStateTrie(state map[[64]byte]*Account, db TrieNodeDatabase) [32]byte {
+	var entries []trieEntry
+	for _, acc := range state {
+		if !isCommittedAccount(acc) {
+			continue
+		}
+		entries = append(entries, trieEntry{trieKeyPath(acc.Address), encodeTrieAccount(acc)})
+	}
+
+	if len(entries) == 0 {
+		return [32]byte{}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].path, entries[j].path) < 0
+	})
+
+	return buildTrieNode(entries, 0, db)
+}
+
+//StateRoot calculates the root of the state trie without persisting any nodes.
+func StateRoot(state map[[64]byte]*Account) [32]byte {
+	return BuildStateTrie(state, NewMemoryTrieDatabase())
+}
+
+/**
+	StateTrie keeps the trie of a state in db together with the encoded accounts it commits to. The root of a changed
+	state is calculated by rebuilding the nodes on the paths of the changed accounts only, the result is the same as the
+	one of BuildStateTrie.
+ */
+type StateTrie struct {
+	db     TrieNodeDatabase
+	root   [32]byte
+	values map[[64]byte][]byte
+}
+
+func NewStateTrie(db TrieNodeDatabase) *StateTrie {
+	return &StateTrie{db: db, values: make(map[[64]byte][]byte)}
+}
+
+//Root updates the trie to the given state and returns its root. The new nodes are written to db. If a node of the trie
+//is missing in db, the trie is built from scratch.
+func (trie *StateTrie) Root(state map[[64]byte]*Account) [32]byte {
+	values := make(map[[64]byte][]byte)
+	var changes []trieEntry
+	for _, acc := range state {
+		if !isCommittedAccount(acc) {
+			continue
+		}
+		value := encodeTrieAccount(acc)
+		values[acc.Address] = value
+		if !bytes.Equal(trie.values[acc.Address], value) {
+			changes = append(changes, trieEntry{trieKeyPath(acc.Address), value})
+		}
+	}
+
+	//Removed accounts are changes without value.
+	for address := range trie.values {
+		if _, exists := values[address]; !exists {
+			changes = append(changes, trieEntry{trieKeyPath(address), nil})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].path, changes[j].path) < 0
+	})
+
+	root, err := updateTrieNode(trie.db, trie.root, 0, changes)
+	if err != nil {
+		root = BuildStateTrie(state, trie.db)
+	}
+
+	trie.root, trie.values = root, values
+	return root
+}
+
+//GetTrieAccount looks up the account with the given address in the trie with the given root.
+func GetTrieAccount(db TrieNodeDatabase, root [32]byte, address [64]byte) (*Account, error) {
+	value, _, err := lookupTrie(db, root, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTrieAccount(value)
+}
+
+//ProveAccount returns all nodes on the path from the root to the account. Together with the root, the proof is
+//sufficient to verify the account content without access to the state.
+func ProveAccount(db TrieNodeDatabase, root [32]byte, address [64]byte) (proof [][]byte, err error) {
+	_, proof, err = lookupTrie(db, root, address)
+	return proof, err
+}
+
+//VerifyAccountProof checks the proof against the root and returns the proven account.
+func VerifyAccountProof(root [32]byte, address [64]byte, proof [][]byte) (*Account, error) {
+	db := NewMemoryTrieDatabase()
+	for _, node := range proof {
+		db.PutTrieNode(sha3.Sum256(node), node)
+	}
+
+	return GetTrieAccount(db, root, address)
+}
+
+func trieKeyPath(address [64]byte) []byte {
+	key := sha3.Sum256(address[:])
+	path := make([]byte, TRIE_PATH_LEN)
+	for i, b := range key {
+		path[2*i] = b >> 4
+		path[2*i+1] = b & 0x0f
+	}
+
+	return path
+}
+
+//Entries are sorted by path and all of them share the first depth nibbles.
+func buildTrieNode(entries []trieEntry, depth int, db TrieNodeDatabase) [32]byte {
+	if len(entries) == 1 {
+		return putTrieNode(db, encodeTrieLeaf(entries[0].path[depth:], entries[0].value))
+	}
+
+	//Since the entries are sorted, the prefix shared by the first and the last entry is shared by all entries.
+	first, last := entries[0].path, entries[len(entries)-1].path
+	prefixLen := 0
+	for depth+prefixLen < TRIE_PATH_LEN && first[depth+prefixLen] == last[depth+prefixLen] {
+		prefixLen++
+	}
+
+	if prefixLen > 0 {
+		child := buildTrieNode(entries, depth+prefixLen, db)
+		return putTrieNode(db, encodeTrieExtension(first[depth:depth+prefixLen], child))
+	}
+
+	var children [16]*[32]byte
+	for start := 0; start < len(entries); {
+		nibble := entries[start].path[depth]
+		end := start
+		for end < len(entries) && entries[end].path[depth] == nibble {
+			end++
+		}
+
+		child := buildTrieNode(entries[start:end], depth+1, db)
+		children[nibble] = &child
+		start = end
+	}
+
+	return putTrieNode(db, encodeTrieBranch(children))
+}
+
+//Applies the changes (sorted by path, sharing the first depth nibbles) to the subtrie with the given hash. A branch keeps
+//its children which did not change, all other nodes are rebuilt with buildTrieNode() from the entries below them.
+func updateTrieNode(db TrieNodeDatabase, hash [32]byte, depth int, changes []trieEntry) ([32]byte, error) {
+	if len(changes) == 0 {
+		return hash, nil
+	}
+
+	if hash == [32]byte{} {
+		return buildTrieEntries(mergeTrieEntries(nil, changes), depth, db), nil
+	}
+
+	node := db.GetTrieNode(hash)
+	if node == nil || sha3.Sum256(node) != hash {
+		return hash, errors.New(fmt.Sprintf("Trie node %x missing or corrupted.", hash[0:8]))
+	}
+
+	if node[0] != TRIE_BRANCH_NODE {
+		entries, err := collectTrieEntries(db, hash, changes[0].path[:depth])
+		if err != nil {
+			return hash, err
+		}
+		return buildTrieEntries(mergeTrieEntries(entries, changes), depth, db), nil
+	}
+
+	children, err := decodeTrieBranch(node)
+	if err != nil {
+		return hash, err
+	}
+
+	for start := 0; start < len(changes); {
+		nibble := changes[start].path[depth]
+		end := start
+		for end < len(changes) && changes[end].path[depth] == nibble {
+			end++
+		}
+
+		var child [32]byte
+		if children[nibble] != nil {
+			child = *children[nibble]
+		}
+		if child, err = updateTrieNode(db, child, depth+1, changes[start:end]); err != nil {
+			return hash, err
+		}
+
+		if child == [32]byte{} {
+			children[nibble] = nil
+		} else {
+			children[nibble] = &child
+		}
+		start = end
+	}
+
+	var remaining []int
+	for i, child := range children {
+		if child != nil {
+			remaining = append(remaining, i)
+		}
+	}
+
+	switch len(remaining) {
+	case 0:
+		return [32]byte{}, nil
+	case 1:
+		//A branch has at least two children, the entries of a single one are merged into an extension or a leaf.
+		prefix := append(append([]byte{}, changes[0].path[:depth]...), byte(remaining[0]))
+		entries, err := collectTrieEntries(db, *children[remaining[0]], prefix)
+		if err != nil {
+			return hash, err
+		}
+		return buildTrieEntries(entries, depth, db), nil
+	}
+
+	return putTrieNode(db, encodeTrieBranch(children)), nil
+}
+
+//Returns the entries of the subtrie with the given hash sorted by path, prefix is the path leading to the subtrie.
+func collectTrieEntries(db TrieNodeDatabase, hash [32]byte, prefix []byte) ([]trieEntry, error) {
+	node := db.GetTrieNode(hash)
+	if node == nil || sha3.Sum256(node) != hash {
+		return nil, errors.New(fmt.Sprintf("Trie node %x missing or corrupted.", hash[0:8]))
+	}
+
+	switch node[0] {
+	case TRIE_LEAF_NODE:
+		path, value, err := decodeTrieLeaf(node)
+		if err != nil {
+			return nil, err
+		}
+		return []trieEntry{{append(append([]byte{}, prefix...), path...), value}}, nil
+	case TRIE_EXTENSION_NODE:
+		path, child, err := decodeTrieExtension(node)
+		if err != nil {
+			return nil, err
+		}
+		return collectTrieEntries(db, child, append(append([]byte{}, prefix...), path...))
+	case TRIE_BRANCH_NODE:
+		children, err := decodeTrieBranch(node)
+		if err != nil {
+			return nil, err
+		}
+		var entries []trieEntry
+		for i, child := range children {
+			if child == nil {
+				continue
+			}
+			childEntries, err := collectTrieEntries(db, *child, append(append([]byte{}, prefix...), byte(i)))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, childEntries...)
+		}
+		return entries, nil
+	default:
+		return nil, errors.New("Invalid trie node type.")
+	}
+}
+
+//Changes without value remove the entry with their path.
+func mergeTrieEntries(entries []trieEntry, changes []trieEntry) (merged []trieEntry) {
+	byPath := make(map[string]trieEntry)
+	for _, entry := range entries {
+		byPath[string(entry.path)] = entry
+	}
+	for _, change := range changes {
+		if change.value == nil {
+			delete(byPath, string(change.path))
+		} else {
+			byPath[string(change.path)] = change
+		}
+	}
+
+	for _, entry := range byPath {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return bytes.Compare(merged[i].path, merged[j].path) < 0
+	})
+	return merged
+}
+
+func buildTrieEntries(entries []trieEntry, depth int, db TrieNodeDatabase) [32]byte {
+	if len(entries) == 0 {
+		return [32]byte{}
+	}
+	return buildTrieNode(entries, depth, db)
+}
+
+//WalkTrie calls visit for the hash of every node of the trie with the given root. The children of a node are skipped
+//if visit returns false, e.g. because the node has been visited from another root before. Missing nodes are skipped.
+func WalkTrie(db TrieNodeDatabase, root [32]byte, visit func(hash [32]byte) bool) {
+	if root == [32]byte{} || !visit(root) {
+		return
+	}
+
+	node := db.GetTrieNode(root)
+	if len(node) == 0 {
+		return
+	}
+
+	switch node[0] {
+	case TRIE_EXTENSION_NODE:
+		if _, child, err := decodeTrieExtension(node); err == nil {
+			WalkTrie(db, child, visit)
+		}
+	case TRIE_BRANCH_NODE:
+		if children, err := decodeTrieBranch(node); err == nil {
+			for _, child := range children {
+				if child != nil {
+					WalkTrie(db, *child, visit)
+				}
+			}
+		}
+	}
+}
+
+func putTrieNode(db TrieNodeDatabase, node []byte) [32]byte {
+	hash := sha3.Sum256(node)
+	db.PutTrieNode(hash, node)
+	return hash
+}
+
+func lookupTrie(db TrieNodeDatabase, root [32]byte, address [64]byte) (value []byte, proof [][]byte, err error) {
+	if root == [32]byte{} {
+		return nil, nil, errors.New("Account not found in empty state trie.")
+	}
+
+	path := trieKeyPath(address)
+	hash := root
+	for {
+		node := db.GetTrieNode(hash)
+		if node == nil || sha3.Sum256(node) != hash {
+			return nil, nil, errors.New(fmt.Sprintf("Trie node %x missing or corrupted.", hash[0:8]))
+		}
+		proof = append(proof, node)
+
+		switch node[0] {
+		case TRIE_LEAF_NODE:
+			nodePath, leafValue, err := decodeTrieLeaf(node)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !bytes.Equal(nodePath, path) {
+				return nil, nil, errors.New(fmt.Sprintf("Account %x not found in state trie.", address[0:8]))
+			}
+			return leafValue, proof, nil
+		case TRIE_EXTENSION_NODE:
+			nodePath, child, err := decodeTrieExtension(node)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(nodePath) > len(path) || !bytes.Equal(nodePath, path[:len(nodePath)]) {
+				return nil, nil, errors.New(fmt.Sprintf("Account %x not found in state trie.", address[0:8]))
+			}
+			path = path[len(nodePath):]
+			hash = child
+		case TRIE_BRANCH_NODE:
+			children, err := decodeTrieBranch(node)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(path) == 0 || children[path[0]] == nil {
+				return nil, nil, errors.New(fmt.Sprintf("Account %x not found in state trie.", address[0:8]))
+			}
+			hash = *children[path[0]]
+			path = path[1:]
+		default:
+			return nil, nil, errors.New("Invalid trie node type.")
+		}
+	}
+}
+
+//Leaf: type (1 Byte) | path length (1 Byte) | path nibbles (1 Byte each) | value
+func encodeTrieLeaf(path []byte, value []byte) []byte {
+	node := []byte{TRIE_LEAF_NODE, byte(len(path))}
+	node = append(node, path...)
+	return append(node, value...)
+}
+
+func decodeTrieLeaf(node []byte) (path []byte, value []byte, err error) {
+	if len(node) < 2 || len(node) < 2+int(node[1]) {
+		return nil, nil, errors.New("Invalid trie leaf node.")
+	}
+
+	return node[2 : 2+int(node[1])], node[2+int(node[1]):], nil
+}
+
+//Extension: type (1 Byte) | path length (1 Byte) | path nibbles (1 Byte each) | child hash (32 Byte)
+func encodeTrieExtension(path []byte, child [32]byte) []byte {
+	node := []byte{TRIE_EXTENSION_NODE, byte(len(path))}
+	node = append(node, path...)
+	return append(node, child[:]...)
+}
+
+func decodeTrieExtension(node []byte) (path []byte, child [32]byte, err error) {
+	if len(node) < 2 || len(node) != 2+int(node[1])+32 {
+		return nil, child, errors.New("Invalid trie extension node.")
+	}
+
+	copy(child[:], node[2+int(node[1]):])
+	return node[2 : 2+int(node[1])], child, nil
+}
+
+//Branch: type (1 Byte) | bitmap of the present children (2 Byte) | child hashes (32 Byte each)
+func encodeTrieBranch(children [16]*[32]byte) []byte {
+	var bitmap uint16
+	var hashes []byte
+	for i, child := range children {
+		if child != nil {
+			bitmap |= 1 << uint(i)
+			hashes = append(hashes, child[:]...)
+		}
+	}
+
+	node := make([]byte, 3)
+	node[0] = TRIE_BRANCH_NODE
+	binary.BigEndian.PutUint16(node[1:3], bitmap)
+	return append(node, hashes...)
+}
+
+func decodeTrieBranch(node []byte) (children [16]*[32]byte, err error) {
+	if len(node) < 3 {
+		return children, errors.New("Invalid trie branch node.")
+	}
+
+	bitmap := binary.BigEndian.Uint16(node[1:3])
+	index := 3
+	for i := range children {
+		if bitmap&(1<<uint(i)) == 0 {
+			continue
+		}
+		if len(node) < index+32 {
+			return children, errors.New("Invalid trie branch node.")
+		}
+		var child [32]byte
+		copy(child[:], node[index:index+32])
+		children[i] = &child
+		index += 32
+	}
+
+	if index != len(node) {
+		return children, errors.New("Invalid trie branch node.")
+	}
+
+	return children, nil
+}
+
+//The trie value of an account has a fixed-size part followed by the length-prefixed contract and contract variables.
+//Multi-signature accounts additionally carry their threshold and key set.
+func encodeTrieAccount(acc *Account) []byte {
+	var buf bytes.Buffer
+	var num [8]byte
+
+	buf.Write(acc.Address[:])
+	buf.Write(acc.Issuer[:])
+	binary.BigEndian.PutUint64(num[:], acc.Balance)
+	buf.Write(num[:])
+	binary.BigEndian.PutUint32(num[:4], acc.TxCnt)
+	buf.Write(num[:4])
+	if acc.IsStaking {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	buf.Write(acc.CommitmentKey[:])
+	binary.BigEndian.PutUint32(num[:4], acc.StakingBlockHeight)
+	buf.Write(num[:4])
+
+	binary.BigEndian.PutUint32(num[:4], uint32(len(acc.Contract)))
+	buf.Write(num[:4])
+	buf.Write(acc.Contract)
+
+	binary.BigEndian.PutUint32(num[:4], uint32(len(acc.ContractVariables)))
+	buf.Write(num[:4])
+	for _, variable := range acc.ContractVariables {
+		binary.BigEndian.PutUint32(num[:4], uint32(len(variable)))
+		buf.Write(num[:4])
+		buf.Write(variable)
+	}
+
+	//Only multi-signature accounts carry their key set, the value of all other accounts stays unchanged.
+	if acc.IsMultiSig() {
+		binary.BigEndian.PutUint32(num[:4], acc.MultiSigThreshold)
+		buf.Write(num[:4])
+		binary.BigEndian.PutUint32(num[:4], uint32(len(acc.MultiSigKeys)))
+		buf.Write(num[:4])
+		for _, key := range acc.MultiSigKeys {
+			buf.Write(key[:])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func decodeTrieAccount(value []byte) (*Account, error) {
+	errInvalid := errors.New("Invalid account in state trie.")
+	fixedLen := 64 + 64 + 8 + 4 + 1 + crypto.COMM_KEY_LENGTH + 4
+	if len(value) < fixedLen+4 {
+		return nil, errInvalid
+	}
+
+	acc := new(Account)
+	index := 0
+	copy(acc.Address[:], value[index:index+64])
+	index += 64
+	copy(acc.Issuer[:], value[index:index+64])
+	index += 64
+	acc.Balance = binary.BigEndian.Uint64(value[index : index+8])
+	index += 8
+	acc.TxCnt = binary.BigEndian.Uint32(value[index : index+4])
+	index += 4
+	acc.IsStaking = value[index] == 1
+	index += 1
+	copy(acc.CommitmentKey[:], value[index:index+crypto.COMM_KEY_LENGTH])
+	index += crypto.COMM_KEY_LENGTH
+	acc.StakingBlockHeight = binary.BigEndian.Uint32(value[index : index+4])
+	index += 4
+
+	readBytes := func() ([]byte, bool) {
+		if len(value) < index+4 {
+			return nil, false
+		}
+		length := int(binary.BigEndian.Uint32(value[index : index+4]))
+		index += 4
+		if length < 0 || len(value) < index+length {
+			return nil, false
+		}
+		if length == 0 {
+			return nil, true
+		}
+		data := make([]byte, length)
+		copy(data, value[index:index+length])
+		index += length
+		return data, true
+	}
+
+	var ok bool
+	if acc.Contract, ok = readBytes(); !ok {
+		return nil, errInvalid
+	}
+
+	if len(value) < index+4 {
+		return nil, errInvalid
+	}
+	nrVariables := int(binary.BigEndian.Uint32(value[index : index+4]))
+	index += 4
+	for i := 0; i < nrVariables; i++ {
+		variable, ok := readBytes()
+		if !ok {
+			return nil, errInvalid
+		}
+		acc.ContractVariables = append(acc.ContractVariables, variable)
+	}
+
+	if index < len(value) {
+		if len(value) < index+8 {
+			return nil, errInvalid
+		}
+		acc.MultiSigThreshold = binary.BigEndian.Uint32(value[index : index+4])
+		nrKeys := int(binary.BigEndian.Uint32(value[index+4 : index+8]))
+		index += 8
+		if acc.MultiSigThreshold == 0 || nrKeys < 0 || len(value) != index+nrKeys*64 {
+			return nil, errInvalid
+		}
+		for i := 0; i < nrKeys; i++ {
+			var key [64]byte
+			copy(key[:], value[index:index+64])
+			acc.MultiSigKeys = append(acc.MultiSigKeys, key)
+			index += 64
+		}
+	}
+
+	if index != len(value) {
+		return nil, errInvalid
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,202 @@
+package protocol
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomTestState(size int) map[[64]byte]*Account {
+	state := make(map[[64]byte]*Account)
+	for i := 0; i < size; i++ {
+		var address [64]byte
+		rand.Read(address[:])
+		acc := NewAccount(address, [64]byte{}, uint64(rand.Intn(1000)+1), false, [256]byte{}, nil, nil)
+		acc.TxCnt = uint32(i)
+		state[address] = &acc
+	}
+
+	return state
+}
+
+func TestStateRootDeterminism(t *testing.T) {
+	state := randomTestState(100)
+
+	root := StateRoot(state)
+	if root == [32]byte{} {
+		t.Error("State root of a non-empty state must not be the zero hash.")
+	}
+
+	//Building the trie from a copy (which is iterated in a different order) must result in the same root.
+	stateCopy := make(map[[64]byte]*Account)
+	for address, acc := range state {
+		accCopy := *acc
+		stateCopy[address] = &accCopy
+	}
+
+	if root != StateRoot(stateCopy) {
+		t.Errorf("State roots of equal states differ: %x vs. %x\n", root, StateRoot(stateCopy))
+	}
+
+	//Any change of an account must change the root.
+	for _, acc := range stateCopy {
+		acc.Balance++
+		break
+	}
+
+	if root == StateRoot(stateCopy) {
+		t.Error("State root did not change after an account has been updated.")
+	}
+}
+
+func TestStateTrieUpdate(t *testing.T) {
+	db := NewMemoryTrieDatabase()
+	trie := NewStateTrie(db)
+	state := randomTestState(50)
+	if trie.Root(state) != StateRoot(state) {
+		t.Fatal("Root of the initial state does not match.")
+	}
+
+	//Accounts are changed, added and removed, down to one account and back, such that branches collapse.
+	for round := 0; round < 20; round++ {
+		for address, acc := range state {
+			switch rand.Intn(4) {
+			case 0:
+				delete(state, address)
+			case 1:
+				acc.Balance++
+			}
+		}
+		for address, acc := range randomTestState(rand.Intn(5)) {
+			state[address] = acc
+		}
+		if round == 10 {
+			for address := range state {
+				if len(state) > 1 {
+					delete(state, address)
+				}
+			}
+		}
+
+		if root := trie.Root(state); root != StateRoot(state) {
+			t.Fatalf("Root after update %d does not match: %x vs. %x\n", round, root, StateRoot(state))
+		}
+	}
+
+	//The accounts can be proven with the updated trie.
+	root := trie.Root(state)
+	for address := range state {
+		if _, err := GetTrieAccount(db, root, address); err != nil {
+			t.Errorf("Account of the updated trie not found: %v\n", err)
+		}
+	}
+
+	//Without the nodes of the previous trie, the trie is built from scratch.
+	for address := range state {
+		state[address].Balance++
+	}
+	if NewStateTrie(NewMemoryTrieDatabase()).Root(state) != StateRoot(state) {
+		t.Error("Root of a trie without nodes does not match.")
+	}
+	trie.db = NewMemoryTrieDatabase()
+	if trie.Root(state) != StateRoot(state) {
+		t.Error("Root of a trie with missing nodes does not match.")
+	}
+}
+
+func TestStateRootIgnoresEmptyAccounts(t *testing.T) {
+	state := randomTestState(10)
+	root := StateRoot(state)
+
+	var address [64]byte
+	rand.Read(address[:])
+	emptyAcc := NewAccount(address, [64]byte{}, 0, false, [256]byte{}, nil, nil)
+	state[address] = &emptyAcc
+
+	if root != StateRoot(state) {
+		t.Error("Empty account changed the state root.")
+	}
+
+	emptyAcc.MultiSigKeys, emptyAcc.MultiSigThreshold = [][64]byte{accA.Address, accB.Address}, 2
+	if root == StateRoot(state) {
+		t.Error("Multi-signature account without balance is not part of the state root.")
+	}
+
+	if StateRoot(make(map[[64]byte]*Account)) != [32]byte{} {
+		t.Error("State root of an empty state must be the zero hash.")
+	}
+}
+
+func TestStateTrieAccountProof(t *testing.T) {
+	state := randomTestState(50)
+
+	var address [64]byte
+	rand.Read(address[:])
+	acc := NewAccount(address, [64]byte{}, 500, true, [256]byte{1, 2, 3}, []byte{0x01, 0x02}, []ByteArray{{0x03}, {0x04, 0x05}})
+	acc.StakingBlockHeight = 42
+	state[address] = &acc
+
+	db := NewMemoryTrieDatabase()
+	root := BuildStateTrie(state, db)
+
+	trieAcc, err := GetTrieAccount(db, root, address)
+	if err != nil {
+		t.Fatalf("Account lookup failed: %v\n", err)
+	}
+	if !reflect.DeepEqual(*trieAcc, acc) {
+		t.Errorf("Account from trie does not match: %v vs. %v\n", trieAcc, acc)
+	}
+
+	proof, err := ProveAccount(db, root, address)
+	if err != nil {
+		t.Fatalf("Proof generation failed: %v\n", err)
+	}
+
+	provenAcc, err := VerifyAccountProof(root, address, proof)
+	if err != nil {
+		t.Fatalf("Proof verification failed: %v\n", err)
+	}
+	if !reflect.DeepEqual(*provenAcc, acc) {
+		t.Errorf("Proven account does not match: %v vs. %v\n", provenAcc, acc)
+	}
+
+	//A manipulated proof must not verify.
+	proof[len(proof)-1][len(proof[len(proof)-1])-1] ^= 0xff
+	if _, err := VerifyAccountProof(root, address, proof); err == nil {
+		t.Error("Manipulated proof has been verified.")
+	}
+
+	//Unknown accounts must not be found.
+	var unknown [64]byte
+	rand.Read(unknown[:])
+	if _, err := GetTrieAccount(db, root, unknown); err == nil {
+		t.Error("Unknown account found in state trie.")
+	}
+}
+
+func TestStateTrieMultiSigAccount(t *testing.T) {
+	state := randomTestState(10)
+
+	var address [64]byte
+	rand.Read(address[:])
+	acc := NewAccount(address, [64]byte{}, 500, false, [256]byte{}, nil, nil)
+	state[address] = &acc
+	singleSigRoot := BuildStateTrie(state, NewMemoryTrieDatabase())
+
+	acc.MultiSigKeys = [][64]byte{accA.Address, accB.Address}
+	acc.MultiSigThreshold = 2
+
+	db := NewMemoryTrieDatabase()
+	root := BuildStateTrie(state, db)
+	if root == singleSigRoot {
+		t.Error("Key set of a multi-signature account is not part of the state root.")
+	}
+
+	trieAcc, err := GetTrieAccount(db, root, address)
+	if err != nil {
+		t.Fatalf("Account lookup failed: %v\n", err)
+	}
+	if !reflect.DeepEqual(*trieAcc, acc) {
+		t.Errorf("Multi-signature account from trie does not match: %v vs. %v\n", trieAcc, acc)
+	}
+}
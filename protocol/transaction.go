@@ -1,5 +1,13 @@
 package protocol
 
+import (
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+)
+
+const (
+	VALIDITY_SIZE = 8 //ValidFrom | ValidUntil, part of the *TX_SIZE constants
+)
+
 type Transaction interface {
 	Hash() [32]byte
 	Encode() []byte
@@ -7,4 +15,29 @@ type Transaction interface {
 	//is apparently not allowed)
 	TxFee() uint64
 	Size() uint64
+	//Block heights from and until which the tx can be included in a block, 0 denotes no bound.
+	Validity() (validFrom uint32, validUntil uint32)
+	//Id of the registered tx type, see TxType.
+	Type() byte
+}
+
+//Returns whether the tx can be included in a block with the given height.
+func IsValidAt(tx Transaction, height uint32) bool {
+	validFrom, validUntil := tx.Validity()
+	return height >= validFrom && (validUntil == 0 || height <= validUntil)
+}
+
+//Returns whether the tx can no longer be included in any block after the given height.
+func IsExpired(tx Transaction, height uint32) bool {
+	_, validUntil := tx.Validity()
+	return validUntil != 0 && height >= validUntil
+}
+
+//The validity window is part of the signed hash, as an extension of the tx hash (see extendHash).
+func hashWithValidity(txHash [32]byte, validFrom uint32, validUntil uint32) [32]byte {
+	return extendHash(txHash, validFrom != 0 || validUntil != 0, validFrom, validUntil)
+}
+
+func signTxHash(txHash [32]byte, sigKey crypto.PrivateKey) (sig [64]byte, err error) {
+	return crypto.Sign(sigKey, txHash[:])
 }
@@ -0,0 +1,134 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+//The reasons a tx is rejected for. TXREJECT_UNKNOWN is given to txs whose reason is lost, e.g. after a restart.
+const (
+	TXREJECT_UNKNOWN = iota
+	TXREJECT_BAD_SIGNATURE
+	TXREJECT_NONCE_MISMATCH
+	TXREJECT_INSUFFICIENT_FUNDS
+	TXREJECT_BOUNDS_VIOLATION
+	TXREJECT_VM_ERROR
+	TXREJECT_FEE_TOO_LOW
+	TXREJECT_INVALID
+	TXREJECT_MEMPOOL
+)
+
+var rejectionNames = map[uint8]string{
+	TXREJECT_UNKNOWN:            "unknown",
+	TXREJECT_BAD_SIGNATURE:      "bad signature",
+	TXREJECT_NONCE_MISMATCH:     "nonce mismatch",
+	TXREJECT_INSUFFICIENT_FUNDS: "insufficient funds",
+	TXREJECT_BOUNDS_VIOLATION:   "bounds violation",
+	TXREJECT_VM_ERROR:           "vm error",
+	TXREJECT_FEE_TOO_LOW:        "fee too low",
+	TXREJECT_INVALID:            "invalid",
+	TXREJECT_MEMPOOL:            "not admitted to the mempool",
+}
+
+//The error a tx is rejected with by the validation.
+type TxRejection struct {
+	Code   uint8
+	Reason string
+}
+
+func NewTxRejection(code uint8, reason string) *TxRejection {
+	return &TxRejection{code, reason}
+}
+
+func (rejection *TxRejection) Error() string {
+	return rejection.Reason
+}
+
+//The rejection code of an error returned by the validation, TXREJECT_UNKNOWN for errors which are no *TxRejection.
+func RejectionCode(err error) uint8 {
+	if rejection, ok := err.(*TxRejection); ok {
+		return rejection.Code
+	}
+	return TXREJECT_UNKNOWN
+}
+
+func RejectionName(code uint8) string {
+	if name, exists := rejectionNames[code]; exists {
+		return name
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+//The stages of the lifecycle of a tx.
+const (
+	TXSTATUS_UNKNOWN = iota
+	TXSTATUS_PENDING
+	TXSTATUS_QUEUED
+	TXSTATUS_INCLUDED
+	TXSTATUS_REJECTED
+)
+
+/**
+	The status of a tx as seen by a miner, e.g. for wallets to tell their users why a payment did not go through. A
+	pending tx can be included in the next block, a queued one waits for a tx of its account with a lower nonce. Height
+	is only set for included txs, Code and Reason only for rejected ones.
+ */
+type TxStatus struct {
+	TxHash [32]byte
+	Status uint8
+	Height uint32
+	Code   uint8
+	Reason string
+}
+
+//TxHash | Status | Height | Code | Reason
+func (status *TxStatus) Encode() []byte {
+	if status == nil {
+		return nil
+	}
+
+	enc := newEncoder()
+	enc.putFixed(status.TxHash[:])
+	enc.putByte(status.Status)
+	enc.putUint32(status.Height)
+	enc.putByte(status.Code)
+	enc.putBytes([]byte(status.Reason))
+
+	return enc.bytes()
+}
+
+func (*TxStatus) Decode(encoded []byte) (*TxStatus, error) {
+	if encoded == nil {
+		return nil, errors.New("TxStatus encoding is empty.")
+	}
+
+	status := new(TxStatus)
+	dec := newDecoder(encoded, "TxStatus")
+
+	dec.getFixed(status.TxHash[:])
+	status.Status = dec.getByte()
+	status.Height = dec.getUint32()
+	status.Code = dec.getByte()
+	status.Reason = string(dec.getBytes())
+
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (status TxStatus) String() string {
+	switch status.Status {
+	case TXSTATUS_PENDING:
+		return fmt.Sprintf("Tx (%x) is pending", status.TxHash[0:8])
+	case TXSTATUS_QUEUED:
+		return fmt.Sprintf("Tx (%x) is queued", status.TxHash[0:8])
+	case TXSTATUS_INCLUDED:
+		return fmt.Sprintf("Tx (%x) is included at height %d", status.TxHash[0:8], status.Height)
+	case TXSTATUS_REJECTED:
+		return fmt.Sprintf("Tx (%x) is rejected (%v): %v", status.TxHash[0:8], RejectionName(status.Code), status.Reason)
+	default:
+		return fmt.Sprintf("Tx (%x) is unknown", status.TxHash[0:8])
+	}
+}
@@ -0,0 +1,37 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTxStatusSerialization(t *testing.T) {
+	status := &TxStatus{
+		TxHash: [32]byte{'0', 1},
+		Status: TXSTATUS_REJECTED,
+		Code:   TXREJECT_INSUFFICIENT_FUNDS,
+		Reason: "Not enough funds to complete the transaction!",
+	}
+
+	var decodedStatus *TxStatus
+	decodedStatus, err := decodedStatus.Decode(status.Encode())
+	if err != nil {
+		t.Fatalf("TxStatus decoding failed: %v\n", err)
+	}
+	if *decodedStatus != *status {
+		t.Errorf("TxStatus encoding/decoding failed: %v vs. %v\n", decodedStatus, status)
+	}
+
+	if _, err := decodedStatus.Decode(status.Encode()[:40]); err == nil {
+		t.Error("Truncated TxStatus was decoded.")
+	}
+}
+
+func TestRejectionCode(t *testing.T) {
+	if code := RejectionCode(NewTxRejection(TXREJECT_VM_ERROR, "Out of gas.")); code != TXREJECT_VM_ERROR {
+		t.Errorf("Wrong rejection code: %d\n", code)
+	}
+	if code := RejectionCode(errors.New("Untyped error.")); code != TXREJECT_UNKNOWN {
+		t.Errorf("Untyped error has rejection code %d\n", code)
+	}
+}
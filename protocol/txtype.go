@@ -0,0 +1,183 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Identifiers of the built-in tx types. They are equal to the message types the txs were broadcast with before the
+//registry existed, such that the messages stay compatible.
+const (
+	FUNDSTX_TYPE    = 1
+	CONTRACTTX_TYPE = 2
+	CONFIGTX_TYPE   = 3
+	STAKETX_TYPE    = 4
+)
+
+//The p2p messages the built-in tx types are broadcast, requested and sent with, package p2p takes its message types
+//for txs from here.
+const (
+	FUNDSTX_BRDCST    = 1
+	CONTRACTTX_BRDCST = 2
+	CONFIGTX_BRDCST   = 3
+	STAKETX_BRDCST    = 4
+
+	FUNDSTX_REQ    = 10
+	CONTRACTTX_REQ = 11
+	CONFIGTX_REQ   = 12
+	STAKETX_REQ    = 13
+
+	FUNDSTX_RES    = 20
+	CONTRACTTX_RES = 21
+	CONFIGTX_RES   = 22
+	STAKETX_RES    = 23
+)
+
+/**
+	A tx type declares everything the other packages need to know about a kind of transaction: how it is decoded, which
+	address decides its shard, the storage bucket of validated txs and the p2p messages it is broadcast, requested and
+	sent with. Blocks list the hashes of the txs under the id of their type. The verification and the state changes
+	depend on the state and are therefore hooked in by the miner. All code paths dispatch through the registry instead
+	of switching over the concrete types, adding a tx type only means registering it.
+ */
+type TxType struct {
+	Id   byte
+	Name string
+
+	Decode func(encoded []byte) (Transaction, error)
+	//Txs are processed by the shard of this address.
+	ShardKey func(tx Transaction) [64]byte
+	//The shard key is not an account (e.g., the signature of a ConfigTx), the txs are not counted against it.
+	NoAccount bool
+	//The txs with a nonce of an account are included in the order of increasing nonce, nil if the txs have none.
+	Nonce func(tx Transaction) uint32
+	//Returns the account paying the fee, false if the fee is created (e.g., txs signed by a root account).
+	FeePayer func(tx Transaction) ([64]byte, bool)
+	//The accounts in whose history the tx is listed, nil if the tx does not concern any account.
+	Addresses func(tx Transaction) [][64]byte
+
+	Bucket    string
+	BrdcstMsg uint8
+	ReqMsg    uint8
+	ResMsg    uint8
+
+	//Set by the miner. Verify and Add return a *TxRejection if the tx is invalid.
+	Verify   func(tx Transaction) error
+	Add      func(block *Block, tx Transaction) error
+	Apply    func(txs []Transaction, block *Block) error
+	Rollback func(txs []Transaction, block *Block)
+}
+
+var (
+	txTypes     = make(map[byte]*TxType)
+	txTypeOrder []*TxType
+)
+
+//Txs are processed in the order their types were registered. Types must be registered before any tx is processed,
+//e.g. in an init function.
+func RegisterTxType(txType *TxType) error {
+	if _, exists := txTypes[txType.Id]; exists {
+		return errors.New(fmt.Sprintf("Tx type %d is already registered.", txType.Id))
+	}
+
+	txTypes[txType.Id] = txType
+	txTypeOrder = append(txTypeOrder, txType)
+
+	return nil
+}
+
+func GetTxType(id byte) *TxType {
+	return txTypes[id]
+}
+
+func TxTypes() []*TxType {
+	return txTypeOrder
+}
+
+//Returns nil if the type of the tx is not registered.
+func TypeOf(tx Transaction) *TxType {
+	return txTypes[tx.Type()]
+}
+
+func init() {
+	RegisterTxType(&TxType{
+		Id:   CONTRACTTX_TYPE,
+		Name: "ContractTx",
+		Decode: func(encoded []byte) (Transaction, error) {
+			var tx *ContractTx
+			return decodedTx(tx.Decode(encoded))
+		},
+		ShardKey: func(tx Transaction) [64]byte { return tx.(*ContractTx).Issuer },
+		FeePayer: func(tx Transaction) ([64]byte, bool) { return [64]byte{}, false },
+		Addresses: func(tx Transaction) [][64]byte {
+			return [][64]byte{tx.(*ContractTx).Issuer, tx.(*ContractTx).PubKey}
+		},
+		Bucket:    "closedaccs",
+		BrdcstMsg: CONTRACTTX_BRDCST,
+		ReqMsg:    CONTRACTTX_REQ,
+		ResMsg:    CONTRACTTX_RES,
+	})
+
+	RegisterTxType(&TxType{
+		Id:   FUNDSTX_TYPE,
+		Name: "FundsTx",
+		Decode: func(encoded []byte) (Transaction, error) {
+			var tx *FundsTx
+			return decodedTx(tx.Decode(encoded))
+		},
+		ShardKey: func(tx Transaction) [64]byte { return tx.(*FundsTx).From },
+		Nonce:    func(tx Transaction) uint32 { return tx.(*FundsTx).TxCnt },
+		FeePayer: func(tx Transaction) ([64]byte, bool) { return tx.(*FundsTx).From, true },
+		Addresses: func(tx Transaction) (addresses [][64]byte) {
+			addresses = append(addresses, tx.(*FundsTx).From)
+			for _, recipient := range tx.(*FundsTx).Recipients() {
+				addresses = append(addresses, recipient.To)
+			}
+			return addresses
+		},
+		Bucket:    "closedfunds",
+		BrdcstMsg: FUNDSTX_BRDCST,
+		ReqMsg:    FUNDSTX_REQ,
+		ResMsg:    FUNDSTX_RES,
+	})
+
+	RegisterTxType(&TxType{
+		Id:   CONFIGTX_TYPE,
+		Name: "ConfigTx",
+		Decode: func(encoded []byte) (Transaction, error) {
+			var tx *ConfigTx
+			return decodedTx(tx.Decode(encoded))
+		},
+		ShardKey:  func(tx Transaction) [64]byte { return tx.(*ConfigTx).Sig },
+		NoAccount: true,
+		FeePayer:  func(tx Transaction) ([64]byte, bool) { return [64]byte{}, false },
+		Bucket:    "closedconfigs",
+		BrdcstMsg: CONFIGTX_BRDCST,
+		ReqMsg:    CONFIGTX_REQ,
+		ResMsg:    CONFIGTX_RES,
+	})
+
+	RegisterTxType(&TxType{
+		Id:   STAKETX_TYPE,
+		Name: "StakeTx",
+		Decode: func(encoded []byte) (Transaction, error) {
+			var tx *StakeTx
+			return decodedTx(tx.Decode(encoded))
+		},
+		ShardKey:  func(tx Transaction) [64]byte { return tx.(*StakeTx).Account },
+		FeePayer:  func(tx Transaction) ([64]byte, bool) { return tx.(*StakeTx).Account, true },
+		Addresses: func(tx Transaction) [][64]byte { return [][64]byte{tx.(*StakeTx).Account} },
+		Bucket:    "closedstakes",
+		BrdcstMsg: STAKETX_BRDCST,
+		ReqMsg:    STAKETX_REQ,
+		ResMsg:    STAKETX_RES,
+	})
+}
+
+//A typed nil pointer must not end up in the Transaction interface.
+func decodedTx(tx Transaction, err error) (Transaction, error) {
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
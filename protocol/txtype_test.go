@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTxTypeRegistry(t *testing.T) {
+	//Processing order of the built-in types.
+	expectedIds := []byte{CONTRACTTX_TYPE, FUNDSTX_TYPE, CONFIGTX_TYPE, STAKETX_TYPE}
+	for i, id := range expectedIds {
+		if TxTypes()[i].Id != id || GetTxType(id) != TxTypes()[i] {
+			t.Errorf("Tx type %d is not registered at position %d.\n", id, i)
+		}
+	}
+
+	if err := RegisterTxType(&TxType{Id: FUNDSTX_TYPE}); err == nil {
+		t.Error("Tx type could be registered twice.\n")
+	}
+
+	rand := rand.New(rand.NewSource(time.Now().Unix()))
+	tx, _ := ConstrFundsTx(0x01, rand.Uint64()%1000+1, rand.Uint64()%100+1, rand.Uint32(), accA.Address, accB.Address, PrivKeyA, nil)
+
+	txType := TypeOf(tx)
+	if txType != GetTxType(FUNDSTX_TYPE) {
+		t.Fatalf("FundsTx is of type %v.\n", txType.Name)
+	}
+
+	decodedTx, err := txType.Decode(tx.Encode())
+	if err != nil || decodedTx.Hash() != tx.Hash() {
+		t.Errorf("FundsTx could not be decoded by its type: %v\n", err)
+	}
+
+	if _, err := txType.Decode(tx.Encode()[:10]); err == nil {
+		t.Error("Truncated FundsTx could be decoded by its type.\n")
+	}
+
+	if txType.ShardKey(tx) != accA.Address || txType.Nonce(tx) != tx.TxCnt {
+		t.Error("FundsTx shard key or nonce is wrong.\n")
+	}
+
+	if payer, paysFee := txType.FeePayer(tx); !paysFee || payer != accA.Address {
+		t.Error("FundsTx fee is not paid by the sender.\n")
+	}
+
+	if addresses := txType.Addresses(tx); len(addresses) != 2 || addresses[0] != accA.Address || addresses[1] != accB.Address {
+		t.Errorf("FundsTx is not listed in the history of sender and receiver: %x\n", addresses)
+	}
+
+	block := new(Block)
+	block.AddTxHash(FUNDSTX_TYPE, tx.Hash())
+	if hashes := block.TxHashes(txType.Id); len(hashes) != 1 || hashes[0] != tx.Hash() {
+		t.Error("FundsTx hashes of the block are wrong.\n")
+	}
+}
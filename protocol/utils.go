@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"fmt"
 	"math"
 	"time"
 
@@ -11,9 +10,25 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-//Serializes the input and returns the sha3 hash function applied on ths input
+//Serializes the input canonically (see canonical.go) and returns the sha3 hash function applied on this input
 func SerializeHashContent(data interface{}) (hash [32]byte) {
-	return sha3.Sum256([]byte(fmt.Sprintf("%v", data)))
+	return sha3.Sum256(CanonicalEncoding(data))
+}
+
+//Fields added to a hashed data structure are not hashed together with the original fields. Instead, the hash of the
+//original fields is hashed again together with the new fields, but only if the new fields are used. Objects which do
+//not use them keep the hash they had before the fields were introduced, such that their signatures and the references
+//to them stay valid. The result equals the hash of a struct holding the original hash followed by the new fields.
+func extendHash(hash [32]byte, used bool, fields ...interface{}) [32]byte {
+	if !used {
+		return hash
+	}
+
+	content := CanonicalEncoding(hash)
+	for _, field := range fields {
+		content = append(content, CanonicalEncoding(field)...)
+	}
+	return sha3.Sum256(content)
 }
 
 func Encode(data [][]byte, sliceSize int) []byte {
@@ -1,8 +1,7 @@
 package protocol
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
 	"fmt"
 )
 
@@ -40,31 +39,50 @@ func (valMapping *ValShardMapping) GetSize() int {
 	return size
 }
 
+//ValMapping | EpochHeight
+//The mapping is encoded as (validator address | shard id (8 Byte)) entries, sorted by address.
 func (valMapping *ValShardMapping) Encode() []byte {
 	if valMapping == nil {
 		return nil
 	}
 
-	encoded := ValShardMapping{
-		ValMapping:                valMapping.ValMapping,
-		EpochHeight:			   valMapping.EpochHeight,
+	var addresses [][64]byte
+	for address := range valMapping.ValMapping {
+		addresses = append(addresses, address)
 	}
+	sortAddresses(addresses)
 
-	buffer := new(bytes.Buffer)
-	gob.NewEncoder(buffer).Encode(encoded)
-	return buffer.Bytes()
+	enc := newEncoder()
+	enc.putUint32(uint32(len(addresses)))
+	for _, address := range addresses {
+		enc.putFixed(address[:])
+		enc.putInt64(int64(valMapping.ValMapping[address]))
+	}
+	enc.putInt64(int64(valMapping.EpochHeight))
+
+	return enc.bytes()
 }
 
-func (valMapping *ValShardMapping) Decode(encoded []byte) (valMappingDecoded *ValShardMapping) {
+func (*ValShardMapping) Decode(encoded []byte) (*ValShardMapping, error) {
 	if encoded == nil {
-		return nil
+		return nil, errors.New("ValShardMapping encoding is empty.")
+	}
+
+	valMapping := NewMapping()
+	dec := newDecoder(encoded, "ValShardMapping")
+	nrEntries := dec.getLength(72)
+	for i := 0; i < nrEntries && dec.err == nil; i++ {
+		var address [64]byte
+		dec.getFixed(address[:])
+		valMapping.ValMapping[address] = int(dec.getInt64())
+	}
+	valMapping.EpochHeight = int(dec.getInt64())
+
+	if err := dec.finish(); err != nil {
+		return nil, err
 	}
 
-	var decoded ValShardMapping
-	buffer := bytes.NewBuffer(encoded)
-	decoder := gob.NewDecoder(buffer)
-	decoder.Decode(&decoded)
-	return &decoded
+	return valMapping, nil
 }
 
 func (valMapping ValShardMapping) String() string {
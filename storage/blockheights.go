@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//Heights are encoded big endian, such that the entries of the height buckets are ordered by height.
+func heightKey(height uint32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, height)
+	return key
+}
+
+func blockHeight(encoded []byte) (uint32, bool) {
+	var block *protocol.Block
+	block, err := block.Decode(encoded)
+	if err != nil {
+		return 0, false
+	}
+	return block.Height, true
+}
+
+func epochBlockHeight(encoded []byte) (uint32, bool) {
+	var epochBlock *protocol.EpochBlock
+	epochBlock, err := epochBlock.Decode(encoded)
+	if err != nil {
+		return 0, false
+	}
+	return epochBlock.Height, true
+}
+
+//The block and its height are written in one transaction, the block replaces an earlier one at the same height.
+func (store *BoltStore) writeIndexedBlock(bucket string, heightBucket string, hash [32]byte, height uint32, encoded []byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket([]byte(bucket)).Put(hash[:], encoded); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(heightBucket)).Put(heightKey(height), hash[:])
+	})
+}
+
+//The height is only removed from the index if it still refers to the deleted block.
+func (store *BoltStore) deleteIndexedBlock(bucket string, heightBucket string, hash [32]byte, decodeHeight func(encoded []byte) (uint32, bool)) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if encoded := b.Get(hash[:]); encoded != nil {
+			if height, ok := decodeHeight(encoded); ok {
+				heights := tx.Bucket([]byte(heightBucket))
+				if indexed := heights.Get(heightKey(height)); bytes.Equal(indexed, hash[:]) {
+					if err := heights.Delete(heightKey(height)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return b.Delete(hash[:])
+	})
+}
+
+func (store *BoltStore) readHashByHeight(heightBucket string, height uint32) (hash [32]byte, found bool) {
+	store.db.View(func(tx *bolt.Tx) error {
+		if indexed := tx.Bucket([]byte(heightBucket)).Get(heightKey(height)); indexed != nil {
+			copy(hash[:], indexed)
+			found = true
+		}
+		return nil
+	})
+	return hash, found
+}
+
+//The hashes are collected first, such that fn is free to write to the store.
+func (store *BoltStore) readHashesByHeight(heightBucket string, from uint32, to uint32) (hashes [][32]byte) {
+	low, high := from, to
+	if from > to {
+		low, high = to, from
+	}
+
+	store.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(heightBucket)).Cursor()
+		for k, v := c.Seek(heightKey(low)); k != nil && binary.BigEndian.Uint32(k) <= high; k, v = c.Next() {
+			var hash [32]byte
+			copy(hash[:], v)
+			hashes = append(hashes, hash)
+		}
+		return nil
+	})
+
+	if from > to {
+		for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+			hashes[i], hashes[j] = hashes[j], hashes[i]
+		}
+	}
+	return hashes
+}
+
+func (store *BoltStore) ReadClosedBlockByHeight(height uint32) *protocol.Block {
+	if hash, found := store.readHashByHeight(BLOCKHEIGHTS_BUCKET, height); found {
+		return store.ReadClosedBlock(hash)
+	}
+	return nil
+}
+
+func (store *BoltStore) ReadClosedEpochBlockByHeight(height uint32) *protocol.EpochBlock {
+	if hash, found := store.readHashByHeight(EPOCHBLOCKHEIGHTS_BUCKET, height); found {
+		return store.ReadClosedEpochBlock(hash)
+	}
+	return nil
+}
+
+func (store *BoltStore) ForEachClosedBlock(from uint32, to uint32, fn func(block *protocol.Block) bool) {
+	for _, hash := range store.readHashesByHeight(BLOCKHEIGHTS_BUCKET, from, to) {
+		if block := store.ReadClosedBlock(hash); block != nil && !fn(block) {
+			return
+		}
+	}
+}
+
+func (store *BoltStore) ForEachClosedEpochBlock(from uint32, to uint32, fn func(epochBlock *protocol.EpochBlock) bool) {
+	for _, hash := range store.readHashesByHeight(EPOCHBLOCKHEIGHTS_BUCKET, from, to) {
+		if epochBlock := store.ReadClosedEpochBlock(hash); epochBlock != nil && !fn(epochBlock) {
+			return
+		}
+	}
+}
@@ -1,52 +1,60 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
 
-//There exist open/closed buckets and closed tx buckets for all types (open txs are in volatile storage)
-func DeleteOpenBlock(hash [32]byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
+//There exist open/closed buckets and closed tx buckets for all types (open txs are held in memory and journaled)
+func (store *BoltStore) DeleteOpenBlock(hash [32]byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENBLOCKS_BUCKET))
 		return b.Delete(hash[:])
 	})
 }
 
-func DeleteClosedBlock(hash [32]byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET))
-		return b.Delete(hash[:])
-	})
+func (store *BoltStore) DeleteClosedBlock(hash [32]byte) error {
+	return store.deleteIndexedBlock(CLOSEDBLOCKS_BUCKET, BLOCKHEIGHTS_BUCKET, hash, blockHeight)
 }
 
-func DeleteClosedEpochBlock(hash [32]byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
-		return b.Delete(hash[:])
+func (store *BoltStore) DeleteReceipt(txHash [32]byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RECEIPTS_BUCKET))
+		var receipt *protocol.Receipt
+		if encodedReceipt := b.Get(txHash[:]); encodedReceipt != nil {
+			receipt, _ = receipt.Decode(encodedReceipt)
+		}
+		if err := b.Delete(txHash[:]); err != nil || receipt == nil {
+			return err
+		}
+
+		return deleteAccountTxs(tx.Bucket([]byte(ACCOUNTTXS_BUCKET)), receiptAddresses(receipt), txHash)
 	})
 }
 
-func DeleteOpenEpochBlock(hash [32]byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) DeleteClosedEpochBlock(hash [32]byte) error {
+	return store.deleteIndexedBlock(CLOSEDEPOCHBLOCK_BUCKET, EPOCHBLOCKHEIGHTS_BUCKET, hash, epochBlockHeight)
+}
+
+func (store *BoltStore) DeleteOpenEpochBlock(hash [32]byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENEPOCHBLOCK_BUCKET))
 		return b.Delete(hash[:])
 	})
 }
 
-func DeleteINVALIDOpenTx(transaction protocol.Transaction) {
-	delete(txINVALIDMemPool, transaction.Hash())
-}
-
-func DeleteLastClosedBlock(hash [32]byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) DeleteLastClosedBlock(hash [32]byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDBLOCK_BUCKET))
 		return b.Delete(hash[:])
 	})
 }
 
-func DeleteAllLastClosedBlock() error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) DeleteAllLastClosedBlock() error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDBLOCK_BUCKET))
 		return b.ForEach(func(k, v []byte) error {
 			return b.Delete(k)
@@ -54,8 +62,8 @@ func DeleteAllLastClosedBlock() error {
 	})
 }
 
-func DeleteAllLastClosedEpochBlock() error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) DeleteAllLastClosedEpochBlock() error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDEPOCHBLOCK_BUCKET))
 		return b.ForEach(func(k, v []byte) error {
 			return b.Delete(k)
@@ -63,45 +71,52 @@ func DeleteAllLastClosedEpochBlock() error {
 	})
 }
 
-func DeleteOpenTx(transaction protocol.Transaction) {
-	memPoolMutex.Lock()
-	defer memPoolMutex.Unlock()
-	delete(txMemPool, transaction.Hash())
-}
-
-func DeleteClosedTx(transaction protocol.Transaction) error {
-	var bucket string
-	switch transaction.(type) {
-	case *protocol.FundsTx:
-		bucket = CLOSEDFUNDS_BUCKET
-	case *protocol.ContractTx:
-		bucket = CLOSEDACCS_BUCKET
-	case *protocol.ConfigTx:
-		bucket = CLOSEDCONFIGS_BUCKET
-	case *protocol.StakeTx:
-		bucket = CLOSEDSTAKES_BUCKET
+//The entries of the tx are removed from the account histories as well, e.g. if its block is rolled back.
+func (store *BoltStore) DeleteClosedTx(transaction protocol.Transaction) error {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil {
+		return errors.New(fmt.Sprintf("Transaction type %d is not registered.", transaction.Type()))
 	}
+	bucket := txType.Bucket
 
 	hash := transaction.Hash()
-	return db.Update(func(tx *bolt.Tx) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
-		return b.Delete(hash[:])
+		if err := b.Delete(hash[:]); err != nil {
+			return err
+		}
+
+		return deleteAccountTxs(tx.Bucket([]byte(ACCOUNTTXS_BUCKET)), txAddresses(transaction), hash)
 	})
 }
 
-func DeleteAccount(address [64]byte) {
-	delete(State, address)
+//The height of the entries is not known, the histories of the accounts are searched for the tx.
+func deleteAccountTxs(index *bolt.Bucket, addresses [][64]byte, hash [32]byte) error {
+	var keys [][]byte
+	for _, address := range addresses {
+		c := index.Cursor()
+		for k, _ := c.Seek(address[:]); k != nil && bytes.HasPrefix(k, address[:]); k, _ = c.Next() {
+			if bytes.Equal(k[ACCOUNTTX_KEY_LEN-32:], hash[:]) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+		}
+	}
+
+	for _, key := range keys {
+		if err := index.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func DeleteAll() (err error) {
+func (store *BoltStore) DeleteAll() (err error) {
 	//Delete in-memory storage
-	for key := range txMemPool {
-		delete(txMemPool, key)
-	}
+	store.memPool.clear()
 
 	//Delete disk-based storage
-	for _, bucket := range Buckets {
-		err = clearBucket(bucket)
+	for _, bucket := range store.buckets {
+		err = store.clearBucket(bucket)
 		if err != nil {
 			return err
 		}
@@ -110,8 +125,8 @@ func DeleteAll() (err error) {
 	return nil
 }
 
-func clearBucket(bucketName string) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) clearBucket(bucketName string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketName))
 		return b.ForEach(func(k, v []byte) error {
 			return b.Delete(k)
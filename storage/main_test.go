@@ -44,24 +44,30 @@ var (
 	PubKeyA, PubKeyB ecdsa.PublicKey
 	CommitmentKeyA 	*rsa.PrivateKey
 	RootPrivKey ecdsa.PrivateKey
+	boltStore *BoltStore
 )
 
 func TestMain(m *testing.M) {
 
-	Init(TestDBFileName, TestIpPort)
+	boltStore, _ = Init(TestDBFileName, TestIpPort)
 
-	DeleteAll()
+	boltStore.DeleteAll()
 	addTestingAccounts()
 	addRootAccounts()
 	//we don't want logging msgs when testing, designated messages
 	log.SetOutput(ioutil.Discard)
 	retCode := m.Run()
 
-	TearDown()
+	boltStore.Close()
 	os.Remove(TestDBFileName)
 	os.Exit(retCode)
 }
 
+//The behaviour shared by all stores is tested against each of them.
+func testStores() []Store {
+	return []Store{boltStore, NewMemoryStore()}
+}
+
 func addTestingAccounts() {
 
 	accA, accB, minerAcc = new(protocol.Account), new(protocol.Account), new(protocol.Account)
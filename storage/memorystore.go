@@ -0,0 +1,543 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"sort"
+	"sync"
+)
+
+/**
+	MemoryStore keeps the chain in memory, nothing survives the process. Entries are kept encoded and decoded on every
+	read like in the BoltStore, such that callers never share the objects they read.
+ */
+type MemoryStore struct {
+	stateAccounts
+	*memPool
+	mutex sync.Mutex
+
+	openBlocks            map[[32]byte][]byte
+	closedBlocks          map[[32]byte][]byte
+	lastClosedBlocks      map[[32]byte][]byte
+	blockHeights          map[uint32][32]byte
+	prunedBlocks          map[[32]byte]uint32
+	pruneHeight           uint32
+	openEpochBlocks       map[[32]byte][]byte
+	closedEpochBlocks     map[[32]byte][]byte
+	lastClosedEpochBlocks map[[32]byte][]byte
+	epochBlockHeights     map[uint32][32]byte
+	firstEpochBlock       []byte
+	closedTxs             map[[32]byte][]byte
+	accountTxs            map[[64]byte]map[[32]byte]uint32
+	receipts              map[[32]byte][]byte
+	fraudProofs           map[[32]byte][]byte
+	stateTrie             protocol.MemoryTrieDatabase
+	genesis               []byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{memPool: newMemPool(nil)}
+	store.reset()
+	return store
+}
+
+func (store *MemoryStore) reset() {
+	store.openBlocks = make(map[[32]byte][]byte)
+	store.closedBlocks = make(map[[32]byte][]byte)
+	store.lastClosedBlocks = make(map[[32]byte][]byte)
+	store.blockHeights = make(map[uint32][32]byte)
+	store.prunedBlocks = make(map[[32]byte]uint32)
+	store.pruneHeight = 0
+	store.openEpochBlocks = make(map[[32]byte][]byte)
+	store.closedEpochBlocks = make(map[[32]byte][]byte)
+	store.lastClosedEpochBlocks = make(map[[32]byte][]byte)
+	store.epochBlockHeights = make(map[uint32][32]byte)
+	store.firstEpochBlock = nil
+	store.closedTxs = make(map[[32]byte][]byte)
+	store.accountTxs = make(map[[64]byte]map[[32]byte]uint32)
+	store.receipts = make(map[[32]byte][]byte)
+	store.fraudProofs = make(map[[32]byte][]byte)
+	store.stateTrie = protocol.NewMemoryTrieDatabase()
+	store.genesis = nil
+}
+
+func (store *MemoryStore) read(entries map[[32]byte][]byte, hash [32]byte) []byte {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return entries[hash]
+}
+
+func (store *MemoryStore) write(entries map[[32]byte][]byte, hash [32]byte, encoded []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	entries[hash] = encoded
+	return nil
+}
+
+func (store *MemoryStore) delete(entries map[[32]byte][]byte, hash [32]byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(entries, hash)
+	return nil
+}
+
+func (store *MemoryStore) deleteAll(entries map[[32]byte][]byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for hash := range entries {
+		delete(entries, hash)
+	}
+	return nil
+}
+
+//The block replaces an earlier one at the same height in the index, like in the BoltStore.
+func (store *MemoryStore) writeIndexed(entries map[[32]byte][]byte, heights map[uint32][32]byte, hash [32]byte, height uint32, encoded []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	entries[hash] = encoded
+	heights[height] = hash
+	return nil
+}
+
+func (store *MemoryStore) deleteIndexed(entries map[[32]byte][]byte, heights map[uint32][32]byte, hash [32]byte, decodeHeight func(encoded []byte) (uint32, bool)) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if encoded := entries[hash]; encoded != nil {
+		if height, ok := decodeHeight(encoded); ok && heights[height] == hash {
+			delete(heights, height)
+		}
+	}
+	delete(entries, hash)
+	return nil
+}
+
+func (store *MemoryStore) readHashByHeight(heights map[uint32][32]byte, height uint32) (hash [32]byte, found bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	hash, found = heights[height]
+	return hash, found
+}
+
+func (store *MemoryStore) readHashesByHeight(heights map[uint32][32]byte, from uint32, to uint32) (hashes [][32]byte) {
+	low, high := from, to
+	if from > to {
+		low, high = to, from
+	}
+
+	store.mutex.Lock()
+	var inRange []uint32
+	for height := range heights {
+		if height >= low && height <= high {
+			inRange = append(inRange, height)
+		}
+	}
+	sort.Slice(inRange, func(i, j int) bool {
+		return (inRange[i] < inRange[j]) == (from <= to)
+	})
+	for _, height := range inRange {
+		hashes = append(hashes, heights[height])
+	}
+	store.mutex.Unlock()
+
+	return hashes
+}
+
+//Returns the entry with the lowest hash, like the cursor of a bolt bucket.
+func (store *MemoryStore) first(entries map[[32]byte][]byte) (encoded []byte) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var firstHash [32]byte
+	for hash, entry := range entries {
+		if encoded == nil || bytes.Compare(hash[:], firstHash[:]) < 0 {
+			firstHash, encoded = hash, entry
+		}
+	}
+	return encoded
+}
+
+func decodeBlock(encoded []byte) (block *protocol.Block) {
+	if encoded == nil {
+		return nil
+	}
+	block, _ = block.Decode(encoded)
+	return block
+}
+
+func decodeEpochBlock(encoded []byte) (epochBlock *protocol.EpochBlock) {
+	if encoded == nil {
+		return nil
+	}
+	epochBlock, _ = epochBlock.Decode(encoded)
+	return epochBlock
+}
+
+func (store *MemoryStore) ReadOpenBlock(hash [32]byte) *protocol.Block {
+	return decodeBlock(store.read(store.openBlocks, hash))
+}
+
+func (store *MemoryStore) ReadClosedBlock(hash [32]byte) *protocol.Block {
+	return decodeBlock(store.read(store.closedBlocks, hash))
+}
+
+func (store *MemoryStore) ReadLastClosedBlock() *protocol.Block {
+	return decodeBlock(store.first(store.lastClosedBlocks))
+}
+
+func (store *MemoryStore) WriteOpenBlock(block *protocol.Block) error {
+	return store.write(store.openBlocks, block.Hash, block.Encode())
+}
+
+func (store *MemoryStore) WriteClosedBlock(block *protocol.Block) error {
+	return store.writeIndexed(store.closedBlocks, store.blockHeights, block.Hash, block.Height, block.Encode())
+}
+
+func (store *MemoryStore) WriteLastClosedBlock(block *protocol.Block) error {
+	return store.write(store.lastClosedBlocks, block.Hash, block.Encode())
+}
+
+func (store *MemoryStore) DeleteOpenBlock(hash [32]byte) error {
+	return store.delete(store.openBlocks, hash)
+}
+
+func (store *MemoryStore) DeleteClosedBlock(hash [32]byte) error {
+	return store.deleteIndexed(store.closedBlocks, store.blockHeights, hash, blockHeight)
+}
+
+func (store *MemoryStore) ReadClosedBlockByHeight(height uint32) *protocol.Block {
+	if hash, found := store.readHashByHeight(store.blockHeights, height); found {
+		return store.ReadClosedBlock(hash)
+	}
+	return nil
+}
+
+func (store *MemoryStore) ForEachClosedBlock(from uint32, to uint32, fn func(block *protocol.Block) bool) {
+	for _, hash := range store.readHashesByHeight(store.blockHeights, from, to) {
+		if block := store.ReadClosedBlock(hash); block != nil && !fn(block) {
+			return
+		}
+	}
+}
+
+func (store *MemoryStore) DeleteLastClosedBlock(hash [32]byte) error {
+	return store.delete(store.lastClosedBlocks, hash)
+}
+
+func (store *MemoryStore) DeleteAllLastClosedBlock() error {
+	return store.deleteAll(store.lastClosedBlocks)
+}
+
+func (store *MemoryStore) ReadOpenEpochBlock(hash [32]byte) *protocol.EpochBlock {
+	return decodeEpochBlock(store.read(store.openEpochBlocks, hash))
+}
+
+func (store *MemoryStore) ReadClosedEpochBlock(hash [32]byte) *protocol.EpochBlock {
+	return decodeEpochBlock(store.read(store.closedEpochBlocks, hash))
+}
+
+func (store *MemoryStore) ReadLastClosedEpochBlock() *protocol.EpochBlock {
+	return decodeEpochBlock(store.first(store.lastClosedEpochBlocks))
+}
+
+func (store *MemoryStore) ReadFirstEpochBlock() (firstEpochBlock *protocol.EpochBlock, err error) {
+	store.mutex.Lock()
+	encoded := store.firstEpochBlock
+	store.mutex.Unlock()
+
+	if encoded == nil {
+		return nil, nil
+	}
+	return firstEpochBlock.Decode(encoded)
+}
+
+func (store *MemoryStore) WriteOpenEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.write(store.openEpochBlocks, epochBlock.Hash, epochBlock.Encode())
+}
+
+func (store *MemoryStore) PruneClosedBlocks(height uint32) (pruned int, err error) {
+	pruneHeight := store.ReadPruneHeight()
+	if height <= pruneHeight {
+		return 0, nil
+	}
+
+	var blocks []*protocol.Block
+	var prunedRoots [][32]byte
+	for _, hash := range store.readHashesByHeight(store.blockHeights, pruneHeight, height-1) {
+		if block := store.ReadClosedBlock(hash); block != nil {
+			blocks = append(blocks, block)
+			prunedRoots = append(prunedRoots, block.MerklePatriciaRoot)
+		}
+	}
+
+	keptRoots := keptStateRoots(store, height)
+	store.mutex.Lock()
+	for _, hash := range prunableTrieNodes(store.stateTrie, prunedRoots, keptRoots) {
+		delete(store.stateTrie, hash)
+	}
+	store.mutex.Unlock()
+
+	for _, block := range blocks {
+		deleteBlockTxs(store, block)
+
+		store.mutex.Lock()
+		delete(store.closedBlocks, block.Hash)
+		delete(store.blockHeights, block.Height)
+		store.prunedBlocks[block.Hash] = block.Height
+		store.mutex.Unlock()
+		pruned++
+	}
+
+	store.mutex.Lock()
+	store.pruneHeight = height
+	store.mutex.Unlock()
+
+	return pruned, nil
+}
+
+func (store *MemoryStore) ReadPruneHeight() uint32 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.pruneHeight
+}
+
+func (store *MemoryStore) ReadPrunedBlockHeight(hash [32]byte) (height uint32, pruned bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	height, pruned = store.prunedBlocks[hash]
+	return height, pruned
+}
+
+func (store *MemoryStore) WriteClosedEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.writeIndexed(store.closedEpochBlocks, store.epochBlockHeights, epochBlock.Hash, epochBlock.Height, epochBlock.Encode())
+}
+
+func (store *MemoryStore) WriteLastClosedEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.write(store.lastClosedEpochBlocks, epochBlock.Hash, epochBlock.Encode())
+}
+
+func (store *MemoryStore) WriteFirstEpochBlock(epochBlock *protocol.EpochBlock) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.firstEpochBlock = epochBlock.Encode()
+	return nil
+}
+
+func (store *MemoryStore) DeleteOpenEpochBlock(hash [32]byte) error {
+	return store.delete(store.openEpochBlocks, hash)
+}
+
+func (store *MemoryStore) DeleteClosedEpochBlock(hash [32]byte) error {
+	return store.deleteIndexed(store.closedEpochBlocks, store.epochBlockHeights, hash, epochBlockHeight)
+}
+
+func (store *MemoryStore) ReadClosedEpochBlockByHeight(height uint32) *protocol.EpochBlock {
+	if hash, found := store.readHashByHeight(store.epochBlockHeights, height); found {
+		return store.ReadClosedEpochBlock(hash)
+	}
+	return nil
+}
+
+func (store *MemoryStore) ForEachClosedEpochBlock(from uint32, to uint32, fn func(epochBlock *protocol.EpochBlock) bool) {
+	for _, hash := range store.readHashesByHeight(store.epochBlockHeights, from, to) {
+		if epochBlock := store.ReadClosedEpochBlock(hash); epochBlock != nil && !fn(epochBlock) {
+			return
+		}
+	}
+}
+
+func (store *MemoryStore) DeleteAllLastClosedEpochBlock() error {
+	return store.deleteAll(store.lastClosedEpochBlocks)
+}
+
+//Txs of all types share one map, they are kept with their type in front.
+func (store *MemoryStore) ReadClosedTx(hash [32]byte) protocol.Transaction {
+	return decodeTypedTx(store.read(store.closedTxs, hash))
+}
+
+func (store *MemoryStore) WriteClosedTx(transaction protocol.Transaction, height uint32) error {
+	if protocol.TypeOf(transaction) == nil {
+		return errors.New(fmt.Sprintf("Transaction type %d is not registered.", transaction.Type()))
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	hash := transaction.Hash()
+	store.closedTxs[hash] = encodeTypedTx(transaction)
+	for _, address := range txAddresses(transaction) {
+		if store.accountTxs[address] == nil {
+			store.accountTxs[address] = make(map[[32]byte]uint32)
+		}
+		store.accountTxs[address][hash] = height
+	}
+	return nil
+}
+
+func (store *MemoryStore) DeleteClosedTx(transaction protocol.Transaction) error {
+	if protocol.TypeOf(transaction) == nil {
+		return errors.New(fmt.Sprintf("Transaction type %d is not registered.", transaction.Type()))
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	hash := transaction.Hash()
+	delete(store.closedTxs, hash)
+	for _, address := range txAddresses(transaction) {
+		delete(store.accountTxs[address], hash)
+	}
+	return nil
+}
+
+func (store *MemoryStore) ReadClosedTxHeight(hash [32]byte) (uint32, bool) {
+	transaction := store.ReadClosedTx(hash)
+	if transaction == nil {
+		return 0, false
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, address := range txAddresses(transaction) {
+		if height, exists := store.accountTxs[address][hash]; exists {
+			return height, true
+		}
+	}
+	return 0, false
+}
+
+func (store *MemoryStore) ReadTxStatus(hash [32]byte) *protocol.TxStatus {
+	return readTxStatus(store, store.memPool, hash)
+}
+
+//The history is ordered like the one of the BoltStore: by height, then by tx hash.
+func (store *MemoryStore) ReadAccountTxs(address [64]byte, offset int, limit int) (accountTxs []*AccountTx) {
+	store.mutex.Lock()
+	var history []*AccountTx
+	for hash, height := range store.accountTxs[address] {
+		history = append(history, &AccountTx{height, hash})
+	}
+	store.mutex.Unlock()
+
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].Height != history[j].Height {
+			return history[i].Height > history[j].Height
+		}
+		return bytes.Compare(history[i].TxHash[:], history[j].TxHash[:]) > 0
+	})
+
+	if offset < 0 {
+		offset = 0
+	}
+	for i := offset; i < len(history) && len(accountTxs) < limit; i++ {
+		accountTxs = append(accountTxs, history[i])
+	}
+	return accountTxs
+}
+
+func (store *MemoryStore) ReadReceipt(txHash [32]byte) (receipt *protocol.Receipt) {
+	if encoded := store.read(store.receipts, txHash); encoded != nil {
+		receipt, _ = receipt.Decode(encoded)
+	}
+	return receipt
+}
+
+func (store *MemoryStore) WriteReceipt(receipt *protocol.Receipt, height uint32) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	txHash := receipt.Tx.Hash()
+	store.receipts[txHash] = receipt.Encode()
+	for _, address := range receiptAddresses(receipt) {
+		if store.accountTxs[address] == nil {
+			store.accountTxs[address] = make(map[[32]byte]uint32)
+		}
+		store.accountTxs[address][txHash] = height
+	}
+	return nil
+}
+
+func (store *MemoryStore) DeleteReceipt(txHash [32]byte) error {
+	receipt := store.ReadReceipt(txHash)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.receipts, txHash)
+	if receipt != nil {
+		for _, address := range receiptAddresses(receipt) {
+			delete(store.accountTxs[address], txHash)
+		}
+	}
+	return nil
+}
+
+func (store *MemoryStore) ReadFraudProof(hash [32]byte) (proof *protocol.FraudProof) {
+	if encoded := store.read(store.fraudProofs, hash); encoded != nil {
+		proof, _ = proof.Decode(encoded)
+	}
+	return proof
+}
+
+func (store *MemoryStore) WriteFraudProof(proof *protocol.FraudProof) error {
+	return store.write(store.fraudProofs, proof.Hash(), proof.Encode())
+}
+
+func (store *MemoryStore) WriteStateTrie(nodes protocol.MemoryTrieDatabase) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for hash, node := range nodes {
+		store.stateTrie.PutTrieNode(hash, node)
+	}
+	return nil
+}
+
+func (store *MemoryStore) ReadStateTrieNode(hash [32]byte) []byte {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.stateTrie.GetTrieNode(hash)
+}
+
+func (store *MemoryStore) ReadStateTrieAccount(root [32]byte, address [64]byte) (*protocol.Account, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return protocol.GetTrieAccount(store.stateTrie, root, address)
+}
+
+func (store *MemoryStore) ReadStateTrieProof(root [32]byte, address [64]byte) ([][]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return protocol.ProveAccount(store.stateTrie, root, address)
+}
+
+func (store *MemoryStore) ReadGenesis() (genesis *protocol.Genesis, err error) {
+	store.mutex.Lock()
+	encoded := store.genesis
+	store.mutex.Unlock()
+
+	if encoded == nil {
+		return nil, nil
+	}
+	return genesis.Decode(encoded)
+}
+
+func (store *MemoryStore) WriteGenesis(genesis *protocol.Genesis) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.genesis = genesis.Encode()
+	return nil
+}
+
+func (store *MemoryStore) DeleteAll() error {
+	store.memPool.clear()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.reset()
+	return nil
+}
+
+func (store *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,642 @@
+package storage
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"sort"
+	"sync"
+)
+
+var (
+	//The mempool holds at most MemPoolCapacity open txs, at most MemPoolAccountCapacity of them per account. A value
+	//of 0 disables the limit.
+	MemPoolCapacity        = 10000
+	MemPoolAccountCapacity = 500
+)
+
+const (
+	//The number of admission decisions kept for diagnostics.
+	ADMISSION_LOG_SIZE = 100
+	//A tx replaces an open tx of the same account with the same nonce if its fee is higher by at least this percentage.
+	REPLACEMENT_FEE_BUMP = 10
+)
+
+//The decision of the mempool on a tx written to it. A full mempool evicts the txs with the lowest fee rate in favour
+//of a tx with a higher one, a tx with a sufficiently higher fee replaces the open tx with the same nonce. A tx whose
+//nonce does not follow the nonce sequence of its account is queued, Promoted counts the queued txs of the account
+//which became pending with the tx.
+type Admission struct {
+	TxHash   [32]byte
+	Admitted bool
+	Code     uint8 //Rejection code of a tx which was not admitted, see protocol.TXREJECT_*
+	Reason   string
+	Evicted  [][32]byte
+	Replaced bool
+	Queued   bool
+	Promoted int
+}
+
+func (admission *Admission) String() string {
+	decision := "admitted"
+	if !admission.Admitted {
+		decision = "rejected"
+	}
+	if admission.Queued {
+		decision = "queued"
+	}
+	str := fmt.Sprintf("Tx (%x) %v", admission.TxHash[0:8], decision)
+	if admission.Reason != "" {
+		str += ": " + admission.Reason
+	}
+	for _, hash := range admission.Evicted {
+		if admission.Replaced {
+			str += fmt.Sprintf(", replaced (%x)", hash[0:8])
+		} else {
+			str += fmt.Sprintf(", evicted (%x)", hash[0:8])
+		}
+	}
+	if admission.Promoted > 0 {
+		str += fmt.Sprintf(", promoted %d queued tx(s)", admission.Promoted)
+	}
+	return str
+}
+
+//A journal persists the mempools, such that they survive a restart.
+type txJournal interface {
+	writeJournal(changes []journalChange)
+}
+
+//A change of a journaled mempool, a nil tx deletes the entry.
+type journalChange struct {
+	bucket      string
+	hash        [32]byte
+	transaction protocol.Transaction
+}
+
+//The mempools of a store. Changes are passed on to the journal, if there is one. They are collected under the mutex
+//and written once it is released, such that the mempool is not locked while the journal syncs to disk. The journal
+//mutex keeps the changes in order.
+type memPool struct {
+	mutex          sync.Mutex
+	open           map[[32]byte]protocol.Transaction
+	invalid        map[[32]byte]protocol.Transaction
+	journal        txJournal
+	journalMutex   sync.Mutex
+	journalChanges []journalChange
+	admissions     []*Admission
+	//Checks the signature and whether the sender can pay, set by the miner. It depends on the state, the mempool does
+	//not know the state.
+	verifier func(transaction protocol.Transaction) error
+	//The reasons the txs of the invalid mempool were rejected for, they are not journaled.
+	rejections map[[32]byte]error
+	//The nonces of the accounts in the state, a snapshot set by the miner under its lock. The state is changed by the
+	//miner concurrently with the mempool being read.
+	nonces map[[64]byte]uint32
+}
+
+func newMemPool(journal txJournal) *memPool {
+	return &memPool{
+		open:       make(map[[32]byte]protocol.Transaction),
+		invalid:    make(map[[32]byte]protocol.Transaction),
+		journal:    journal,
+		rejections: make(map[[32]byte]error),
+		nonces:     make(map[[64]byte]uint32),
+	}
+}
+
+func (pool *memPool) ReadOpenTx(hash [32]byte) (transaction protocol.Transaction) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.open[hash]
+}
+
+func (pool *memPool) GetMemPoolSize() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return len(pool.open)
+}
+
+//The pending and the queued txs, in the order of their priority (see prioritize()).
+func (pool *memPool) ReadAllOpenTxs() (allOpenTxs []protocol.Transaction) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return prioritize(pool.openTxs())
+}
+
+//Needed for the miner to prepare a new block, the txs are returned in the order they should be included.
+func (pool *memPool) ReadPendingTxs() []protocol.Transaction {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pending, _ := splitQueued(pool.openTxs(), pool.nonces)
+	return prioritize(pending)
+}
+
+func (pool *memPool) ReadQueuedTxs() []protocol.Transaction {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	_, queued := splitQueued(pool.openTxs(), pool.nonces)
+	return prioritize(queued)
+}
+
+func (pool *memPool) openTxs() (txs []protocol.Transaction) {
+	for _, tx := range pool.open {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+//The last admission decisions, oldest first.
+func (pool *memPool) ReadAdmissions() []*Admission {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return append([]*Admission(nil), pool.admissions...)
+}
+
+//The status of a tx in the mempools: pending or queued if it is open, rejected if it is invalid or was not admitted to
+//the mempool recently. Nil if the mempools do not know the tx.
+func (pool *memPool) readMemPoolStatus(hash [32]byte) *protocol.TxStatus {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if _, exists := pool.invalid[hash]; exists {
+		status := &protocol.TxStatus{TxHash: hash, Status: protocol.TXSTATUS_REJECTED}
+		if reason := pool.rejections[hash]; reason != nil {
+			status.Code, status.Reason = protocol.RejectionCode(reason), reason.Error()
+		}
+		return status
+	}
+
+	if tx, exists := pool.open[hash]; exists {
+		status := &protocol.TxStatus{TxHash: hash, Status: protocol.TXSTATUS_PENDING}
+		if account, ok := txAccount(tx); ok && pool.queuedAccountTxs(account, nil)[hash] {
+			status.Status = protocol.TXSTATUS_QUEUED
+		}
+		return status
+	}
+
+	for i := len(pool.admissions) - 1; i >= 0; i-- {
+		if admission := pool.admissions[i]; admission.TxHash == hash && !admission.Admitted {
+			return &protocol.TxStatus{TxHash: hash, Status: protocol.TXSTATUS_REJECTED, Code: admission.Code, Reason: admission.Reason}
+		}
+	}
+
+	return nil
+}
+
+func (pool *memPool) ReadINVALIDOpenTx(hash [32]byte) (transaction protocol.Transaction) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.invalid[hash]
+}
+
+//Replaces the snapshot of the nonces the txs are queued by, the miner passes the TxCnt of every account in the state.
+func (pool *memPool) WriteAccountNonces(nonces map[[64]byte]uint32) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if nonces == nil {
+		nonces = make(map[[64]byte]uint32)
+	}
+	pool.nonces = nonces
+}
+
+//The verifier is set by the miner, txs which do not pass it are rejected before they can replace or evict another tx.
+func (pool *memPool) SetTxVerifier(verifier func(transaction protocol.Transaction) error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.verifier = verifier
+}
+
+//A tx is only admitted if it passes the verifier. A tx with the nonce of an open tx of the same account replaces it if
+//its fee is higher by REPLACEMENT_FEE_BUMP percent, otherwise it is rejected. Other txs are rejected if their account
+//has reached its limit, or if the mempool is full and no tx with a lower fee rate can be evicted. Only the last tx of a
+//nonce sequence can be evicted, such that no gap is left.
+func (pool *memPool) WriteOpenTx(transaction protocol.Transaction) *Admission {
+	pool.mutex.Lock()
+	verifier := pool.verifier
+	pool.mutex.Unlock()
+
+	//The verifier reads the state under the lock of the miner, it must not be called while the mempool is locked.
+	if verifier != nil {
+		if err := verifier(transaction); err != nil {
+			pool.mutex.Lock()
+			defer pool.mutex.Unlock()
+			admission := &Admission{TxHash: transaction.Hash(), Code: protocol.RejectionCode(err), Reason: err.Error()}
+			pool.logAdmission(admission)
+			return admission
+		}
+	}
+
+	return pool.WriteVerifiedOpenTx(transaction)
+}
+
+//Writes a tx without passing it to the verifier, e.g. the txs of a block which are verified with the block.
+func (pool *memPool) WriteVerifiedOpenTx(transaction protocol.Transaction) *Admission {
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	admission := &Admission{TxHash: transaction.Hash(), Admitted: true}
+	defer pool.logAdmission(admission)
+
+	if _, exists := pool.open[admission.TxHash]; exists {
+		admission.Reason = "Already in the mempool."
+		return admission
+	}
+
+	account, hasAccount := txAccount(transaction)
+	if pending := pool.sameNonceTx(transaction); pending != nil {
+		pendingHash := pending.Hash()
+		if minFee := replacementFee(pending); transaction.TxFee() < minFee {
+			admission.Admitted, admission.Code = false, protocol.TXREJECT_MEMPOOL
+			admission.Reason = fmt.Sprintf("Tx (%x) with the same nonce is pending, a replacement needs a fee of at least %d.", pendingHash[0:8], minFee)
+			return admission
+		}
+
+		pool.deleteOpen(pendingHash)
+		admission.Evicted, admission.Replaced = [][32]byte{pendingHash}, true
+		pool.writeQueued(transaction, account, admission)
+		return admission
+	}
+
+	if hasAccount && MemPoolAccountCapacity > 0 && pool.countAccountTxs(account) >= MemPoolAccountCapacity {
+		admission.Admitted, admission.Code = false, protocol.TXREJECT_MEMPOOL
+		admission.Reason = fmt.Sprintf("Account (%x) has reached the limit of %d open txs.", account[0:8], MemPoolAccountCapacity)
+		return admission
+	}
+
+	if MemPoolCapacity > 0 && len(pool.open) >= MemPoolCapacity {
+		candidate, candidateQueued := pool.evictionCandidate()
+		_, hasNonce := txNonce(transaction)
+		//A pending tx is preferred to a queued one regardless of the fee rate.
+		pendingOverQueued := candidateQueued && !pool.wouldQueue(transaction, account)
+		if candidate == nil || (!pendingOverQueued && !higherPriority(transaction, candidate)) {
+			admission.Admitted, admission.Code = false, protocol.TXREJECT_MEMPOOL
+			admission.Reason = fmt.Sprintf("The mempool is full and the fee rate %.4f is not above the lowest one.", feeRate(transaction))
+			return admission
+		}
+		if candidateAccount, _ := txAccount(candidate); hasNonce && hasAccount && candidateAccount == account {
+			admission.Admitted, admission.Code = false, protocol.TXREJECT_MEMPOOL
+			admission.Reason = "The mempool is full and the tx would only evict a tx of its own account."
+			return admission
+		}
+
+		pool.deleteOpen(candidate.Hash())
+		admission.Evicted = append(admission.Evicted, candidate.Hash())
+	}
+
+	pool.writeQueued(transaction, account, admission)
+	return admission
+}
+
+//Writes the tx and records in the admission whether it is queued and how many queued txs of its account it promoted.
+func (pool *memPool) writeQueued(transaction protocol.Transaction, account [64]byte, admission *Admission) {
+	queuedBefore := pool.queuedAccountTxs(account, nil)
+	pool.writeOpen(transaction)
+	queuedAfter := pool.queuedAccountTxs(account, nil)
+
+	if queuedAfter[admission.TxHash] {
+		admission.Queued = true
+		admission.Reason = "Queued until the txs with the preceding nonces arrive."
+	}
+	for hash := range queuedBefore {
+		if !queuedAfter[hash] {
+			admission.Promoted++
+		}
+	}
+}
+
+//Returns whether the tx would be queued if it was written.
+func (pool *memPool) wouldQueue(transaction protocol.Transaction, account [64]byte) bool {
+	return pool.queuedAccountTxs(account, transaction)[transaction.Hash()]
+}
+
+//The queued txs of the account, including the given extra tx if it is not nil.
+func (pool *memPool) queuedAccountTxs(account [64]byte, extra protocol.Transaction) map[[32]byte]bool {
+	var accountTxs []protocol.Transaction
+	for _, tx := range pool.open {
+		if txAccount, ok := txAccount(tx); ok && txAccount == account {
+			accountTxs = append(accountTxs, tx)
+		}
+	}
+	if extra != nil {
+		accountTxs = append(accountTxs, extra)
+	}
+
+	queuedTxs := make(map[[32]byte]bool)
+	_, queued := splitQueued(accountTxs, pool.nonces)
+	for _, tx := range queued {
+		queuedTxs[tx.Hash()] = true
+	}
+	return queuedTxs
+}
+
+func (pool *memPool) writeOpen(transaction protocol.Transaction) {
+	pool.deleteInvalid(transaction.Hash())
+	pool.open[transaction.Hash()] = transaction
+	pool.journalTx(OPENTXS_BUCKET, transaction.Hash(), transaction)
+}
+
+//The reason is the error the validation rejected the tx with, preferably a *protocol.TxRejection. The tx is removed from
+//the open mempool, such that it neither takes up its capacity nor is considered for eviction.
+func (pool *memPool) WriteINVALIDOpenTx(transaction protocol.Transaction, reason error) {
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.deleteOpen(transaction.Hash())
+	pool.invalid[transaction.Hash()] = transaction
+	pool.rejections[transaction.Hash()] = reason
+	pool.journalTx(INVALIDOPENTXS_BUCKET, transaction.Hash(), transaction)
+}
+
+func (pool *memPool) DeleteOpenTx(transaction protocol.Transaction) {
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.deleteOpen(transaction.Hash())
+}
+
+func (pool *memPool) deleteOpen(hash [32]byte) {
+	delete(pool.open, hash)
+	pool.journalTx(OPENTXS_BUCKET, hash, nil)
+}
+
+func (pool *memPool) DeleteINVALIDOpenTx(transaction protocol.Transaction) {
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.deleteInvalid(transaction.Hash())
+}
+
+func (pool *memPool) deleteInvalid(hash [32]byte) {
+	if _, exists := pool.invalid[hash]; !exists {
+		return
+	}
+	delete(pool.invalid, hash)
+	delete(pool.rejections, hash)
+	pool.journalTx(INVALIDOPENTXS_BUCKET, hash, nil)
+}
+
+//Removes the txs which can no longer be included in a block after the given height from both mempools.
+func (pool *memPool) DeleteExpiredOpenTxs(height uint32) (expiredTxs []protocol.Transaction) {
+	return pool.DeleteStaleOpenTxs(func(tx protocol.Transaction) bool {
+		return protocol.IsExpired(tx, height)
+	})
+}
+
+//Removes the txs for which isStale returns true from both mempools and their journals.
+func (pool *memPool) DeleteStaleOpenTxs(isStale func(tx protocol.Transaction) bool) (staleTxs []protocol.Transaction) {
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for bucket, txs := range map[string]map[[32]byte]protocol.Transaction{
+		OPENTXS_BUCKET:        pool.open,
+		INVALIDOPENTXS_BUCKET: pool.invalid,
+	} {
+		for hash, tx := range txs {
+			if isStale(tx) {
+				delete(txs, hash)
+				pool.journalTx(bucket, hash, nil)
+				staleTxs = append(staleTxs, tx)
+			}
+		}
+	}
+
+	return staleTxs
+}
+
+//Records a change for the journal, called with the mutex held.
+func (pool *memPool) journalTx(bucket string, hash [32]byte, transaction protocol.Transaction) {
+	if pool.journal != nil {
+		pool.journalChanges = append(pool.journalChanges, journalChange{bucket, hash, transaction})
+	}
+}
+
+//Writes the collected changes to the journal in one transaction, called after the mutex is released.
+func (pool *memPool) flushJournal() {
+	pool.journalMutex.Lock()
+	defer pool.journalMutex.Unlock()
+
+	pool.mutex.Lock()
+	changes := pool.journalChanges
+	pool.journalChanges = nil
+	pool.mutex.Unlock()
+
+	if len(changes) > 0 {
+		pool.journal.writeJournal(changes)
+	}
+}
+
+//Only clears the in-memory mempools, the journal is cleared with the other buckets.
+func (pool *memPool) clear() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.journalChanges = nil
+	pool.open = make(map[[32]byte]protocol.Transaction)
+	pool.invalid = make(map[[32]byte]protocol.Transaction)
+	pool.admissions = nil
+	pool.rejections = make(map[[32]byte]error)
+	pool.nonces = make(map[[64]byte]uint32)
+}
+
+func (pool *memPool) logAdmission(admission *Admission) {
+	pool.admissions = append(pool.admissions, admission)
+	if len(pool.admissions) > ADMISSION_LOG_SIZE {
+		pool.admissions = pool.admissions[len(pool.admissions)-ADMISSION_LOG_SIZE:]
+	}
+}
+
+func (pool *memPool) countAccountTxs(account [64]byte) (count int) {
+	for _, tx := range pool.open {
+		if txAccount, ok := txAccount(tx); ok && txAccount == account {
+			count++
+		}
+	}
+	return count
+}
+
+//The open tx of the same account with the same nonce, nil if there is none or the tx has no nonce.
+func (pool *memPool) sameNonceTx(transaction protocol.Transaction) protocol.Transaction {
+	nonce, hasNonce := txNonce(transaction)
+	account, hasAccount := txAccount(transaction)
+	if !hasNonce || !hasAccount {
+		return nil
+	}
+
+	for _, tx := range pool.open {
+		if txAccount, _ := txAccount(tx); txAccount == account && tx.Type() == transaction.Type() && nonceOf(tx) == nonce {
+			return tx
+		}
+	}
+	return nil
+}
+
+//The minimum fee of a tx replacing the given one, at least one coin more.
+func replacementFee(transaction protocol.Transaction) uint64 {
+	bump := transaction.TxFee() * REPLACEMENT_FEE_BUMP / 100
+	if bump == 0 {
+		bump = 1
+	}
+	return transaction.TxFee() + bump
+}
+
+//The queued tx with the lowest fee rate, if there are queued txs. Otherwise, the tx with the lowest fee rate among the
+//txs without a nonce and the last txs of the nonce sequences.
+func (pool *memPool) evictionCandidate() (candidate protocol.Transaction, queued bool) {
+	//Queued txs are evicted first, they cannot be included before the missing txs arrive.
+	pendingTxs, queuedTxs := splitQueued(pool.openTxs(), pool.nonces)
+	if len(queuedTxs) > 0 {
+		return lowestPriorityTx(queuedTxs), true
+	}
+	return lowestPriorityTx(pendingTxs), false
+}
+
+func lowestPriorityTx(txs []protocol.Transaction) (candidate protocol.Transaction) {
+	lastOfAccount := make(map[[64]byte]protocol.Transaction)
+	for _, tx := range txs {
+		nonce, hasNonce := txNonce(tx)
+		if account, ok := txAccount(tx); hasNonce && ok {
+			if last, exists := lastOfAccount[account]; !exists || nonce > nonceOf(last) {
+				lastOfAccount[account] = tx
+			}
+			continue
+		}
+		if candidate == nil || higherPriority(candidate, tx) {
+			candidate = tx
+		}
+	}
+
+	for _, tx := range lastOfAccount {
+		if candidate == nil || higherPriority(candidate, tx) {
+			candidate = tx
+		}
+	}
+	return candidate
+}
+
+//Splits the txs into the pending ones, which continue the nonce sequence of their account in the state, and the queued
+//ones, which wait for a tx with a missing nonce. Txs without a nonce and txs of accounts which are not in the nonces
+//are pending, their nonce cannot be checked. Txs with a nonce below the one of the account stay pending as well, they
+//are rejected when a block is prepared.
+func splitQueued(txs []protocol.Transaction, nonces map[[64]byte]uint32) (pending []protocol.Transaction, queued []protocol.Transaction) {
+	sequences := make(map[[64]byte][]protocol.Transaction)
+	for _, tx := range txs {
+		_, hasNonce := txNonce(tx)
+		account, ok := txAccount(tx)
+		if _, inState := nonces[account]; hasNonce && ok && inState {
+			sequences[account] = append(sequences[account], tx)
+		} else {
+			pending = append(pending, tx)
+		}
+	}
+
+	for account, sequence := range sequences {
+		sort.Slice(sequence, func(i, j int) bool { return nonceOf(sequence[i]) < nonceOf(sequence[j]) })
+		next := nonces[account]
+		for _, tx := range sequence {
+			if nonce := nonceOf(tx); nonce > next {
+				queued = append(queued, tx)
+				continue
+			} else if nonce == next {
+				next++
+			}
+			pending = append(pending, tx)
+		}
+	}
+	return pending, queued
+}
+
+//The account a tx is counted against, i.e. the account deciding its shard. Txs whose shard key is not an account
+//(e.g., ConfigTxs) are not counted against any account.
+func txAccount(transaction protocol.Transaction) (account [64]byte, ok bool) {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil || txType.ShardKey == nil || txType.NoAccount {
+		return account, false
+	}
+	return txType.ShardKey(transaction), true
+}
+
+func txNonce(transaction protocol.Transaction) (nonce uint32, ok bool) {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil || txType.Nonce == nil {
+		return 0, false
+	}
+	return txType.Nonce(transaction), true
+}
+
+func nonceOf(transaction protocol.Transaction) uint32 {
+	nonce, _ := txNonce(transaction)
+	return nonce
+}
+
+//The fee per byte of a tx.
+func feeRate(transaction protocol.Transaction) float64 {
+	size := transaction.Size()
+	if size == 0 {
+		size = 1
+	}
+	return float64(transaction.TxFee()) / float64(size)
+}
+
+//Txs with a higher fee rate are preferred, ties are broken by the tx hash such that the order is deterministic.
+func higherPriority(tx protocol.Transaction, other protocol.Transaction) bool {
+	if rate, otherRate := feeRate(tx), feeRate(other); rate != otherRate {
+		return rate > otherRate
+	}
+	hash, otherHash := tx.Hash(), other.Hash()
+	return bytes.Compare(hash[:], otherHash[:]) < 0
+}
+
+//The txs with a nonce of an account form a queue ordered by increasing nonce, all other txs a queue of their own.
+type txQueues [][]protocol.Transaction
+
+func (queues txQueues) Len() int           { return len(queues) }
+func (queues txQueues) Less(i, j int) bool { return higherPriority(queues[i][0], queues[j][0]) }
+func (queues txQueues) Swap(i, j int)      { queues[i], queues[j] = queues[j], queues[i] }
+
+func (queues *txQueues) Push(queue interface{}) {
+	*queues = append(*queues, queue.([]protocol.Transaction))
+}
+
+func (queues *txQueues) Pop() interface{} {
+	old := *queues
+	queue := old[len(old)-1]
+	*queues = old[:len(old)-1]
+	return queue
+}
+
+//Orders the txs by decreasing fee rate, while the txs of an account with a nonce follow each other in the order of
+//increasing nonce. The next tx of each account competes with its own fee rate.
+func prioritize(txs []protocol.Transaction) (ordered []protocol.Transaction) {
+	var queues txQueues
+	queueOfAccount := make(map[[64]byte]int)
+	for _, tx := range txs {
+		_, hasNonce := txNonce(tx)
+		if account, ok := txAccount(tx); hasNonce && ok {
+			if index, exists := queueOfAccount[account]; exists {
+				queues[index] = append(queues[index], tx)
+				continue
+			}
+			queueOfAccount[account] = len(queues)
+		}
+		queues = append(queues, []protocol.Transaction{tx})
+	}
+
+	for _, queue := range queues {
+		sort.Slice(queue, func(i, j int) bool { return nonceOf(queue[i]) < nonceOf(queue[j]) })
+	}
+
+	heap.Init(&queues)
+	for queues.Len() > 0 {
+		ordered = append(ordered, queues[0][0])
+		if len(queues[0]) > 1 {
+			queues[0] = queues[0][1:]
+			heap.Fix(&queues, 0)
+		} else {
+			heap.Pop(&queues)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+const (
+	//Holds the schema version of the database. It is not cleared with the other buckets, the layout stays the same.
+	META_BUCKET        = "meta"
+	SCHEMA_VERSION_KEY = "schemaversion"
+)
+
+/**
+	A migration upgrades the database from the previous schema version to its version. Migrations run in order when
+	the database is opened, each one in a single transaction together with the update of the schema version, such that
+	an interrupted upgrade is resumed with the migration that did not finish.
+
+	Databases without schema version were created before versioning was introduced and have version 1. Changes of the
+	bucket layout or of the encodings (e.g., a new tx index) add a migration with the next version.
+ */
+type migration struct {
+	version     uint32
+	description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+var migrations = []migration{
+	{2, "drop a chain in the gob encoding and index the closed txs by account", migrateVersion1},
+	{3, "index the closed blocks and epoch blocks by height", indexBlockHeights},
+}
+
+//The schema version of newly created databases.
+func schemaVersion() uint32 {
+	return migrations[len(migrations)-1].version
+}
+
+func (store *BoltStore) ReadSchemaVersion() (version uint32) {
+	store.db.View(func(tx *bolt.Tx) error {
+		version = readSchemaVersion(tx)
+		return nil
+	})
+	return version
+}
+
+//Returns 0 if the database has no schema version.
+func readSchemaVersion(tx *bolt.Tx) uint32 {
+	if b := tx.Bucket([]byte(META_BUCKET)); b != nil {
+		if encoded := b.Get([]byte(SCHEMA_VERSION_KEY)); len(encoded) == 4 {
+			return binary.BigEndian.Uint32(encoded)
+		}
+	}
+	return 0
+}
+
+func writeSchemaVersion(tx *bolt.Tx, version uint32) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(META_BUCKET))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(SCHEMA_VERSION_KEY), heightKey(version))
+}
+
+//Called before the buckets are created. New databases start with the current schema version, databases created
+//before versioning with version 1.
+func (store *BoltStore) initSchemaVersion() error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		if readSchemaVersion(tx) != 0 {
+			return nil
+		}
+		if tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET)) != nil {
+			return writeSchemaVersion(tx, 1)
+		}
+		return writeSchemaVersion(tx, schemaVersion())
+	})
+}
+
+//Runs the migrations the database has not seen yet, in order.
+func (store *BoltStore) migrate() error {
+	version := store.ReadSchemaVersion()
+	if version > schemaVersion() {
+		return errors.New(fmt.Sprintf("Database schema version %d is newer than the supported version %d.", version, schemaVersion()))
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		logger.Printf("Migrating the database to schema version %d: %v\n", m.version, m.description)
+		err := store.db.Update(func(tx *bolt.Tx) error {
+			if err := m.migrate(tx); err != nil {
+				return err
+			}
+			return writeSchemaVersion(tx, m.version)
+		})
+		if err != nil {
+			return errors.New(fmt.Sprintf("Migration to schema version %d failed: %v", m.version, err))
+		}
+		version = m.version
+	}
+
+	return nil
+}
+
+func migrateVersion1(tx *bolt.Tx) error {
+	if err := dropGobEncodedChain(tx); err != nil {
+		return err
+	}
+	return indexAccountTxs(tx)
+}
+
+//Databases written before the binary encoding (see protocol/encoding.go) hold gob encodings, which cannot be decoded
+//anymore. Their txs and blocks are not valid under the current rules either, such that re-encoding them is pointless.
+//All buckets are therefore cleared and the chain is synchronized again from the network.
+func dropGobEncodedChain(tx *bolt.Tx) error {
+	gobEncoded := false
+	for _, bucket := range []string{CLOSEDBLOCKS_BUCKET, LASTCLOSEDBLOCK_BUCKET, CLOSEDEPOCHBLOCK_BUCKET, LASTCLOSEDEPOCHBLOCK_BUCKET} {
+		if b := tx.Bucket([]byte(bucket)); b != nil {
+			if _, v := b.Cursor().First(); v != nil && isGobEncodedBlock(v) {
+				gobEncoded = true
+			}
+		}
+	}
+	if !gobEncoded {
+		return nil
+	}
+
+	logger.Printf("The database holds a chain in the gob encoding of earlier versions, it is dropped and synchronized again.\n")
+
+	for _, name := range bucketNames(tx) {
+		if string(name) == META_BUCKET {
+			continue
+		}
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Blocks and epoch blocks were gob encodings of their structs, both with the fields Hash and Height. Gob ignores the
+//other fields, whereas the binary encoding is not a valid gob stream.
+func isGobEncodedBlock(encoded []byte) bool {
+	var header struct {
+		Hash   [32]byte
+		Height uint32
+	}
+	return gob.NewDecoder(bytes.NewReader(encoded)).Decode(&header) == nil
+}
+
+//Lists the txs of all closed blocks in the histories of their accounts. An entry which cannot be decoded fails the
+//migration, the history would be incomplete otherwise.
+func indexAccountTxs(tx *bolt.Tx) error {
+	index := tx.Bucket([]byte(ACCOUNTTXS_BUCKET))
+
+	return tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET)).ForEach(func(k, v []byte) error {
+		var block *protocol.Block
+		block, err := block.Decode(v)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Closed block (%x) could not be decoded: %v", k, err))
+		}
+
+		for _, txType := range protocol.TxTypes() {
+			b := tx.Bucket([]byte(txType.Bucket))
+			for _, txHash := range block.TxHashes(txType.Id) {
+				encoded := b.Get(txHash[:])
+				if encoded == nil {
+					continue
+				}
+				closedTx, err := txType.Decode(encoded)
+				if err != nil {
+					return errors.New(fmt.Sprintf("%v (%x) of block (%x) could not be decoded: %v", txType.Name, txHash, k, err))
+				}
+				for _, address := range txAddresses(closedTx) {
+					if err := index.Put(accountTxKey(address, block.Height, txHash), []byte{}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+//If there are several closed blocks at a height, the one on the chain of the last closed block is indexed. Epoch
+//blocks are indexed by their height. An entry which cannot be decoded fails the migration.
+func indexBlockHeights(tx *bolt.Tx) error {
+	closedBlocks := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET))
+	heights := tx.Bucket([]byte(BLOCKHEIGHTS_BUCKET))
+
+	err := closedBlocks.ForEach(func(k, v []byte) error {
+		height, ok := blockHeight(v)
+		if !ok {
+			return errors.New(fmt.Sprintf("Closed block (%x) could not be decoded.", k))
+		}
+		return heights.Put(heightKey(height), k)
+	})
+	if err != nil {
+		return err
+	}
+
+	//The chain is followed back to the first block of the epoch, whose predecessor is an epoch block.
+	hash, _ := tx.Bucket([]byte(LASTCLOSEDBLOCK_BUCKET)).Cursor().First()
+	for hash != nil {
+		encoded := closedBlocks.Get(hash)
+		if encoded == nil {
+			break
+		}
+		var block *protocol.Block
+		block, err := block.Decode(encoded)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Closed block (%x) could not be decoded: %v", hash, err))
+		}
+		if err := heights.Put(heightKey(block.Height), block.Hash[:]); err != nil {
+			return err
+		}
+		hash = block.PrevHash[:]
+	}
+
+	epochBlockHeights := tx.Bucket([]byte(EPOCHBLOCKHEIGHTS_BUCKET))
+	return tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET)).ForEach(func(k, v []byte) error {
+		//The first epoch block is kept under a fixed key next to its entry by hash.
+		if string(k) == "firstepochblock" {
+			return nil
+		}
+		height, ok := epochBlockHeight(v)
+		if !ok {
+			return errors.New(fmt.Sprintf("Closed epoch block (%x) could not be decoded.", k))
+		}
+		return epochBlockHeights.Put(heightKey(height), k)
+	})
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"encoding/binary"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+func (store *BoltStore) PruneClosedBlocks(height uint32) (pruned int, err error) {
+	pruneHeight := store.ReadPruneHeight()
+	if height <= pruneHeight {
+		return 0, nil
+	}
+
+	var blocks []*protocol.Block
+	var prunedRoots [][32]byte
+	for _, hash := range store.readHashesByHeight(BLOCKHEIGHTS_BUCKET, pruneHeight, height-1) {
+		if block := store.ReadClosedBlock(hash); block != nil {
+			blocks = append(blocks, block)
+			prunedRoots = append(prunedRoots, block.MerklePatriciaRoot)
+		}
+	}
+
+	//The trie nodes are pruned before the blocks, an interrupted pruning still finds the roots of the blocks.
+	prunable := prunableTrieNodes(trieNodeStore{store.db}, prunedRoots, keptStateRoots(store, height))
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(STATETRIE_BUCKET))
+		for _, hash := range prunable {
+			if err := b.Delete(hash[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, block := range blocks {
+		deleteBlockTxs(store, block)
+
+		//The block is replaced by its pruned entry in one transaction.
+		err = store.db.Update(func(tx *bolt.Tx) error {
+			if err := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET)).Delete(block.Hash[:]); err != nil {
+				return err
+			}
+			if err := tx.Bucket([]byte(BLOCKHEIGHTS_BUCKET)).Delete(heightKey(block.Height)); err != nil {
+				return err
+			}
+			return tx.Bucket([]byte(PRUNEDBLOCKS_BUCKET)).Put(block.Hash[:], heightKey(block.Height))
+		})
+		if err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+
+	//The prune height is only raised once all blocks below it are pruned, an interrupted pruning is resumed with the
+	//next one.
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PRUNEHEIGHT_BUCKET)).Put([]byte("pruneheight"), heightKey(height))
+	})
+
+	return pruned, err
+}
+
+func (store *BoltStore) ReadPruneHeight() (height uint32) {
+	store.db.View(func(tx *bolt.Tx) error {
+		if encoded := tx.Bucket([]byte(PRUNEHEIGHT_BUCKET)).Get([]byte("pruneheight")); len(encoded) == 4 {
+			height = binary.BigEndian.Uint32(encoded)
+		}
+		return nil
+	})
+	return height
+}
+
+func (store *BoltStore) ReadPrunedBlockHeight(hash [32]byte) (height uint32, pruned bool) {
+	store.db.View(func(tx *bolt.Tx) error {
+		if encoded := tx.Bucket([]byte(PRUNEDBLOCKS_BUCKET)).Get(hash[:]); len(encoded) == 4 {
+			height = binary.BigEndian.Uint32(encoded)
+			pruned = true
+		}
+		return nil
+	})
+	return height, pruned
+}
@@ -1,16 +1,16 @@
 package storage
 
 import (
-	"errors"
-	"fmt"
+	"bytes"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
 
-//Always return nil if requested hash is not in the storage. This return value is then checked against by the caller
-func ReadOpenBlock(hash [32]byte) (block *protocol.Block) {
+//Always return nil if requested hash is not in the storage or cannot be decoded. This return value is then checked
+//against by the caller
+func (store *BoltStore) ReadOpenBlock(hash [32]byte) (block *protocol.Block) {
 	var encodedBlock []byte
-	db.View(func(tx *bolt.Tx) error {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENBLOCKS_BUCKET))
 		encodedBlock = b.Get(hash[:])
 		return nil
@@ -20,12 +20,13 @@ func ReadOpenBlock(hash [32]byte) (block *protocol.Block) {
 		return nil
 	}
 
-	return block.Decode(encodedBlock)
+	block, _ = block.Decode(encodedBlock)
+	return block
 }
 
-func ReadOpenEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
+func (store *BoltStore) ReadOpenEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
 	var encodedEpochBlock []byte
-	db.View(func(tx *bolt.Tx) error {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENEPOCHBLOCK_BUCKET))
 		encodedEpochBlock = b.Get(hash[:])
 		return nil
@@ -35,14 +36,15 @@ func ReadOpenEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
 		return nil
 	}
 
-	return epochBlock.Decode(encodedEpochBlock)
+	epochBlock, _ = epochBlock.Decode(encodedEpochBlock)
+	return epochBlock
 }
 
-func ReadClosedEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
-	db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadClosedEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
 		encodedBlock := b.Get(hash[:])
-		epochBlock = epochBlock.Decode(encodedBlock)
+		epochBlock, _ = epochBlock.Decode(encodedBlock)
 		return nil
 	})
 
@@ -53,11 +55,11 @@ func ReadClosedEpochBlock(hash [32]byte) (epochBlock *protocol.EpochBlock) {
 	return epochBlock
 }
 
-func ReadClosedBlock(hash [32]byte) (block *protocol.Block) {
-	db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadClosedBlock(hash [32]byte) (block *protocol.Block) {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET))
 		encodedBlock := b.Get(hash[:])
-		block = block.Decode(encodedBlock)
+		block, _ = block.Decode(encodedBlock)
 		return nil
 	})
 
@@ -68,12 +70,38 @@ func ReadClosedBlock(hash [32]byte) (block *protocol.Block) {
 	return block
 }
 
-func ReadLastClosedBlock() (block *protocol.Block) {
-	db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadFraudProof(hash [32]byte) (proof *protocol.FraudProof) {
+	store.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(FRAUDPROOFS_BUCKET))
+		encodedProof := b.Get(hash[:])
+		if encodedProof != nil {
+			proof, _ = proof.Decode(encodedProof)
+		}
+		return nil
+	})
+
+	return proof
+}
+
+func (store *BoltStore) ReadReceipt(txHash [32]byte) (receipt *protocol.Receipt) {
+	store.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RECEIPTS_BUCKET))
+		encodedReceipt := b.Get(txHash[:])
+		if encodedReceipt != nil {
+			receipt, _ = receipt.Decode(encodedReceipt)
+		}
+		return nil
+	})
+
+	return receipt
+}
+
+func (store *BoltStore) ReadLastClosedBlock() (block *protocol.Block) {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDBLOCK_BUCKET))
 		cb := b.Cursor()
 		_, encodedBlock := cb.First()
-		block = block.Decode(encodedBlock)
+		block, _ = block.Decode(encodedBlock)
 		return nil
 	})
 
@@ -84,12 +112,12 @@ func ReadLastClosedBlock() (block *protocol.Block) {
 	return block
 }
 
-func ReadLastClosedEpochBlock() (epochBlock *protocol.EpochBlock) {
-	db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadLastClosedEpochBlock() (epochBlock *protocol.EpochBlock) {
+	store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDEPOCHBLOCK_BUCKET))
 		cb := b.Cursor()
 		_, encodedBlock := cb.First()
-		epochBlock = epochBlock.Decode(encodedBlock)
+		epochBlock, _ = epochBlock.Decode(encodedBlock)
 		return nil
 	})
 
@@ -104,121 +132,177 @@ func ReadReceivedBlockStash() (receivedBlocks []*protocol.Block){
 	return ReceivedBlockStash
 }
 
-func ReadINVALIDOpenTx(hash [32]byte) (transaction protocol.Transaction) {
+func ReadReceivedBlock(hash [32]byte) *protocol.Block {
+	for _, block := range ReceivedBlockStash {
+		if block.Hash == hash {
+			return block
+		}
+	}
 
-	return txINVALIDMemPool[hash]
+	return nil
 }
 
-func ReadAllClosedBlocks() (allClosedBlocks []*protocol.Block) {
-	if nextBlock := ReadLastClosedBlock(); nextBlock != nil {
-		hasNext := true
+//Reloads the journaled mempools. Entries of unregistered tx types or which cannot be decoded are evicted, as are the
+//txs with the lowest fee rates if the open txs exceed MemPoolCapacity, e.g. since it was lowered. Whether the txs are
+//still valid depends on the state, the miner re-verifies them once the state is built.
+func (store *BoltStore) loadOpenTxs() {
+	pool := store.memPool
+	defer pool.flushJournal()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for bucket, memPool := range map[string]map[[32]byte]protocol.Transaction{
+		OPENTXS_BUCKET:        pool.open,
+		INVALIDOPENTXS_BUCKET: pool.invalid,
+	} {
+		var undecodable [][32]byte
+
+		store.db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(bucket))
+			return b.ForEach(func(k, v []byte) error {
+				var hash [32]byte
+				copy(hash[:], k)
+
+				if transaction := decodeTypedTx(v); transaction != nil && transaction.Hash() == hash {
+					memPool[hash] = transaction
+				} else {
+					undecodable = append(undecodable, hash)
+				}
+				return nil
+			})
+		})
 
-		allClosedBlocks = append(allClosedBlocks, nextBlock)
+		for _, hash := range undecodable {
+			pool.journalTx(bucket, hash, nil)
+		}
 
-		if nextBlock.Hash != [32]byte{} {
-			for hasNext && nextBlock.Height > 1 {
-				nextBlock = ReadClosedBlock(nextBlock.PrevHash)
-				allClosedBlocks = append(allClosedBlocks, nextBlock)
-				if nextBlock.Hash == [32]byte{} {
-					hasNext = false
-				}
-			}
+		if len(undecodable) > 0 {
+			logger.Printf("Evicted %d undecodable transactions from %v\n", len(undecodable), bucket)
 		}
 	}
 
-	return allClosedBlocks
-}
-
-func ReadOpenTx(hash [32]byte) (transaction protocol.Transaction) {
-	memPoolMutex.Lock()
-	defer memPoolMutex.Unlock()
-	return txMemPool[hash]
-}
+	//Earlier versions kept invalid txs in the open mempool as well.
+	for hash := range pool.invalid {
+		if _, exists := pool.open[hash]; exists {
+			pool.deleteOpen(hash)
+		}
+	}
 
+	//Only the last tx of a nonce sequence is evicted, such that no gap is left.
+	evicted := 0
+	for MemPoolCapacity > 0 && len(pool.open) > MemPoolCapacity {
+		pool.deleteOpen(lowestPriorityTx(pool.openTxs()).Hash())
+		evicted++
+	}
+	if evicted > 0 {
+		logger.Printf("Evicted %d transactions exceeding the mempool capacity of %d\n", evicted, MemPoolCapacity)
+	}
 
-func GetMemPoolSize() int {
-	memPoolMutex.Lock()
-	defer memPoolMutex.Unlock()
-	return len(txMemPool)
+	logger.Printf("Reloaded %d open transactions from the journal\n", len(pool.open))
 }
 
-//Needed for the miner to prepare a new block
-func ReadAllOpenTxs() (allOpenTxs []protocol.Transaction) {
-	memPoolMutex.Lock()
-	defer memPoolMutex.Unlock()
-	for key := range txMemPool {
-		allOpenTxs = append(allOpenTxs, txMemPool[key])
+//The closed tx buckets of all registered tx types are checked.
+//Transactions which cannot be decoded are treated as if they were not in the storage.
+func (store *BoltStore) ReadClosedTx(hash [32]byte) (transaction protocol.Transaction) {
+	for _, txType := range protocol.TxTypes() {
+		if encodedTx := store.readClosedTx(txType.Bucket, hash); encodedTx != nil {
+			if tx, err := txType.Decode(encodedTx); err == nil {
+				return tx
+			}
+			return nil
+		}
 	}
-	return
+
+	return nil
 }
 
-//Personally I like it better to test (which tx type it is) here, and get returned the interface. Simplifies the code
-func ReadClosedTx(hash [32]byte) (transaction protocol.Transaction) {
-	if encodedTx := readClosedTx(CLOSEDFUNDS_BUCKET, hash); encodedTx != nil {
-		var tx *protocol.FundsTx
-		return tx.Decode(encodedTx)
+//The height is looked up in the history of the first account the tx concerns.
+func (store *BoltStore) ReadClosedTxHeight(hash [32]byte) (height uint32, found bool) {
+	transaction := store.ReadClosedTx(hash)
+	if transaction == nil {
+		return 0, false
 	}
-
-	if encodedTx := readClosedTx(CLOSEDACCS_BUCKET, hash); encodedTx != nil {
-		var tx *protocol.ContractTx
-		return tx.Decode(encodedTx)
+	addresses := txAddresses(transaction)
+	if len(addresses) == 0 {
+		return 0, false
 	}
 
-	if encodedTx := readClosedTx(CLOSEDCONFIGS_BUCKET, hash); encodedTx != nil {
-		var tx *protocol.ConfigTx
-		return tx.Decode(encodedTx)
-	}
+	store.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(ACCOUNTTXS_BUCKET)).Cursor()
+		for k, _ := c.Seek(addresses[0][:]); k != nil && bytes.HasPrefix(k, addresses[0][:]); k, _ = c.Next() {
+			if accountTx := decodeAccountTxKey(k); accountTx.TxHash == hash {
+				height, found = accountTx.Height, true
+				return nil
+			}
+		}
+		return nil
+	})
 
-	if encodedTx := readClosedTx(CLOSEDSTAKES_BUCKET, hash); encodedTx != nil {
-		var tx *protocol.StakeTx
-		return tx.Decode(encodedTx)
-	}
+	return height, found
+}
 
-	return nil
+func (store *BoltStore) ReadTxStatus(hash [32]byte) *protocol.TxStatus {
+	return readTxStatus(store, store.memPool, hash)
 }
 
-func readClosedTx(bucketName string, hash [32]byte) (encodedTx []byte) {
-	db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketName))
-		encodedTx = b.Get(hash[:])
+func (store *BoltStore) ReadAccountTxs(address [64]byte, offset int, limit int) (accountTxs []*AccountTx) {
+	store.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(ACCOUNTTXS_BUCKET)).Cursor()
+
+		//The entries are ordered by height, the history is read backwards from the entry after the last one.
+		k, _ := c.Seek(append(address[:], bytes.Repeat([]byte{0xff}, ACCOUNTTX_KEY_LEN-64)...))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, address[:]) && len(accountTxs) < limit; k, _ = c.Prev() {
+			if offset > 0 {
+				offset--
+				continue
+			}
+			accountTxs = append(accountTxs, decodeAccountTxKey(k))
+		}
 		return nil
 	})
-	return encodedTx
-}
 
-func ReadAccount(pubKey [64]byte) (acc *protocol.Account, err error) {
-	if acc = State[pubKey]; acc != nil {
-		return acc, nil
-	} else {
-		return nil, errors.New(fmt.Sprintf("Acc (%x) not in the state.", pubKey[0:8]))
-	}
+	return accountTxs
 }
 
-func ReadRootAccount(pubKey [64]byte) (acc *protocol.Account, err error) {
-	if IsRootKey(pubKey) {
-		acc, err = ReadAccount(pubKey)
-		return acc, err
-	}
-
-	return nil, err
+func (store *BoltStore) readClosedTx(bucketName string, hash [32]byte) (encodedTx []byte) {
+	store.db.View(func(tx *bolt.Tx) error {
+		//Tx types registered after the initialization have no bucket yet.
+		if b := tx.Bucket([]byte(bucketName)); b != nil {
+			encodedTx = b.Get(hash[:])
+		}
+		return nil
+	})
+	return encodedTx
 }
 
-func ReadGenesis() (genesis *protocol.Genesis, err error) {
-	err = db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadGenesis() (genesis *protocol.Genesis, err error) {
+	err = store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(GENESIS_BUCKET))
 		encoded := b.Get([]byte("genesis"))
-		genesis = genesis.Decode(encoded)
-		return nil
+		if encoded == nil {
+			return nil
+		}
+		genesis, err = genesis.Decode(encoded)
+		return err
 	})
 	return genesis, err
 }
 
-func ReadFirstEpochBlock() (firstEpochBlock *protocol.EpochBlock, err error) {
-	err = db.View(func(tx *bolt.Tx) error {
+func (store *BoltStore) ReadFirstEpochBlock() (firstEpochBlock *protocol.EpochBlock, err error) {
+	err = store.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
 		encoded := b.Get([]byte("firstepochblock"))
-		firstEpochBlock = firstEpochBlock.Decode(encoded)
-		return nil
+		if encoded == nil {
+			return nil
+		}
+		firstEpochBlock, err = firstEpochBlock.Decode(encoded)
+		return err
 	})
 	return firstEpochBlock, err
 }
@@ -241,4 +325,14 @@ func ReadStateTransitionFromOwnStash(height int) *protocol.StateTransition {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+//Returns the accounts of the shard this miner held at the given epoch height. All of them are returned, such that they
+//can be checked against the root of the shard in the epoch block.
+func ReadShardStateSnapshot(height int) *protocol.ShardState {
+	snapshot := shardStateSnapshot
+	if snapshot == nil || snapshot.Height != height {
+		return nil
+	}
+
+	return snapshot
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//trieNodeStore gives the state trie access to the nodes persisted in the statetrie bucket.
+type trieNodeStore struct {
+	db *bolt.DB
+}
+
+func (nodes trieNodeStore) GetTrieNode(hash [32]byte) (node []byte) {
+	nodes.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(STATETRIE_BUCKET))
+		//The slice returned by bolt is only valid during the transaction.
+		if encoded := b.Get(hash[:]); encoded != nil {
+			node = make([]byte, len(encoded))
+			copy(node, encoded)
+		}
+		return nil
+	})
+
+	return node
+}
+
+func (nodes trieNodeStore) PutTrieNode(hash [32]byte, node []byte) {
+	nodes.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(STATETRIE_BUCKET))
+		return b.Put(hash[:], node)
+	})
+}
+
+//Persists the nodes of a state trie in one transaction. Nodes are content-addressed, existing ones are shared
+//between the tries of different heights.
+func (store *BoltStore) WriteStateTrie(nodes protocol.MemoryTrieDatabase) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(STATETRIE_BUCKET))
+		for hash, node := range nodes {
+			if b.Get(hash[:]) != nil {
+				continue
+			}
+			if err := b.Put(hash[:], node); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (store *BoltStore) ReadStateTrieNode(hash [32]byte) []byte {
+	return trieNodeStore{store.db}.GetTrieNode(hash)
+}
+
+//Returns the account as it was committed to by the state root (e.g., Block.MerklePatriciaRoot).
+func (store *BoltStore) ReadStateTrieAccount(root [32]byte, address [64]byte) (*protocol.Account, error) {
+	return protocol.GetTrieAccount(trieNodeStore{store.db}, root, address)
+}
+
+//Returns the proof for the account under the given state root, verifiable with protocol.VerifyAccountProof.
+func (store *BoltStore) ReadStateTrieProof(root [32]byte, address [64]byte) ([][]byte, error) {
+	return protocol.ProveAccount(trieNodeStore{store.db}, root, address)
+}
@@ -1,32 +1,35 @@
 package storage
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 	"log"
-	"sync"
+	"os"
 	"time"
 )
 
 var (
-	db                 *bolt.DB
 	logger             *log.Logger
 	State              = make(map[[64]byte]*protocol.Account)
 	//This map keeps track of the relative account adjustments within a shard, such as balance, txcount and stakingheight
 	RelativeState                     = make(map[[64]byte]*protocol.RelativeAccount)
 	RootKeys                          = make(map[[64]byte]*protocol.Account)
-	txMemPool                         = make(map[[32]byte]protocol.Transaction)
 	ReceivedStateStash                      = protocol.NewStateStash()
 	OwnBlockStash           []*protocol.Block
 	OwnStateTransitionStash []*protocol.StateTransition
 	AllClosedBlocksAsc      []*protocol.Block
 	BootstrapServer         string
-	Buckets                 []string
-	memPoolMutex                                  	   = &sync.Mutex{}
 	ThisShardID             int // ID of the shard this validator is assigned to
-	txINVALIDMemPool        = make(map[[32]byte]protocol.Transaction)
 	ReceivedBlockStash      = make([]*protocol.Block, 0)
+	//In partitioned mode, State only holds the accounts of the shard given by PartitionShardID and PartitionNumberOfShards
+	//and all staking accounts. A number of shards of 0 denotes that State holds all accounts.
+	PartitionedState        bool
+	PartitionShardID        int
+	PartitionNumberOfShards int
+	//The accounts held at the last epoch block, served to validators which are newly assigned to the shard.
+	shardStateSnapshot      *protocol.ShardState
 )
 
 const (
@@ -42,35 +45,98 @@ const (
 	CLOSEDEPOCHBLOCK_BUCKET = "closedepochblocks"
 	LASTCLOSEDEPOCHBLOCK_BUCKET = "lastclosedepochblocks"
 	OPENEPOCHBLOCK_BUCKET	= "openepochblock"
+	STATETRIE_BUCKET		= "statetrie"
+	FRAUDPROOFS_BUCKET		= "fraudproofs"
+	RECEIPTS_BUCKET			= "receipts"
+	OPENTXS_BUCKET			= "opentxs"
+	INVALIDOPENTXS_BUCKET	= "invalidopentxs"
+	ACCOUNTTXS_BUCKET		= "accounttxs"
+	BLOCKHEIGHTS_BUCKET		= "blockheights"
+	EPOCHBLOCKHEIGHTS_BUCKET = "epochblockheights"
+	PRUNEDBLOCKS_BUCKET		= "prunedblocks"
+	PRUNEHEIGHT_BUCKET		= "pruneheight"
+
+	//The entries of the account histories are keyed by address (64 Byte) | height (4 Byte) | tx hash (32 Byte), such
+	//that the entries of an account are ordered by height.
+	ACCOUNTTX_KEY_LEN		= 100
 )
 
-//Entry function for the storage package
-func Init(dbname string, bootstrapIpport string) error {
+//Entry function for the storage package, returns the store of the given database.
+func Init(dbname string, bootstrapIpport string) (*BoltStore, error) {
 	BootstrapServer = bootstrapIpport
 	logger = InitLogger()
 
-	Buckets = []string {
-		OPENBLOCKS_BUCKET,
-		CLOSEDBLOCKS_BUCKET,
-		CLOSEDFUNDS_BUCKET,
-		CLOSEDACCS_BUCKET,
-		CLOSEDSTAKES_BUCKET,
-		CLOSEDCONFIGS_BUCKET,
-		LASTCLOSEDBLOCK_BUCKET,
-		GENESIS_BUCKET,
-		CLOSEDEPOCHBLOCK_BUCKET,
-		LASTCLOSEDEPOCHBLOCK_BUCKET,
-		OPENEPOCHBLOCK_BUCKET,
+	store, err := NewBoltStore(dbname)
+	if err != nil {
+		logger.Fatal(ERROR_MSG, err)
+		return nil, err
+	}
+
+	return store, nil
+}
+
+//BoltStore keeps the chain in a bolt database and journals the mempools in it.
+type BoltStore struct {
+	stateAccounts
+	*memPool
+	db      *bolt.DB
+	buckets []string
+}
+
+//Opens the database, the buckets of an existing one are kept, such that the node resumes with its chain after a
+//restart. Databases of an older schema version are migrated first.
+func NewBoltStore(dbname string) (*BoltStore, error) {
+	if logger == nil {
+		logger = InitLogger()
+	}
+
+	store := &BoltStore{
+		buckets: []string {
+			OPENBLOCKS_BUCKET,
+			CLOSEDBLOCKS_BUCKET,
+			CLOSEDFUNDS_BUCKET,
+			CLOSEDACCS_BUCKET,
+			CLOSEDSTAKES_BUCKET,
+			CLOSEDCONFIGS_BUCKET,
+			LASTCLOSEDBLOCK_BUCKET,
+			GENESIS_BUCKET,
+			CLOSEDEPOCHBLOCK_BUCKET,
+			LASTCLOSEDEPOCHBLOCK_BUCKET,
+			OPENEPOCHBLOCK_BUCKET,
+			STATETRIE_BUCKET,
+			FRAUDPROOFS_BUCKET,
+			RECEIPTS_BUCKET,
+			OPENTXS_BUCKET,
+			INVALIDOPENTXS_BUCKET,
+			ACCOUNTTXS_BUCKET,
+			BLOCKHEIGHTS_BUCKET,
+			EPOCHBLOCKHEIGHTS_BUCKET,
+			PRUNEDBLOCKS_BUCKET,
+			PRUNEHEIGHT_BUCKET,
+		},
+	}
+	store.memPool = newMemPool(store)
+
+	//Validated txs of registered tx types are stored in the bucket of their type.
+	for _, txType := range protocol.TxTypes() {
+		if !store.bucketExists(txType.Bucket) {
+			store.buckets = append(store.buckets, txType.Bucket)
+		}
 	}
 
 	var err error
-	db, err = bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	store.db, err = bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
-		logger.Fatal(ERROR_MSG, err)
-		return err
+		return nil, err
 	}
-	for _, bucket := range Buckets {
-		err = db.View(func(tx *bolt.Tx) error {
+
+	if err = store.initSchemaVersion(); err != nil {
+		store.db.Close()
+		return nil, err
+	}
+
+	for _, bucket := range store.buckets {
+		err = store.db.View(func(tx *bolt.Tx) error {
 			b := tx.Bucket([]byte(bucket))
 			if b == nil {
 				return fmt.Errorf("Bucket not found")
@@ -78,21 +144,80 @@ func Init(dbname string, bootstrapIpport string) error {
 			return nil
 		})
 
-		if(err == nil){
-			err = clearBucket(bucket)
-			logger.Printf("Bucket cleared: %v", bucket)
+		if err != nil {
+			err = CreateBucket(bucket, store.db)
 			if err != nil {
-				return err
+				store.db.Close()
+				return nil, err
 			}
-		} else {
-			err = CreateBucket(bucket, db)
-			if err != nil {
+		}
+	}
+
+	if err = store.migrate(); err != nil {
+		store.db.Close()
+		return nil, err
+	}
+
+	store.loadOpenTxs()
+
+	return store, nil
+}
+
+//Opens an existing database read-only, e.g. to inspect it while no miner runs on it. Neither buckets are created nor
+//migrations run, the database must therefore be of the current schema version. Writes to the store fail.
+func NewReadOnlyBoltStore(dbname string) (*BoltStore, error) {
+	if logger == nil {
+		logger = InitLogger()
+	}
+
+	//Bolt would create a missing database.
+	if _, err := os.Stat(dbname); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	store := &BoltStore{db: db, memPool: newMemPool(nil)}
+	if version := store.ReadSchemaVersion(); version != schemaVersion() {
+		db.Close()
+		return nil, fmt.Errorf("Database schema version %d differs from the current version %d, start the miner on it once to migrate it.", version, schemaVersion())
+	}
+
+	return store, nil
+}
+
+//Deletes all buckets of an existing database without opening it as a store, such that a database which cannot be
+//migrated can be wiped as well. The database is afterwards initialized like a new one.
+func WipeBoltStore(dbname string) error {
+	if _, err := os.Stat(dbname); os.IsNotExist(err) {
+		return nil
+	}
+
+	db, err := bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range bucketNames(tx) {
+			if err := tx.DeleteBucket(name); err != nil {
 				return err
 			}
 		}
-	}
+		return nil
+	})
+}
 
-	return nil
+func bucketNames(tx *bolt.Tx) (names [][]byte) {
+	tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		names = append(names, append([]byte{}, name...))
+		return nil
+	})
+	return names
 }
 
 func CreateBucket(bucketName string, db *bolt.DB) (err error) {
@@ -115,6 +240,30 @@ func DeleteBucket(bucketName string, db *bolt.DB) (err error) {
 	})
 }
 
-func TearDown() {
-	db.Close()
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *BoltStore) bucketExists(bucket string) bool {
+	for _, existing := range store.buckets {
+		if existing == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+
+func accountTxKey(address [64]byte, height uint32, txHash [32]byte) []byte {
+	key := make([]byte, ACCOUNTTX_KEY_LEN)
+	copy(key[:64], address[:])
+	binary.BigEndian.PutUint32(key[64:68], height)
+	copy(key[68:], txHash[:])
+	return key
+}
+
+func decodeAccountTxKey(key []byte) *AccountTx {
+	accountTx := &AccountTx{Height: binary.BigEndian.Uint32(key[64:68])}
+	copy(accountTx.TxHash[:], key[68:])
+	return accountTx
 }
@@ -1,19 +1,33 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"math"
 	"math/rand"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
 )
 
 //In-memory, k/v storage is tested with the test below
 func TestReadWriteDeleteTx(t *testing.T) {
+	for _, store := range testStores() {
+		testReadWriteDeleteTx(t, store)
+	}
+}
+
+func testReadWriteDeleteTx(t *testing.T, store Store) {
+	//More txs per account are written than the mempool admits by default.
+	defer func(accountCapacity int) { MemPoolAccountCapacity = accountCapacity }(MemPoolAccountCapacity)
+	MemPoolAccountCapacity = 0
 
 	rand := rand.New(rand.NewSource(time.Now().Unix()))
 
@@ -27,14 +41,14 @@ func TestReadWriteDeleteTx(t *testing.T) {
 	loopMax := testsize
 	for i := 0; i < loopMax; i++ {
 		tx, _ := protocol.ConstrFundsTx(0x01, rand.Uint64()%100000+1, rand.Uint64()%10+1, uint32(i), accA.Address, accB.Address, &PrivKeyA, nil)
-		WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 		hashFundsSlice = append(hashFundsSlice, tx)
 	}
 
 	loopMax = testsize
 	for i := 0; i < 1000; i++ {
 		tx, _, _ := protocol.ConstrContractTx(0, rand.Uint64()%100+1, &RootPrivKey, nil, nil)
-		WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 		hashAccSlice = append(hashAccSlice, tx)
 	}
 
@@ -43,7 +57,7 @@ func TestReadWriteDeleteTx(t *testing.T) {
 	for cnt := 0; cnt < loopMax; cnt++ {
 		tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), uint8(rand.Uint32()%5+1), rand.Uint64()%2342873423, rand.Uint64()%1000+1, uint8(cnt), &RootPrivKey)
 		hashConfigSlice = append(hashConfigSlice, tx)
-		WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 	}
 
 	loopMax = testsize
@@ -54,250 +68,262 @@ func TestReadWriteDeleteTx(t *testing.T) {
 		}
 		tx, _ := protocol.ConstrStakeTx(0, uint64(cnt), isStaking, accA.Address, &PrivKeyA, &CommitmentKeyA.PublicKey)
 		hashStakeSlice = append(hashStakeSlice, tx)
-		WriteOpenTx(tx)
+		store.WriteOpenTx(tx)
 	}
 
 	for _, tx := range hashFundsSlice {
-		if ReadOpenTx(tx.Hash()) == nil {
+		if store.ReadOpenTx(tx.Hash()) == nil {
 			t.Errorf("Error writing transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashAccSlice {
-		if ReadOpenTx(tx.Hash()) == nil {
+		if store.ReadOpenTx(tx.Hash()) == nil {
 			t.Errorf("Error writing transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashConfigSlice {
-		if ReadOpenTx(tx.Hash()) == nil {
+		if store.ReadOpenTx(tx.Hash()) == nil {
 			t.Errorf("Error writing transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashStakeSlice {
-		if ReadOpenTx(tx.Hash()) == nil {
+		if store.ReadOpenTx(tx.Hash()) == nil {
 			t.Errorf("Error writing transaction hash: %x\n", tx)
 		}
 	}
 
 	//Read all open txs, received in random order
-	opentxs := ReadAllOpenTxs()
+	opentxs := store.ReadAllOpenTxs()
 	//Comparing the total number of txs should be enough
 	lenTotalTxs := len(hashStakeSlice) + len(hashConfigSlice) + len(hashFundsSlice) + len(hashAccSlice)
 	if len(opentxs) != lenTotalTxs {
-		errorMsg := fmt.Sprintf("ReadAllOpenTxs() returned an invalid list of transactions\n"+
+		errorMsg := fmt.Sprintf("store.ReadAllOpenTxs() returned an invalid list of transactions\n"+
 			" (open: %d, total %d)\n", len(opentxs), lenTotalTxs)
 		t.Error(errorMsg)
 	}
 
 	//Deleting open txs
 	for _, tx := range hashFundsSlice {
-		DeleteOpenTx(tx)
+		store.DeleteOpenTx(tx)
 	}
 
 	for _, tx := range hashAccSlice {
-		DeleteOpenTx(tx)
+		store.DeleteOpenTx(tx)
 	}
 
 	for _, tx := range hashConfigSlice {
-		DeleteOpenTx(tx)
+		store.DeleteOpenTx(tx)
 	}
 
 	for _, tx := range hashStakeSlice {
-		DeleteOpenTx(tx)
+		store.DeleteOpenTx(tx)
 	}
 
 	//Make sure all txs are actually deleted
 	for _, tx := range hashFundsSlice {
-		if ReadOpenTx(tx.Hash()) != nil {
+		if store.ReadOpenTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashAccSlice {
-		if ReadOpenTx(tx.Hash()) != nil {
+		if store.ReadOpenTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashConfigSlice {
-		if ReadOpenTx(tx.Hash()) != nil {
+		if store.ReadOpenTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashStakeSlice {
-		if ReadOpenTx(tx.Hash()) != nil {
+		if store.ReadOpenTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	//Same with k/v-based closed tx storage
 	for _, tx := range hashAccSlice {
-		WriteClosedTx(tx)
+		store.WriteClosedTx(tx, 1)
 	}
 
 	for _, tx := range hashFundsSlice {
-		WriteClosedTx(tx)
+		store.WriteClosedTx(tx, 1)
 	}
 
 	for _, tx := range hashConfigSlice {
-		WriteClosedTx(tx)
+		store.WriteClosedTx(tx, 1)
 	}
 
 	for _, tx := range hashStakeSlice {
-		WriteClosedTx(tx)
+		store.WriteClosedTx(tx, 1)
 	}
 
 	for _, tx := range hashAccSlice {
-		if ReadClosedTx(tx.Hash()) == nil {
+		if store.ReadClosedTx(tx.Hash()) == nil {
 			t.Errorf("Error writing to k/v storage: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashFundsSlice {
-		if ReadClosedTx(tx.Hash()) == nil {
+		if store.ReadClosedTx(tx.Hash()) == nil {
 			t.Errorf("Error writing to k/v storage: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashConfigSlice {
-		if ReadClosedTx(tx.Hash()) == nil {
+		if store.ReadClosedTx(tx.Hash()) == nil {
 			t.Errorf("Error writing to k/v storage: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashStakeSlice {
-		if ReadClosedTx(tx.Hash()) == nil {
+		if store.ReadClosedTx(tx.Hash()) == nil {
 			t.Errorf("Error writing to k/v storage: %x\n", tx)
 		}
 	}
 
 	//Delete transactions from closed storage
 	for _, tx := range hashAccSlice {
-		DeleteClosedTx(tx)
+		store.DeleteClosedTx(tx)
 	}
 
 	for _, tx := range hashFundsSlice {
-		DeleteClosedTx(tx)
+		store.DeleteClosedTx(tx)
 	}
 
 	for _, tx := range hashConfigSlice {
-		DeleteClosedTx(tx)
+		store.DeleteClosedTx(tx)
 	}
 
 	for _, tx := range hashStakeSlice {
-		DeleteClosedTx(tx)
+		store.DeleteClosedTx(tx)
 	}
 
 	//Make sure all txs are actually deleted
 	for _, tx := range hashAccSlice {
-		if ReadClosedTx(tx.Hash()) != nil {
+		if store.ReadClosedTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashFundsSlice {
-		if ReadClosedTx(tx.Hash()) != nil {
+		if store.ReadClosedTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashConfigSlice {
-		if ReadClosedTx(tx.Hash()) != nil {
+		if store.ReadClosedTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 
 	for _, tx := range hashStakeSlice {
-		if ReadClosedTx(tx.Hash()) != nil {
+		if store.ReadClosedTx(tx.Hash()) != nil {
 			t.Errorf("Error deleting transaction hash: %x\n", tx)
 		}
 	}
 }
 
-//Disk-based k/v storage
+//k/v storage of the blocks
 func TestReadWriteDeleteBlock(t *testing.T) {
+	for _, store := range testStores() {
+		testReadWriteDeleteBlock(t, store)
+	}
+}
+
+func testReadWriteDeleteBlock(t *testing.T, store Store) {
 
 	//No panic
-	DeleteOpenBlock([32]byte{'0'})
+	store.DeleteOpenBlock([32]byte{'0'})
 
 	b, b2, b3 := new(protocol.Block), new(protocol.Block), new(protocol.Block)
 	b.Hash = [32]byte{'0'}
 	b2.Hash = [32]byte{'1'}
 	b3.Hash = [32]byte{'2'}
-	WriteOpenBlock(b)
-	WriteOpenBlock(b2)
-	WriteOpenBlock(b3)
+	store.WriteOpenBlock(b)
+	store.WriteOpenBlock(b2)
+	store.WriteOpenBlock(b3)
 
-	if ReadOpenBlock(b.Hash) == nil || ReadOpenBlock(b2.Hash) == nil || ReadOpenBlock(b3.Hash) == nil {
+	if store.ReadOpenBlock(b.Hash) == nil || store.ReadOpenBlock(b2.Hash) == nil || store.ReadOpenBlock(b3.Hash) == nil {
 		t.Error("Failed to write block to open block storage.\n")
 	}
 
-	newb1 := ReadOpenBlock(b.Hash)
-	newb2 := ReadOpenBlock(b2.Hash)
-	newb3 := ReadOpenBlock(b3.Hash)
+	newb1 := store.ReadOpenBlock(b.Hash)
+	newb2 := store.ReadOpenBlock(b2.Hash)
+	newb3 := store.ReadOpenBlock(b3.Hash)
 
-	DeleteOpenBlock(newb1.Hash)
-	DeleteOpenBlock(newb2.Hash)
-	DeleteOpenBlock(newb3.Hash)
+	store.DeleteOpenBlock(newb1.Hash)
+	store.DeleteOpenBlock(newb2.Hash)
+	store.DeleteOpenBlock(newb3.Hash)
 
-	WriteClosedBlock(newb1)
-	WriteClosedBlock(newb2)
-	WriteClosedBlock(newb3)
+	store.WriteClosedBlock(newb1)
+	store.WriteClosedBlock(newb2)
+	store.WriteClosedBlock(newb3)
 
-	if ReadOpenBlock(newb1.Hash) != nil ||
-		ReadOpenBlock(newb2.Hash) != nil ||
-		ReadOpenBlock(newb3.Hash) != nil ||
-		ReadClosedBlock(b.Hash) == nil ||
-		ReadClosedBlock(b2.Hash) == nil ||
-		ReadClosedBlock(b3.Hash) == nil {
+	if store.ReadOpenBlock(newb1.Hash) != nil ||
+		store.ReadOpenBlock(newb2.Hash) != nil ||
+		store.ReadOpenBlock(newb3.Hash) != nil ||
+		store.ReadClosedBlock(b.Hash) == nil ||
+		store.ReadClosedBlock(b2.Hash) == nil ||
+		store.ReadClosedBlock(b3.Hash) == nil {
 		t.Error("Failed to write block to closed block storage.\n")
 	}
 
-	DeleteClosedBlock(newb1.Hash)
-	DeleteClosedBlock(newb2.Hash)
-	DeleteClosedBlock(newb3.Hash)
+	store.DeleteClosedBlock(newb1.Hash)
+	store.DeleteClosedBlock(newb2.Hash)
+	store.DeleteClosedBlock(newb3.Hash)
 
-	if ReadClosedBlock(b.Hash) != nil ||
-		ReadClosedBlock(b2.Hash) != nil ||
-		ReadClosedBlock(b3.Hash) != nil {
+	if store.ReadClosedBlock(b.Hash) != nil ||
+		store.ReadClosedBlock(b2.Hash) != nil ||
+		store.ReadClosedBlock(b3.Hash) != nil {
 		t.Error("Failed to delete block from closed block storage.\n")
 	}
 
-	WriteLastClosedBlock(newb1)
+	store.WriteLastClosedBlock(newb1)
 
-	if ReadLastClosedBlock() == nil {
+	if store.ReadLastClosedBlock() == nil {
 		t.Error("Failed to write block to last closed block storage.\n")
 	}
-	if !reflect.DeepEqual(newb1, ReadLastClosedBlock()) {
+	if !reflect.DeepEqual(newb1, store.ReadLastClosedBlock()) {
 		t.Error("Failed to read last closed block from storage")
 	}
 
-	DeleteLastClosedBlock(newb1.Hash)
+	store.DeleteLastClosedBlock(newb1.Hash)
 
-	if ReadLastClosedBlock() != nil {
+	if store.ReadLastClosedBlock() != nil {
 		t.Error("Failed to delete last closed block from storage.\n")
 	}
 
-	WriteLastClosedBlock(newb1)
+	store.WriteLastClosedBlock(newb1)
 
-	if ReadLastClosedBlock() == nil {
+	if store.ReadLastClosedBlock() == nil {
 		t.Error("Failed to write block to last closed block storage.\n")
 	}
 
-	DeleteAllLastClosedBlock()
+	store.DeleteAllLastClosedBlock()
 
-	if ReadLastClosedBlock() != nil {
+	if store.ReadLastClosedBlock() != nil {
 		t.Error("Failed to delete last closed block from storage.\n")
 	}
 }
 
 func TestReadWriteDeleteEpochBlock(t *testing.T) {
+	for _, store := range testStores() {
+		testReadWriteDeleteEpochBlock(t, store)
+	}
+}
+
+func testReadWriteDeleteEpochBlock(t *testing.T, store Store) {
 
 	//No panic
-	DeleteOpenEpochBlock([32]byte{'0'})
+	store.DeleteOpenEpochBlock([32]byte{'0'})
 
 	//Initialize epoch blocks
 	b, b2, b3 := new(protocol.EpochBlock), new(protocol.EpochBlock), new(protocol.EpochBlock)
@@ -347,46 +373,298 @@ func TestReadWriteDeleteEpochBlock(t *testing.T) {
 	b3.PrevShardHashes = prevShardHashesEpochBlock3
 	b3.Height = heightEpochBlock3
 
-	WriteOpenEpochBlock(b)
-	WriteOpenEpochBlock(b2)
-	WriteOpenEpochBlock(b3)
+	store.WriteOpenEpochBlock(b)
+	store.WriteOpenEpochBlock(b2)
+	store.WriteOpenEpochBlock(b3)
 
-	if ReadOpenEpochBlock(b.Hash) == nil || ReadOpenEpochBlock(b2.Hash) == nil || ReadOpenEpochBlock(b3.Hash) == nil {
+	if store.ReadOpenEpochBlock(b.Hash) == nil || store.ReadOpenEpochBlock(b2.Hash) == nil || store.ReadOpenEpochBlock(b3.Hash) == nil {
 		t.Error("Failed to write epoch block to open block storage.\n")
 	}
 
-	newb1 := ReadOpenEpochBlock(b.Hash)
-	newb2 := ReadOpenEpochBlock(b2.Hash)
-	newb3 := ReadOpenEpochBlock(b3.Hash)
+	newb1 := store.ReadOpenEpochBlock(b.Hash)
+	newb2 := store.ReadOpenEpochBlock(b2.Hash)
+	newb3 := store.ReadOpenEpochBlock(b3.Hash)
 
-	DeleteOpenEpochBlock(newb1.Hash)
-	DeleteOpenEpochBlock(newb2.Hash)
-	DeleteOpenEpochBlock(newb3.Hash)
+	store.DeleteOpenEpochBlock(newb1.Hash)
+	store.DeleteOpenEpochBlock(newb2.Hash)
+	store.DeleteOpenEpochBlock(newb3.Hash)
 
-	WriteClosedEpochBlock(newb1)
-	WriteClosedEpochBlock(newb2)
-	WriteClosedEpochBlock(newb3)
+	store.WriteClosedEpochBlock(newb1)
+	store.WriteClosedEpochBlock(newb2)
+	store.WriteClosedEpochBlock(newb3)
 
-	if ReadOpenEpochBlock(newb1.Hash) != nil ||
-		ReadOpenEpochBlock(newb2.Hash) != nil ||
-		ReadOpenEpochBlock(newb3.Hash) != nil ||
-		ReadClosedEpochBlock(b.Hash) == nil ||
-		ReadClosedEpochBlock(b2.Hash) == nil ||
-		ReadClosedEpochBlock(b3.Hash) == nil {
+	if store.ReadOpenEpochBlock(newb1.Hash) != nil ||
+		store.ReadOpenEpochBlock(newb2.Hash) != nil ||
+		store.ReadOpenEpochBlock(newb3.Hash) != nil ||
+		store.ReadClosedEpochBlock(b.Hash) == nil ||
+		store.ReadClosedEpochBlock(b2.Hash) == nil ||
+		store.ReadClosedEpochBlock(b3.Hash) == nil {
 		t.Error("Failed to write epoch block to closed block storage.\n")
 	}
 
-	DeleteClosedEpochBlock(newb1.Hash)
-	DeleteClosedEpochBlock(newb2.Hash)
-	DeleteClosedEpochBlock(newb3.Hash)
+	store.DeleteClosedEpochBlock(newb1.Hash)
+	store.DeleteClosedEpochBlock(newb2.Hash)
+	store.DeleteClosedEpochBlock(newb3.Hash)
 
-	if ReadClosedEpochBlock(b.Hash) != nil ||
-		ReadClosedEpochBlock(b2.Hash) != nil ||
-		ReadClosedEpochBlock(b3.Hash) != nil {
+	if store.ReadClosedEpochBlock(b.Hash) != nil ||
+		store.ReadClosedEpochBlock(b2.Hash) != nil ||
+		store.ReadClosedEpochBlock(b3.Hash) != nil {
 		t.Error("Failed to delete block from closed block storage.\n")
 	}
 }
 
+//The history of an account lists its closed txs newest first and follows rollbacks.
+func TestAccountTxs(t *testing.T) {
+	for _, store := range testStores() {
+		testAccountTxs(t, store)
+	}
+}
+
+func testAccountTxs(t *testing.T, store Store) {
+	fundsTxAB, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	fundsTxBA, _ := protocol.ConstrFundsTx(0x01, 50, 1, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+	stakeTxA, _ := protocol.ConstrStakeTx(0, 1, true, accA.Address, &PrivKeyA, &CommitmentKeyA.PublicKey)
+	store.WriteClosedTx(fundsTxAB, 1)
+	store.WriteClosedTx(fundsTxBA, 2)
+	store.WriteClosedTx(stakeTxA, 3)
+
+	history := store.ReadAccountTxs(accA.Address, 0, 10)
+	if len(history) != 3 ||
+		*history[0] != (AccountTx{3, stakeTxA.Hash()}) ||
+		*history[1] != (AccountTx{2, fundsTxBA.Hash()}) ||
+		*history[2] != (AccountTx{1, fundsTxAB.Hash()}) {
+		t.Errorf("History of account A is wrong: %v\n", history)
+	}
+
+	if page := store.ReadAccountTxs(accA.Address, 1, 1); len(page) != 1 || page[0].TxHash != fundsTxBA.Hash() {
+		t.Errorf("Page of the history of account A is wrong: %v\n", page)
+	}
+	if page := store.ReadAccountTxs(accA.Address, 3, 10); len(page) != 0 {
+		t.Errorf("Page after the end of the history is not empty: %v\n", page)
+	}
+	if history := store.ReadAccountTxs(accB.Address, 0, 10); len(history) != 2 {
+		t.Errorf("History of account B is wrong: %v\n", history)
+	}
+
+	//A credited cross-shard tx is listed in the history of the receiver only.
+	crossShardTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 1, accB.Address, accA.Address, &PrivKeyB, nil)
+	store.WriteReceipt(protocol.NewReceipt(crossShardTx, 1, [32]byte{}, nil), 4)
+	if history := store.ReadAccountTxs(accA.Address, 0, 1); len(history) != 1 || *history[0] != (AccountTx{4, crossShardTx.Hash()}) {
+		t.Errorf("Credited receipt is not in the history of account A: %v\n", history)
+	}
+	if history := store.ReadAccountTxs(accB.Address, 0, 10); len(history) != 2 {
+		t.Errorf("Credited receipt is in the history of the sender: %v\n", history)
+	}
+	store.DeleteReceipt(crossShardTx.Hash())
+	if history := store.ReadAccountTxs(accA.Address, 0, 10); len(history) != 3 || store.ReadReceipt(crossShardTx.Hash()) != nil {
+		t.Errorf("Deleted receipt is still in the history of account A: %v\n", history)
+	}
+
+	store.DeleteClosedTx(fundsTxBA)
+	if history := store.ReadAccountTxs(accA.Address, 0, 10); len(history) != 2 || history[1].TxHash != fundsTxAB.Hash() {
+		t.Errorf("Deleted tx is still in the history of account A: %v\n", history)
+	}
+	if history := store.ReadAccountTxs(accB.Address, 0, 10); len(history) != 1 || history[0].TxHash != fundsTxAB.Hash() {
+		t.Errorf("Deleted tx is still in the history of account B: %v\n", history)
+	}
+
+	store.DeleteClosedTx(fundsTxAB)
+	store.DeleteClosedTx(stakeTxA)
+	if history := store.ReadAccountTxs(accA.Address, 0, 10); len(history) != 0 {
+		t.Errorf("History of account A is not empty: %v\n", history)
+	}
+}
+
+//The height index lists the last block written at a height and follows rollbacks.
+func TestClosedBlocksByHeight(t *testing.T) {
+	for _, store := range testStores() {
+		testClosedBlocksByHeight(t, store)
+	}
+}
+
+func testClosedBlocksByHeight(t *testing.T, store Store) {
+	var blocks []*protocol.Block
+	for height := uint32(1); height <= 4; height++ {
+		b := new(protocol.Block)
+		b.Hash = [32]byte{'h', byte(height)}
+		b.Height = height
+		store.WriteClosedBlock(b)
+		blocks = append(blocks, b)
+	}
+
+	if b := store.ReadClosedBlockByHeight(3); b == nil || b.Hash != blocks[2].Hash {
+		t.Errorf("Failed to read block by height: %v\n", b)
+	}
+	if b := store.ReadClosedBlockByHeight(5); b != nil {
+		t.Errorf("Read block at a height without block: %v\n", b)
+	}
+
+	var heights []uint32
+	store.ForEachClosedBlock(2, 10, func(b *protocol.Block) bool {
+		heights = append(heights, b.Height)
+		return true
+	})
+	if !reflect.DeepEqual(heights, []uint32{2, 3, 4}) {
+		t.Errorf("Ascending range is wrong: %v\n", heights)
+	}
+
+	heights = nil
+	store.ForEachClosedBlock(4, 1, func(b *protocol.Block) bool {
+		heights = append(heights, b.Height)
+		return b.Height > 3
+	})
+	if !reflect.DeepEqual(heights, []uint32{4, 3}) {
+		t.Errorf("Descending range is wrong: %v\n", heights)
+	}
+
+	//A competing block replaces the block at its height, deleting the replaced block keeps the competing one.
+	competing := new(protocol.Block)
+	competing.Hash = [32]byte{'c', 4}
+	competing.Height = 4
+	store.WriteClosedBlock(competing)
+	store.DeleteClosedBlock(blocks[3].Hash)
+	if b := store.ReadClosedBlockByHeight(4); b == nil || b.Hash != competing.Hash {
+		t.Errorf("Competing block is not indexed at its height: %v\n", b)
+	}
+
+	//Rolling back removes the block from the index.
+	store.DeleteClosedBlock(competing.Hash)
+	if b := store.ReadClosedBlockByHeight(4); b != nil {
+		t.Errorf("Rolled back block is still indexed: %v\n", b)
+	}
+
+	epochBlock := new(protocol.EpochBlock)
+	epochBlock.Hash = [32]byte{'e', 5}
+	epochBlock.Height = 5
+	store.WriteClosedEpochBlock(epochBlock)
+	if b := store.ReadClosedEpochBlockByHeight(5); b == nil || b.Hash != epochBlock.Hash {
+		t.Errorf("Failed to read epoch block by height: %v\n", b)
+	}
+	if b := store.ReadClosedBlockByHeight(5); b != nil {
+		t.Errorf("Epoch block is indexed as block: %v\n", b)
+	}
+
+	count := 0
+	store.ForEachClosedEpochBlock(0, 10, func(b *protocol.EpochBlock) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("Range over epoch blocks returned %v epoch blocks instead of 1\n", count)
+	}
+
+	store.DeleteClosedEpochBlock(epochBlock.Hash)
+	if b := store.ReadClosedEpochBlockByHeight(5); b != nil {
+		t.Errorf("Deleted epoch block is still indexed: %v\n", b)
+	}
+
+	for _, b := range blocks[:3] {
+		store.DeleteClosedBlock(b.Hash)
+	}
+}
+
+//Pruning deletes the blocks below the prune height with their txs and remembers their hashes.
+func TestPruneClosedBlocks(t *testing.T) {
+	for _, store := range testStores() {
+		testPruneClosedBlocks(t, store)
+	}
+}
+
+func testPruneClosedBlocks(t *testing.T, store Store) {
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+
+	//Every block commits to a state of its own, the accounts of accY are shared by all of them.
+	accX := protocol.NewAccount([64]byte{'x'}, [64]byte{}, 0, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	accY := protocol.NewAccount([64]byte{'y'}, [64]byte{}, 1, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+
+	var blocks []*protocol.Block
+	for height := uint32(1); height <= 4; height++ {
+		b := new(protocol.Block)
+		b.Hash = [32]byte{'p', byte(height)}
+		b.Height = height
+		accX.Balance = 100 * uint64(height)
+		stateTrie := protocol.NewMemoryTrieDatabase()
+		b.MerklePatriciaRoot = protocol.BuildStateTrie(map[[64]byte]*protocol.Account{accX.Address: &accX, accY.Address: &accY}, stateTrie)
+		store.WriteStateTrie(stateTrie)
+		if height == 2 {
+			b.AddTxHash(protocol.FUNDSTX_TYPE, fundsTx.Hash())
+			store.WriteClosedTx(fundsTx, height)
+		}
+		store.WriteClosedBlock(b)
+		blocks = append(blocks, b)
+	}
+
+	//The state of the epoch block stays provable.
+	epochBlock := protocol.NewEpochBlock(nil, 2)
+	epochBlock.Hash = [32]byte{'p', 'e'}
+	epochBlock.MerklePatriciaRoot = blocks[1].MerklePatriciaRoot
+	store.WriteClosedEpochBlock(epochBlock)
+
+	trieNodes := countTrieNodes(store)
+	if pruned, err := store.PruneClosedBlocks(3); err != nil || pruned != 2 {
+		t.Errorf("Pruning returned %d pruned blocks instead of 2: %v\n", pruned, err)
+	}
+	if store.ReadPruneHeight() != 3 {
+		t.Errorf("Prune height is %d instead of 3\n", store.ReadPruneHeight())
+	}
+
+	for _, b := range blocks[:2] {
+		if store.ReadClosedBlock(b.Hash) != nil || store.ReadClosedBlockByHeight(b.Height) != nil {
+			t.Errorf("Block at height %d was not pruned\n", b.Height)
+		}
+		if height, pruned := store.ReadPrunedBlockHeight(b.Hash); !pruned || height != b.Height {
+			t.Errorf("Pruned block at height %d is not listed as pruned\n", b.Height)
+		}
+	}
+	for _, b := range blocks[2:] {
+		if store.ReadClosedBlock(b.Hash) == nil {
+			t.Errorf("Block at height %d was pruned\n", b.Height)
+		}
+		if _, pruned := store.ReadPrunedBlockHeight(b.Hash); pruned {
+			t.Errorf("Block at height %d is listed as pruned\n", b.Height)
+		}
+	}
+
+	if store.ReadClosedTx(fundsTx.Hash()) != nil || len(store.ReadAccountTxs(accA.Address, 0, 10)) != 0 {
+		t.Error("Tx of a pruned block was not pruned.")
+	}
+
+	if count := countTrieNodes(store); count >= trieNodes {
+		t.Errorf("State trie was not pruned: %d nodes before, %d after\n", trieNodes, count)
+	}
+	if _, err := store.ReadStateTrieAccount(blocks[0].MerklePatriciaRoot, accX.Address); err == nil {
+		t.Error("State trie of a pruned block was not pruned.")
+	}
+	for _, root := range [][32]byte{blocks[1].MerklePatriciaRoot, blocks[2].MerklePatriciaRoot, blocks[3].MerklePatriciaRoot} {
+		if _, err := store.ReadStateTrieAccount(root, accY.Address); err != nil {
+			t.Errorf("State trie of a kept block or epoch block was pruned: %v\n", err)
+		}
+	}
+
+	//The prune height is never lowered.
+	if pruned, _ := store.PruneClosedBlocks(2); pruned != 0 || store.ReadPruneHeight() != 3 {
+		t.Error("Pruning below the prune height changed the store.")
+	}
+
+	for _, b := range blocks[2:] {
+		store.DeleteClosedBlock(b.Hash)
+	}
+	store.DeleteClosedEpochBlock(epochBlock.Hash)
+}
+
+func countTrieNodes(store Store) (count int) {
+	switch store := store.(type) {
+	case *BoltStore:
+		store.db.View(func(tx *bolt.Tx) error {
+			count = tx.Bucket([]byte(STATETRIE_BUCKET)).Stats().KeyN
+			return nil
+		})
+	case *MemoryStore:
+		count = len(store.stateTrie)
+	}
+	return count
+}
+
 func TestRelativeStateTransition(t *testing.T) {
 	var statePrev = make(map[[64]byte]protocol.Account)
 	var stateNow = make(map[[64]byte]*protocol.Account)
@@ -548,4 +826,702 @@ func TestApplyRelativeStateTransition(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+func TestRevertRelativeStateTransition(t *testing.T) {
+	var state = make(map[[64]byte]*protocol.Account)
+	var stateRelative = make(map[[64]byte]*protocol.RelativeAccount)
+
+	accA := protocol.NewAccount([64]byte{'0'},[64]byte{},100,true,[crypto.COMM_KEY_LENGTH]byte{},nil,nil)
+	accA.TxCnt = 1
+	accA.StakingBlockHeight = 5
+	state[[64]byte{'0'}] = &accA
+
+	accB := protocol.NewAccount([64]byte{'1'},[64]byte{},100,true,[crypto.COMM_KEY_LENGTH]byte{},nil,nil)
+	state[[64]byte{'1'}] = &accB
+
+	accARelState := protocol.NewRelativeAccount([64]byte{'0'},[64]byte{},-10,true,[crypto.COMM_KEY_LENGTH]byte{},nil,nil)
+	accARelState.TxCnt = 9
+	accARelState.StakingBlockHeight = 10
+	stateRelative[[64]byte{'0'}] = &accARelState
+
+	accBRelState := protocol.NewRelativeAccount([64]byte{'1'},[64]byte{},10,true,[crypto.COMM_KEY_LENGTH]byte{},nil,nil)
+	stateRelative[[64]byte{'1'}] = &accBRelState
+
+	//Applying and reverting a relative state must result in the initial state
+	state = ApplyRelativeState(state,stateRelative)
+	state = RevertRelativeState(state,stateRelative)
+
+	if state[[64]byte{'0'}].Balance != 100 || state[[64]byte{'0'}].TxCnt != 1 || state[[64]byte{'0'}].StakingBlockHeight != 5 {
+		t.Errorf("reverting relative state failed: %v\n", state[[64]byte{'0'}])
+	}
+
+	if state[[64]byte{'1'}].Balance != 100 {
+		t.Errorf("reverting relative state failed: %v\n", state[[64]byte{'1'}])
+	}
+}
+
+func TestReadShardStateSnapshot(t *testing.T) {
+	var addressA, addressB [64]byte
+	addressA[7], addressB[7] = 1, 3
+
+	accA := protocol.NewAccount(addressA, [64]byte{}, 100, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	accB := protocol.NewAccount(addressB, [64]byte{}, 200, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+
+	WriteShardStateSnapshot(protocol.NewShardState(10, 1, 2, []*protocol.Account{&accA, &accB}))
+	defer WriteShardStateSnapshot(nil)
+
+	if ReadShardStateSnapshot(20) != nil {
+		t.Error("Shard state of another epoch height has been returned.")
+	}
+
+	//All accounts are returned, the root of the shard covers all of them.
+	shardState := ReadShardStateSnapshot(10)
+	if shardState == nil || len(shardState.Accounts) != 2 || shardState.Root() != protocol.StateRoot(map[[64]byte]*protocol.Account{addressA: &accA, addressB: &accB}) {
+		t.Fatalf("Shard state does not contain all accounts of the shard: %v\n", shardState)
+	}
+
+	if shardState.ShardID != 1 || shardState.NumberOfShards != 2 {
+		t.Errorf("Shard state does not denote the shard of the snapshot: %v\n", shardState)
+	}
+}
+
+//Open txs are reloaded from the journal when the storage is initialized again.
+func TestOpenTxJournal(t *testing.T) {
+	boltStore.DeleteAll()
+
+	openTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	invalidTx, _ := protocol.ConstrFundsTx(0x01, 200, 1, 1, accA.Address, accB.Address, &PrivKeyA, nil)
+	deletedTx, _ := protocol.ConstrFundsTx(0x01, 300, 1, 2, accA.Address, accB.Address, &PrivKeyA, nil)
+	boltStore.WriteOpenTx(openTx)
+	boltStore.WriteINVALIDOpenTx(invalidTx, nil)
+	boltStore.WriteOpenTx(deletedTx)
+	boltStore.DeleteOpenTx(deletedTx)
+
+	//An entry which cannot be decoded is evicted.
+	garbageHash := [32]byte{0x01}
+	writeJournal(t, OPENTXS_BUCKET, garbageHash, []byte{protocol.FUNDSTX_TYPE, 0x42})
+
+	boltStore.Close()
+	boltStore, _ = Init(TestDBFileName, TestIpPort)
+
+	if !reflect.DeepEqual(boltStore.ReadOpenTx(openTx.Hash()), openTx) {
+		t.Errorf("Open tx was not reloaded: %v\n", boltStore.ReadOpenTx(openTx.Hash()))
+	}
+	if !reflect.DeepEqual(boltStore.ReadINVALIDOpenTx(invalidTx.Hash()), invalidTx) {
+		t.Errorf("Invalid open tx was not reloaded: %v\n", boltStore.ReadINVALIDOpenTx(invalidTx.Hash()))
+	}
+	if boltStore.ReadOpenTx(deletedTx.Hash()) != nil || boltStore.ReadOpenTx(garbageHash) != nil {
+		t.Error("Deleted or undecodable tx was reloaded.")
+	}
+	if boltStore.GetMemPoolSize() != 1 {
+		t.Errorf("Mempool size is %d after reloading, expected 1.\n", boltStore.GetMemPoolSize())
+	}
+
+	staleTxs := boltStore.DeleteStaleOpenTxs(func(tx protocol.Transaction) bool {
+		return tx.Hash() == invalidTx.Hash()
+	})
+	if len(staleTxs) != 1 || boltStore.ReadINVALIDOpenTx(invalidTx.Hash()) != nil {
+		t.Errorf("Stale tx was not removed: %v\n", staleTxs)
+	}
+
+	//The txs with the lowest fee rates are evicted if the journal exceeds the capacity of the mempool.
+	boltStore.DeleteAll()
+	highFeeTx, _ := protocol.ConstrFundsTx(0x01, 100, 5, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+	boltStore.WriteOpenTx(openTx)
+	boltStore.WriteOpenTx(highFeeTx)
+
+	prevCapacity := MemPoolCapacity
+	defer func() { MemPoolCapacity = prevCapacity }()
+	MemPoolCapacity = 1
+	boltStore.Close()
+	boltStore, _ = Init(TestDBFileName, TestIpPort)
+	MemPoolCapacity = prevCapacity
+
+	if boltStore.GetMemPoolSize() != 1 || boltStore.ReadOpenTx(highFeeTx.Hash()) == nil {
+		t.Errorf("Mempool holds %d txs after reloading with a capacity of 1, expected the tx with the higher fee.\n", boltStore.GetMemPoolSize())
+	}
+
+	//The eviction is journaled.
+	boltStore.Close()
+	boltStore, _ = Init(TestDBFileName, TestIpPort)
+	if boltStore.ReadOpenTx(openTx.Hash()) != nil {
+		t.Error("Evicted tx was reloaded.")
+	}
+
+	boltStore.DeleteAll()
+}
+
+//The chain of an existing database is kept when the storage is initialized again.
+func TestInitKeepsChain(t *testing.T) {
+	boltStore.DeleteAll()
+
+	block := protocol.NewBlock([32]byte{0x01}, 2)
+	block.Hash = block.HashBlock()
+	boltStore.WriteClosedBlock(block)
+	boltStore.WriteLastClosedBlock(block)
+
+	boltStore.Close()
+	boltStore, _ = Init(TestDBFileName, TestIpPort)
+
+	if closedBlock := boltStore.ReadClosedBlock(block.Hash); closedBlock == nil || closedBlock.Hash != block.Hash {
+		t.Errorf("Closed block was not kept: %v\n", closedBlock)
+	}
+	if lastBlock := boltStore.ReadLastClosedBlock(); lastBlock == nil || lastBlock.Hash != block.Hash {
+		t.Errorf("Last closed block was not kept: %v\n", lastBlock)
+	}
+
+	boltStore.DeleteAll()
+	if boltStore.ReadClosedBlock(block.Hash) != nil {
+		t.Error("Closed block was not deleted.")
+	}
+}
+
+//Databases created before schema versioning are migrated to the current schema version when they are opened.
+func TestMigrations(t *testing.T) {
+	const dbname = "migration_test.db"
+	defer os.Remove(dbname)
+
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+
+	// b1 <- b2, b2 competes with fork at height 2
+	b1 := protocol.NewBlock([32]byte{}, 1)
+	b1.TxData[protocol.FUNDSTX_TYPE] = [][32]byte{fundsTx.Hash()}
+	b1.Hash = b1.HashBlock()
+	b2 := protocol.NewBlock(b1.Hash, 2)
+	b2.Hash = b2.HashBlock()
+	fork := protocol.NewBlock([32]byte{'f'}, 2)
+	fork.Hash = fork.HashBlock()
+	epochBlock := protocol.NewEpochBlock(nil, 3)
+	epochBlock.Hash = [32]byte{'e'}
+
+	//Layout before versioning, without indexes.
+	db, err := bolt.Open(dbname, 0600, nil)
+	if err != nil {
+		t.Fatalf("Database could not be created: %v\n", err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		closedBlocks, _ := tx.CreateBucket([]byte(CLOSEDBLOCKS_BUCKET))
+		for _, b := range []*protocol.Block{b2, fork, b1} {
+			closedBlocks.Put(b.Hash[:], b.Encode())
+		}
+		lastClosedBlock, _ := tx.CreateBucket([]byte(LASTCLOSEDBLOCK_BUCKET))
+		lastClosedBlock.Put(b2.Hash[:], b2.Encode())
+		closedFunds, _ := tx.CreateBucket([]byte(CLOSEDFUNDS_BUCKET))
+		fundsTxHash := fundsTx.Hash()
+		closedFunds.Put(fundsTxHash[:], fundsTx.Encode())
+		closedEpochBlocks, _ := tx.CreateBucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
+		closedEpochBlocks.Put(epochBlock.Hash[:], epochBlock.Encode())
+		closedEpochBlocks.Put([]byte("firstepochblock"), epochBlock.Encode())
+		return nil
+	})
+	db.Close()
+
+	store, err := NewBoltStore(dbname)
+	if err != nil {
+		t.Fatalf("Database could not be migrated: %v\n", err)
+	}
+
+	if store.ReadSchemaVersion() != schemaVersion() {
+		t.Errorf("Database has schema version %d instead of %d\n", store.ReadSchemaVersion(), schemaVersion())
+	}
+	if b := store.ReadClosedBlockByHeight(2); b == nil || b.Hash != b2.Hash {
+		t.Errorf("Block of the chain is not indexed at height 2: %v\n", b)
+	}
+	if b := store.ReadClosedBlockByHeight(1); b == nil || b.Hash != b1.Hash {
+		t.Errorf("Block of the chain is not indexed at height 1: %v\n", b)
+	}
+	if b := store.ReadClosedEpochBlockByHeight(3); b == nil || b.Hash != epochBlock.Hash {
+		t.Errorf("Epoch block is not indexed at height 3: %v\n", b)
+	}
+	if history := store.ReadAccountTxs(accB.Address, 0, 10); len(history) != 1 || *history[0] != (AccountTx{1, fundsTx.Hash()}) {
+		t.Errorf("Closed tx is not in the history of account B: %v\n", history)
+	}
+
+	//The schema version survives the deletion of the chain.
+	store.DeleteAll()
+	store.Close()
+
+	db, _ = bolt.Open(dbname, 0600, nil)
+	db.Update(func(tx *bolt.Tx) error {
+		if readSchemaVersion(tx) != schemaVersion() {
+			t.Errorf("Schema version was deleted with the chain: %d\n", readSchemaVersion(tx))
+		}
+		//A database of a newer version is not opened.
+		return writeSchemaVersion(tx, schemaVersion()+1)
+	})
+	db.Close()
+
+	if store, err := NewBoltStore(dbname); err == nil {
+		store.Close()
+		t.Error("Database of a newer schema version was opened.")
+	}
+
+	//A migration fails on an entry it cannot decode and names it.
+	for _, bucket := range []string{CLOSEDBLOCKS_BUCKET, CLOSEDFUNDS_BUCKET, CLOSEDEPOCHBLOCK_BUCKET} {
+		os.Remove(dbname)
+		db, _ = bolt.Open(dbname, 0600, nil)
+		db.Update(func(tx *bolt.Tx) error {
+			closedBlocks, _ := tx.CreateBucket([]byte(CLOSEDBLOCKS_BUCKET))
+			closedBlocks.Put(b1.Hash[:], b1.Encode())
+			closedFunds, _ := tx.CreateBucket([]byte(CLOSEDFUNDS_BUCKET))
+			closedEpochBlocks, _ := tx.CreateBucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
+			switch bucket {
+			case CLOSEDBLOCKS_BUCKET:
+				closedBlocks.Put([]byte{'c'}, []byte{0x01})
+			case CLOSEDFUNDS_BUCKET:
+				fundsTxHash := fundsTx.Hash()
+				closedFunds.Put(fundsTxHash[:], []byte{0x01})
+			case CLOSEDEPOCHBLOCK_BUCKET:
+				closedEpochBlocks.Put([]byte{'c'}, []byte{0x01})
+			}
+			return nil
+		})
+		db.Close()
+
+		store, err := NewBoltStore(dbname)
+		if err == nil {
+			store.Close()
+			t.Errorf("Database with an undecodable entry in %v was migrated.\n", bucket)
+			continue
+		}
+		if bucket != CLOSEDFUNDS_BUCKET && !strings.Contains(err.Error(), "(63)") {
+			t.Errorf("Migration error does not name the entry of %v: %v\n", bucket, err)
+		}
+		if fundsTxHash := fundsTx.Hash(); bucket == CLOSEDFUNDS_BUCKET && !strings.Contains(err.Error(), fmt.Sprintf("%x", fundsTxHash)) {
+			t.Errorf("Migration error does not name the tx: %v\n", err)
+		}
+	}
+}
+
+//Databases of the versions before the binary encoding hold gob encodings of the blocks and txs. Their chain is
+//dropped, such that the node can synchronize it again.
+func TestMigrateGobEncodedDatabase(t *testing.T) {
+	const dbname = "gob_migration_test.db"
+	defer os.Remove(dbname)
+
+	//The fields of the blocks and epoch blocks as they were gob encoded.
+	type gobBlock struct {
+		Header      byte
+		ShardId     int
+		Hash        [32]byte
+		PrevHash    [32]byte
+		Height      uint32
+		Beneficiary [64]byte
+		Timestamp   int64
+		FundsTxData [][32]byte
+	}
+	type gobEpochBlock struct {
+		Header          byte
+		Hash            [32]byte
+		PrevShardHashes [][32]byte
+		Height          uint32
+		NofShards       int
+	}
+	gobEncode := func(value interface{}) []byte {
+		buffer := new(bytes.Buffer)
+		gob.NewEncoder(buffer).Encode(value)
+		return buffer.Bytes()
+	}
+
+	block := gobBlock{Hash: [32]byte{'b'}, PrevHash: [32]byte{'e'}, Height: 2, Timestamp: 1546300800, FundsTxData: [][32]byte{{'f'}}}
+	epochBlock := gobEpochBlock{Hash: [32]byte{'e'}, Height: 1, NofShards: 1}
+
+	//Layout of the versions before the binary encoding, without schema version.
+	db, err := bolt.Open(dbname, 0600, nil)
+	if err != nil {
+		t.Fatalf("Database could not be created: %v\n", err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		closedBlocks, _ := tx.CreateBucket([]byte(CLOSEDBLOCKS_BUCKET))
+		closedBlocks.Put(block.Hash[:], gobEncode(block))
+		lastClosedBlock, _ := tx.CreateBucket([]byte(LASTCLOSEDBLOCK_BUCKET))
+		lastClosedBlock.Put(block.Hash[:], gobEncode(block))
+		closedFunds, _ := tx.CreateBucket([]byte(CLOSEDFUNDS_BUCKET))
+		closedFunds.Put([]byte{'f'}, gobEncode(struct{ Amount, Fee uint64 }{100, 1}))
+		closedEpochBlocks, _ := tx.CreateBucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
+		closedEpochBlocks.Put(epochBlock.Hash[:], gobEncode(epochBlock))
+		closedEpochBlocks.Put([]byte("firstepochblock"), gobEncode(epochBlock))
+		genesis, _ := tx.CreateBucket([]byte(GENESIS_BUCKET))
+		genesis.Put([]byte("genesis"), gobEncode(struct{ RootAddress [64]byte }{[64]byte{'r'}}))
+		return nil
+	})
+	db.Close()
+
+	store, err := NewBoltStore(dbname)
+	if err != nil {
+		t.Fatalf("Database in the gob encoding could not be migrated: %v\n", err)
+	}
+	defer store.Close()
+
+	if store.ReadSchemaVersion() != schemaVersion() {
+		t.Errorf("Database has schema version %d instead of %d\n", store.ReadSchemaVersion(), schemaVersion())
+	}
+	if b := store.ReadLastClosedBlock(); b != nil {
+		t.Errorf("Gob-encoded last closed block was kept: %v\n", b)
+	}
+	if b := store.ReadClosedEpochBlock(epochBlock.Hash); b != nil {
+		t.Errorf("Gob-encoded epoch block was kept: %v\n", b)
+	}
+	if genesis, _ := store.ReadGenesis(); genesis != nil {
+		t.Errorf("Gob-encoded genesis was kept: %v\n", genesis)
+	}
+
+	//The node synchronizes the chain into the migrated database.
+	b1 := protocol.NewBlock([32]byte{}, 1)
+	b1.Hash = b1.HashBlock()
+	if err := store.WriteClosedBlock(b1); err != nil {
+		t.Fatalf("Block could not be written to the migrated database: %v\n", err)
+	}
+	if b := store.ReadClosedBlockByHeight(1); b == nil || b.Hash != b1.Hash {
+		t.Errorf("Block of the migrated database is not indexed at height 1: %v\n", b)
+	}
+}
+
+//A database which cannot be migrated is wiped before it is opened.
+func TestWipeBoltStore(t *testing.T) {
+	const dbname = "wipe_test.db"
+	defer os.Remove(dbname)
+
+	if err := WipeBoltStore(dbname); err != nil {
+		t.Errorf("Wiping a missing database failed: %v\n", err)
+	}
+
+	db, err := bolt.Open(dbname, 0600, nil)
+	if err != nil {
+		t.Fatalf("Database could not be created: %v\n", err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		closedBlocks, _ := tx.CreateBucket([]byte(CLOSEDBLOCKS_BUCKET))
+		return closedBlocks.Put([]byte{'c'}, []byte{0x01})
+	})
+	db.Close()
+
+	if store, err := NewBoltStore(dbname); err == nil {
+		store.Close()
+		t.Fatal("Database with an undecodable block was migrated.")
+	}
+
+	if err := WipeBoltStore(dbname); err != nil {
+		t.Fatalf("Database could not be wiped: %v\n", err)
+	}
+
+	store, err := NewBoltStore(dbname)
+	if err != nil {
+		t.Fatalf("Wiped database could not be opened: %v\n", err)
+	}
+	defer store.Close()
+
+	if store.ReadSchemaVersion() != schemaVersion() {
+		t.Errorf("Wiped database has schema version %d instead of %d\n", store.ReadSchemaVersion(), schemaVersion())
+	}
+	store.db.View(func(tx *bolt.Tx) error {
+		if encoded := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET)).Get([]byte{'c'}); encoded != nil {
+			t.Errorf("Block was not wiped: %x\n", encoded)
+		}
+		return nil
+	})
+}
+
+//A new database starts with the current schema version.
+func TestNewDatabaseSchemaVersion(t *testing.T) {
+	if boltStore.ReadSchemaVersion() != schemaVersion() {
+		t.Errorf("New database has schema version %d instead of %d\n", boltStore.ReadSchemaVersion(), schemaVersion())
+	}
+}
+
+//Txs with a higher fee rate come first, the txs of a sender in the order of their txCnt.
+func TestMemPoolPriority(t *testing.T) {
+	store := NewMemoryStore()
+
+	a0, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	a1, _ := protocol.ConstrFundsTx(0x01, 100, 50, 1, accA.Address, accB.Address, &PrivKeyA, nil)
+	b0, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+	for _, tx := range []*protocol.FundsTx{a1, b0, a0} {
+		store.WriteOpenTx(tx)
+	}
+
+	txs := store.ReadAllOpenTxs()
+	if len(txs) != 3 || txs[0] != protocol.Transaction(b0) || txs[1] != protocol.Transaction(a0) || txs[2] != protocol.Transaction(a1) {
+		t.Errorf("Open txs were not prioritized: %v\n", txs)
+	}
+}
+
+func TestMemPoolLimits(t *testing.T) {
+	defer func(capacity int, accountCapacity int) {
+		MemPoolCapacity, MemPoolAccountCapacity = capacity, accountCapacity
+	}(MemPoolCapacity, MemPoolAccountCapacity)
+	MemPoolCapacity, MemPoolAccountCapacity = 3, 2
+
+	store := NewMemoryStore()
+
+	a0, _ := protocol.ConstrFundsTx(0x01, 100, 5, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	a1, _ := protocol.ConstrFundsTx(0x01, 100, 5, 1, accA.Address, accB.Address, &PrivKeyA, nil)
+	a2, _ := protocol.ConstrFundsTx(0x01, 100, 5, 2, accA.Address, accB.Address, &PrivKeyA, nil)
+	b0, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+	lowFeeTx, _ := protocol.ConstrFundsTx(0x01, 100, 0, 0, rootAcc.Address, accA.Address, &RootPrivKey, nil)
+	root0, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, rootAcc.Address, accA.Address, &RootPrivKey, nil)
+	root1, _ := protocol.ConstrFundsTx(0x01, 100, 100, 1, rootAcc.Address, accA.Address, &RootPrivKey, nil)
+
+	if !store.WriteOpenTx(a0).Admitted || !store.WriteOpenTx(a1).Admitted {
+		t.Fatal("Txs below the limits were rejected.")
+	}
+	if admission := store.WriteOpenTx(a2); admission.Admitted {
+		t.Errorf("Tx above the limit of its account was admitted: %v\n", admission)
+	}
+	if !store.WriteOpenTx(b0).Admitted {
+		t.Fatal("Tx of another account was rejected.")
+	}
+
+	//The mempool is full.
+	if admission := store.WriteOpenTx(lowFeeTx); admission.Admitted {
+		t.Errorf("Tx with the lowest fee rate was admitted to a full mempool: %v\n", admission)
+	}
+	if admission := store.WriteOpenTx(root0); !admission.Admitted || len(admission.Evicted) != 1 || admission.Evicted[0] != b0.Hash() {
+		t.Errorf("Tx with the lowest fee rate was not evicted: %v\n", admission)
+	}
+	//a0 has the lowest fee rate left, but only the last tx of the nonce sequence can be evicted.
+	if admission := store.WriteOpenTx(root1); !admission.Admitted || len(admission.Evicted) != 1 || admission.Evicted[0] != a1.Hash() {
+		t.Errorf("Last tx of the nonce sequence was not evicted: %v\n", admission)
+	}
+	if store.GetMemPoolSize() != 3 || store.ReadOpenTx(a0.Hash()) == nil || store.ReadOpenTx(b0.Hash()) != nil {
+		t.Errorf("Mempool holds the wrong txs: %v\n", store.ReadAllOpenTxs())
+	}
+
+	admissions := store.ReadAdmissions()
+	if len(admissions) != 7 || admissions[6].TxHash != root1.Hash() {
+		t.Errorf("Admission decisions were not kept: %v\n", admissions)
+	}
+}
+
+//The signature a ConfigTx is sharded by is no account, the tx is not counted against it.
+func TestMemPoolTxAccount(t *testing.T) {
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	configTx, _ := protocol.ConstrConfigTx(0x01, 1, 1000, 1, 0, &RootPrivKey)
+
+	if account, ok := txAccount(fundsTx); !ok || account != accA.Address {
+		t.Errorf("FundsTx is not counted against its sender: %x\n", account[0:8])
+	}
+	if account, ok := txAccount(configTx); ok {
+		t.Errorf("ConfigTx is counted against its signature (%x).\n", account[0:8])
+	}
+}
+
+func TestMemPoolReplaceByFee(t *testing.T) {
+	store := NewMemoryStore()
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	sameFeeTx, _ := protocol.ConstrFundsTx(0x01, 200, 10, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	replacement, _ := protocol.ConstrFundsTx(0x01, 100, 11, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	nextTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 1, accA.Address, accB.Address, &PrivKeyA, nil)
+
+	store.WriteOpenTx(tx)
+	store.WriteOpenTx(nextTx)
+	if admission := store.WriteOpenTx(sameFeeTx); admission.Admitted {
+		t.Errorf("Tx without a higher fee replaced the pending one: %v\n", admission)
+	}
+
+	admission := store.WriteOpenTx(replacement)
+	if !admission.Admitted || !admission.Replaced || len(admission.Evicted) != 1 || admission.Evicted[0] != tx.Hash() {
+		t.Errorf("Tx with a higher fee did not replace the pending one: %v\n", admission)
+	}
+	if store.ReadOpenTx(tx.Hash()) != nil || store.ReadOpenTx(replacement.Hash()) == nil {
+		t.Error("Replaced tx was not removed from the mempool.")
+	}
+	if store.GetMemPoolSize() != 2 || store.ReadOpenTx(nextTx.Hash()) == nil {
+		t.Error("Tx with another nonce was replaced.")
+	}
+}
+
+func TestMemPoolQueue(t *testing.T) {
+	store := NewMemoryStore()
+	store.WriteAccountNonces(map[[64]byte]uint32{accA.Address: 5})
+
+	tx5, _ := protocol.ConstrFundsTx(0x01, 100, 1, 5, accA.Address, accB.Address, &PrivKeyA, nil)
+	tx6, _ := protocol.ConstrFundsTx(0x01, 100, 1, 6, accA.Address, accB.Address, &PrivKeyA, nil)
+	tx7, _ := protocol.ConstrFundsTx(0x01, 100, 1, 7, accA.Address, accB.Address, &PrivKeyA, nil)
+	//The nonce of accounts without a nonce cannot be checked.
+	txB, _ := protocol.ConstrFundsTx(0x01, 100, 1, 3, accB.Address, accA.Address, &PrivKeyB, nil)
+
+	if admission := store.WriteOpenTx(tx7); !admission.Admitted || !admission.Queued {
+		t.Errorf("Tx with a nonce gap was not queued: %v\n", admission)
+	}
+	if admission := store.WriteOpenTx(txB); !admission.Admitted || admission.Queued {
+		t.Errorf("Tx of an account without a nonce was queued: %v\n", admission)
+	}
+	if admission := store.WriteOpenTx(tx6); !admission.Queued || admission.Promoted != 0 {
+		t.Errorf("Tx with a nonce gap was not queued: %v\n", admission)
+	}
+	if len(store.ReadPendingTxs()) != 1 || len(store.ReadQueuedTxs()) != 2 {
+		t.Errorf("Expected 1 pending and 2 queued txs, got %d and %d\n", len(store.ReadPendingTxs()), len(store.ReadQueuedTxs()))
+	}
+
+	//Filling the gap promotes the queued txs.
+	if admission := store.WriteOpenTx(tx5); admission.Queued || admission.Promoted != 2 {
+		t.Errorf("Queued txs were not promoted: %v\n", admission)
+	}
+	pending := store.ReadPendingTxs()
+	if len(pending) != 4 || len(store.ReadQueuedTxs()) != 0 {
+		t.Fatalf("Expected 4 pending and 0 queued txs, got %d and %d\n", len(pending), len(store.ReadQueuedTxs()))
+	}
+	var nonces []uint32
+	for _, tx := range pending {
+		if fundsTx := tx.(*protocol.FundsTx); fundsTx.From == accA.Address {
+			nonces = append(nonces, fundsTx.TxCnt)
+		}
+	}
+	if len(nonces) != 3 || nonces[0] != 5 || nonces[1] != 6 || nonces[2] != 7 {
+		t.Errorf("Pending txs are not in the order of their nonce: %v\n", nonces)
+	}
+}
+
+func TestMemPoolEvictsQueuedTxs(t *testing.T) {
+	store := NewMemoryStore()
+
+	prevCapacity := MemPoolCapacity
+	defer func() { MemPoolCapacity = prevCapacity }()
+	store.WriteAccountNonces(map[[64]byte]uint32{accA.Address: 0})
+	MemPoolCapacity = 2
+
+	pendingTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	queuedTx, _ := protocol.ConstrFundsTx(0x01, 100, 50, 5, accA.Address, accB.Address, &PrivKeyA, nil)
+	tx, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+
+	store.WriteOpenTx(pendingTx)
+	store.WriteOpenTx(queuedTx)
+	admission := store.WriteOpenTx(tx)
+	if !admission.Admitted || len(admission.Evicted) != 1 || admission.Evicted[0] != queuedTx.Hash() {
+		t.Errorf("Queued tx was not evicted first: %v\n", admission)
+	}
+}
+
+//Invalid txs leave the open mempool, they neither count against its capacity nor are they evicted.
+func TestMemPoolInvalidTxs(t *testing.T) {
+	store := NewMemoryStore()
+
+	prevCapacity := MemPoolCapacity
+	defer func() { MemPoolCapacity = prevCapacity }()
+	MemPoolCapacity = 2
+
+	invalidTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	tx, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+	rootTx, _ := protocol.ConstrFundsTx(0x01, 100, 10, 0, rootAcc.Address, accA.Address, &RootPrivKey, nil)
+
+	store.WriteOpenTx(invalidTx)
+	store.WriteOpenTx(tx)
+	store.WriteINVALIDOpenTx(invalidTx, nil)
+	if store.ReadOpenTx(invalidTx.Hash()) != nil || store.ReadINVALIDOpenTx(invalidTx.Hash()) == nil || store.GetMemPoolSize() != 1 {
+		t.Error("Invalid tx was not moved out of the open mempool.")
+	}
+
+	if admission := store.WriteOpenTx(rootTx); !admission.Admitted || len(admission.Evicted) != 0 {
+		t.Errorf("Invalid tx was counted against the capacity: %v\n", admission)
+	}
+
+	store.DeleteOpenTx(rootTx)
+	store.WriteOpenTx(invalidTx)
+	if store.ReadOpenTx(invalidTx.Hash()) == nil || store.ReadINVALIDOpenTx(invalidTx.Hash()) != nil {
+		t.Error("Readmitted tx was not removed from the invalid mempool.")
+	}
+}
+
+func TestTxStatus(t *testing.T) {
+	for _, store := range []Store{NewMemoryStore(), boltStore} {
+		store.WriteAccountNonces(map[[64]byte]uint32{accA.Address: 0})
+
+		pendingTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+		queuedTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 2, accA.Address, accB.Address, &PrivKeyA, nil)
+		rejectedTx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+		includedTx, _ := protocol.ConstrFundsTx(0x01, 200, 1, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+		unknownTx, _ := protocol.ConstrFundsTx(0x01, 300, 1, 0, accB.Address, accA.Address, &PrivKeyB, nil)
+
+		store.WriteOpenTx(pendingTx)
+		store.WriteOpenTx(queuedTx)
+		store.WriteINVALIDOpenTx(rejectedTx, protocol.NewTxRejection(protocol.TXREJECT_NONCE_MISMATCH, "Sender txCnt does not match."))
+		store.WriteClosedTx(includedTx, 12)
+
+		for _, expected := range []protocol.TxStatus{
+			{TxHash: pendingTx.Hash(), Status: protocol.TXSTATUS_PENDING},
+			{TxHash: queuedTx.Hash(), Status: protocol.TXSTATUS_QUEUED},
+			{TxHash: rejectedTx.Hash(), Status: protocol.TXSTATUS_REJECTED, Code: protocol.TXREJECT_NONCE_MISMATCH, Reason: "Sender txCnt does not match."},
+			{TxHash: includedTx.Hash(), Status: protocol.TXSTATUS_INCLUDED, Height: 12},
+			{TxHash: unknownTx.Hash(), Status: protocol.TXSTATUS_UNKNOWN},
+		} {
+			if status := store.ReadTxStatus(expected.TxHash); *status != expected {
+				t.Errorf("Wrong status %v, expected %v\n", status, expected)
+			}
+		}
+
+		store.WriteAccountNonces(nil)
+		store.DeleteOpenTx(pendingTx)
+		store.DeleteOpenTx(queuedTx)
+		store.DeleteINVALIDOpenTx(rejectedTx)
+		store.DeleteClosedTx(includedTx)
+	}
+}
+
+func TestReadOnlyBoltStore(t *testing.T) {
+	const dbname = "readonly_test.db"
+	defer os.Remove(dbname)
+
+	if _, err := NewReadOnlyBoltStore(dbname); err == nil {
+		t.Error("Missing database was opened read-only.")
+	}
+
+	store, err := NewBoltStore(dbname)
+	if err != nil {
+		t.Fatalf("Database could not be created: %v\n", err)
+	}
+	genesis := protocol.NewGenesis(accA.Address, [crypto.COMM_KEY_LENGTH]byte{})
+	store.WriteGenesis(&genesis)
+	store.Close()
+
+	readOnlyStore, err := NewReadOnlyBoltStore(dbname)
+	if err != nil {
+		t.Fatalf("Database could not be opened read-only: %v\n", err)
+	}
+	defer readOnlyStore.Close()
+
+	if readGenesis, _ := readOnlyStore.ReadGenesis(); readGenesis == nil || readGenesis.Hash() != genesis.Hash() {
+		t.Errorf("Genesis could not be read: %v\n", readGenesis)
+	}
+	if err := readOnlyStore.WriteGenesis(&genesis); err == nil {
+		t.Error("Read-only store was written.")
+	}
+}
+
+//Several stores can be used in one process, e.g. to verify a chain, they do not share their chain.
+func TestStoresAreIndependent(t *testing.T) {
+	store, otherStore := NewMemoryStore(), NewMemoryStore()
+
+	genesis := protocol.NewGenesis(accA.Address, [crypto.COMM_KEY_LENGTH]byte{})
+	store.WriteGenesis(&genesis)
+	block := protocol.NewBlock([32]byte{0x01}, 2)
+	block.Hash = block.HashBlock()
+	store.WriteLastClosedBlock(block)
+	tx, _ := protocol.ConstrFundsTx(0x01, 100, 1, 0, accA.Address, accB.Address, &PrivKeyA, nil)
+	store.WriteOpenTx(tx)
+
+	if readGenesis, _ := store.ReadGenesis(); readGenesis == nil || readGenesis.Hash() != genesis.Hash() {
+		t.Errorf("Genesis was not written: %v\n", readGenesis)
+	}
+	if otherGenesis, _ := otherStore.ReadGenesis(); otherGenesis != nil ||
+		otherStore.ReadLastClosedBlock() != nil ||
+		otherStore.ReadOpenTx(tx.Hash()) != nil ||
+		boltStore.ReadOpenTx(tx.Hash()) != nil {
+		t.Error("Stores share their chain.")
+	}
+
+	store.DeleteAll()
+	if readGenesis, _ := store.ReadGenesis(); readGenesis != nil || store.ReadLastClosedBlock() != nil || store.GetMemPoolSize() != 0 {
+		t.Error("Memory store was not deleted.")
+	}
+}
+
+func writeJournal(t *testing.T, bucket string, hash [32]byte, value []byte) {
+	err := boltStore.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put(hash[:], value)
+	})
+	if err != nil {
+		t.Fatalf("Journal entry could not be written: %v\n", err)
+	}
+}
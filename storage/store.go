@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+/**
+	A store persists the blocks, epoch blocks, transactions and the genesis of a node. The BoltStore keeps them in a
+	bolt database, the MemoryStore in memory (e.g., in tests). Read functions return nil if the requested entry is not
+	in the store or cannot be decoded.
+
+	The interface makes the backend of the chain swappable, it does not isolate nodes from each other. The accounts are
+	not kept per store: ReadAccount and WriteAccount operate on State. The root keys, the stashes, the relative state and
+	the shard assignment are package variables as well, and the miner and p2p keep the store of the node in a package
+	variable. Several stores can be opened in one process, e.g. to verify, import or export a chain, but running
+	several nodes in one process is not supported.
+ */
+type Store interface {
+	ReadOpenBlock(hash [32]byte) *protocol.Block
+	ReadClosedBlock(hash [32]byte) *protocol.Block
+	ReadLastClosedBlock() *protocol.Block
+	WriteOpenBlock(block *protocol.Block) error
+	WriteClosedBlock(block *protocol.Block) error
+	WriteLastClosedBlock(block *protocol.Block) error
+	DeleteOpenBlock(hash [32]byte) error
+	DeleteClosedBlock(hash [32]byte) error
+	DeleteLastClosedBlock(hash [32]byte) error
+	DeleteAllLastClosedBlock() error
+	//Closed blocks are indexed by height, the index lists the last block written at a height and follows rollbacks.
+	ReadClosedBlockByHeight(height uint32) *protocol.Block
+	//Calls fn for the closed blocks from height from to height to (both included), downwards if from is greater than
+	//to, until fn returns false. Heights without a block are skipped.
+	ForEachClosedBlock(from uint32, to uint32, fn func(block *protocol.Block) bool)
+	//Deletes the closed blocks below the given height together with their txs, receipts and the state trie nodes only
+	//their roots reach. The hashes of the pruned blocks are kept, such that requests for them can be told apart from
+	//requests for unknown blocks.
+	PruneClosedBlocks(height uint32) (int, error)
+	//Blocks below the prune height have been pruned, 0 if the store holds the whole chain.
+	ReadPruneHeight() uint32
+	ReadPrunedBlockHeight(hash [32]byte) (uint32, bool)
+
+	ReadOpenEpochBlock(hash [32]byte) *protocol.EpochBlock
+	ReadClosedEpochBlock(hash [32]byte) *protocol.EpochBlock
+	ReadLastClosedEpochBlock() *protocol.EpochBlock
+	ReadFirstEpochBlock() (*protocol.EpochBlock, error)
+	WriteOpenEpochBlock(epochBlock *protocol.EpochBlock) error
+	WriteClosedEpochBlock(epochBlock *protocol.EpochBlock) error
+	WriteLastClosedEpochBlock(epochBlock *protocol.EpochBlock) error
+	WriteFirstEpochBlock(epochBlock *protocol.EpochBlock) error
+	DeleteOpenEpochBlock(hash [32]byte) error
+	DeleteClosedEpochBlock(hash [32]byte) error
+	DeleteAllLastClosedEpochBlock() error
+	ReadClosedEpochBlockByHeight(height uint32) *protocol.EpochBlock
+	ForEachClosedEpochBlock(from uint32, to uint32, fn func(epochBlock *protocol.EpochBlock) bool)
+
+	//Open txs are held in the mempool until they are included in a block, invalid ones in a separate mempool. The
+	//mempool is limited, the admission decisions are kept for diagnostics. Open txs are pending if they continue the
+	//nonce sequence of their account in the nonces written by the miner, otherwise they are queued until the missing txs
+	//arrive.
+	ReadOpenTx(hash [32]byte) protocol.Transaction
+	ReadAllOpenTxs() []protocol.Transaction
+	ReadPendingTxs() []protocol.Transaction
+	ReadQueuedTxs() []protocol.Transaction
+	GetMemPoolSize() int
+	SetTxVerifier(verifier func(transaction protocol.Transaction) error)
+	WriteAccountNonces(nonces map[[64]byte]uint32)
+	WriteOpenTx(transaction protocol.Transaction) *Admission
+	WriteVerifiedOpenTx(transaction protocol.Transaction) *Admission
+	ReadAdmissions() []*Admission
+	DeleteOpenTx(transaction protocol.Transaction)
+	ReadINVALIDOpenTx(hash [32]byte) protocol.Transaction
+	WriteINVALIDOpenTx(transaction protocol.Transaction, reason error)
+	DeleteINVALIDOpenTx(transaction protocol.Transaction)
+	DeleteExpiredOpenTxs(height uint32) []protocol.Transaction
+	DeleteStaleOpenTxs(isStale func(tx protocol.Transaction) bool) []protocol.Transaction
+
+	//Closed txs are listed in the history of the accounts they concern at the height of their block.
+	ReadClosedTx(hash [32]byte) protocol.Transaction
+	WriteClosedTx(transaction protocol.Transaction, height uint32) error
+	DeleteClosedTx(transaction protocol.Transaction) error
+	//Returns at most limit entries of the history of the account, newest first, after skipping offset entries.
+	ReadAccountTxs(address [64]byte, offset int, limit int) []*AccountTx
+	//The height of the block which includes the tx, false if the tx is not listed in the history of any account.
+	ReadClosedTxHeight(hash [32]byte) (uint32, bool)
+	ReadTxStatus(hash [32]byte) *protocol.TxStatus
+
+	ReadReceipt(txHash [32]byte) *protocol.Receipt
+	//The receipt is listed in the histories of its receivers at the height of the block which credits it.
+	WriteReceipt(receipt *protocol.Receipt, height uint32) error
+	DeleteReceipt(txHash [32]byte) error
+	ReadFraudProof(hash [32]byte) *protocol.FraudProof
+	WriteFraudProof(proof *protocol.FraudProof) error
+
+	WriteStateTrie(nodes protocol.MemoryTrieDatabase) error
+	ReadStateTrieNode(hash [32]byte) []byte
+	ReadStateTrieAccount(root [32]byte, address [64]byte) (*protocol.Account, error)
+	ReadStateTrieProof(root [32]byte, address [64]byte) ([][]byte, error)
+
+	ReadAccount(pubKey [64]byte) (*protocol.Account, error)
+	ReadRootAccount(pubKey [64]byte) (*protocol.Account, error)
+	WriteAccount(account *protocol.Account)
+	DeleteAccount(address [64]byte)
+
+	ReadGenesis() (*protocol.Genesis, error)
+	WriteGenesis(genesis *protocol.Genesis) error
+
+	//Deletes the chain and the mempools.
+	DeleteAll() error
+	Close() error
+}
+
+//An entry of the tx history of an account.
+type AccountTx struct {
+	Height uint32
+	TxHash [32]byte
+}
+
+//The addresses in whose history the tx is listed.
+func txAddresses(transaction protocol.Transaction) [][64]byte {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil || txType.Addresses == nil {
+		return nil
+	}
+	return txType.Addresses(transaction)
+}
+
+//The receivers of a receipt, the sender is listed in the history of the shard which includes the tx.
+func receiptAddresses(receipt *protocol.Receipt) (addresses [][64]byte) {
+	for _, recipient := range receipt.Tx.Recipients() {
+		addresses = append(addresses, recipient.To)
+	}
+	return addresses
+}
+
+//A closed tx is included, even if this miner rejected it before, e.g. since its nonce did not match yet.
+func readTxStatus(store Store, pool *memPool, hash [32]byte) *protocol.TxStatus {
+	if tx := store.ReadClosedTx(hash); tx != nil {
+		height, _ := store.ReadClosedTxHeight(hash)
+		return &protocol.TxStatus{TxHash: hash, Status: protocol.TXSTATUS_INCLUDED, Height: height}
+	}
+	if status := pool.readMemPoolStatus(hash); status != nil {
+		return status
+	}
+	return &protocol.TxStatus{TxHash: hash, Status: protocol.TXSTATUS_UNKNOWN}
+}
+
+//Deletes the txs of a block which is pruned and the receipts it credited.
+func deleteBlockTxs(store Store, block *protocol.Block) {
+	for _, txType := range protocol.TxTypes() {
+		for _, txHash := range block.TxHashes(txType.Id) {
+			if tx := store.ReadClosedTx(txHash); tx != nil {
+				store.DeleteClosedTx(tx)
+			}
+		}
+	}
+	for _, receipt := range block.Receipts {
+		store.DeleteReceipt(receipt.Tx.Hash())
+	}
+}
+
+//The state roots which stay provable once the closed blocks below the given height are pruned: the roots of the kept
+//blocks and of all epoch blocks.
+func keptStateRoots(store Store, height uint32) (roots [][32]byte) {
+	store.ForEachClosedBlock(height, math.MaxUint32, func(block *protocol.Block) bool {
+		roots = append(roots, block.MerklePatriciaRoot)
+		return true
+	})
+	store.ForEachClosedEpochBlock(0, math.MaxUint32, func(epochBlock *protocol.EpochBlock) bool {
+		roots = append(roots, epochBlock.MerklePatriciaRoot)
+		return true
+	})
+	return roots
+}
+
+//The trie nodes which are only reachable from the roots of pruned blocks. Nodes are shared between the tries of
+//different heights, the ones still reachable from a kept root stay.
+func prunableTrieNodes(db protocol.TrieNodeDatabase, prunedRoots [][32]byte, keptRoots [][32]byte) (hashes [][32]byte) {
+	kept := make(map[[32]byte]bool)
+	for _, root := range keptRoots {
+		protocol.WalkTrie(db, root, func(hash [32]byte) bool {
+			if kept[hash] {
+				return false
+			}
+			kept[hash] = true
+			return true
+		})
+	}
+
+	pruned := make(map[[32]byte]bool)
+	for _, root := range prunedRoots {
+		protocol.WalkTrie(db, root, func(hash [32]byte) bool {
+			if kept[hash] || pruned[hash] {
+				return false
+			}
+			pruned[hash] = true
+			hashes = append(hashes, hash)
+			return true
+		})
+	}
+	return hashes
+}
+
+//stateAccounts serves the accounts of State to the stores.
+type stateAccounts struct{}
+
+func (stateAccounts) ReadAccount(pubKey [64]byte) (acc *protocol.Account, err error) {
+	if acc = State[pubKey]; acc != nil {
+		return acc, nil
+	} else {
+		return nil, errors.New(fmt.Sprintf("Acc (%x) not in the state.", pubKey[0:8]))
+	}
+}
+
+func (accounts stateAccounts) ReadRootAccount(pubKey [64]byte) (acc *protocol.Account, err error) {
+	if IsRootKey(pubKey) {
+		acc, err = accounts.ReadAccount(pubKey)
+		return acc, err
+	}
+
+	return nil, err
+}
+
+func (stateAccounts) WriteAccount(account *protocol.Account) {
+	State[account.Address] = account
+}
+
+func (stateAccounts) DeleteAccount(address [64]byte) {
+	delete(State, address)
+}
+
+//Txs are encoded with their type in front wherever the type is not given by the bucket, such that they can be decoded.
+func encodeTypedTx(transaction protocol.Transaction) []byte {
+	return append([]byte{transaction.Type()}, transaction.Encode()...)
+}
+
+func decodeTypedTx(encoded []byte) protocol.Transaction {
+	if len(encoded) == 0 {
+		return nil
+	}
+
+	txType := protocol.GetTxType(encoded[0])
+	if txType == nil {
+		return nil
+	}
+
+	tx, err := txType.Decode(encoded[1:])
+	if err != nil {
+		return nil
+	}
+	return tx
+}
+
+var (
+	_ Store = (*BoltStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
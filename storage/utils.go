@@ -20,22 +20,22 @@ func IsRootKey(pubKey [64]byte) bool {
 }
 
 //Get all pubKeys involved in ContractTx, FundsTx of a given block
-func GetTxPubKeys(block *protocol.Block) (txPubKeys [][64]byte) {
-	txPubKeys = GetContractTxPubKeys(block.ContractTxData)
-	txPubKeys = append(txPubKeys, GetFundsTxPubKeys(block.FundsTxData)...)
+func GetTxPubKeys(store Store, block *protocol.Block) (txPubKeys [][64]byte) {
+	txPubKeys = GetContractTxPubKeys(store, block.TxHashes(protocol.CONTRACTTX_TYPE))
+	txPubKeys = append(txPubKeys, GetFundsTxPubKeys(store, block.TxHashes(protocol.FUNDSTX_TYPE))...)
 
 	return txPubKeys
 }
 
 //Get all pubKey involved in ContractTx
-func GetContractTxPubKeys(contractTxData [][32]byte) (contractTxPubKeys [][64]byte) {
+func GetContractTxPubKeys(store Store, contractTxData [][32]byte) (contractTxPubKeys [][64]byte) {
 	for _, txHash := range contractTxData {
 		var tx protocol.Transaction
 		var contractTx *protocol.ContractTx
 
-		tx = ReadClosedTx(txHash)
+		tx = store.ReadClosedTx(txHash)
 		if tx == nil {
-			tx = ReadOpenTx(txHash)
+			tx = store.ReadOpenTx(txHash)
 		}
 
 		contractTx = tx.(*protocol.ContractTx)
@@ -47,19 +47,21 @@ func GetContractTxPubKeys(contractTxData [][32]byte) (contractTxPubKeys [][64]by
 }
 
 //Get all pubKey involved in FundsTx
-func GetFundsTxPubKeys(fundsTxData [][32]byte) (fundsTxPubKeys [][64]byte) {
+func GetFundsTxPubKeys(store Store, fundsTxData [][32]byte) (fundsTxPubKeys [][64]byte) {
 	for _, txHash := range fundsTxData {
 		var tx protocol.Transaction
 		var fundsTx *protocol.FundsTx
 
-		tx = ReadClosedTx(txHash)
+		tx = store.ReadClosedTx(txHash)
 		if tx == nil {
-			tx = ReadOpenTx(txHash)
+			tx = store.ReadOpenTx(txHash)
 		}
 
 		fundsTx = tx.(*protocol.FundsTx)
 		fundsTxPubKeys = append(fundsTxPubKeys, fundsTx.From)
-		fundsTxPubKeys = append(fundsTxPubKeys, fundsTx.To)
+		for _, recipient := range fundsTx.Recipients() {
+			fundsTxPubKeys = append(fundsTxPubKeys, recipient.To)
+		}
 	}
 
 	return fundsTxPubKeys
@@ -111,3 +113,16 @@ func ApplyRelativeState(statePrev map[[64]byte]*protocol.Account, stateRel map[[
 	}
 	return statePrev
 }
+
+//Reverts the relative account adjustments of ApplyRelativeState, e.g. if a state transition turned out to be invalid.
+//Accounts are not deleted, since they might have been changed in the meantime.
+func RevertRelativeState(stateNow map[[64]byte]*protocol.Account, stateRel map[[64]byte]*protocol.RelativeAccount) (stateReverted map[[64]byte]*protocol.Account) {
+	for krel, accRel := range stateRel {
+		if accNow, ok := stateNow[krel]; ok {
+			accNow.Balance = accNow.Balance - uint64(accRel.Balance)
+			accNow.TxCnt = accNow.TxCnt - uint32(accRel.TxCnt)
+			accNow.StakingBlockHeight = accNow.StakingBlockHeight - uint32(accRel.StakingBlockHeight)
+		}
+	}
+	return stateNow
+}
@@ -14,13 +14,13 @@ func TestSerializeHashContent(t *testing.T) {
 
 	hash := protocol.SerializeHashContent(data)
 
-  if fmt.Sprintf("%x", hash) != "ca4510738395af1429224dd785675309c344b2b549632e20275c69b15ed1d210" {
-		t.Errorf("Error serializing: %x != %v\n", hash, "ca4510738395af1429224dd785675309c344b2b549632e20275c69b15ed1d210")
+  if fmt.Sprintf("%x", hash) != "8b0a2385d83c8bf7be27e59996f7d881d3bf1fc6606f81ce600b753ad94192a2" {
+		t.Errorf("Error serializing: %x != %v\n", hash, "8b0a2385d83c8bf7be27e59996f7d881d3bf1fc6606f81ce600b753ad94192a2")
 	}
 }
 
 func TestGetAccount(t *testing.T) {
-	acc, err := ReadAccount(accA.Address)
+	acc, err := boltStore.ReadAccount(accA.Address)
 
 	if acc != accA && err == nil {
 		t.Errorf("Error fetching account from state: %x\n", accA.Address)
@@ -31,7 +31,7 @@ func TestGetAccount(t *testing.T) {
 	}
 
 	var nilAddress [64]byte
-	acc, err = ReadAccount(nilAddress)
+	acc, err = boltStore.ReadAccount(nilAddress)
 
 	if acc != nil || err.Error() != fmt.Sprintf("Acc (%x) not in the state.", nilAddress[0:8]) {
 		t.Errorf("Error fetching account from state: %x\n", nilAddress)
@@ -39,14 +39,14 @@ func TestGetAccount(t *testing.T) {
 }
 
 func TestGetRootAccount(t *testing.T) {
-	root, err := ReadRootAccount(rootAcc.Address)
+	root, err := boltStore.ReadRootAccount(rootAcc.Address)
 
 	if root == nil || err != nil {
 		t.Errorf("Error fetching root account from state: %x\n", rootAcc.Address)
 	}
 
 	var nilAddress [64]byte
-	root, err = ReadRootAccount(nilAddress)
+	root, err = boltStore.ReadRootAccount(nilAddress)
 
 	if root != nil {
 		t.Errorf("Error fetching account from state: %x\n", nilAddress)
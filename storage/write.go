@@ -1,49 +1,69 @@
 package storage
 
 import (
+	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
 
-func WriteOpenBlock(block *protocol.Block) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteOpenBlock(block *protocol.Block) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENBLOCKS_BUCKET))
 		return b.Put(block.Hash[:], block.Encode())
 	})
 }
 
-func WriteOpenEpochBlock(epochBlock *protocol.EpochBlock) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteOpenEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(OPENEPOCHBLOCK_BUCKET))
 		return b.Put(epochBlock.Hash[:], epochBlock.Encode())
 	})
 }
 
-func WriteClosedBlock(block *protocol.Block) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(CLOSEDBLOCKS_BUCKET))
-		return b.Put(block.Hash[:], block.Encode())
+func (store *BoltStore) WriteClosedBlock(block *protocol.Block) error {
+	return store.writeIndexedBlock(CLOSEDBLOCKS_BUCKET, BLOCKHEIGHTS_BUCKET, block.Hash, block.Height, block.Encode())
+}
+
+func (store *BoltStore) WriteClosedEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.writeIndexedBlock(CLOSEDEPOCHBLOCK_BUCKET, EPOCHBLOCKHEIGHTS_BUCKET, epochBlock.Hash, epochBlock.Height, epochBlock.Encode())
+}
+
+//Fraud proofs are kept, such that slashing proofs which refer to them can be verified.
+func (store *BoltStore) WriteFraudProof(proof *protocol.FraudProof) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(FRAUDPROOFS_BUCKET))
+		proofHash := proof.Hash()
+		return b.Put(proofHash[:], proof.Encode())
 	})
 }
 
-func WriteClosedEpochBlock(epochBlock *protocol.EpochBlock) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
-		return b.Put(epochBlock.Hash[:], epochBlock.Encode())
+//Receipts are keyed by the hash of their FundsTx, every cross-shard transfer can only be credited once.
+func (store *BoltStore) WriteReceipt(receipt *protocol.Receipt, height uint32) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RECEIPTS_BUCKET))
+		txHash := receipt.Tx.Hash()
+		if err := b.Put(txHash[:], receipt.Encode()); err != nil {
+			return err
+		}
+
+		index := tx.Bucket([]byte(ACCOUNTTXS_BUCKET))
+		for _, address := range receiptAddresses(receipt) {
+			if err := index.Put(accountTxKey(address, height, txHash), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
-func WriteFirstEpochBlock(epochBlock *protocol.EpochBlock) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteFirstEpochBlock(epochBlock *protocol.EpochBlock) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(CLOSEDEPOCHBLOCK_BUCKET))
 		return b.Put([]byte("firstepochblock"), epochBlock.Encode())
 	})
 }
 
-func WriteINVALIDOpenTx(transaction protocol.Transaction) {
-	txINVALIDMemPool[transaction.Hash()] = transaction
-}
-
 func WriteToReceivedStash(block *protocol.Block) {
 	ReceivedBlockStash = append(ReceivedBlockStash, block)
 	//When lenght of stash is > 50 --> Remove first added Block
@@ -62,53 +82,72 @@ func BlockAlreadyInStash(slice []*protocol.Block, newBlockHash [32]byte) bool {
 	return false
 }
 
-func WriteLastClosedEpochBlock(epochBlock *protocol.EpochBlock) (err error) {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteLastClosedEpochBlock(epochBlock *protocol.EpochBlock) (err error) {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDEPOCHBLOCK_BUCKET))
 		return b.Put(epochBlock.Hash[:], epochBlock.Encode())
 	})
 }
 
-func WriteLastClosedBlock(block *protocol.Block) (err error) {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteLastClosedBlock(block *protocol.Block) (err error) {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LASTCLOSEDBLOCK_BUCKET))
 		return b.Put(block.Hash[:], block.Encode())
 	})
 }
 
-//Changing the "tx" shortcut here and using "transaction" to distinguish between bolt's transactions
-func WriteOpenTx(transaction protocol.Transaction) {
-	memPoolMutex.Lock()
-	defer memPoolMutex.Unlock()
-	txMemPool[transaction.Hash()] = transaction
-}
-
-func WriteClosedTx(transaction protocol.Transaction) error {
-	var bucket string
-	switch transaction.(type) {
-	case *protocol.FundsTx:
-		bucket = CLOSEDFUNDS_BUCKET
-	case *protocol.ContractTx:
-		bucket = CLOSEDACCS_BUCKET
-	case *protocol.ConfigTx:
-		bucket = CLOSEDCONFIGS_BUCKET
-	case *protocol.StakeTx:
-		bucket = CLOSEDSTAKES_BUCKET
+//A failed write to the journal only loses the changes on restart, the in-memory mempool is not affected.
+func (store *BoltStore) writeJournal(changes []journalChange) {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		for _, change := range changes {
+			b := tx.Bucket([]byte(change.bucket))
+			if change.transaction == nil {
+				if err := b.Delete(change.hash[:]); err != nil {
+					return err
+				}
+			} else if err := b.Put(change.hash[:], encodeTypedTx(change.transaction)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Printf("%d mempool changes could not be journaled: %v\n", len(changes), err)
+	}
+}
+
+//The tx and its entries in the account histories are written in one transaction.
+func (store *BoltStore) WriteClosedTx(transaction protocol.Transaction, height uint32) error {
+	txType := protocol.TypeOf(transaction)
+	if txType == nil {
+		return errors.New(fmt.Sprintf("Transaction type %d is not registered.", transaction.Type()))
 	}
+	bucket := txType.Bucket
 
 	hash := transaction.Hash()
-	return db.Update(func(tx *bolt.Tx) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
-		return b.Put(hash[:], transaction.Encode())
+		if err := b.Put(hash[:], transaction.Encode()); err != nil {
+			return err
+		}
+
+		index := tx.Bucket([]byte(ACCOUNTTXS_BUCKET))
+		for _, address := range txAddresses(transaction) {
+			if err := index.Put(accountTxKey(address, height, hash), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
-func WriteAccount(account *protocol.Account) {
-	State[account.Address] = account
+func WriteShardStateSnapshot(shardState *protocol.ShardState) {
+	shardStateSnapshot = shardState
 }
 
-func WriteGenesis(genesis *protocol.Genesis) error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (store *BoltStore) WriteGenesis(genesis *protocol.Genesis) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(GENESIS_BUCKET))
 		return b.Put([]byte("genesis"), genesis.Encode())
 	})
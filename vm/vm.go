@@ -1,7 +1,6 @@
 package vm
 
 import (
-	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -1065,17 +1064,11 @@ func (vm *VM) Exec(trace bool) bool {
 				return false
 			}
 
-			r, s := new(big.Int), new(big.Int)
-
-			sig := vm.context.GetSig()
-			r.SetBytes(sig[:32])
-			s.SetBytes(sig[32:])
-
 			var pubKeySig [64]byte
 			copy(pubKeySig[:], signature[:])
-			pubKey := crypto.GetPubKeyFromAddress(pubKeySig)
 
-			result := ecdsa.Verify(pubKey, hash, r, s)
+			//The signature scheme is given by the tag of the address.
+			result := crypto.VerifySignature(pubKeySig, hash, vm.context.GetSig())
 			vm.evaluationStack.Push(BoolToByteArray(result))
 
 		case ERRHALT: